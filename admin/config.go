@@ -0,0 +1,6 @@
+package admin
+
+// Config contains user configurable options for the admin API
+type Config struct {
+	Listen string // Address to listen on, e.g. localhost:9618; empty disables the admin API
+}
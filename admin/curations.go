@@ -0,0 +1,114 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// curationResponse is a curation with its ID
+type curationResponse struct {
+	ID string `json:"id"`
+	*indexer.Curation
+}
+
+// handleCurations manages search boosts and pins, like the curation
+// command. GET /curations lists them, PUT /curations[?id=<id>] stores the
+// curation in the JSON body, replacing the one with the given ID, and
+// DELETE /curations?id=<id> removes one.
+func (s *Server) handleCurations(w http.ResponseWriter, r *http.Request) {
+	if s.indexer == nil {
+		writeError(w, http.StatusNotImplemented, "curations are not supported by the index backend")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.listCurations(w, r)
+	case http.MethodPut:
+		s.putCuration(w, r)
+	case http.MethodDelete:
+		s.deleteCuration(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "use GET, PUT or DELETE")
+	}
+}
+
+// listCurations writes all curations
+func (s *Server) listCurations(w http.ResponseWriter, r *http.Request) {
+	curations, err := s.indexer.Curations(r.Context())
+	if err != nil {
+		log.WithError(err).Error("Error listing curations")
+		writeError(w, http.StatusInternalServerError, "error listing curations")
+		return
+	}
+
+	response := make([]curationResponse, len(curations))
+	for n := range curations {
+		response[n] = curationResponse{
+			ID:       curations[n].ID,
+			Curation: &curations[n],
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// putCuration stores the curation in the request body
+func (s *Server) putCuration(w http.ResponseWriter, r *http.Request) {
+	c := new(indexer.Curation)
+	if err := json.NewDecoder(r.Body).Decode(c); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid curation: "+err.Error())
+		return
+	}
+
+	hash, err := crawler.NormalizeHash(c.Hash)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid hash: "+c.Hash)
+		return
+	}
+	c.Hash = hash
+	c.ID = r.URL.Query().Get("id")
+
+	if err := c.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.ID, err = s.indexer.AddCuration(r.Context(), c)
+	if err != nil {
+		log.WithError(err).Error("Error storing curation")
+		writeError(w, http.StatusInternalServerError, "error storing curation")
+		return
+	}
+
+	log.WithField("curation", c.String()).Info("Stored curation")
+
+	writeJSON(w, http.StatusOK, &curationResponse{
+		ID:       c.ID,
+		Curation: c,
+	})
+}
+
+// deleteCuration removes the curation with the given ID
+func (s *Server) deleteCuration(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "no id given")
+		return
+	}
+
+	if err := s.indexer.RemoveCuration(r.Context(), id); err != nil {
+		log.WithError(err).WithField("id", id).Warn("Error removing curation")
+		writeError(w, http.StatusInternalServerError, "error removing curation")
+		return
+	}
+
+	log.WithField("id", id).Info("Removed curation")
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"removed": id,
+	})
+}
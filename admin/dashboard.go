@@ -0,0 +1,160 @@
+package admin
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs-search/ipfs-search/worker"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// dashboardInterval is the time between samples of queues and workers
+	dashboardInterval = 10 * time.Second
+
+	// dashboardSamples is the number of samples kept: an hour
+	dashboardSamples = 360
+
+	// dashboardErrors is the number of recent warnings and errors kept
+	dashboardErrors = 50
+
+	// dashboardJobs is the number of crawl jobs shown
+	dashboardJobs = 10
+)
+
+// sample is the state of queues and workers at a moment
+type sample struct {
+	Time   time.Time         `json:"time"`
+	Queues map[string]int    `json:"queues"` // Messages by queue
+	Done   map[string]uint64 `json:"done"`   // Works finished by worker group, since starting
+}
+
+// history keeps the most recent samples, oldest first
+type history struct {
+	mu      sync.Mutex
+	samples []sample
+}
+
+// add keeps a sample, dropping the oldest when full
+func (h *history) add(s sample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.samples = append(h.samples, s)
+	if len(h.samples) > dashboardSamples {
+		h.samples = h.samples[len(h.samples)-dashboardSamples:]
+	}
+}
+
+// list returns a copy of the samples kept
+func (h *history) list() []sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]sample(nil), h.samples...)
+}
+
+// takeSample returns the current depth of the queues consumed by worker
+// groups and the works they finished; queues which can't be inspected are
+// left out
+func (s *Server) takeSample() sample {
+	current := sample{
+		Time:   time.Now().UTC(),
+		Queues: make(map[string]int, len(s.groups)),
+		Done:   make(map[string]uint64, len(s.groups)),
+	}
+
+	for name, group := range s.groups {
+		current.Done[name] = group.Stats().Done
+
+		state, err := s.connection.Inspect(name)
+		if err != nil {
+			log.WithError(err).WithField("queue", name).Debug("Error inspecting queue for dashboard")
+			continue
+		}
+		current.Queues[name] = state.Messages
+	}
+
+	return current
+}
+
+// sample records samples for the dashboard until the context is cancelled
+func (s *Server) sample(ctx context.Context) {
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+
+	for {
+		s.history.add(s.takeSample())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dashboardResponse is the data shown by the dashboard
+type dashboardResponse struct {
+	Workers map[string]worker.Stats `json:"workers"`
+	Queues  map[string]*queue.State `json:"queues"`
+	History []sample                `json:"history"`
+	Errors  []logEntry              `json:"errors"`
+	Jobs    []indexer.JobStatus     `json:"jobs,omitempty"` // Unless the index backend can't tell
+}
+
+// handleDashboardData returns the data of the dashboard, as
+// GET /dashboard.json
+func (s *Server) handleDashboardData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	response := &dashboardResponse{
+		Workers: make(map[string]worker.Stats, len(s.groups)),
+		Queues:  make(map[string]*queue.State, len(s.groups)),
+		History: s.history.list(),
+		Errors:  s.errors.list(),
+	}
+
+	for name, group := range s.groups {
+		response.Workers[name] = group.Stats()
+
+		state, err := s.connection.Inspect(name)
+		if err != nil {
+			log.WithError(err).WithField("queue", name).Warn("Error inspecting queue")
+			continue
+		}
+		response.Queues[name] = state
+	}
+
+	if s.indexer != nil {
+		jobs, err := s.indexer.Jobs(r.Context(), dashboardJobs)
+		if err != nil {
+			log.WithError(err).Warn("Error getting crawl jobs")
+		}
+		response.Jobs = jobs
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleDashboard serves the dashboard, as GET /
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(dashboardHTML))
+}
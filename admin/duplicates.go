@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"github.com/ipfs-search/ipfs-search/crawler"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// defaultDuplicates is the amount of near-duplicates returned by default
+const defaultDuplicates = 10
+
+// handleDuplicates lists files with text nearly identical to that of a
+// file, closest first, as GET /duplicates?hash=<hash>[&size=<count>]
+func (s *Server) handleDuplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	if s.indexer == nil {
+		writeError(w, http.StatusNotImplemented, "near-duplicates are not supported by the index backend")
+		return
+	}
+
+	hash, err := crawler.NormalizeHash(r.URL.Query().Get("hash"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	size, err := parseLimit(r, "size", defaultDuplicates)
+	if err != nil || size == 0 {
+		writeError(w, http.StatusBadRequest, "invalid size")
+		return
+	}
+
+	duplicates, err := s.indexer.NearDuplicates(r.Context(), hash, int(size))
+	if err != nil {
+		log.WithError(err).WithField("hash", hash).Error("Error finding near-duplicates")
+		writeError(w, http.StatusInternalServerError, "error finding near-duplicates")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, duplicates)
+}
@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// logEntry is a warning or error logged by the crawler
+type logEntry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// recentErrors is a log hook keeping the most recent warnings and errors,
+// so operators can see what goes wrong without access to the logs
+type recentErrors struct {
+	size int
+
+	mu      sync.Mutex
+	entries []logEntry // Oldest first
+}
+
+// Levels returns the levels of entries kept
+func (e *recentErrors) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel}
+}
+
+// Fire keeps an entry, dropping the oldest when full
+func (e *recentErrors) Fire(entry *log.Entry) error {
+	fields := make(map[string]string, len(entry.Data))
+	for k, v := range entry.Data {
+		fields[k] = fmt.Sprint(v)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.entries = append(e.entries, logEntry{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+		Fields:  fields,
+	})
+	if len(e.entries) > e.size {
+		e.entries = e.entries[len(e.entries)-e.size:]
+	}
+
+	return nil
+}
+
+// list returns the entries kept, most recent first
+func (e *recentErrors) list() []logEntry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	entries := make([]logEntry, len(e.entries))
+	for n, entry := range e.entries {
+		entries[len(entries)-1-n] = entry
+	}
+
+	return entries
+}
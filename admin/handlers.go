@@ -0,0 +1,191 @@
+package admin
+
+import (
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs-search/ipfs-search/worker"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+// statusResponse describes workers and the queues they consume
+type statusResponse struct {
+	Workers map[string]worker.Stats `json:"workers"`
+	Queues  map[string]*queue.State `json:"queues"`
+}
+
+// handleStatus returns worker and queue stats, as GET /status
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	response := &statusResponse{
+		Workers: make(map[string]worker.Stats, len(s.groups)),
+		Queues:  make(map[string]*queue.State, len(s.groups)),
+	}
+
+	for _, name := range s.groupNames() {
+		response.Workers[name] = s.groups[name].Stats()
+
+		state, err := s.connection.Inspect(name)
+		if err != nil {
+			log.WithError(err).WithField("queue", name).Warn("Error inspecting queue")
+			writeError(w, http.StatusBadGateway, "error inspecting queue")
+			return
+		}
+		response.Queues[name] = state
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleHashes queues hashes for crawling with highest priority, as
+// POST /hashes?hash=<hash>[&hash=<hash>...]
+func (s *Server) handleHashes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	hashes := r.URL.Query()["hash"]
+	if len(hashes) == 0 {
+		writeError(w, http.StatusBadRequest, "no hash given")
+		return
+	}
+
+	// Validate all hashes before queueing any
+	normalized := make([]string, len(hashes))
+	for n, hash := range hashes {
+		var err error
+		if normalized[n], err = crawler.NormalizeHash(hash); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid hash: "+hash)
+			return
+		}
+	}
+
+	for _, hash := range normalized {
+		args := &crawler.Args{
+			Hash:       hash,
+			Provenance: &indexer.Provenance{Source: crawler.SourceAdd},
+		}
+
+		if err := crawler.Publish(r.Context(), s.hashQueue, args, 9); err != nil {
+			log.WithError(err).WithField("hash", hash).Error("Error queueing hash")
+			writeError(w, http.StatusInternalServerError, "error queueing hash")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string][]string{
+		"queued": normalized,
+	})
+}
+
+// handlePause stops consuming queues, as POST /pause[?group=<queue>];
+// prefetched messages are returned to the queues
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, true)
+}
+
+// handleResume resumes consuming queues, as POST /resume[?group=<queue>]
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, false)
+}
+
+// setPaused pauses or resumes selected worker groups
+func (s *Server) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	groups, ok := s.selectGroups(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown group")
+		return
+	}
+
+	stats := make(map[string]worker.Stats, len(groups))
+	for name, group := range groups {
+		if paused {
+			group.Pause()
+		} else {
+			group.Resume()
+		}
+		stats[name] = group.Stats()
+
+		log.WithFields(log.Fields{
+			"group":  name,
+			"paused": paused,
+		}).Info("Changed worker group state")
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// parseLimit returns the named parameter as worker count, or current when
+// it is not given
+func parseLimit(r *http.Request, name string, current uint) (uint, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return current, nil
+	}
+
+	limit, err := strconv.ParseUint(value, 10, 32)
+	return uint(limit), err
+}
+
+// handleWorkers changes the limits of worker groups, as
+// POST /workers?group=<queue>[&min=<count>][&max=<count>]
+func (s *Server) handleWorkers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	if r.URL.Query().Get("group") == "" {
+		writeError(w, http.StatusBadRequest, "no group given")
+		return
+	}
+
+	groups, ok := s.selectGroups(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown group")
+		return
+	}
+
+	stats := make(map[string]worker.Stats, len(groups))
+	for name, group := range groups {
+		current := group.Stats()
+
+		min, err := parseLimit(r, "min", current.Min)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid min")
+			return
+		}
+
+		max, err := parseLimit(r, "max", current.Max)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid max")
+			return
+		}
+
+		if err := group.SetLimits(min, max); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		stats[name] = group.Stats()
+
+		log.WithFields(log.Fields{
+			"group": name,
+			"min":   min,
+			"max":   max,
+		}).Info("Changed worker limits")
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,142 @@
+package admin
+
+// dashboardHTML is the operator dashboard: a single page without external
+// resources, refreshing from /dashboard.json
+const dashboardHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ipfs-search crawler</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h2 { margin-top: 1.5em; font-size: 1.1em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.2em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+td.n { text-align: right; }
+.graphs { display: flex; flex-wrap: wrap; gap: 1.5em; }
+.graph { font-size: 0.85em; }
+svg { background: #f6f6f6; display: block; }
+polyline { fill: none; stroke: #36c; stroke-width: 1.5; }
+.error { color: #b00; }
+.warning { color: #a60; }
+.fields { color: #666; font-size: 0.85em; }
+</style>
+</head>
+<body>
+<h1>ipfs-search crawler</h1>
+<p id="updated"></p>
+<h2>Workers</h2>
+<table id="workers"></table>
+<h2>Queues</h2>
+<table id="queues"></table>
+<h2>Throughput (items/s)</h2>
+<div class="graphs" id="throughput"></div>
+<h2>Queue depth</h2>
+<div class="graphs" id="depth"></div>
+<h2>Jobs</h2>
+<table id="jobs"></table>
+<h2>Recent errors</h2>
+<table id="errors"></table>
+<script>
+function esc(s) {
+	return String(s).replace(/[&<>"]/g, function(c) {
+		return {"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;"}[c];
+	});
+}
+
+function row(cells, header) {
+	var tag = header ? "th" : "td";
+	return "<tr>" + cells.map(function(c) {
+		var n = typeof c === "number";
+		return "<" + tag + (n ? ' class="n"' : "") + ">" + esc(c) + "</" + tag + ">";
+	}).join("") + "</tr>";
+}
+
+function graph(title, points) {
+	var w = 360, h = 80, max = 0;
+	points.forEach(function(p) { max = Math.max(max, p[1]); });
+	var t0 = points.length ? points[0][0] : 0;
+	var t1 = points.length ? points[points.length - 1][0] : 1;
+	var span = Math.max(t1 - t0, 1);
+	var line = points.map(function(p) {
+		var x = (p[0] - t0) / span * w;
+		var y = h - (max ? p[1] / max * (h - 4) : 0);
+		return x.toFixed(1) + "," + y.toFixed(1);
+	}).join(" ");
+	var last = points.length ? points[points.length - 1][1] : 0;
+	return '<div class="graph">' + esc(title) + ": " + esc(Math.round(last * 100) / 100) +
+		" (max " + esc(Math.round(max * 100) / 100) + ")" +
+		'<svg width="' + w + '" height="' + h + '"><polyline points="' + line + '"/></svg></div>';
+}
+
+function render(d) {
+	document.getElementById("updated").textContent = "Updated " + new Date().toLocaleTimeString();
+
+	var groups = Object.keys(d.workers || {}).sort();
+	document.getElementById("workers").innerHTML = row(["Group", "Running", "Min", "Max", "Utilization", "Done", "State"], true) +
+		groups.map(function(g) {
+			var s = d.workers[g];
+			var state = s.paused ? "paused" : (s.throttled ? "throttled" : "running");
+			return row([g, s.running, s.min, s.max, Math.round((s.utilization || 0) * 100) + "%", s.done || 0, state]);
+		}).join("");
+
+	var queues = Object.keys(d.queues || {}).sort();
+	document.getElementById("queues").innerHTML = row(["Queue", "Messages", "Consumers"], true) +
+		queues.map(function(q) {
+			var s = d.queues[q];
+			return row([q, s.messages, s.consumers]);
+		}).join("");
+
+	var history = (d.history || []).map(function(s) {
+		s.t = new Date(s.time).getTime() / 1000;
+		return s;
+	});
+
+	document.getElementById("throughput").innerHTML = groups.map(function(g) {
+		var points = [];
+		for (var i = 1; i < history.length; i++) {
+			var dt = history[i].t - history[i - 1].t;
+			var done = (history[i].done[g] || 0) - (history[i - 1].done[g] || 0);
+			if (dt > 0 && done >= 0) {
+				points.push([history[i].t, done / dt]);
+			}
+		}
+		return graph(g, points);
+	}).join("");
+
+	document.getElementById("depth").innerHTML = queues.map(function(q) {
+		return graph(q, history.filter(function(s) { return q in s.queues; }).map(function(s) {
+			return [s.t, s.queues[q]];
+		}));
+	}).join("");
+
+	var jobs = d.jobs || [];
+	document.getElementById("jobs").innerHTML = jobs.length ? row(["Job", "Files", "Directories", "Invalid", "Last seen"], true) +
+		jobs.map(function(j) {
+			var c = j.counts || {};
+			return row([j.job, c.file || 0, c.directory || 0, c.invalid || 0, new Date(j.last_seen).toLocaleString()]);
+		}).join("") : row(["No jobs"]);
+
+	var errors = d.errors || [];
+	document.getElementById("errors").innerHTML = errors.length ? errors.map(function(e) {
+		var fields = Object.keys(e.fields || {}).sort().map(function(k) {
+			return k + "=" + e.fields[k];
+		}).join(" ");
+		return '<tr class="' + esc(e.level) + '"><td>' + esc(new Date(e.time).toLocaleTimeString()) +
+			"</td><td>" + esc(e.level) + "</td><td>" + esc(e.message) +
+			' <span class="fields">' + esc(fields) + "</span></td></tr>";
+	}).join("") : row(["No errors"]);
+}
+
+function refresh() {
+	fetch("dashboard.json").then(function(r) { return r.json(); }).then(render).catch(function(err) {
+		document.getElementById("updated").textContent = "Error refreshing: " + err;
+	});
+}
+
+refresh();
+setInterval(refresh, 10000);
+</script>
+</body>
+</html>
+`
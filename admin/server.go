@@ -0,0 +1,148 @@
+/*
+Package admin implements an HTTP API for controlling a running crawler, so
+operators can add hashes, inspect and pause crawling, change the amount
+of workers and curate search results without restarting it. It has no
+authentication, so it should only listen on trusted interfaces.
+*/
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs-search/ipfs-search/worker"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// shutdownTimeout is the time allowed for open requests to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// Server serves the admin API
+type Server struct {
+	config     *Config
+	connection *queue.Connection
+	hashQueue  *queue.Queue
+	indexer    *indexer.Indexer              // Optional, nil disables curations
+	groups     map[string]*worker.Autoscaler // Worker groups by queue name
+	mux        *http.ServeMux
+	history    *history      // Samples shown by the dashboard
+	errors     *recentErrors // Warnings and errors shown by the dashboard
+}
+
+// New returns a new admin server controlling worker groups consuming the
+// queues they are keyed by. Without indexer, e.g. for index backends only
+// supported for crawling, curations can not be managed.
+func New(config *Config, connection *queue.Connection, indexer *indexer.Indexer, groups map[string]*worker.Autoscaler) (*Server, error) {
+	hashQueue, err := connection.NewChannelQueue("hashes")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		config:     config,
+		connection: connection,
+		hashQueue:  hashQueue,
+		indexer:    indexer,
+		groups:     groups,
+		mux:        http.NewServeMux(),
+		history:    new(history),
+		errors:     &recentErrors{size: dashboardErrors},
+	}
+	log.AddHook(s.errors)
+
+	s.mux.HandleFunc("/", s.handleDashboard)
+	s.mux.HandleFunc("/dashboard.json", s.handleDashboardData)
+
+	s.mux.HandleFunc("/status", s.handleStatus)
+	s.mux.HandleFunc("/hashes", s.handleHashes)
+	s.mux.HandleFunc("/pause", s.handlePause)
+	s.mux.HandleFunc("/resume", s.handleResume)
+	s.mux.HandleFunc("/workers", s.handleWorkers)
+	s.mux.HandleFunc("/curations", s.handleCurations)
+	s.mux.HandleFunc("/duplicates", s.handleDuplicates)
+
+	return s, nil
+}
+
+// writeJSON writes v as JSON response with given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithError(err).Warn("Error writing response")
+	}
+}
+
+// writeError writes an error message as JSON response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{
+		"error": message,
+	})
+}
+
+// selectGroups returns the worker groups named by the group parameter, or
+// all groups when it is not given
+func (s *Server) selectGroups(r *http.Request) (map[string]*worker.Autoscaler, bool) {
+	names := r.URL.Query()["group"]
+	if len(names) == 0 {
+		return s.groups, true
+	}
+
+	selected := make(map[string]*worker.Autoscaler, len(names))
+	for _, name := range names {
+		group, ok := s.groups[name]
+		if !ok {
+			return nil, false
+		}
+		selected[name] = group
+	}
+
+	return selected, true
+}
+
+// groupNames returns the names of all worker groups, sorted
+func (s *Server) groupNames() []string {
+	names := make([]string, 0, len(s.groups))
+	for name := range s.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Serve listens for requests until the context is cancelled
+func (s *Server) Serve(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:    s.config.Listen,
+		Handler: s.mux,
+	}
+
+	go s.sample(ctx)
+
+	errc := make(chan error, 1)
+	go func() {
+		log.WithField("address", s.config.Listen).Info("Admin API listening")
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Query analytics modes
+const (
+	AnalyticsOff       = ""          // No query analytics
+	AnalyticsAggregate = "aggregate" // Exact counts by query
+	AnalyticsPrivate   = "private"   // Counts with noise, rare queries suppressed
+)
+
+// maxQueryLength limits the runes of queries counted, longer queries are
+// cut off
+const maxQueryLength = 100
+
+// pathQuery is counted instead of queries for IPFS paths, which identify
+// the content sought
+const pathQuery = "<ipfs path>"
+
+// redacted is logged instead of queries in private mode
+const redacted = "<redacted>"
+
+// sensitivity is how much a single search changes the counters: it adds
+// one to a query and one to an outcome
+const sensitivity = 2
+
+// analytics aggregates search counts by normalized query and outcome
+// between flushes. Queries are only ever held as counters, so no log of
+// individual searches exists; in private mode the counters written are
+// (epsilon, delta)-differentially private.
+type analytics struct {
+	mode      string
+	epsilon   float64
+	threshold int64
+
+	queries  *counter // By normalized query
+	outcomes *counter // results or no-results
+
+	mu    sync.Mutex
+	start time.Time // Start of the current period
+	rand  *rand.Rand
+}
+
+// newAnalytics returns query analytics for configuration, nil when off
+func newAnalytics(config *Config) (*analytics, error) {
+	switch config.QueryAnalytics {
+	case AnalyticsOff:
+		return nil, nil
+	case AnalyticsAggregate:
+	case AnalyticsPrivate:
+		if config.PrivacyEpsilon <= 0 {
+			return nil, fmt.Errorf("privacy epsilon should be positive, not %v", config.PrivacyEpsilon)
+		}
+	default:
+		return nil, fmt.Errorf("unknown query analytics mode '%s'", config.QueryAnalytics)
+	}
+
+	return &analytics{
+		mode:      config.QueryAnalytics,
+		epsilon:   config.PrivacyEpsilon,
+		threshold: config.PrivacyThreshold,
+		queries:   newCounter(),
+		outcomes:  newCounter(),
+		start:     time.Now(),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// private returns whether queries should not be disclosed beyond noisy
+// aggregates, also in logs
+func (a *analytics) private() bool {
+	return a != nil && a.mode == AnalyticsPrivate
+}
+
+// loggable returns the query to log, which is redacted in private mode
+func (a *analytics) loggable(query string) string {
+	if a.private() {
+		return redacted
+	}
+	return query
+}
+
+// normalizeQuery returns the key queries are counted by: lowercased, with
+// single spaces and cut off at maxQueryLength. In private mode, all IPFS
+// paths are counted as one.
+func (a *analytics) normalizeQuery(query string) string {
+	if _, ok := detectPath(query); ok && a.private() {
+		return pathQuery
+	}
+
+	query = strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	if runes := []rune(query); len(runes) > maxQueryLength {
+		query = string(runes[:maxQueryLength])
+	}
+
+	return query
+}
+
+// record counts a search for query with its total number of results
+func (a *analytics) record(query string, total int64) {
+	if a == nil {
+		return
+	}
+
+	a.queries.add(a.normalizeQuery(query))
+
+	if total > 0 {
+		a.outcomes.add("results")
+	} else {
+		a.outcomes.add("no-results")
+	}
+}
+
+// laplace returns noise drawn from the Laplace distribution with scale
+func (a *analytics) laplace(scale float64) float64 {
+	a.mu.Lock()
+	u := a.rand.Float64() - 0.5
+	a.mu.Unlock()
+
+	return -scale * math.Copysign(1, u) * math.Log(1-2*math.Abs(u))
+}
+
+// scale returns the scale of the Laplace noise added to counts. As every
+// search adds one to a query and one to an outcome, noise of scale
+// sensitivity/epsilon makes the noisy counters epsilon-differentially
+// private per search.
+func (a *analytics) scale() float64 {
+	return sensitivity / a.epsilon
+}
+
+// delta returns the probability that a query searched only once is
+// written, as its noisy count reaches the threshold. Leaving out queries
+// below the threshold discloses which queries were searched at all with
+// that probability, so each period's counters are (epsilon, delta)- rather
+// than epsilon-differentially private per search.
+func (a *analytics) delta() float64 {
+	// Written when the noise is at least threshold - 1.5, after rounding
+	return 0.5 * math.Exp(-math.Max(float64(a.threshold)-1.5, 0)/a.scale())
+}
+
+// release returns the count to write: exact, or in private mode with
+// Laplace noise and false when it falls below threshold
+func (a *analytics) release(count int64, threshold int64) (int64, bool) {
+	if !a.private() {
+		return count, true
+	}
+
+	noisy := int64(math.Round(float64(count) + a.laplace(a.scale())))
+	if noisy < threshold || noisy <= 0 {
+		return 0, false
+	}
+
+	return noisy, true
+}
+
+// take returns the rollups of the counts since the previous call, resetting
+// them
+func (a *analytics) take(now time.Time) []indexer.Rollup {
+	a.mu.Lock()
+	start := a.start
+	a.start = now
+	a.mu.Unlock()
+
+	newRollup := func(dimension, value string, count int64) indexer.Rollup {
+		return indexer.Rollup{
+			Timestamp: start.UTC().Format(time.RFC3339),
+			Period:    now.Sub(start).Round(time.Second).String(),
+			Dimension: dimension,
+			Value:     value,
+			Count:     count,
+		}
+	}
+
+	var rollups []indexer.Rollup
+
+	for query, count := range a.queries.take() {
+		if count, ok := a.release(count, a.threshold); ok {
+			rollups = append(rollups, newRollup("query", query, count))
+		}
+	}
+
+	// Outcomes have too few values to identify searches by
+	for outcome, count := range a.outcomes.take() {
+		if count, ok := a.release(count, 0); ok {
+			rollups = append(rollups, newRollup("search", outcome, count))
+		}
+	}
+
+	return rollups
+}
+
+// flushAnalytics writes query counts to the stats index
+func (s *Server) flushAnalytics(ctx context.Context) error {
+	rollups := s.analytics.take(time.Now())
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	log.WithField("rollups", len(rollups)).Debug("Writing query analytics")
+
+	return s.indexer.WriteRollups(ctx, rollups)
+}
+
+// writeAnalytics periodically writes query counts to the stats index until
+// the context is cancelled
+func (s *Server) writeAnalytics(ctx context.Context) {
+	ticker := time.NewTicker(s.config.AnalyticsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.flushAnalytics(ctx); err != nil {
+				log.WithError(err).Error("Error writing query analytics")
+			}
+		}
+	}
+}
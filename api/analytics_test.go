@@ -0,0 +1,144 @@
+package api
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestNewAnalytics(t *testing.T) {
+	tests := []struct {
+		mode    string
+		epsilon float64
+		enabled bool
+		valid   bool
+	}{
+		{AnalyticsOff, 0, false, true},
+		{AnalyticsAggregate, 0, true, true},
+		{AnalyticsPrivate, 1, true, true},
+		{AnalyticsPrivate, 0, false, false},
+		{"raw", 1, false, false},
+	}
+
+	for _, test := range tests {
+		a, err := newAnalytics(&Config{QueryAnalytics: test.mode, PrivacyEpsilon: test.epsilon})
+		if (err == nil) != test.valid {
+			t.Errorf("newAnalytics(%q, %v) error = %v, valid %v", test.mode, test.epsilon, err, test.valid)
+		}
+		if (a != nil) != test.enabled {
+			t.Errorf("newAnalytics(%q, %v) = %v, enabled %v", test.mode, test.epsilon, a, test.enabled)
+		}
+	}
+}
+
+// recordSearches records searches for queries, with total results
+func recordSearches(a *analytics, searches map[string]int, total int64) {
+	for query, n := range searches {
+		for ; n > 0; n-- {
+			a.record(query, total)
+		}
+	}
+}
+
+func TestAnalyticsAggregate(t *testing.T) {
+	a, _ := newAnalytics(&Config{QueryAnalytics: AnalyticsAggregate})
+	start := a.start
+
+	recordSearches(a, map[string]int{"Cat  Pictures": 2, "cat pictures": 1}, 5)
+	recordSearches(a, map[string]int{"QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u": 1}, 0)
+
+	rollups := a.take(start.Add(time.Hour))
+
+	got := make(map[string]int64)
+	for _, r := range rollups {
+		got[r.Dimension+":"+r.Value] = r.Count
+		if r.Period != "1h0m0s" {
+			t.Errorf("rollup period %s, want 1h0m0s", r.Period)
+		}
+	}
+
+	want := map[string]int64{
+		"query:cat pictures": 3,
+		"query:qmwatwq7fvpp2efgu71ukfnqhyxdyh566qy47cnjdgvs8u": 1,
+		"search:results":    3,
+		"search:no-results": 1,
+	}
+	for key, count := range want {
+		if got[key] != count {
+			t.Errorf("count of %s = %d, want %d", key, got[key], count)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("rollups %v, want %v", got, want)
+	}
+
+	if rollups := a.take(start.Add(2 * time.Hour)); len(rollups) != 0 {
+		t.Errorf("rollups after take = %v, want none", rollups)
+	}
+
+	if q := a.loggable("cat"); q != "cat" {
+		t.Errorf("loggable() = %s, want query", q)
+	}
+}
+
+func TestAnalyticsPrivate(t *testing.T) {
+	a, _ := newAnalytics(&Config{QueryAnalytics: AnalyticsPrivate, PrivacyEpsilon: 1, PrivacyThreshold: 10})
+	a.rand = rand.New(rand.NewSource(1))
+
+	recordSearches(a, map[string]int{
+		"popular": 1000,
+		"rare":    1,
+		"QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u":             20,
+		"/ipfs/QmWATWQ7fVPP2EFGu71UkfnqhYXDYH566qy47CnJDgvs8u/a.txt": 20,
+	}, 1)
+
+	got := make(map[string]int64)
+	for _, r := range a.take(time.Now()) {
+		got[r.Dimension+":"+r.Value] = r.Count
+	}
+
+	// Noise of scale 1 stays well within 20 at this seed
+	for key, count := range map[string]int64{"query:popular": 1000, "query:" + pathQuery: 40, "search:results": 1041} {
+		if got[key] < count-20 || got[key] > count+20 {
+			t.Errorf("count of %s = %d, want about %d", key, got[key], count)
+		}
+	}
+
+	if _, ok := got["query:rare"]; ok {
+		t.Errorf("rare query disclosed: %v", got)
+	}
+	if len(got) != 3 {
+		t.Errorf("rollups %v, want popular, paths and results", got)
+	}
+
+	if q := a.loggable("rare"); q != redacted {
+		t.Errorf("loggable() = %s, want %s", q, redacted)
+	}
+}
+
+func TestAnalyticsNoiseScale(t *testing.T) {
+	a, _ := newAnalytics(&Config{QueryAnalytics: AnalyticsPrivate, PrivacyEpsilon: 0.5, PrivacyThreshold: 10})
+	a.rand = rand.New(rand.NewSource(1))
+
+	// A search changes two counters, so the budget is split between them
+	if scale := a.scale(); scale != 4 {
+		t.Fatalf("scale() = %v, want 4", scale)
+	}
+
+	// The mean absolute deviation of Laplace noise is its scale
+	const samples = 100000
+	var deviation float64
+	for n := 0; n < samples; n++ {
+		count, _ := a.release(1000, 0)
+		deviation += math.Abs(float64(count - 1000))
+	}
+	if mean := deviation / samples; math.Abs(mean-4) > 0.1 {
+		t.Errorf("mean absolute noise %v, want about 4", mean)
+	}
+
+	// Noise of at least 8.5 lifts a single search over the threshold
+	if delta, want := a.delta(), 0.5*math.Exp(-8.5/4); math.Abs(delta-want) > 1e-12 {
+		t.Errorf("delta() = %v, want %v", delta, want)
+	}
+}
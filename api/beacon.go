@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// counter aggregates counts by key between flushes, e.g. beacons by hash
+type counter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newCounter() *counter {
+	return &counter{
+		counts: make(map[string]int64),
+	}
+}
+
+// add increments the count for a key
+func (c *counter) add(key string) {
+	c.mu.Lock()
+	c.counts[key]++
+	c.mu.Unlock()
+}
+
+// take returns and resets accumulated counts
+func (c *counter) take() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := c.counts
+	c.counts = make(map[string]int64)
+
+	return counts
+}
+
+// handleBeacon counts an access (result click, metadata fetch) for a hash,
+// reported by frontends as POST /beacon?hash=<hash>
+func (s *Server) handleBeacon(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "beacons should be POSTed")
+		return
+	}
+
+	hash, err := crawler.NormalizeHash(r.URL.Query().Get("hash"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid hash")
+		return
+	}
+
+	s.beacons.add(hash)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// flush writes accumulated counts to the index
+func (s *Server) flush(ctx context.Context) error {
+	counts := s.beacons.take()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	log.WithField("documents", len(counts)).Debug("Writing beacon counts")
+
+	return s.indexer.AddPopularity(ctx, counts)
+}
+
+// flushBeacons periodically writes accumulated counts to the index until
+// the context is cancelled
+func (s *Server) flushBeacons(ctx context.Context) {
+	ticker := time.NewTicker(s.config.BeaconFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.flush(ctx); err != nil {
+				log.WithError(err).Error("Error writing beacon counts")
+			}
+		}
+	}
+}
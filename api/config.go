@@ -0,0 +1,23 @@
+package api
+
+import (
+	"time"
+)
+
+// Config contains user configurable options for the API server
+type Config struct {
+	Listen              string        // Address to listen on, e.g. localhost:9616
+	BeaconFlushInterval time.Duration // Time between writing aggregated beacon counts to the index
+	CrawlTimeout        time.Duration // Maximum time to wait for on-demand crawls
+	CrawlConcurrency    int           // Maximum on-demand crawls at once
+	IngestInterval      time.Duration // Time for a client to earn another anonymous submission
+	IngestBurst         int           // Maximum anonymous submissions by a client at once
+	IngestDifficulty    uint          // Required leading zero bits of proof of work, 0 to disable
+	TrustedProxies      []string      // Addresses or CIDR ranges of proxies whose X-Forwarded-For is used
+	PublisherSecret     string        // Key for signing publisher tokens, random when empty
+
+	QueryAnalytics    string        // AnalyticsOff, AnalyticsAggregate or AnalyticsPrivate
+	AnalyticsInterval time.Duration // Time between writing query counts to the stats index
+	PrivacyEpsilon    float64       // Privacy budget per search and period in private mode; lower adds more noise
+	PrivacyThreshold  int64         // Queries with lower noisy counts are left out in private mode, with probability delta for single searches
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// crawl synchronously crawls a hash until ctx is done, after which the
+// crawl is abandoned; directory entries queued until then are crawled
+// regardless
+func (s *Server) crawl(ctx context.Context, hash string) error {
+	i, err := s.crawler.NewIndexable(&crawler.Args{
+		Hash:       hash,
+		Provenance: &indexer.Provenance{Source: crawler.SourceAPI},
+	})
+	if err != nil {
+		return err
+	}
+
+	return i.Crawl(ctx)
+}
+
+// acquireCrawl takes one of the slots for concurrent crawls, returning
+// false when none is available
+func (s *Server) acquireCrawl() bool {
+	select {
+	case s.crawls <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseCrawl returns a slot taken by acquireCrawl
+func (s *Server) releaseCrawl() {
+	<-s.crawls
+}
+
+// handleCrawl crawls and indexes a hash on demand, bypassing the queues,
+// and returns the resulting document, as POST /crawl?hash=<hash>. Requests
+// are rate limited per client like ingestion, and the amount of crawls at
+// once is limited; 503 is returned when all are in use. Responds with 504
+// when crawling takes longer than the configured timeout.
+func (s *Server) handleCrawl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	if _, ok := s.limit(w, r); !ok {
+		return
+	}
+
+	hash, err := crawler.NormalizeHash(r.URL.Query().Get("hash"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid hash")
+		return
+	}
+
+	if !s.acquireCrawl() {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusServiceUnavailable, "too many crawls in progress")
+		return
+	}
+	defer s.releaseCrawl()
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.CrawlTimeout)
+	defer cancel()
+
+	err = s.crawl(ctx, hash)
+	if err == context.DeadlineExceeded {
+		writeError(w, http.StatusGatewayTimeout, "timeout crawling hash")
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("hash", hash).Warn("Error crawling hash")
+		writeError(w, http.StatusBadGateway, "error crawling hash")
+		return
+	}
+
+	document, err := s.indexer.GetDocument(r.Context(), hash)
+	if err != nil {
+		log.WithError(err).WithField("hash", hash).Error("Error getting document")
+		writeError(w, http.StatusInternalServerError, "error getting document")
+		return
+	}
+	if document == nil {
+		// Crawled but not indexed, e.g. unsupported type
+		writeError(w, http.StatusNotFound, "hash not indexed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, document)
+}
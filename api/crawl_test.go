@@ -0,0 +1,21 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestAcquireCrawl(t *testing.T) {
+	s := &Server{crawls: make(chan struct{}, 2)}
+
+	if !s.acquireCrawl() || !s.acquireCrawl() {
+		t.Fatal("acquireCrawl() refused while slots are available")
+	}
+	if s.acquireCrawl() {
+		t.Fatal("acquireCrawl() succeeded without slots")
+	}
+
+	s.releaseCrawl()
+	if !s.acquireCrawl() {
+		t.Error("acquireCrawl() refused after release")
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"net/url"
+	"strings"
+)
+
+// ipfsPath is an IPFS path detected in a query
+type ipfsPath struct {
+	Hash string `json:"hash"` // Canonical hash of the root
+	Path string `json:"path"` // Path below the root, if any, unescaped
+}
+
+// String returns the full IPFS path
+func (p *ipfsPath) String() string {
+	if p.Path == "" {
+		return "/ipfs/" + p.Hash
+	}
+	return "/ipfs/" + p.Hash + "/" + p.Path
+}
+
+// splitPath splits s into a canonical hash and an unescaped remaining path
+func splitPath(s string) (*ipfsPath, bool) {
+	parts := strings.SplitN(strings.Trim(s, "/"), "/", 2)
+
+	hash, err := crawler.NormalizeHash(parts[0])
+	if err != nil {
+		return nil, false
+	}
+
+	p := &ipfsPath{Hash: hash}
+
+	if len(parts) == 2 {
+		// Percent-encoded names, as copied from browsers, are unescaped
+		path, err := url.PathUnescape(parts[1])
+		if err != nil {
+			path = parts[1]
+		}
+		p.Path = strings.Trim(path, "/")
+	}
+
+	return p, true
+}
+
+// detectPath returns the IPFS path contained in a query, if the entire
+// query is one. Recognised are bare CIDs, /ipfs/ paths, ipfs:// URLs and
+// both path and subdomain gateway URLs, optionally followed by a path.
+func detectPath(query string) (*ipfsPath, bool) {
+	query = strings.TrimSpace(query)
+
+	if query == "" || strings.ContainsAny(query, " \t\n") {
+		return nil, false
+	}
+
+	if strings.HasPrefix(query, "ipfs://") {
+		return splitPath(strings.TrimPrefix(query, "ipfs://"))
+	}
+
+	if u, err := url.Parse(query); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		// Path gateway: https://ipfs.io/ipfs/<cid>/<path>
+		if strings.HasPrefix(u.EscapedPath(), "/ipfs/") {
+			return splitPath(strings.TrimPrefix(u.EscapedPath(), "/ipfs/"))
+		}
+
+		// Subdomain gateway: https://<cid>.ipfs.dweb.link/<path>
+		labels := strings.Split(u.Hostname(), ".")
+		if len(labels) > 2 && labels[1] == "ipfs" {
+			return splitPath(labels[0] + u.EscapedPath())
+		}
+
+		return nil, false
+	}
+
+	// /ipfs/<cid>/<path>, ipfs/<cid>/<path> or <cid>/<path>
+	query = strings.TrimPrefix(query, "/")
+	query = strings.TrimPrefix(query, "ipfs/")
+
+	return splitPath(query)
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestDetectPath(t *testing.T) {
+	const v0 = "QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX"
+	const v1 = "bafybeibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq"
+
+	tests := []struct {
+		query string
+		path  string // Expected full path, empty when no path is detected
+	}{
+		{v0, "/ipfs/" + v0},
+		{"  " + v0 + "\n", "/ipfs/" + v0},
+		{v1, "/ipfs/" + v0},
+		{"/ipfs/" + v0, "/ipfs/" + v0},
+		{"ipfs/" + v0 + "/", "/ipfs/" + v0},
+		{v0 + "/docs/readme.md", "/ipfs/" + v0 + "/docs/readme.md"},
+		{"ipfs://" + v0 + "/a", "/ipfs/" + v0 + "/a"},
+		{"https://ipfs.io/ipfs/" + v0 + "/my%20file.txt", "/ipfs/" + v0 + "/my file.txt"},
+		{"https://" + v1 + ".ipfs.dweb.link/wiki/", "/ipfs/" + v0 + "/wiki"},
+		{"https://" + v1 + ".ipfs.dweb.link/Caf%C3%A9", "/ipfs/" + v0 + "/Café"},
+		{"/ipfs/" + v0 + "/Ελληνικά/文件.txt", "/ipfs/" + v0 + "/Ελληνικά/文件.txt"},
+		{"https://example.com/ipns/docs.ipfs.io", ""},
+		{"https://example.com/" + v0, ""},
+		{"ipfs whitepaper", ""},
+		{v0 + " whitepaper", ""},
+		{"", ""},
+		{"Qm", ""},
+	}
+
+	for _, test := range tests {
+		p, ok := detectPath(test.query)
+		if ok != (test.path != "") {
+			t.Errorf("detectPath(%q) detected %v, want %v", test.query, ok, test.path != "")
+			continue
+		}
+
+		if ok && p.String() != test.path {
+			t.Errorf("detectPath(%q) = %q, want %q", test.query, p.String(), test.path)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"math/bits"
+	"net/http"
+	"time"
+)
+
+// ingestPriority is the queue priority for anonymous submissions, below
+// hashes added by operators
+const ingestPriority = 5
+
+// workTTL is the time to complete and submit proof of work for a challenge
+const workTTL = 10 * time.Minute
+
+// leadingZeros returns the number of leading zero bits in b
+func leadingZeros(b []byte) int {
+	n := 0
+	for _, c := range b {
+		if c != 0 {
+			return n + bits.LeadingZeros8(c)
+		}
+		n += 8
+	}
+	return n
+}
+
+// validWork checks the proof of work for a challenge: the SHA-256 of
+// "<challenge>:<nonce>" should start with at least difficulty zero bits.
+func validWork(challenge, nonce string, difficulty uint) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	return leadingZeros(sum[:]) >= int(difficulty)
+}
+
+// checkWork verifies that the challenge was issued for the hash and has not
+// expired, and that the nonce proves work for it
+func (s *Server) checkWork(hash, challenge, nonce string) bool {
+	if s.config.IngestDifficulty == 0 {
+		return true
+	}
+
+	if signed, ok := s.verify("work", challenge); !ok || signed != hash {
+		return false
+	}
+
+	return validWork(challenge, nonce, s.config.IngestDifficulty)
+}
+
+// handleIngest queues a hash submitted anonymously, as
+// POST /ingest?hash=<hash>[&challenge=<challenge>&nonce=<nonce>].
+// Submissions are rate limited per client. When a difficulty is configured,
+// a nonce proving work is required for a challenge from
+// GET /ingest?hash=<hash>, which also returns the current difficulty.
+// Challenges are signed for the hash and expire, so that work can not be
+// done in advance or reused for other hashes.
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use GET or POST")
+		return
+	}
+
+	hash, err := crawler.NormalizeHash(r.URL.Query().Get("hash"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid hash")
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"hash":       hash,
+			"difficulty": s.config.IngestDifficulty,
+			"challenge":  s.sign("work", hash, workTTL),
+			"expires":    time.Now().Add(workTTL),
+			"algorithm":  "sha256(<challenge>:<nonce>) with leading zero bits",
+		})
+		return
+	}
+
+	client, ok := s.limit(w, r)
+	if !ok {
+		return
+	}
+
+	if !s.checkWork(hash, r.URL.Query().Get("challenge"), r.URL.Query().Get("nonce")) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("invalid or expired proof of work, difficulty is %d", s.config.IngestDifficulty))
+		return
+	}
+
+	args := &crawler.Args{
+		Hash: hash,
+		Provenance: &indexer.Provenance{
+			Source:    crawler.SourceIngest,
+			Submitter: client,
+		},
+	}
+
+	err = crawler.Publish(r.Context(), s.crawler.HashQueue, args, ingestPriority)
+	if err != nil {
+		log.WithError(err).WithField("hash", hash).Error("Error queueing hash")
+		writeError(w, http.StatusInternalServerError, "error queueing hash")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"hash":   hash,
+		"client": client,
+	}).Debug("Hash ingested")
+
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"hash": hash,
+	})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// lookupCrawlTTL is the time within which hashes queued by lookups should
+// be crawled; afterwards, nobody is likely to be waiting for them anymore
+const lookupCrawlTTL = time.Hour
+
+// lookupResponse is returned for queries which are IPFS paths
+type lookupResponse struct {
+	*ipfsPath
+	Permalink string      `json:"permalink,omitempty"` // Of the path looked up
+	Document  interface{} `json:"document,omitempty"`
+	Queued    bool        `json:"queued,omitempty"`
+
+	// Recent crawl attempts, explaining why a hash is not (yet) indexed
+	History []indexer.Attempt `json:"history,omitempty"`
+}
+
+// resolve returns the hash an IPFS path refers to
+func (s *Server) resolve(p *ipfsPath) (string, error) {
+	if p.Path == "" {
+		return p.Hash, nil
+	}
+
+	resolved, err := s.crawler.Shell.ResolvePath(p.String())
+	if err != nil {
+		return "", err
+	}
+
+	return crawler.NormalizeHash(strings.TrimPrefix(resolved, "/ipfs/"))
+}
+
+// handleLookup detects whether a query is a CID or IPFS path, as
+// GET /lookup?q=<query>[&crawl=1]. For paths, the indexed document is
+// returned directly; frontends should fall back to the search API when
+// the query is no path (404 without hash). Unknown hashes are queued for
+// crawling when crawl is set, within lookupCrawlTTL. Recent crawl attempts for the hash, if any,
+// are returned as history.
+func (s *Server) handleLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	p, ok := detectPath(r.URL.Query().Get("q"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "query is not an IPFS path")
+		return
+	}
+
+	hash, err := s.resolve(p)
+	if err != nil {
+		log.WithError(err).WithField("path", p.String()).Warn("Error resolving path")
+		writeError(w, http.StatusBadGateway, "error resolving path")
+		return
+	}
+	response := &lookupResponse{
+		ipfsPath: &ipfsPath{Hash: hash},
+	}
+	response.Permalink, _ = indexer.Permalink(p.Hash, p.Path)
+
+	document, err := s.indexer.GetDocument(r.Context(), hash)
+	if err != nil {
+		log.WithError(err).WithField("hash", hash).Error("Error getting document")
+		writeError(w, http.StatusInternalServerError, "error getting document")
+		return
+	}
+
+	response.History, err = s.indexer.History(r.Context(), hash)
+	if err != nil {
+		// The document is still useful without history
+		log.WithError(err).WithField("hash", hash).Warn("Error getting crawl history")
+	}
+
+	if document != nil {
+		response.Document = document
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	if r.URL.Query().Get("crawl") != "" {
+		args := &crawler.Args{
+			Hash:       hash,
+			Provenance: &indexer.Provenance{Source: crawler.SourceAPI},
+		}
+
+		err = crawler.PublishWithin(r.Context(), s.crawler.HashQueue, args, 9, lookupCrawlTTL)
+		if err != nil {
+			log.WithError(err).WithField("hash", hash).Error("Error queueing hash")
+			writeError(w, http.StatusInternalServerError, "error queueing hash")
+			return
+		}
+
+		response.Queued = true
+		writeJSON(w, http.StatusAccepted, response)
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, response)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+// permalinkPrefix is the path permalinks are resolved under
+const permalinkPrefix = "/permalink/"
+
+// permalinkResponse is returned for resolved permalinks
+type permalinkResponse struct {
+	Permalink string            `json:"permalink"`
+	Path      *ipfsPath         `json:"path"`
+	Document  *indexer.Document `json:"document"`
+}
+
+// handlePermalink resolves a permalink into the indexed document it refers
+// to, as GET /permalink/<cid>[/<path>]. Any CID version or encoding is
+// accepted, redirecting to the canonical permalink, so links keep working
+// when document IDs change across reindexes.
+func (s *Server) handlePermalink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.EscapedPath(), permalinkPrefix)
+	p, ok := splitPath(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid permalink")
+		return
+	}
+
+	permalink, err := indexer.Permalink(p.Hash, p.Path)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "invalid permalink")
+		return
+	}
+	if permalink != id {
+		http.Redirect(w, r, permalinkPrefix+permalink, http.StatusMovedPermanently)
+		return
+	}
+
+	hash, err := s.resolve(p)
+	if err != nil {
+		log.WithError(err).WithField("path", p.String()).Warn("Error resolving permalink")
+		writeError(w, http.StatusBadGateway, "error resolving path")
+		return
+	}
+
+	document, err := s.indexer.GetDocument(r.Context(), hash)
+	if err != nil {
+		log.WithError(err).WithField("hash", hash).Error("Error getting document")
+		writeError(w, http.StatusInternalServerError, "error getting document")
+		return
+	}
+	if document == nil {
+		writeError(w, http.StatusNotFound, "permalink not indexed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &permalinkResponse{
+		Permalink: permalink,
+		Path:      p,
+		Document:  document,
+	})
+}
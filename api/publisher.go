@@ -0,0 +1,301 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/cids"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/libp2p/go-libp2p-crypto"
+	"github.com/libp2p/go-libp2p-peer"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	challengeTTL = time.Hour
+	tokenTTL     = 24 * time.Hour
+
+	// The DNSLink challenge is expected in a TXT record of this subdomain
+	challengeSubdomain = "_ipfs-search-challenge"
+)
+
+// newSecret returns a random secret for signing challenges and tokens,
+// used when none is configured. Issued tokens expire on restarts then.
+func newSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+// sign returns an encoded value for purpose and name, valid for ttl
+func (s *Server) sign(purpose, name string, ttl time.Duration) string {
+	expiry := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	message := purpose + ":" + name + ":" + expiry
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(message))
+
+	return base64.RawURLEncoding.EncodeToString([]byte(message + ":" + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))))
+}
+
+// verify returns the name a value has been signed for with purpose, if it
+// is valid and has not expired
+func (s *Server) verify(purpose, value string) (string, bool) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", false
+	}
+
+	parts := strings.Split(string(decoded), ":")
+	if len(parts) != 4 || parts[0] != purpose {
+		return "", false
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.Join(parts[:3], ":")))
+	expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(parts[3])) {
+		return "", false
+	}
+
+	expiry, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+// ipnsKey returns the peer ID for names which are IPNS keys
+func ipnsKey(name string) (peer.ID, bool) {
+	if id, err := peer.IDB58Decode(name); err == nil {
+		return id, true
+	}
+
+	// CIDv1 representation of keys
+	if c, err := cids.Decode(name); err == nil {
+		if id, err := peer.IDFromBytes(c.Hash()); err == nil {
+			return id, true
+		}
+	}
+
+	return "", false
+}
+
+// verifyKey checks a signature of the challenge made with the private key
+// of an IPNS name; key is the base64 encoded public key
+func verifyKey(id peer.ID, challenge, key, signature string) error {
+	keyBytes, err := crypto.ConfigDecodeKey(key)
+	if err != nil {
+		return err
+	}
+
+	pub, err := crypto.UnmarshalPublicKey(keyBytes)
+	if err != nil {
+		return err
+	}
+
+	if !id.MatchesPublicKey(pub) {
+		return fmt.Errorf("key does not match name")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+
+	ok, err := pub.Verify([]byte(challenge), sig)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+// verifyDNSLink checks for the challenge in a TXT record of the domain
+func verifyDNSLink(domain, challenge string) error {
+	records, err := net.LookupTXT(challengeSubdomain + "." + domain)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == challenge {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("challenge not found in TXT records")
+}
+
+// handleChallenge returns a challenge for proving control over a name, as
+// POST /publisher/challenge?name=<IPNS key or DNSLink domain>
+func (s *Server) handleChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Query().Get("name"), "/ipns/")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "missing name")
+		return
+	}
+
+	challenge := s.sign("challenge", name, challengeTTL)
+
+	instructions := fmt.Sprintf("add a TXT record to %s.%s with the challenge", challengeSubdomain, name)
+	if _, ok := ipnsKey(name); ok {
+		instructions = "sign the challenge with the key of the name and submit the signature and public key"
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"name":         name,
+		"challenge":    challenge,
+		"instructions": instructions,
+	})
+}
+
+// handleVerify verifies a completed challenge and returns a token to
+// manage metadata with, as POST /publisher/verify?challenge=<challenge>
+// with key=<public key>&signature=<signature> for IPNS names
+func (s *Server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	challenge := r.URL.Query().Get("challenge")
+	name, ok := s.verify("challenge", challenge)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid or expired challenge")
+		return
+	}
+
+	var err error
+	if id, ok := ipnsKey(name); ok {
+		err = verifyKey(id, challenge, r.URL.Query().Get("key"), r.URL.Query().Get("signature"))
+	} else {
+		err = verifyDNSLink(name, challenge)
+	}
+
+	if err != nil {
+		log.WithError(err).WithField("name", name).Debug("Publisher verification failed")
+		writeError(w, http.StatusForbidden, fmt.Sprintf("verification failed: %s", err))
+		return
+	}
+
+	log.WithField("name", name).Info("Publisher verified")
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"name":  name,
+		"token": s.sign("token", name, tokenTTL),
+	})
+}
+
+// publishedHash returns the hash of content published under a name: the
+// content the name refers to, or for a path, the content below it
+func (s *Server) publishedHash(name, path string) (string, error) {
+	p := "/ipns/" + name
+	if path = strings.Trim(path, "/"); path != "" {
+		p += "/" + path
+	}
+
+	resolved, err := s.crawler.Shell.ResolvePath(p)
+	if err != nil {
+		return "", err
+	}
+
+	return crawler.NormalizeHash(strings.TrimPrefix(resolved, "/ipfs/"))
+}
+
+// handlePublisherMetadata manages metadata of content published under a
+// verified name, with header "Authorization: Bearer <token>". PUT
+// /publisher/metadata[?path=<path>] with a JSON body with title,
+// description and tags sets the metadata for the content the name, or the
+// path below it, refers to; DELETE removes it. Publisher metadata is kept
+// apart from, and never replaces, overrides set by operators, and can only
+// be replaced or removed by the publisher who set it.
+func (s *Server) handlePublisherMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "use PUT or DELETE")
+		return
+	}
+
+	name, ok := s.verify("token", strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid or expired token")
+		return
+	}
+
+	o := new(indexer.Override)
+	if r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(o); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+	}
+
+	var err error
+	o.Hash, err = s.publishedHash(name, r.URL.Query().Get("path"))
+	if err != nil {
+		log.WithError(err).WithField("name", name).Warn("Error resolving name")
+		writeError(w, http.StatusBadGateway, "error resolving name")
+		return
+	}
+	o.Publisher = name
+
+	existing, err := s.indexer.GetPublisherOverride(r.Context(), o.Hash)
+	if err != nil {
+		log.WithError(err).WithField("hash", o.Hash).Error("Error getting override")
+		writeError(w, http.StatusInternalServerError, "error getting override")
+		return
+	}
+	if existing != nil && existing.Publisher != name {
+		writeError(w, http.StatusConflict, fmt.Sprintf("metadata for %s has been set by %s", o.Hash, existing.Publisher))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "no metadata set for "+o.Hash)
+			return
+		}
+
+		if err := s.indexer.RemovePublisherOverride(r.Context(), o.Hash); err != nil {
+			log.WithError(err).WithField("hash", o.Hash).Error("Error removing override")
+			writeError(w, http.StatusInternalServerError, "error removing override")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{
+			"removed": o.Hash,
+		})
+		return
+	}
+
+	if err := s.indexer.SetOverride(r.Context(), o); err != nil {
+		log.WithError(err).WithField("hash", o.Hash).Error("Error storing override")
+		writeError(w, http.StatusInternalServerError, "error storing override")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"hash":     o.Hash,
+		"override": o,
+	})
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket for a single client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter allows clients a burst of requests, refilled at one per
+// interval
+type rateLimiter struct {
+	interval time.Duration
+	burst    int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+func newRateLimiter(interval time.Duration, burst int) *rateLimiter {
+	return &rateLimiter{
+		interval:  interval,
+		burst:     burst,
+		buckets:   make(map[string]*bucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last request
+func (l *rateLimiter) refill(b *bucket, now time.Time) {
+	b.tokens += float64(now.Sub(b.last)) / float64(l.interval)
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+	b.last = now
+}
+
+// prune forgets clients whose bucket has been refilled completely, as
+// these are indistinguishable from new clients
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Duration(l.burst)*l.interval {
+		return
+	}
+
+	for client, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= float64(l.burst) {
+			delete(l.buckets, client)
+		}
+	}
+
+	l.lastPrune = now
+}
+
+// allow takes a token for a client; when none is left it returns false
+// and the time until the next token is available
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[client] = b
+	}
+
+	l.refill(b, now)
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(l.interval))
+	}
+
+	b.tokens--
+
+	return true, 0
+}
+
+// parseProxies parses addresses or CIDR ranges of trusted proxies
+func parseProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy '%s'", proxy)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %v", proxy, err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// trusted returns whether an address belongs to a trusted proxy
+func trusted(proxies []*net.IPNet, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientAddress returns the address identifying the client of a request.
+// For requests from trusted proxies, X-Forwarded-For is followed from the
+// end, as earlier entries are set by the client and can be forged, up to
+// the first address which is not a trusted proxy.
+func clientAddress(r *http.Request, proxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !trusted(proxies, host) {
+		return host
+	}
+
+	var forwarded []string
+	for _, header := range r.Header["X-Forwarded-For"] {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+
+	for n := len(forwarded) - 1; n >= 0; n-- {
+		address := strings.TrimSpace(forwarded[n])
+		if net.ParseIP(address) == nil {
+			// Garbage from an untrusted hop, the proxy before is the client
+			return host
+		}
+
+		host = address
+		if !trusted(proxies, host) {
+			break
+		}
+	}
+
+	return host
+}
+
+// limit takes a token for the client of a request from the limiter shared
+// by anonymous submissions and on-demand crawls, returning the client.
+// When it is rate limited, an error response is written and false returned.
+func (s *Server) limit(w http.ResponseWriter, r *http.Request) (string, bool) {
+	client := clientAddress(r, s.proxies)
+
+	if ok, wait := s.ingestLimiter.allow(client); !ok {
+		w.Header().Set("Retry-After", fmt.Sprint(math.Ceil(wait.Seconds())))
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return client, false
+	}
+
+	return client, true
+}
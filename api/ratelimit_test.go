@@ -0,0 +1,168 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseProxies(t *testing.T) {
+	tests := []struct {
+		proxies []string
+		valid   bool
+	}{
+		{nil, true},
+		{[]string{"127.0.0.1", "::1"}, true},
+		{[]string{"10.0.0.0/8", "fd00::/8"}, true},
+		{[]string{"localhost"}, false},
+		{[]string{"10.0.0.0/33"}, false},
+	}
+
+	for _, test := range tests {
+		networks, err := parseProxies(test.proxies)
+		if (err == nil) != test.valid {
+			t.Errorf("parseProxies(%v) error = %v, valid %v", test.proxies, err, test.valid)
+			continue
+		}
+		if test.valid && len(networks) != len(test.proxies) {
+			t.Errorf("parseProxies(%v) = %v", test.proxies, networks)
+		}
+	}
+}
+
+func TestClientAddress(t *testing.T) {
+	proxies, err := parseProxies([]string{"127.0.0.1", "10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"192.0.2.1:1234", nil, "192.0.2.1"},
+		// Forwarded addresses from untrusted peers are ignored
+		{"192.0.2.1:1234", []string{"198.51.100.1"}, "192.0.2.1"},
+		{"127.0.0.1:1234", nil, "127.0.0.1"},
+		{"127.0.0.1:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		// Entries added by the client are ignored
+		{"127.0.0.1:1234", []string{"203.0.113.1, 198.51.100.1"}, "198.51.100.1"},
+		{"127.0.0.1:1234", []string{"203.0.113.1", "198.51.100.1"}, "198.51.100.1"},
+		// Chains of trusted proxies are followed
+		{"127.0.0.1:1234", []string{"203.0.113.1, 198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"127.0.0.1:1234", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"127.0.0.1:1234", []string{"garbage"}, "127.0.0.1"},
+		{"127.0.0.1:1234", []string{"garbage, 10.0.0.2"}, "10.0.0.2"},
+	}
+
+	for _, test := range tests {
+		r := &http.Request{
+			RemoteAddr: test.remote,
+			Header:     http.Header{},
+		}
+		for _, forwarded := range test.forwarded {
+			r.Header.Add("X-Forwarded-For", forwarded)
+		}
+
+		if got := clientAddress(r, proxies); got != test.want {
+			t.Errorf("clientAddress(%s, %v) = %s, want %s", test.remote, test.forwarded, got, test.want)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(time.Hour, 2)
+
+	tests := []struct {
+		client string
+		allow  bool
+	}{
+		{"a", true},
+		{"a", true},
+		{"b", true},
+		{"a", false},
+		{"b", true},
+		{"b", false},
+	}
+
+	for n, test := range tests {
+		allow, wait := l.allow(test.client)
+		if allow != test.allow {
+			t.Errorf("%d: allow(%s) = %v, want %v", n, test.client, allow, test.allow)
+		}
+		if !allow && (wait <= 0 || wait > time.Hour) {
+			t.Errorf("%d: allow(%s) wait = %s", n, test.client, wait)
+		}
+	}
+}
+
+// work returns a nonce proving work for a challenge
+func work(challenge string, difficulty uint) string {
+	for n := 0; ; n++ {
+		nonce := strconv.Itoa(n)
+		if validWork(challenge, nonce, difficulty) {
+			return nonce
+		}
+	}
+}
+
+func TestCheckWork(t *testing.T) {
+	s := &Server{
+		config: &Config{IngestDifficulty: 8},
+		secret: []byte("secret"),
+	}
+
+	hash := "bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"
+	challenge := s.sign("work", hash, workTTL)
+	nonce := work(challenge, 8)
+
+	other := s.sign("work", "bafkreifjjcie6lypi6ny7amxnfftagclbuxndqonfipmb64f2km2devei4", workTTL)
+	expired := s.sign("work", hash, -time.Minute)
+	forged := (&Server{secret: []byte("other")}).sign("work", hash, workTTL)
+
+	tests := []struct {
+		name      string
+		challenge string
+		nonce     string
+		valid     bool
+	}{
+		{"valid", challenge, nonce, true},
+		{"no challenge", "", nonce, false},
+		{"other hash", other, work(other, 8), false},
+		{"expired", expired, work(expired, 8), false},
+		{"forged", forged, work(forged, 8), false},
+		{"wrong purpose", s.sign("token", hash, workTTL), nonce, false},
+	}
+
+	for _, test := range tests {
+		if got := s.checkWork(hash, test.challenge, test.nonce); got != test.valid {
+			t.Errorf("checkWork() with %s challenge = %v, want %v", test.name, got, test.valid)
+		}
+	}
+
+	s.config.IngestDifficulty = 0
+	if !s.checkWork(hash, "", "") {
+		t.Errorf("checkWork() without difficulty = false, want true")
+	}
+}
+
+func TestLeadingZeros(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		want int
+	}{
+		{[]byte{}, 0},
+		{[]byte{0xff}, 0},
+		{[]byte{0x01}, 7},
+		{[]byte{0x00, 0x80}, 8},
+		{[]byte{0x00, 0x00}, 16},
+	}
+
+	for _, test := range tests {
+		if got := leadingZeros(test.b); got != test.want {
+			t.Errorf("leadingZeros(%x) = %d, want %d", test.b, got, test.want)
+		}
+	}
+}
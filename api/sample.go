@@ -0,0 +1,69 @@
+package api
+
+import (
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+// defaultSampleSize and maxSampleSize limit the amount of sampled documents
+const (
+	defaultSampleSize = 10
+	maxSampleSize     = 100
+)
+
+// sampleTypes are the document types which can be sampled
+var sampleTypes = map[string]bool{
+	"":          true,
+	"file":      true,
+	"directory": true,
+	"invalid":   true,
+}
+
+// handleSample returns a random sample of indexed documents, as
+// GET /sample[?type=<type>][&q=<query>][&size=<size>][&seed=<seed>]. Like
+// the sample command, a given seed returns the same sample for an
+// unchanged index.
+func (s *Server) handleSample(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	params := r.URL.Query()
+
+	doctype := params.Get("type")
+	if !sampleTypes[doctype] {
+		writeError(w, http.StatusBadRequest, "invalid type")
+		return
+	}
+
+	size := defaultSampleSize
+	if p := params.Get("size"); p != "" {
+		var err error
+		size, err = strconv.Atoi(p)
+		if err != nil || size < 1 || size > maxSampleSize {
+			writeError(w, http.StatusBadRequest, "invalid size")
+			return
+		}
+	}
+
+	var seed int64
+	if p := params.Get("seed"); p != "" {
+		var err error
+		seed, err = strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid seed")
+			return
+		}
+	}
+
+	documents, err := s.indexer.Sample(r.Context(), doctype, params.Get("q"), size, seed)
+	if err != nil {
+		log.WithError(err).Error("Error sampling documents")
+		writeError(w, http.StatusInternalServerError, "error sampling documents")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, documents)
+}
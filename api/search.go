@@ -0,0 +1,287 @@
+package api
+
+import (
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resultsPerPage is the amount of search results returned at once
+const resultsPerPage = 15
+
+// maxResults is the maximum window of results Elasticsearch pages through
+const maxResults = 10000
+
+// splitRange splits a range parameter, "<from>..<to>", into its bounds;
+// either bound may be left out
+func splitRange(s string) (string, string, error) {
+	parts := strings.Split(s, "..")
+	if len(parts) != 2 || (parts[0] == "" && parts[1] == "") {
+		return "", "", fmt.Errorf("invalid range '%s', expected <from>..<to>", s)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// parseRange parses both bounds of a range parameter with parse, leaving
+// missing bounds open
+func parseRange(s string, parse func(string) (interface{}, error)) (from, to interface{}, err error) {
+	f, t, err := splitRange(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if f != "" {
+		if from, err = parse(f); err != nil {
+			return nil, nil, err
+		}
+	}
+	if t != "" {
+		if to, err = parse(t); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return from, to, nil
+}
+
+// parseTime parses an RFC 3339 timestamp into the format dates are indexed in
+func parseTime(s string) (interface{}, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// parseSize parses a size in bytes
+func parseSize(s string) (interface{}, error) {
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// parseDuration parses a duration in seconds
+func parseDuration(s string) (interface{}, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseScore parses a score between 0 and 1
+func parseScore(s string) (interface{}, error) {
+	score, err := strconv.ParseFloat(s, 64)
+	if err == nil && (score < 0 || score > 1) {
+		err = fmt.Errorf("score %s out of range 0..1", s)
+	}
+	return score, err
+}
+
+// rangeParams are the range parameters of the search API, with the field
+// they restrict, how their bounds are parsed and whether documents without
+// the field match
+var rangeParams = []struct {
+	param   string
+	field   string
+	parse   func(string) (interface{}, error)
+	missing bool
+}{
+	{"last-seen", "last-seen", parseTime, false},
+	{"size", "size", parseSize, false},
+	{"total-size", "total-size", parseSize, false},
+	{"references", "reference-count", parseSize, false},
+	{"created", "content-created", parseTime, false},
+	{"modified", "content-modified", parseTime, false},
+	{"duration", "media.duration", parseDuration, false},
+	{"height", "media.video.height", parseSize, false},
+	{"nsfw", "nsfw_score", parseScore, true},
+}
+
+// searchRanges returns the ranges given as query parameters
+func searchRanges(values url.Values) ([]indexer.Range, error) {
+	var ranges []indexer.Range
+
+	for _, p := range rangeParams {
+		value := values.Get(p.param)
+		if value == "" {
+			continue
+		}
+
+		from, to, err := parseRange(value, p.parse)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", p.param, err)
+		}
+
+		ranges = append(ranges, indexer.Range{
+			Field:   p.field,
+			From:    from,
+			To:      to,
+			Missing: p.missing,
+		})
+	}
+
+	return ranges, nil
+}
+
+// parseFloats parses n comma separated numbers
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected %d comma separated values", n)
+	}
+
+	values := make([]float64, n)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// validPoint returns whether a latitude and longitude are within range
+func validPoint(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// distanceUnit matches distances with a unit Elasticsearch understands
+var distanceUnit = regexp.MustCompile(`^\d+(\.\d+)?(mi|yd|ft|in|km|m|cm|mm|nmi)$`)
+
+// parseDistance parses a near parameter, "<lat>,<lon>,<distance>"
+func parseDistance(s string) (*indexer.GeoDistance, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid near '%s', expected <lat>,<lon>,<distance>", s)
+	}
+
+	point, err := parseFloats(strings.Join(parts[:2], ","), 2)
+	if err != nil || !validPoint(point[0], point[1]) {
+		return nil, fmt.Errorf("invalid near '%s': invalid point", s)
+	}
+
+	distance := strings.TrimSpace(parts[2])
+	if !distanceUnit.MatchString(distance) {
+		return nil, fmt.Errorf("invalid near '%s': invalid distance, e.g. 10km", s)
+	}
+
+	return &indexer.GeoDistance{Lat: point[0], Lon: point[1], Distance: distance}, nil
+}
+
+// parseBox parses a box parameter, "<top>,<left>,<bottom>,<right>"
+func parseBox(s string) (*indexer.GeoBox, error) {
+	v, err := parseFloats(s, 4)
+	if err != nil || !validPoint(v[0], v[1]) || !validPoint(v[2], v[3]) || v[0] < v[2] {
+		return nil, fmt.Errorf("invalid box '%s', expected <top>,<left>,<bottom>,<right>", s)
+	}
+
+	return &indexer.GeoBox{Top: v[0], Left: v[1], Bottom: v[2], Right: v[3]}, nil
+}
+
+// searchLocation returns the location filters given as query parameters
+func searchLocation(values url.Values, options *indexer.SearchOptions) (err error) {
+	if near := values.Get("near"); near != "" {
+		if options.Distance, err = parseDistance(near); err != nil {
+			return err
+		}
+	}
+
+	if box := values.Get("box"); box != "" {
+		if options.Box, err = parseBox(box); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// searchResponse is a page of search results
+type searchResponse struct {
+	*indexer.SearchResult
+	Page     int `json:"page"`
+	PageSize int `json:"page_size"`
+}
+
+// handleSearch returns a page of documents matching a query, ranked by
+// relevance, quality, popularity and operator curations, as
+// GET /search?q=<query>[&page=<page>][&last-seen=<from>..<to>][&size=<min>..<max>]
+// [&created=<from>..<to>][&modified=<from>..<to>][&duration=<min>..<max>][&height=<min>..<max>][&nsfw=<min>..<max>]
+// [&author=<name>][&tag=<tag>]
+// [&language=<code>][&category=<category>][&near=<lat>,<lon>,<distance>][&box=<top>,<left>,<bottom>,<right>].
+// Pages are numbered from 0. Ranges are inclusive and either bound may be
+// left out; last-seen, created and modified take RFC 3339 timestamps and
+// size is in bytes. duration is the length of audio and video in seconds
+// and height the vertical resolution of video in pixels. nsfw is the
+// nsfw_score set by enrichment services, from 0 to 1; documents without
+// one, e.g. directories, match as well. created and modified are the dates of the content
+// according to its metadata, rather than crawl dates. author matches
+// documents by that author regardless of case and whitespace; tag matches
+// documents tagged by the operator's tagging rules and language documents
+// in a language by its ISO 639-1 code, e.g. en. category matches documents
+// classified in a topic category. near and box only return geotagged
+// files; distances have a unit, e.g. 10km.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "missing query")
+		return
+	}
+
+	page := 0
+	if p := r.URL.Query().Get("page"); p != "" {
+		var err error
+		page, err = strconv.Atoi(p)
+		if err != nil || page < 0 || (page+1)*resultsPerPage > maxResults {
+			writeError(w, http.StatusBadRequest, "invalid page")
+			return
+		}
+	}
+
+	ranges, err := searchRanges(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	options := &indexer.SearchOptions{
+		Ranges:   ranges,
+		From:     page * resultsPerPage,
+		Size:     resultsPerPage,
+		Source:   true,
+		Author:   crawler.NormalizeAuthor(r.URL.Query().Get("author")),
+		Tag:      r.URL.Query().Get("tag"),
+		Language: strings.ToLower(r.URL.Query().Get("language")),
+		Category: r.URL.Query().Get("category"),
+	}
+
+	if err := searchLocation(r.URL.Query(), options); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := s.indexer.Search(r.Context(), query, options)
+	if err != nil {
+		log.WithError(err).WithField("query", s.analytics.loggable(query)).Error("Error searching")
+		writeError(w, http.StatusInternalServerError, "error searching")
+		return
+	}
+
+	s.analytics.record(query, result.Total)
+
+	writeJSON(w, http.StatusOK, &searchResponse{
+		SearchResult: result,
+		Page:         page,
+		PageSize:     resultsPerPage,
+	})
+}
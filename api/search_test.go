@@ -0,0 +1,109 @@
+package api
+
+import (
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestSearchRanges(t *testing.T) {
+	tests := []struct {
+		query  string
+		ranges []indexer.Range
+		valid  bool
+	}{
+		{"", nil, true},
+		{"size=10..20", []indexer.Range{{Field: "size", From: uint64(10), To: uint64(20)}}, true},
+		{"size=..20", []indexer.Range{{Field: "size", To: uint64(20)}}, true},
+		{"size=10..", []indexer.Range{{Field: "size", From: uint64(10)}}, true},
+		{
+			"last-seen=2019-01-01T01:00:00%2B01:00..&size=..5",
+			[]indexer.Range{
+				{Field: "last-seen", From: "2019-01-01T00:00:00Z"},
+				{Field: "size", To: uint64(5)},
+			},
+			true,
+		},
+		{
+			"created=2010-01-01T00:00:00Z..&modified=..2020-01-01T00:00:00Z",
+			[]indexer.Range{
+				{Field: "content-created", From: "2010-01-01T00:00:00Z"},
+				{Field: "content-modified", To: "2020-01-01T00:00:00Z"},
+			},
+			true,
+		},
+		{"nsfw=..0.5", []indexer.Range{{Field: "nsfw_score", To: 0.5, Missing: true}}, true},
+		{"nsfw=..1.5", nil, false},
+		{"size=..", nil, false},
+		{"size=10", nil, false},
+		{"size=1..2..3", nil, false},
+		{"size=-1..", nil, false},
+		{"last-seen=yesterday..", nil, false},
+	}
+
+	for _, test := range tests {
+		values, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ranges, err := searchRanges(values)
+		if (err == nil) != test.valid {
+			t.Errorf("searchRanges(%q) error = %v, valid %v", test.query, err, test.valid)
+			continue
+		}
+
+		if !reflect.DeepEqual(ranges, test.ranges) {
+			t.Errorf("searchRanges(%q) = %#v, want %#v", test.query, ranges, test.ranges)
+		}
+	}
+}
+
+func TestSearchLocation(t *testing.T) {
+	tests := []struct {
+		query    string
+		distance *indexer.GeoDistance
+		box      *indexer.GeoBox
+		valid    bool
+	}{
+		{"", nil, nil, true},
+		{"near=52.37,4.89,10km", &indexer.GeoDistance{Lat: 52.37, Lon: 4.89, Distance: "10km"}, nil, true},
+		{"near=-33.9,151.2,2.5mi", &indexer.GeoDistance{Lat: -33.9, Lon: 151.2, Distance: "2.5mi"}, nil, true},
+		{"box=53,4,52,5", nil, &indexer.GeoBox{Top: 53, Left: 4, Bottom: 52, Right: 5}, true},
+		{
+			"near=52,4,1km&box=53,4,52,5",
+			&indexer.GeoDistance{Lat: 52, Lon: 4, Distance: "1km"},
+			&indexer.GeoBox{Top: 53, Left: 4, Bottom: 52, Right: 5},
+			true,
+		},
+		{"near=52.37,4.89", nil, nil, false},
+		{"near=52.37,4.89,10", nil, nil, false},
+		{"near=91,4.89,10km", nil, nil, false},
+		{"near=north,4.89,10km", nil, nil, false},
+		{"box=52,4,53,5", nil, nil, false},
+		{"box=53,4,52", nil, nil, false},
+		{"box=53,190,52,5", nil, nil, false},
+	}
+
+	for _, test := range tests {
+		values, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		options := new(indexer.SearchOptions)
+		err = searchLocation(values, options)
+		if (err == nil) != test.valid {
+			t.Errorf("searchLocation(%q) error = %v, valid %v", test.query, err, test.valid)
+			continue
+		}
+		if !test.valid {
+			continue
+		}
+
+		if !reflect.DeepEqual(options.Distance, test.distance) || !reflect.DeepEqual(options.Box, test.box) {
+			t.Errorf("searchLocation(%q) = %+v, %+v, want %+v, %+v", test.query, options.Distance, options.Box, test.distance, test.box)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Package api implements an HTTP API for interacting with the index and crawler.
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"time"
+)
+
+// shutdownTimeout is the time allowed for open requests to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// Server serves the HTTP API
+type Server struct {
+	config  *Config
+	indexer *indexer.Indexer
+	crawler *crawler.Crawler
+	beacons *counter
+	mux     *http.ServeMux
+
+	analytics *analytics // Optional, nil disables query analytics
+
+	ingestLimiter *rateLimiter  // Shared by anonymous submissions and on-demand crawls
+	crawls        chan struct{} // Slots for concurrent on-demand crawls
+	secret        []byte        // Key for signing publisher challenges, tokens and work challenges
+	proxies       []*net.IPNet  // Trusted reverse proxies
+}
+
+// New returns a new API server
+func New(config *Config, indexer *indexer.Indexer, crawler *crawler.Crawler) (*Server, error) {
+	proxies, err := parseProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := newAnalytics(config)
+	if err != nil {
+		return nil, err
+	}
+	if a.private() {
+		log.WithFields(log.Fields{
+			"epsilon": config.PrivacyEpsilon,
+			"delta":   a.delta(),
+		}).Info("Writing differentially private query analytics")
+	}
+
+	s := &Server{
+		config:  config,
+		indexer: indexer,
+		crawler: crawler,
+		beacons: newCounter(),
+		mux:     http.NewServeMux(),
+
+		analytics: a,
+
+		ingestLimiter: newRateLimiter(config.IngestInterval, config.IngestBurst),
+		crawls:        make(chan struct{}, config.CrawlConcurrency),
+		secret:        []byte(config.PublisherSecret),
+		proxies:       proxies,
+	}
+
+	if len(s.secret) == 0 {
+		s.secret = newSecret()
+	}
+
+	s.mux.HandleFunc("/beacon", s.handleBeacon)
+	s.mux.HandleFunc("/search", s.handleSearch)
+	s.mux.HandleFunc("/suggest", s.handleSuggest)
+	s.mux.HandleFunc("/sample", s.handleSample)
+	s.mux.HandleFunc("/lookup", s.handleLookup)
+	s.mux.HandleFunc(permalinkPrefix, s.handlePermalink)
+	s.mux.HandleFunc("/crawl", s.handleCrawl)
+	s.mux.HandleFunc("/ingest", s.handleIngest)
+	s.mux.HandleFunc("/publisher/challenge", s.handleChallenge)
+	s.mux.HandleFunc("/publisher/verify", s.handleVerify)
+	s.mux.HandleFunc("/publisher/metadata", s.handlePublisherMetadata)
+
+	return s, nil
+}
+
+// writeJSON writes v as JSON response with given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithError(err).Warn("Error writing response")
+	}
+}
+
+// writeError writes an error message as JSON response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{
+		"error": message,
+	})
+}
+
+// Serve listens for requests until the context is cancelled
+func (s *Server) Serve(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:    s.config.Listen,
+		Handler: s.mux,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		log.WithField("address", s.config.Listen).Info("API listening")
+		errc <- srv.ListenAndServe()
+	}()
+
+	go s.flushBeacons(ctx)
+
+	if s.analytics != nil {
+		go s.writeAnalytics(ctx)
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+
+	// Write remaining counts
+	if flushErr := s.flush(shutdownCtx); flushErr != nil {
+		log.WithError(flushErr).Error("Error writing beacon counts")
+	}
+
+	if s.analytics != nil {
+		if flushErr := s.flushAnalytics(shutdownCtx); flushErr != nil {
+			log.WithError(flushErr).Error("Error writing query analytics")
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// handleSuggest returns a "did you mean" alternative for a query, as
+// GET /suggest?q=<query>; suggestion is empty when none is found
+func (s *Server) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "missing query")
+		return
+	}
+
+	suggestion, err := s.indexer.Suggest(r.Context(), query)
+	if err != nil {
+		log.WithError(err).Error("Error getting suggestion")
+		writeError(w, http.StatusInternalServerError, "error getting suggestion")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"query":      query,
+		"suggestion": suggestion,
+	})
+}
@@ -0,0 +1,148 @@
+/*
+Package archive lists the contents of zip and tar archives without
+unpacking them. Only the parts of archives describing their entries are
+read, through random access, so listing large archives stored in IPFS
+requires little transfer. Tar archives must be uncompressed; other
+formats, such as rar and 7z, are not supported.
+*/
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Entry types
+const (
+	File      = "file"
+	Directory = "directory"
+	Link      = "link"
+)
+
+// Entry is a file in an archive
+type Entry struct {
+	Name string `json:"name"`
+	Size uint64 `json:"size"`
+	Type string `json:"type"`
+}
+
+// Formats of archives
+const (
+	Zip = "zip"
+	Tar = "tar"
+)
+
+// ErrUnknownFormat is returned for content which is not a known archive
+var ErrUnknownFormat = errors.New("unknown archive format")
+
+// headerSize is the amount of bytes needed to recognize an archive
+const headerSize = 512
+
+// Format returns the format of an archive from its first bytes, or an empty
+// string if it is not a known archive
+func Format(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return Zip
+	case len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar")):
+		return Tar
+	}
+
+	return ""
+}
+
+// List returns up to max entries of the archive of given size read from r,
+// and whether these are all of its entries
+func List(r io.ReaderAt, size int64, max int) ([]Entry, bool, error) {
+	head := make([]byte, headerSize)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, false, err
+	}
+
+	switch Format(head[:n]) {
+	case Zip:
+		return listZip(r, size, max)
+	case Tar:
+		return listTar(io.NewSectionReader(r, 0, size), max)
+	}
+
+	return nil, false, ErrUnknownFormat
+}
+
+// listZip lists a zip archive from its central directory at the end
+func listZip(r io.ReaderAt, size int64, max int) ([]Entry, bool, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entries := make([]Entry, 0, len(z.File))
+	for _, f := range z.File {
+		if len(entries) == max {
+			return entries, false, nil
+		}
+
+		e := Entry{
+			Name: strings.TrimSuffix(f.Name, "/"),
+			Size: f.UncompressedSize64,
+			Type: File,
+		}
+
+		switch mode := f.Mode(); {
+		case mode.IsDir():
+			e.Type = Directory
+		case mode&os.ModeSymlink != 0:
+			e.Type = Link
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, true, nil
+}
+
+// listTar lists a tar archive from its headers; the tar reader seeks past
+// the content of entries rather than reading it
+func listTar(r io.ReadSeeker, max int) ([]Entry, bool, error) {
+	t := tar.NewReader(r)
+
+	var entries []Entry
+	for {
+		h, err := t.Next()
+		if err == io.EOF {
+			return entries, true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(entries) == max {
+			return entries, false, nil
+		}
+
+		e := Entry{
+			Name: strings.TrimSuffix(h.Name, "/"),
+			Type: File,
+		}
+
+		switch h.Typeflag {
+		case tar.TypeReg:
+			e.Size = uint64(h.Size)
+		case tar.TypeDir:
+			e.Type = Directory
+		case tar.TypeSymlink, tar.TypeLink:
+			e.Type = Link
+		default:
+			// Devices, fifos and extended headers are not files
+			continue
+		}
+
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var want = []Entry{
+	{"docs", 0, Directory},
+	{"docs/readme.txt", 5, File},
+	{"data.csv", 8, File},
+}
+
+// testZip returns a zip archive with the entries of want
+func testZip(t *testing.T) []byte {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+
+	if _, err := w.Create("docs/"); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"docs/readme.txt": "hello", "data.csv": "a,b\n1,2\n"} {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// testTar returns a tar archive with the entries of want
+func testTar(t *testing.T) []byte {
+	var b bytes.Buffer
+	w := tar.NewWriter(&b)
+
+	w.WriteHeader(&tar.Header{Name: "docs/", Typeflag: tar.TypeDir, Mode: 0755})
+	for _, f := range []struct{ name, content string }{{"docs/readme.txt", "hello"}, {"data.csv", "a,b\n1,2\n"}} {
+		w.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(f.content))})
+		w.Write([]byte(f.content))
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// sorted returns entries in the order of want, as zip entries written
+// from a map have no fixed order
+func sorted(entries []Entry) []Entry {
+	result := make([]Entry, 0, len(entries))
+	for _, w := range want {
+		for _, e := range entries {
+			if e.Name == w.Name {
+				result = append(result, e)
+			}
+		}
+	}
+	return result
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		format  string
+		archive []byte
+	}{
+		{Zip, testZip(t)},
+		{Tar, testTar(t)},
+	}
+
+	for _, test := range tests {
+		if format := Format(test.archive); format != test.format {
+			t.Errorf("Format() = %q, want %q", format, test.format)
+		}
+
+		entries, complete, err := List(bytes.NewReader(test.archive), int64(len(test.archive)), 10)
+		if err != nil {
+			t.Fatalf("%s: List() error %v", test.format, err)
+		}
+		if !complete {
+			t.Errorf("%s: List() incomplete", test.format)
+		}
+		if got := sorted(entries); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: List() = %v, want %v", test.format, entries, want)
+		}
+
+		entries, complete, err = List(bytes.NewReader(test.archive), int64(len(test.archive)), 2)
+		if err != nil || complete || len(entries) != 2 {
+			t.Errorf("%s: List() limited to 2 = %v, %v, %v; want 2 incomplete entries", test.format, entries, complete, err)
+		}
+	}
+}
+
+func TestListUnknown(t *testing.T) {
+	content := []byte("plain text, not an archive")
+
+	if format := Format(content); format != "" {
+		t.Errorf("Format() = %q, want none", format)
+	}
+	if _, _, err := List(bytes.NewReader(content), int64(len(content)), 10); err != ErrUnknownFormat {
+		t.Errorf("List() = %v, want %v", err, ErrUnknownFormat)
+	}
+}
@@ -0,0 +1,115 @@
+// Package budget limits the memory held by items in flight, such as
+// extracted content and directory listings, across all workers.
+package budget
+
+import (
+	"context"
+	"sync"
+)
+
+// Budget tracks reserved bytes against a limit. A nil Budget is unlimited.
+type Budget struct {
+	limit uint64
+
+	mu      sync.Mutex
+	used    uint64
+	changed chan struct{} // Closed and replaced on release
+}
+
+// New returns a budget of limit bytes, or nil for limit 0
+func New(limit uint64) *Budget {
+	if limit == 0 {
+		return nil
+	}
+
+	return &Budget{
+		limit:   limit,
+		changed: make(chan struct{}),
+	}
+}
+
+// wait blocks until ok, called with lock held, returns true or the context
+// is done
+func (b *Budget) wait(ctx context.Context, ok func() bool) error {
+	for {
+		b.mu.Lock()
+		if ok() {
+			b.mu.Unlock()
+			return nil
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// clamp limits reservations to the whole budget, so that they can be
+// granted at all
+func (b *Budget) clamp(n uint64) uint64 {
+	if n > b.limit {
+		return b.limit
+	}
+	return n
+}
+
+// Acquire reserves n bytes, waiting for other reservations to be released
+// when the budget would be exceeded. Reservations larger than the limit
+// take the whole budget, waiting for everything else to be released.
+func (b *Budget) Acquire(ctx context.Context, n uint64) error {
+	if b == nil {
+		return nil
+	}
+
+	n = b.clamp(n)
+
+	return b.wait(ctx, func() bool {
+		if b.used+n <= b.limit {
+			b.used += n
+			return true
+		}
+
+		return false
+	})
+}
+
+// Release returns n previously acquired bytes to the budget
+func (b *Budget) Release(n uint64) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used -= b.clamp(n)
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Wait blocks while the budget is exhausted, or until the context is done
+func (b *Budget) Wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	return b.wait(ctx, func() bool {
+		return b.used < b.limit
+	})
+}
+
+// Used returns the amount of bytes currently reserved
+func (b *Budget) Used() uint64 {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.used
+}
@@ -0,0 +1,93 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	tests := []struct {
+		name     string
+		held     uint64
+		n        uint64
+		acquired bool
+		used     uint64
+	}{
+		{"empty", 0, 50, true, 50},
+		{"fits", 40, 60, true, 100},
+		{"exceeds", 50, 60, false, 50},
+		{"over limit on empty", 0, 500, true, 100},
+		{"over limit", 1, 500, false, 1},
+	}
+
+	for _, test := range tests {
+		b := New(100)
+		if err := b.Acquire(context.Background(), test.held); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		err := b.Acquire(ctx, test.n)
+		cancel()
+
+		if acquired := err == nil; acquired != test.acquired {
+			t.Errorf("%s: Acquire(%d) with %d held = %v, acquired %v", test.name, test.n, test.held, err, test.acquired)
+		}
+		if used := b.Used(); used != test.used {
+			t.Errorf("%s: Used() = %d, want %d", test.name, used, test.used)
+		}
+
+		if acquired := err == nil; acquired {
+			b.Release(test.n)
+			if used := b.Used(); used != test.held {
+				t.Errorf("%s: Used() after Release(%d) = %d, want %d", test.name, test.n, used, test.held)
+			}
+		}
+	}
+}
+
+func TestAcquireWaits(t *testing.T) {
+	b := New(100)
+	if err := b.Acquire(context.Background(), 80); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- b.Acquire(context.Background(), 500)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Acquire() over limit did not wait for release")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	b.Release(80)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if used := b.Used(); used != 100 {
+		t.Errorf("Used() = %d, want 100", used)
+	}
+}
+
+func TestNil(t *testing.T) {
+	b := New(0)
+	if b != nil {
+		t.Fatalf("New(0) = %v, want nil", b)
+	}
+
+	if err := b.Acquire(context.Background(), 1<<40); err != nil {
+		t.Errorf("Acquire() on nil = %v", err)
+	}
+	if err := b.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() on nil = %v", err)
+	}
+	b.Release(1 << 40)
+	if used := b.Used(); used != 0 {
+		t.Errorf("Used() on nil = %d", used)
+	}
+}
@@ -0,0 +1,71 @@
+// Package cids decodes CIDs of any hash function, and recognizes identity
+// CIDs, which hold their content inline rather than a digest of it.
+package cids
+
+import (
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+	"strconv"
+)
+
+// hashFunctions are multihash functions in use on IPFS which the multihash
+// library does not know, with their name and default digest length. CIDs
+// using them fail to decode unless registered.
+var hashFunctions = []struct {
+	code   uint64
+	name   string
+	length int
+}{
+	{0x1e, "blake3", 32},
+	{0x20, "sha2-384", 48},
+	{0xd5, "md5", 16},
+	{0x1012, "sha2-256-trunc254-padded", 32}, // Filecoin piece commitments
+	{0x1013, "sha2-224", 28},
+	{0x1014, "sha2-512-224", 28},
+	{0x1015, "sha2-512-256", 32},
+	{0xb401, "poseidon-bls12_381-a2-fc1", 32}, // Filecoin sector commitments
+}
+
+func init() {
+	for _, f := range hashFunctions {
+		if _, ok := mh.Codes[f.code]; ok {
+			continue
+		}
+
+		mh.Codes[f.code] = f.name
+		mh.Names[f.name] = f.code
+		mh.DefaultLengths[f.code] = f.length
+	}
+}
+
+// Decode parses a CID in any version and multibase, like cid.Decode,
+// including CIDs of the hash functions registered by this package
+func Decode(hash string) (cid.Cid, error) {
+	return cid.Decode(hash)
+}
+
+// HashFunction returns the name of the hash function of a CID, e.g.
+// sha2-256 or blake2b-256, or its code in hex when it is unknown
+func HashFunction(c cid.Cid) string {
+	code := c.Prefix().MhType
+	if name, ok := mh.Codes[code]; ok {
+		return name
+	}
+
+	return "0x" + strconv.FormatUint(code, 16)
+}
+
+// Inline returns the content of the block of an identity CID, which its
+// multihash holds instead of a digest, without fetching it
+func Inline(c cid.Cid) ([]byte, bool) {
+	if c.Prefix().MhType != mh.ID {
+		return nil, false
+	}
+
+	decoded, err := mh.Decode(c.Hash())
+	if err != nil {
+		return nil, false
+	}
+
+	return decoded.Digest, true
+}
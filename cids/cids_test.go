@@ -0,0 +1,55 @@
+package cids
+
+import (
+	"github.com/multiformats/go-multibase"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		hash     string
+		function string
+		valid    bool
+	}{
+		{"QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX", "sha2-256", true},
+		{"bafk2bzacea73ycjnxe2qov7cvnhx52lzfp6nf5jcblnfus6gqreh6ygganbws", "blake2b-256", true},
+		{"bafkr4ihkr4ld3m4gqkjf4reryxsy2s5tkbxprqkow6fin2iiyvreuzzab4", "blake3", true},
+		{"bafkqac3jobthglltmvqxey3i", "id", true},
+		{"bafkr4ihkr4ld3m4gqkjf4reryxsy2s5tkbxprqkow6fin2iiyvreuzza", "", false},
+		{"notahash", "", false},
+	}
+
+	for _, test := range tests {
+		c, err := Decode(test.hash)
+		if (err == nil) != test.valid {
+			t.Errorf("Decode(%s) error = %v, valid %v", test.hash, err, test.valid)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if f := HashFunction(c); f != test.function {
+			t.Errorf("HashFunction(%s) = %s, want %s", test.hash, f, test.function)
+		}
+		if v1, _ := c.StringOfBase(multibase.Base32); c.Version() == 1 && v1 != test.hash {
+			t.Errorf("Decode(%s) = %s", test.hash, v1)
+		}
+	}
+}
+
+func TestInline(t *testing.T) {
+	c, err := Decode("bafkqac3jobthglltmvqxey3i")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, ok := Inline(c); !ok || string(data) != "ipfs-search" {
+		t.Errorf("Inline() = %q, %v, want ipfs-search", data, ok)
+	}
+
+	c, _ = Decode("QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX")
+	if _, ok := Inline(c); ok {
+		t.Error("Inline() of sha2-256 CID returned content")
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Package classifier assigns topic categories to text documents through an
+external classification service. Requests of concurrent crawlers are
+collected into batches, as classification models process batches far
+more efficiently than single documents.
+
+The service receives POST requests with a JSON body like
+{"texts": ["first document", "second document"]} and responds with the
+categories of every text, in order: {"categories": [["science"], []]}.
+*/
+package classifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Config configures the classification service
+type Config struct {
+	URL       string        // Endpoint of the service
+	Timeout   time.Duration // Time the service has to classify a batch
+	BatchSize int           // Maximum texts classified at once
+	BatchWait time.Duration // Time texts wait for a batch to fill up
+	MaxLength int           // Bytes of text sent per document
+}
+
+// result is the outcome of classifying a single text
+type result struct {
+	categories []string
+	err        error
+}
+
+// request is a text waiting to be classified
+type request struct {
+	text   string
+	result chan result
+}
+
+// Classifier classifies texts in batches
+type Classifier struct {
+	config   *Config
+	client   *http.Client
+	requests chan *request
+}
+
+// New returns a classifier for configuration, or nil if no service is
+// configured
+func New(config *Config) *Classifier {
+	if config == nil || config.URL == "" {
+		return nil
+	}
+
+	c := &Classifier{
+		config:   config,
+		client:   &http.Client{Timeout: config.Timeout},
+		requests: make(chan *request),
+	}
+
+	go c.run()
+
+	return c
+}
+
+// run collects requests into batches, sending a batch when it is full or
+// when its first request has waited BatchWait
+func (c *Classifier) run() {
+	for {
+		batch := []*request{<-c.requests}
+		timer := time.NewTimer(c.config.BatchWait)
+
+	collect:
+		for len(batch) < c.config.BatchSize {
+			select {
+			case r := <-c.requests:
+				batch = append(batch, r)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		go c.classifyBatch(batch)
+	}
+}
+
+// classifyBatch classifies a batch of texts and delivers the results
+func (c *Classifier) classifyBatch(batch []*request) {
+	texts := make([]string, len(batch))
+	for n, r := range batch {
+		texts[n] = r.text
+	}
+
+	categories, err := c.post(texts)
+	for n, r := range batch {
+		if err != nil {
+			r.result <- result{err: err}
+			continue
+		}
+
+		r.result <- result{categories: categories[n]}
+	}
+}
+
+// post sends texts to the service, returning their categories in order
+func (c *Classifier) post(texts []string) ([][]string, error) {
+	body, err := json.Marshal(map[string]interface{}{"texts": texts})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Post(c.config.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("undesired status '%s' from classifier", resp.Status)
+	}
+
+	var response struct {
+		Categories [][]string `json:"categories"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid classifier response: %v", err)
+	}
+
+	if len(response.Categories) != len(texts) {
+		return nil, fmt.Errorf("classifier returned %d results for %d texts", len(response.Categories), len(texts))
+	}
+
+	return response.Categories, nil
+}
+
+// truncate returns the first max bytes of text, without splitting
+// characters
+func truncate(text string, max int) string {
+	if max <= 0 || len(text) <= max {
+		return text
+	}
+
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+
+	return text[:max]
+}
+
+// Classify returns the categories of text. A nil classifier returns none.
+func (c *Classifier) Classify(ctx context.Context, text string) ([]string, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	r := &request{
+		text:   truncate(text, c.config.MaxLength),
+		result: make(chan result, 1),
+	}
+
+	select {
+	case c.requests <- r:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case res := <-r.result:
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		var categories []string
+		for _, category := range res.categories {
+			if category = strings.TrimSpace(category); category != "" {
+				categories = append(categories, category)
+			}
+		}
+
+		return categories, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
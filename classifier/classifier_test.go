@@ -0,0 +1,124 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestClassifier returns a classifier for a service categorizing texts
+// by their first word, recording the sizes of batches
+func newTestClassifier(t *testing.T, config Config) (*Classifier, *[]int) {
+	var mu sync.Mutex
+	var batches []int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Texts []string `json:"texts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+
+		mu.Lock()
+		batches = append(batches, len(request.Texts))
+		mu.Unlock()
+
+		categories := make([][]string, len(request.Texts))
+		for n, text := range request.Texts {
+			categories[n] = []string{strings.Fields(text)[0], " "}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"categories": categories})
+	}))
+	t.Cleanup(server.Close)
+
+	config.URL = server.URL
+	config.Timeout = time.Second
+
+	return New(&config), &batches
+}
+
+func TestClassify(t *testing.T) {
+	c, batches := newTestClassifier(t, Config{BatchSize: 3, BatchWait: time.Second})
+
+	var wg sync.WaitGroup
+	for _, word := range []string{"science", "music", "sports"} {
+		wg.Add(1)
+		go func(word string) {
+			defer wg.Done()
+
+			categories, err := c.Classify(context.Background(), word+" text")
+			if err != nil {
+				t.Error(err)
+			}
+			if !reflect.DeepEqual(categories, []string{word}) {
+				t.Errorf("Classify(%s) = %v, want [%s]", word, categories, word)
+			}
+		}(word)
+	}
+	wg.Wait()
+
+	if !reflect.DeepEqual(*batches, []int{3}) {
+		t.Errorf("batches = %v, want a single batch of 3", *batches)
+	}
+}
+
+func TestClassifyBatchWait(t *testing.T) {
+	c, batches := newTestClassifier(t, Config{BatchSize: 10, BatchWait: 10 * time.Millisecond})
+
+	categories, err := c.Classify(context.Background(), "science text")
+	if err != nil || !reflect.DeepEqual(categories, []string{"science"}) {
+		t.Errorf("Classify() = %v, %v; want [science]", categories, err)
+	}
+	if !reflect.DeepEqual(*batches, []int{1}) {
+		t.Errorf("batches = %v, want a single batch of 1", *batches)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"categories": []}`))
+	}))
+	defer server.Close()
+
+	c := New(&Config{URL: server.URL, Timeout: time.Second, BatchSize: 1})
+	if _, err := c.Classify(context.Background(), "text"); err == nil {
+		t.Error("Classify() with missing result succeeded")
+	}
+}
+
+func TestNil(t *testing.T) {
+	c := New(&Config{})
+	if c != nil {
+		t.Fatalf("New() without URL = %v, want nil", c)
+	}
+
+	if categories, err := c.Classify(context.Background(), "text"); categories != nil || err != nil {
+		t.Errorf("Classify() on nil = %v, %v", categories, err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text string
+		max  int
+		want string
+	}{
+		{"text", 0, "text"},
+		{"text", 10, "text"},
+		{"text", 2, "te"},
+		{"café", 4, "caf"},
+	}
+
+	for _, test := range tests {
+		if got := truncate(test.text, test.max); got != test.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", test.text, test.max, got, test.want)
+		}
+	}
+}
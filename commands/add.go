@@ -1,28 +1,163 @@
 package commands
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/ipfs-search/ipfs-search/config"
 	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
 	"github.com/ipfs-search/ipfs-search/queue"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"time"
 )
 
-// AddHash queues a single IPFS hash for indexing
-func AddHash(cfg *config.Config, hash string) error {
-	conn, err := queue.NewConnection(cfg.AMQP.AMQPURL)
+// AddOptions determine which hashes are added and whether to wait for them
+type AddOptions struct {
+	Hashes  []string      // Hashes given as arguments
+	Sources []io.Reader   // Readers providing hashes, one per line
+	Wait    bool          // Wait for crawls to complete and write manifests
+	Timeout time.Duration // Maximum time to wait for all crawls
+	TTL     time.Duration // Time after which crawls not started expire, 0 for never
+
+	StructureOnly bool // Index directory structure and file names without file contents
+}
+
+// hashAdder publishes hashes to the hash queue
+type hashAdder struct {
+	queue     *queue.Queue
+	job       string        // Recorded in provenance of added hashes
+	ttl       time.Duration // Deadline of added hashes from now, 0 for none
+	structure bool          // Crawl added hashes in structure-only mode
+	added     []string
+	skipped   int
+}
+
+// add queues a hash with highest priority, as it is supposed to be
+// available. Invalid hashes are skipped.
+func (a *hashAdder) add(hash string) error {
+	normalized, err := crawler.NormalizeHash(hash)
+	if err != nil {
+		log.WithError(err).WithField("hash", hash).Warn("Skipping invalid hash")
+		a.skipped++
+		return nil
+	}
+
+	err = crawler.PublishWithin(context.Background(), a.queue, &crawler.Args{
+		Hash: hash,
+		Provenance: &indexer.Provenance{
+			Source: crawler.SourceAdd,
+			Job:    a.job,
+		},
+		StructureOnly: a.structure,
+	}, 9, a.ttl)
+	if err != nil {
+		return err
+	}
+
+	a.added = append(a.added, normalized)
+	return nil
+}
+
+// addFrom queues hashes read from r, one per line. Blank lines and lines
+// starting with # are ignored, as are any fields after the hash.
+func (a *hashAdder) addFrom(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if err := a.add(fields[0]); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// writeManifests waits for the crawls of roots to complete, writing their
+// manifests to out as JSON lines. An error is returned when any crawl did
+// not complete or yielded invalid items.
+func writeManifests(ctx context.Context, cfg *config.Config, roots []string, timeout time.Duration, out io.Writer) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	encoder := json.NewEncoder(out)
+	failed := 0
+
+	for _, root := range roots {
+		m, err := waitForManifest(ctx, i, root, deadline)
+		if err != nil {
+			return err
+		}
+
+		if !m.Complete || len(m.Errors) > 0 {
+			failed++
+		}
+
+		if err := encoder.Encode(m); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d crawls incomplete or with errors", failed, len(roots))
+	}
+
+	return nil
+}
+
+// AddHashes queues IPFS hashes for indexing, given as arguments and read
+// from sources. Without waiting, a summary is written to out.
+func AddHashes(ctx context.Context, cfg *config.Config, options *AddOptions, out io.Writer) error {
+	conn, err := getConnection(cfg)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
-	queue, err := conn.NewChannelQueue("hashes")
+	q, err := conn.NewChannelQueue("hashes")
 	if err != nil {
 		return err
 	}
 
-	// Add with highest priority, as this is supposed to be available
-	err = queue.Publish(&crawler.Args{
-		Hash: hash,
-	}, 9)
+	a := &hashAdder{
+		queue:     q,
+		job:       fmt.Sprintf("add-%d", time.Now().Unix()),
+		ttl:       options.TTL,
+		structure: options.StructureOnly,
+	}
+	log.WithField("job", a.job).Debug("Adding hashes")
+
+	for _, hash := range options.Hashes {
+		if err := a.add(hash); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range options.Sources {
+		if err := a.addFrom(r); err != nil {
+			return err
+		}
+	}
+
+	if options.Wait {
+		log.WithFields(log.Fields{
+			"added":   len(a.added),
+			"skipped": a.skipped,
+		}).Info("Waiting for crawls to complete")
+
+		return writeManifests(ctx, cfg, a.added, options.Timeout, out)
+	}
 
+	_, err = fmt.Fprintf(out, "Added %d hashes to queue, skipped %d invalid\n", len(a.added), a.skipped)
 	return err
 }
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/api"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/denylist"
+	"github.com/ipfs-search/ipfs-search/extractor/tika"
+	log "github.com/sirupsen/logrus"
+)
+
+// API serves the HTTP API until the context is cancelled
+func API(ctx context.Context, cfg *config.Config) error {
+	stopTracing, err := startTracing(ctx, cfg, "ipfs-search-api")
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := getConnection(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	fileQueue, err := conn.NewChannelQueue("files")
+	if err != nil {
+		return err
+	}
+
+	hashQueue, err := conn.NewChannelQueue("hashes")
+	if err != nil {
+		return err
+	}
+
+	sh := getShell(cfg)
+
+	dl := denylist.New(cfg.DenylistConfig())
+	if err := dl.Load(ctx); err != nil {
+		return err
+	}
+	go dl.Watch(ctx)
+
+	// Used for lookups and crawls requested through the API
+	c := &crawler.Crawler{
+		Config:    cfg.CrawlerConfig(),
+		Shell:     sh,
+		Indexer:   i,
+		Extractor: tika.New(cfg.TikaConfig()),
+		FileQueue: fileQueue,
+		HashQueue: hashQueue,
+		Denylist:  dl,
+	}
+
+	s, err := api.New(cfg.APIConfig(), i, c)
+	if err != nil {
+		return err
+	}
+
+	err = s.Serve(ctx)
+
+	log.Infof("API stopped: %s", err)
+
+	return err
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/worker"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// throttleDiscovery limits the hash workers, which discover new content,
+// to their minimum while the high disk watermark is forecast to be hit
+// within capacity.throttle_within, checking every capacity.interval until
+// the context is cancelled
+func throttleDiscovery(ctx context.Context, cfg *config.Config, i *indexer.Indexer, hashes *worker.Autoscaler) {
+	throttled := false
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, statusTimeout)
+		c, err := i.Capacity(checkCtx, cfg.Capacity.Window)
+		cancel()
+
+		switch {
+		case err != nil:
+			// Keep the current state rather than guessing
+			log.WithError(err).Warn("Error forecasting disk capacity")
+		case c.Within("high", cfg.Capacity.ThrottleWithin) != throttled:
+			throttled = !throttled
+			hashes.Throttle(throttled)
+
+			if throttled {
+				log.Warnf("High disk watermark forecast within %s, throttling discovery", cfg.Capacity.ThrottleWithin)
+			} else {
+				log.Info("Disk capacity recovered, no longer throttling discovery")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cfg.Capacity.Interval):
+		}
+	}
+}
@@ -2,11 +2,14 @@ package commands
 
 import (
 	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/admin"
 	"github.com/ipfs-search/ipfs-search/config"
 	"github.com/ipfs-search/ipfs-search/crawler/factory"
+	"github.com/ipfs-search/ipfs-search/indexer"
 	"github.com/ipfs-search/ipfs-search/worker"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
-	"log"
 )
 
 // block blocks until context is cancelled
@@ -21,7 +24,7 @@ func block(ctx context.Context) error {
 func errorLoop(errc <-chan error) {
 	for {
 		err := <-errc
-		log.Printf("%T: %v", err, err)
+		log.WithField("type", fmt.Sprintf("%T", err)).Error(err)
 	}
 }
 
@@ -31,29 +34,129 @@ func startWorkers(ctx context.Context, cfg *config.Config, errc chan<- error) (*
 		return nil, err
 	}
 
-	hashGroup := worker.Group{
-		Count:   cfg.Crawler.HashWorkers,
-		Wait:    cfg.Crawler.HashWait,
-		Factory: factory.NewHashWorker,
+	hashLoad, err := factory.HashLoad()
+	if err != nil {
+		return nil, err
+	}
+
+	fileLoad, err := factory.FileLoad()
+	if err != nil {
+		return nil, err
+	}
+
+	hashGroup := &worker.Autoscaler{
+		Min:         cfg.Crawler.MinHashWorkers,
+		Max:         cfg.Crawler.HashWorkers,
+		Wait:        cfg.Crawler.HashWait,
+		Interval:    cfg.Crawler.ScaleInterval,
+		Factory:     factory.NewHashWorker,
+		Load:        hashLoad,
+		Utilization: factory.Utilization("hashes"),
+	}
+	fileGroup := &worker.Autoscaler{
+		Min:         cfg.Crawler.MinFileWorkers,
+		Max:         cfg.Crawler.FileWorkers,
+		Wait:        cfg.Crawler.FileWait,
+		Interval:    cfg.Crawler.ScaleInterval,
+		Factory:     factory.NewFileWorker,
+		Load:        fileLoad,
+		Utilization: factory.Utilization("files"),
 	}
-	fileGroup := worker.Group{
-		Count:   cfg.Crawler.FileWorkers,
-		Wait:    cfg.Crawler.FileWait,
-		Factory: factory.NewFileWorker,
+
+	groups := map[string]*worker.Autoscaler{
+		"hashes": hashGroup,
+		"files":  fileGroup,
+	}
+
+	// Dedicated pools for routed files
+	for n, r := range cfg.CrawlerConfig().Routes {
+		load, err := factory.RouteLoad(r)
+		if err != nil {
+			return nil, err
+		}
+
+		groups[r.Queue()] = &worker.Autoscaler{
+			Min:         1,
+			Max:         cfg.Crawler.Routes[n].Workers,
+			Wait:        cfg.Crawler.FileWait,
+			Interval:    cfg.Crawler.ScaleInterval,
+			Factory:     factory.RouteWorkerFactory(r),
+			Load:        load,
+			Utilization: factory.Utilization(r.Queue()),
+		}
+	}
+
+	var server *admin.Server
+	if cfg.Admin.Listen != "" {
+		server, err = newAdmin(cfg, groups)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Throttling discovery requires the Elasticsearch 5 backend
+	var i *indexer.Indexer
+	if cfg.Capacity.ThrottleWithin > 0 && !indexer.IsTypeless(cfg.ElasticSearch.Backend) {
+		i, err = getIndexer(cfg)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Create error group and context
 	errg, ctx := errgroup.WithContext(ctx)
 
-	// Start work loop
-	errg.Go(func() error { return hashGroup.Work(ctx) })
-	errg.Go(func() error { return fileGroup.Work(ctx) })
+	if server != nil {
+		errg.Go(func() error { return server.Serve(ctx) })
+	}
+
+	// Start work loops
+	for _, group := range groups {
+		group := group
+		errg.Go(func() error { return group.Work(ctx) })
+	}
+
+	// Reload denylist while crawling
+	go factory.WatchDenylist(ctx)
+
+	// Route IPFS requests around unhealthy nodes
+	go factory.WatchIPFS(ctx)
+
+	// Slow down discovery before the cluster runs out of disk space
+	if i != nil {
+		go throttleDiscovery(ctx, cfg, i, hashGroup)
+	}
 
 	return errg, nil
 }
 
+// newAdmin returns an admin API server controlling worker groups
+func newAdmin(cfg *config.Config, groups map[string]*worker.Autoscaler) (*admin.Server, error) {
+	conn, err := getConnection(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Curations are only supported by the Elasticsearch 5 backend
+	var i *indexer.Indexer
+	if !indexer.IsTypeless(cfg.ElasticSearch.Backend) {
+		i, err = getIndexer(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return admin.New(cfg.AdminConfig(), conn, i, groups)
+}
+
 // Crawl configures and initializes crawling
 func Crawl(ctx context.Context, cfg *config.Config) error {
+	stopTracing, err := startTracing(ctx, cfg, "ipfs-search-crawler")
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
 	errc := make(chan error, 1)
 
 	errg, err := startWorkers(ctx, cfg, errc)
@@ -61,16 +164,16 @@ func Crawl(ctx context.Context, cfg *config.Config) error {
 		return err
 	}
 
-	log.Printf("Waiting for messages")
+	log.Info("Waiting for messages")
 
 	// Log messages, wait for context break
 	go errorLoop(errc)
 	err = block(ctx)
 
-	log.Printf("Shutting down: %s", err)
-	log.Print("Waiting for processes to finish")
+	log.Infof("Shutting down: %s", err)
+	log.Info("Waiting for processes to finish")
 
 	err = errg.Wait()
-	log.Printf("Error group finished: %s", err)
+	log.Infof("Error group finished: %s", err)
 	return err
 }
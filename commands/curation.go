@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"io"
+)
+
+// AddCuration stores a boost or pin and writes its ID to w
+func AddCuration(ctx context.Context, cfg *config.Config, c *indexer.Curation, w io.Writer) error {
+	hash, err := crawler.NormalizeHash(c.Hash)
+	if err != nil {
+		return fmt.Errorf("invalid hash '%s': %v", c.Hash, err)
+	}
+	c.Hash = hash
+
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	id, err := i.AddCuration(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, id)
+
+	return nil
+}
+
+// ListCurations writes all boosts and pins to w
+func ListCurations(ctx context.Context, cfg *config.Config, w io.Writer) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	curations, err := i.Curations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range curations {
+		fmt.Fprintln(w, c.String())
+	}
+
+	return nil
+}
+
+// RemoveCuration removes a boost or pin by ID
+func RemoveCuration(ctx context.Context, cfg *config.Config, id string) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	return i.RemoveCuration(ctx, id)
+}
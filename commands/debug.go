@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"expvar"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+)
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return runtime.NumGoroutine()
+	}))
+}
+
+// debugMux returns a handler for pprof profiles, expvar variables
+// (including memstats) and full goroutine dumps
+func debugMux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	mux.HandleFunc("/debug/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := runtimepprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+			log.WithError(err).Warn("Error writing goroutine dump")
+		}
+	})
+
+	return mux
+}
+
+// ServeDebug serves runtime debugging endpoints on addr in the background,
+// for the lifetime of the process. It returns an error when addr can't
+// be listened on.
+func ServeDebug(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("address", listener.Addr()).Info("Debug endpoints listening")
+
+	go func() {
+		if err := http.Serve(listener, debugMux()); err != nil {
+			log.WithError(err).Error("Error serving debug endpoints")
+		}
+	}()
+
+	return nil
+}
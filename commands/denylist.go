@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/denylist"
+	log "github.com/sirupsen/logrus"
+)
+
+// ApplyDenylist removes indexed documents for CIDs on the denylist,
+// returning the amount removed. Anchored entries can't be enumerated;
+// these are removed when encountered by the crawler.
+func ApplyDenylist(ctx context.Context, cfg *config.Config) (int, error) {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	dl := denylist.New(cfg.DenylistConfig())
+	if err := dl.Load(ctx); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, hash := range dl.Hashes() {
+		// Documents are indexed by normalized hash
+		hash, err := crawler.NormalizeHash(hash)
+		if err != nil {
+			return removed, err
+		}
+
+		deleted, err := i.DeleteItem(ctx, hash)
+		if err != nil {
+			return removed, err
+		}
+
+		if deleted {
+			log.WithField("hash", hash).Info("Removed denied hash from index")
+			removed++
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs/go-ipfs-api"
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// dependencyRetry is the time between checks when waiting for dependencies
+const dependencyRetry = 5 * time.Second
+
+// dependency checks whether a service required for crawling is usable,
+// returning a description of it
+type dependency struct {
+	name  string
+	check func(ctx context.Context, cfg *config.Config) (string, error)
+}
+
+var dependencies = []dependency{
+	{"IPFS", checkIPFS},
+	{"AMQP", checkAMQP},
+	{"Elasticsearch", checkElasticSearch},
+	{"ipfs-tika", checkTika},
+	{"Tika server", checkTikaServer},
+}
+
+// checkIPFS checks every IPFS node; crawling can start as long as one of
+// them is reachable, as requests fail over to others
+func checkIPFS(ctx context.Context, cfg *config.Config) (string, error) {
+	var up, down []string
+	var err error
+
+	for _, url := range cfg.IPFS.URLs() {
+		sh := shell.NewShell(url)
+		sh.SetTimeout(statusTimeout)
+
+		var version string
+		version, _, err = sh.Version()
+		if err != nil {
+			log.WithError(err).WithField("node", url).Warn("Cannot reach IPFS node")
+			down = append(down, url)
+			continue
+		}
+
+		up = append(up, fmt.Sprintf("version %s at %s", version, url))
+	}
+
+	if len(up) == 0 {
+		return "", fmt.Errorf("cannot reach IPFS API at %s: %v", strings.Join(down, ", "), err)
+	}
+
+	if len(down) > 0 {
+		return fmt.Sprintf("%s; unreachable: %s", strings.Join(up, ", "), strings.Join(down, ", ")), nil
+	}
+
+	return strings.Join(up, ", "), nil
+}
+
+// redactedBrokerURL returns the broker URL without password
+func redactedBrokerURL(cfg *config.Config) string {
+	uri, err := amqp.ParseURI(cfg.BrokerURL())
+	if err != nil {
+		// Redis Streams, NATS JetStream or in-memory queues
+		if u, err := url.Parse(cfg.BrokerURL()); err == nil && u.Scheme != "" {
+			return u.Redacted()
+		}
+
+		return "invalid URL"
+	}
+
+	uri.Password = ""
+	return uri.String()
+}
+
+func checkAMQP(ctx context.Context, cfg *config.Config) (string, error) {
+	broker := redactedBrokerURL(cfg)
+
+	conn, err := getConnection(cfg)
+	switch err {
+	case nil:
+	case amqp.ErrCredentials:
+		return "", fmt.Errorf("authentication failed at %s; check the AMQP username and password", broker)
+	case amqp.ErrVhost:
+		return "", fmt.Errorf("no access to vhost at %s; check the AMQP vhost and user permissions", broker)
+	default:
+		return "", fmt.Errorf("cannot connect to broker at %s: %v", broker, err)
+	}
+	defer conn.Close()
+
+	return broker, nil
+}
+
+func checkElasticSearch(ctx context.Context, cfg *config.Config) (string, error) {
+	urls := strings.Join(cfg.ElasticSearchConfig().URLs, ", ")
+
+	i, err := getCluster(cfg)
+	if err != nil {
+		return "", fmt.Errorf("cannot connect to Elasticsearch at %s: %v", urls, err)
+	}
+
+	version, err := i.Version(ctx)
+	if err != nil {
+		return "", fmt.Errorf("cannot get Elasticsearch version at %s: %v", urls, err)
+	}
+
+	health, err := i.IndexHealth(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%v; create indices with `ipfs-search index ensure`", err)
+	}
+	if health == "red" {
+		return "", fmt.Errorf("indices are red, some shards are not allocated")
+	}
+
+	return fmt.Sprintf("version %s, indices %s", version, health), nil
+}
+
+// checkListening checks whether an HTTP server is listening at url
+func checkListening(ctx context.Context, name, url string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s URL %s: %v", name, url, err)
+	}
+
+	// Any response means the server is listening
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("cannot reach %s at %s: %v", name, url, err)
+	}
+	resp.Body.Close()
+
+	return url, nil
+}
+
+func checkTika(ctx context.Context, cfg *config.Config) (string, error) {
+	return checkListening(ctx, "ipfs-tika", cfg.Tika.IpfsTikaURL)
+}
+
+// checkTikaServer checks the Tika server used for partial extraction, if
+// configured
+func checkTikaServer(ctx context.Context, cfg *config.Config) (string, error) {
+	if cfg.Tika.TikaServerURL == "" {
+		return "not configured, skipping metadata of large files", nil
+	}
+
+	return checkListening(ctx, "Tika server", cfg.Tika.TikaServerURL)
+}
+
+// checkDependencies checks all dependencies, logging their state, and
+// returns an error describing those unavailable
+func checkDependencies(ctx context.Context, cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+
+	var failed []string
+	for _, d := range dependencies {
+		description, err := d.check(ctx, cfg)
+		if err != nil {
+			log.WithField("dependency", d.name).Error(err)
+			failed = append(failed, fmt.Sprintf("%s: %s", d.name, err))
+			continue
+		}
+
+		log.WithField("dependency", d.name).Infof("Available: %s", description)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Dependencies unavailable:\n  %s", strings.Join(failed, "\n  "))
+	}
+
+	return nil
+}
+
+// CheckDependencies verifies IPFS, the AMQP broker, Elasticsearch and
+// ipfs-tika are usable for crawling. When wait is set, checks are retried
+// until all dependencies are available or the context is cancelled.
+func CheckDependencies(ctx context.Context, cfg *config.Config, wait bool) error {
+	for {
+		err := checkDependencies(ctx, cfg)
+		if err == nil || !wait {
+			return err
+		}
+
+		log.Infof("Waiting %s for dependencies", dependencyRetry)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(dependencyRetry):
+		}
+	}
+}
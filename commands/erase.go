@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/denylist"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"sort"
+	"time"
+)
+
+// EraseOptions describe content to erase and why
+type EraseOptions struct {
+	Request   string // CID or hex SHA-256 checksum of the content
+	Reason    string // Recorded in the audit log and the denylist
+	Operator  string // Who erased the content
+	Recursive bool   // Remove directory entries as well, unless referenced from elsewhere
+}
+
+// requestHashes returns the normalized hashes of a CID, or those of the
+// blocks with a checksum
+func requestHashes(request string) ([]string, error) {
+	if hash, err := crawler.NormalizeHash(request); err == nil {
+		return []string{hash}, nil
+	}
+
+	hashes, err := crawler.ChecksumHashes(request)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is neither a CID nor a SHA-256 checksum", request)
+	}
+
+	return hashes, nil
+}
+
+// Erase removes content from the index for good: it is denied first, so
+// crawlers don't index it again, then its documents, crawl history,
+// overrides and thumbnails are removed, as are links and references to it
+// in other documents, and finally the erasure is recorded in the audit log.
+// Steps which are done are skipped when run again, so failed erasures can
+// be completed by repeating them. The erasure recorded then only lists the
+// documents removed by the run completing it, as those removed by earlier
+// runs are gone without a trace.
+func Erase(ctx context.Context, cfg *config.Config, options *EraseOptions) (*indexer.Erasure, error) {
+	if options.Reason == "" {
+		return nil, fmt.Errorf("erasures require a reason")
+	}
+
+	hashes, err := requestHashes(options.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dl := denylist.New(cfg.DenylistConfig())
+	if err := dl.Load(ctx); err != nil {
+		return nil, err
+	}
+
+	e := &indexer.Erasure{
+		Time:     time.Now().UTC(),
+		Operator: options.Operator,
+		Reason:   options.Reason,
+		Request:  options.Request,
+		Hashes:   hashes,
+	}
+
+	comment := fmt.Sprintf("Erased %s by %s: %s", e.Time.Format(time.RFC3339), e.Operator, e.Reason)
+	for _, hash := range hashes {
+		if err := dl.Deny(hash, comment); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &purger{
+		indexer: i,
+		options: &PurgeOptions{
+			Recursive:   options.Recursive,
+			Unlink:      true,
+			Dereference: true,
+		},
+		purged: make(map[string]bool),
+		shell:  getShell(cfg),
+		meta:   true,
+	}
+
+	for _, hash := range hashes {
+		if err := p.purge(ctx, hash, ""); err != nil {
+			return nil, err
+		}
+
+		// Also when its document is gone already
+		if err := i.DeleteMeta(ctx, hash); err != nil {
+			return nil, err
+		}
+	}
+
+	e.Removed = make([]string, 0, len(p.purged))
+	for hash := range p.purged {
+		e.Removed = append(e.Removed, hash)
+	}
+	sort.Strings(e.Removed)
+	e.Unlinked, e.Dereferenced = p.unlinked, p.dereferenced
+
+	if err := i.RecordErasure(ctx, e); err != nil {
+		return nil, err
+	}
+
+	log.WithField("request", options.Request).WithField("removed", len(e.Removed)).Info("Erased")
+
+	return e, nil
+}
+
+// WriteErasures writes the most recent erasures in the audit log to w as
+// JSON lines
+func WriteErasures(ctx context.Context, cfg *config.Config, size int, w io.Writer) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	erasures, err := i.Erasures(ctx, size)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, e := range erasures {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/metrics"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// trendWindow is the period of scrapes queue trends are estimated over
+const trendWindow = 15 * time.Minute
+
+// Exporter serves queue and index metrics for Prometheus until the context
+// is cancelled, without crawling. Hourly rollups of indexing statistics
+// are written to Elasticsearch as well.
+func Exporter(ctx context.Context, cfg *config.Config) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := getConnection(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(&metrics.Collector{
+		Connection: conn,
+		Indexer:    i,
+		Queues:     crawlerQueues(cfg),
+		Timeout:    statusTimeout,
+		Estimator:  queue.NewEstimator(trendWindow),
+		Window:     cfg.Capacity.Window,
+	})
+
+	rollups := &metrics.RollupWriter{
+		Indexer: i,
+		Timeout: statusTimeout,
+	}
+	go rollups.Work(ctx)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	srv := &http.Server{
+		Addr:    cfg.Metrics.Listen,
+		Handler: mux,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		log.WithField("address", cfg.Metrics.Listen).Info("Exporter listening")
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		if err = srv.Shutdown(context.Background()); err == nil {
+			err = ctx.Err()
+		}
+	}
+
+	log.Infof("Exporter stopped: %s", err)
+
+	return err
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/indexer"
+)
+
+// EnsureIndex creates the indices or verifies and updates their mappings.
+// For typeless backends, indices are only created.
+func EnsureIndex(ctx context.Context, cfg *config.Config) error {
+	if indexer.IsTypeless(cfg.ElasticSearch.Backend) {
+		t, err := indexer.NewTypeless(cfg.ElasticSearchConfig())
+		if err != nil {
+			return err
+		}
+
+		_, err = t.CreateIndex(ctx)
+		return err
+	}
+
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	return i.EnsureIndex(ctx)
+}
+
+// MigrateIndex moves documents from the legacy single index to per-type
+// indices, deleting the legacy index afterwards when deleteLegacy is set
+func MigrateIndex(ctx context.Context, cfg *config.Config, deleteLegacy bool) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	return i.Migrate(ctx, deleteLegacy)
+}
+
+// MigrateIDs moves documents to the IDs of the configured scheme, merging
+// duplicates
+func MigrateIDs(ctx context.Context, cfg *config.Config) (*indexer.IDMigration, error) {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.MigrateIDs(ctx)
+}
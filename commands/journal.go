@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/journal"
+	"io"
+)
+
+// InFlight writes the tasks journaled as in flight to w as JSON lines, in
+// the format of DumpQueues, and returns their number. With requeue, they
+// are published to their queues again and removed from the journal. The
+// crawler using the journal should be stopped, as it appends to it.
+func InFlight(cfg *config.Config, requeue bool, w io.Writer) (int, error) {
+	if cfg.Crawler.Journal == "" {
+		return 0, fmt.Errorf("no journal configured, set crawler.journal")
+	}
+
+	j, err := journal.Open(cfg.Crawler.Journal)
+	if err != nil {
+		return 0, err
+	}
+	defer j.Close()
+
+	var p *publisher
+	if requeue {
+		conn, err := getConnection(cfg)
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+
+		p = newPublisher(conn)
+	}
+
+	encoder := json.NewEncoder(w)
+
+	n := 0
+	for _, e := range j.Pending() {
+		msg := &QueuedMessage{
+			Queue:    e.Queue,
+			Priority: e.Priority,
+			Body:     e.Body,
+		}
+
+		if err := encoder.Encode(msg); err != nil {
+			return n, err
+		}
+
+		if requeue {
+			if err := p.publish(msg); err != nil {
+				return n, err
+			}
+
+			if err := j.Done(e.ID); err != nil {
+				return n, err
+			}
+		}
+
+		n++
+	}
+
+	return n, nil
+}
@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"time"
+)
+
+// manifestBatch is the amount of documents fetched at once
+const manifestBatch = 100
+
+// manifestPoll is the time between checking for newly indexed items
+const manifestPoll = 2 * time.Second
+
+// ManifestError describes an item which could not be indexed
+type ManifestError struct {
+	Hash  string `json:"hash"`
+	Error string `json:"error"`
+}
+
+// Manifest describes the indexed state of a root and everything below it
+type Manifest struct {
+	Root     string          `json:"root"`
+	Complete bool            `json:"complete"`          // Whether all items have been indexed
+	Counts   map[string]int  `json:"counts"`            // Indexed items by type
+	Bytes    uint64          `json:"bytes"`             // Total size of indexed files
+	Errors   []ManifestError `json:"errors,omitempty"`  // Items indexed as invalid
+	Pending  []string        `json:"pending,omitempty"` // Items not indexed yet
+	Elapsed  float64         `json:"elapsed"`           // Seconds spent waiting
+}
+
+// manifestDocument holds the fields of indexed documents used for manifests
+type manifestDocument struct {
+	Size  uint64 `json:"size"`
+	Error string `json:"error"`
+	Links []struct {
+		Hash string
+	} `json:"links"`
+}
+
+// manifestWalker tracks items below a root as they get indexed
+type manifestWalker struct {
+	indexer  *indexer.Indexer
+	manifest *Manifest
+	seen     map[string]bool
+	pending  []string
+}
+
+func newManifestWalker(i *indexer.Indexer, root string) *manifestWalker {
+	return &manifestWalker{
+		indexer: i,
+		manifest: &Manifest{
+			Root:   root,
+			Counts: make(map[string]int),
+		},
+		seen:    map[string]bool{root: true},
+		pending: []string{root},
+	}
+}
+
+// record adds an indexed document to the manifest, returning linked
+// items not seen before
+func (w *manifestWalker) record(d indexer.Document) ([]string, error) {
+	var doc manifestDocument
+	if err := json.Unmarshal(*d.Source, &doc); err != nil {
+		return nil, err
+	}
+
+	m := w.manifest
+	m.Counts[d.Type]++
+
+	switch d.Type {
+	case "file":
+		m.Bytes += doc.Size
+	case "invalid":
+		m.Errors = append(m.Errors, ManifestError{Hash: d.Hash, Error: doc.Error})
+	}
+
+	var links []string
+	for _, link := range doc.Links {
+		hash, err := crawler.NormalizeHash(link.Hash)
+		if err != nil || w.seen[hash] {
+			continue
+		}
+		w.seen[hash] = true
+		links = append(links, hash)
+	}
+
+	return links, nil
+}
+
+// poll fetches pending items, recording those which have been indexed
+func (w *manifestWalker) poll(ctx context.Context) error {
+	var stillPending []string
+
+	for len(w.pending) > 0 {
+		n := len(w.pending)
+		if n > manifestBatch {
+			n = manifestBatch
+		}
+		batch := w.pending[:n]
+		w.pending = w.pending[n:]
+
+		documents, err := w.indexer.GetDocuments(ctx, batch...)
+		if err != nil {
+			return err
+		}
+
+		indexed := make(map[string]bool, len(documents))
+		for _, d := range documents {
+			indexed[d.Hash] = true
+
+			links, err := w.record(d)
+			if err != nil {
+				return err
+			}
+
+			// Links are checked right away, as they may have been indexed
+			w.pending = append(w.pending, links...)
+		}
+
+		for _, hash := range batch {
+			if !indexed[hash] {
+				stillPending = append(stillPending, hash)
+			}
+		}
+	}
+
+	w.pending = stillPending
+
+	return nil
+}
+
+// waitForManifest waits until root and all items below it have been
+// indexed or the deadline has passed, returning the resulting manifest
+func waitForManifest(ctx context.Context, i *indexer.Indexer, root string, deadline time.Time) (*Manifest, error) {
+	start := time.Now()
+	timeout := time.After(time.Until(deadline))
+
+	w := newManifestWalker(i, root)
+
+	for {
+		if err := w.poll(ctx); err != nil {
+			return nil, err
+		}
+
+		if len(w.pending) == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			w.manifest.Pending = w.pending
+			w.manifest.Elapsed = time.Since(start).Seconds()
+			return w.manifest, nil
+		case <-time.After(manifestPoll):
+		}
+	}
+
+	w.manifest.Complete = true
+	w.manifest.Elapsed = time.Since(start).Seconds()
+
+	return w.manifest, nil
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/snapshot"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// MirrorOptions determine which snapshots are mirrored and how often
+type MirrorOptions struct {
+	Name     string        // IPNS name or DNSLink domain snapshots are published under
+	Interval time.Duration // Time between checks for new snapshots
+	Once     bool          // Import the current snapshot and return
+}
+
+// Mirror keeps the local index in sync with snapshots published by another
+// instance, until the context is cancelled
+func Mirror(ctx context.Context, cfg *config.Config, options *MirrorOptions) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := i.EnsureIndex(ctx); err != nil {
+		return err
+	}
+
+	sh := getShell(cfg)
+
+	m := &snapshot.Mirror{
+		Indexer: i,
+		Shell:   sh,
+		Name:    options.Name,
+	}
+
+	for {
+		_, err := m.Sync(ctx)
+		if err != nil {
+			if options.Once {
+				return err
+			}
+
+			// Try again later; the publisher may be temporarily unavailable
+			log.WithError(err).Error("Error syncing mirror")
+		}
+
+		if options.Once {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(options.Interval):
+		}
+	}
+}
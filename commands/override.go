@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"io"
+)
+
+// SetOverride stores a metadata override, replacing extracted metadata of
+// a document without recrawling it
+func SetOverride(ctx context.Context, cfg *config.Config, o *indexer.Override) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Documents are indexed by normalized hash
+	o.Hash, err = crawler.NormalizeHash(o.Hash)
+	if err != nil {
+		return fmt.Errorf("invalid hash: %v", err)
+	}
+
+	return i.SetOverride(ctx, o)
+}
+
+// ListOverrides writes all metadata overrides to w
+func ListOverrides(ctx context.Context, cfg *config.Config, w io.Writer) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	overrides, err := i.Overrides(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range overrides {
+		fmt.Fprintln(w, o.String())
+	}
+
+	return nil
+}
+
+// RemoveOverride removes the metadata override for a hash
+func RemoveOverride(ctx context.Context, cfg *config.Config, hash string) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	hash, err = crawler.NormalizeHash(hash)
+	if err != nil {
+		return fmt.Errorf("invalid hash: %v", err)
+	}
+
+	return i.RemoveOverride(ctx, hash)
+}
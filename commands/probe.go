@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/gateway"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"time"
+)
+
+// gatewayHost returns the host of a gateway URL, by which its results are
+// recorded
+func gatewayHost(gatewayURL string) string {
+	if u, err := url.Parse(gatewayURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return gatewayURL
+}
+
+// probeSample probes a random sample of indexed documents against the
+// gateways, storing the availability of every document and rollups of
+// the documents each gateway retrieved. Returns the number of documents
+// probed.
+func probeSample(ctx context.Context, cfg *config.Config, i *indexer.Indexer, g *gateway.Gateways) (int, error) {
+	now := time.Now().UTC()
+
+	documents, err := i.Sample(ctx, "", "", cfg.Probe.SampleSize, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	urls := g.URLs()
+	retrieved := make(map[string]int64, len(urls))
+	availability := make(map[string]*indexer.Availability, len(documents))
+
+	for _, d := range documents {
+		retrievable := g.Probe(ctx, "/ipfs/"+d.Hash)
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+
+		a := &indexer.Availability{
+			Checked:  now.Format(time.RFC3339),
+			Gateways: make([]string, len(retrievable)),
+			Ratio:    float64(len(retrievable)) / float64(len(urls)),
+		}
+		for n, u := range retrievable {
+			a.Gateways[n] = gatewayHost(u)
+			retrieved[u]++
+		}
+		availability[d.Hash] = a
+	}
+
+	if err := i.SetAvailability(ctx, documents, availability); err != nil {
+		return 0, err
+	}
+
+	var rollups []indexer.Rollup
+	for _, u := range urls {
+		newRollup := func(dimension string, count int64) indexer.Rollup {
+			return indexer.Rollup{
+				Timestamp: now.Format(time.RFC3339),
+				Period:    cfg.Probe.Interval.String(),
+				Dimension: dimension,
+				Value:     gatewayHost(u),
+				Count:     count,
+			}
+		}
+
+		rollups = append(rollups,
+			newRollup("gateway-probed", int64(len(documents))),
+			newRollup("gateway-retrievable", retrieved[u]),
+		)
+
+		log.WithFields(log.Fields{
+			"gateway":     u,
+			"probed":      len(documents),
+			"retrievable": retrieved[u],
+		}).Info("Probed gateway")
+	}
+
+	return len(documents), i.WriteRollups(ctx, rollups)
+}
+
+// Probe periodically probes whether a sample of indexed documents can be
+// retrieved through the configured public gateways, until the context is
+// cancelled. Documents no gateway retrieves rank lower in search results,
+// and the share of documents each gateway retrieves is written to the
+// stats index.
+func Probe(ctx context.Context, cfg *config.Config, once bool) error {
+	g := gateway.New(cfg.ProbeConfig())
+	if g == nil {
+		return fmt.Errorf("no gateways configured to probe")
+	}
+
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	for {
+		n, err := probeSample(ctx, cfg, i, g)
+		if err != nil {
+			return err
+		}
+
+		log.WithField("documents", n).Info("Probed availability on gateways")
+
+		if once {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cfg.Probe.Interval):
+		}
+	}
+}
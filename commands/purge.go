@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/thumbnail"
+	"github.com/ipfs/go-ipfs-api"
+	log "github.com/sirupsen/logrus"
+)
+
+// PurgeOptions determine which documents are removed from the index
+type PurgeOptions struct {
+	Hashes    []string
+	Recursive bool // Remove directory entries as well, unless referenced from elsewhere
+	Unlink    bool // Remove links to the documents from their parent directories
+
+	// Dereference removes references to purged directories from their
+	// entries which are not purged themselves
+	Dereference bool
+}
+
+// purgedDocument holds the fields of a document relevant for purging
+type purgedDocument struct {
+	References indexer.References `json:"references"`
+	Links      []struct {
+		Hash string `json:"Hash"`
+	} `json:"links"`
+	Thumbnail *thumbnail.Thumbnail `json:"thumbnail"`
+}
+
+// purger removes documents from the index, keeping track of those removed
+// and the documents changed to no longer refer to them
+type purger struct {
+	indexer *indexer.Indexer
+	options *PurgeOptions
+	purged  map[string]bool
+
+	unlinked     []string // Directories links were removed from
+	dereferenced []string // Entries references were removed from
+
+	// Thumbnails of purged documents are removed when set
+	shell *shell.Shell
+
+	// Crawl history and overrides of purged documents are removed as well
+	meta bool
+}
+
+// unlink removes links to hash from the parent directory document
+func (p *purger) unlink(ctx context.Context, parent string, hash string) error {
+	doc, err := p.indexer.GetDocument(ctx, parent)
+	if err != nil || doc == nil || doc.Type != "directory" {
+		return err
+	}
+
+	var d struct {
+		Links []map[string]interface{} `json:"links"`
+	}
+	if err := json.Unmarshal(*doc.Source, &d); err != nil {
+		return err
+	}
+
+	links := make([]map[string]interface{}, 0, len(d.Links))
+	for _, link := range d.Links {
+		if link["Hash"] != hash {
+			links = append(links, link)
+		}
+	}
+
+	if len(links) == len(d.Links) {
+		return nil
+	}
+
+	log.WithField("hash", hash).WithField("parent", parent).Info("Removing link from parent")
+
+	p.unlinked = append(p.unlinked, parent)
+
+	return p.indexer.IndexItem(ctx, "directory", parent, map[string]interface{}{
+		"links": links,
+	})
+}
+
+// dereference removes references to the purged directory parent from the
+// document of its entry hash
+func (p *purger) dereference(ctx context.Context, hash string, parent string) error {
+	if p.purged[hash] {
+		return nil
+	}
+
+	doc, err := p.indexer.GetDocument(ctx, hash)
+	if err != nil || doc == nil {
+		return err
+	}
+
+	var d purgedDocument
+	if err := json.Unmarshal(*doc.Source, &d); err != nil {
+		return err
+	}
+
+	remaining := make(indexer.References, 0, len(d.References))
+	for _, r := range d.References {
+		if r.ParentHash != parent {
+			remaining = append(remaining, r)
+		}
+	}
+
+	if len(remaining) == len(d.References) {
+		return nil
+	}
+
+	log.WithField("hash", hash).WithField("parent", parent).Info("Removing reference to parent")
+
+	p.dereferenced = append(p.dereferenced, hash)
+
+	return p.indexer.IndexItem(ctx, doc.Type, hash, map[string]interface{}{
+		"references":      remaining,
+		"reference-count": len(remaining),
+	})
+}
+
+// purge removes the document for hash; parent is set for entries of
+// recursively purged directories
+func (p *purger) purge(ctx context.Context, hash string, parent string) error {
+	if p.purged[hash] {
+		return nil
+	}
+
+	doc, err := p.indexer.GetDocument(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		log.WithField("hash", hash).Warn("Not indexed, skipping")
+		return nil
+	}
+
+	var d purgedDocument
+	if err := json.Unmarshal(*doc.Source, &d); err != nil {
+		return err
+	}
+
+	if parent != "" {
+		// Keep entries which are also referenced from elsewhere
+		var remaining indexer.References
+		for _, r := range d.References {
+			if !p.purged[r.ParentHash] {
+				remaining = append(remaining, r)
+			}
+		}
+
+		if len(remaining) > 0 {
+			log.WithField("hash", hash).Info("Referenced elsewhere, removing reference")
+
+			return p.indexer.IndexItem(ctx, doc.Type, hash, map[string]interface{}{
+				"references": remaining,
+			})
+		}
+	}
+
+	p.purged[hash] = true
+
+	if p.options.Unlink && parent == "" {
+		for _, r := range d.References {
+			if err := p.unlink(ctx, r.ParentHash, hash); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.shell != nil && d.Thumbnail != nil {
+		log.WithField("hash", hash).Info("Removing thumbnail")
+
+		if err := thumbnail.Remove(ctx, p.shell, d.Thumbnail); err != nil {
+			return fmt.Errorf("removing thumbnail of %s: %v", hash, err)
+		}
+	}
+
+	// Before the document, so it is found again when this fails
+	if p.meta {
+		if err := p.indexer.DeleteMeta(ctx, hash); err != nil {
+			return err
+		}
+	}
+
+	log.WithField("hash", hash).WithField("type", doc.Type).Info("Purging")
+
+	if _, err := p.indexer.DeleteItem(ctx, hash); err != nil {
+		return err
+	}
+
+	if doc.Type == "directory" {
+		for _, link := range d.Links {
+			switch {
+			case p.options.Recursive:
+				err = p.purge(ctx, link.Hash, hash)
+			case p.options.Dereference:
+				err = p.dereference(ctx, link.Hash, hash)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Purge removes documents from the index, returning the amount removed.
+// Purged content may be indexed again when encountered; use the denylist
+// to prevent this.
+func Purge(ctx context.Context, cfg *config.Config, options *PurgeOptions) (int, error) {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	p := &purger{
+		indexer: i,
+		options: options,
+		purged:  make(map[string]bool),
+	}
+
+	for _, hash := range options.Hashes {
+		// Documents are indexed by normalized hash
+		normalized, err := crawler.NormalizeHash(hash)
+		if err != nil {
+			return len(p.purged), fmt.Errorf("invalid hash '%s': %v", hash, err)
+		}
+
+		if err := p.purge(ctx, normalized, ""); err != nil {
+			return len(p.purged), err
+		}
+	}
+
+	return len(p.purged), nil
+}
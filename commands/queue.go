@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/queue"
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"io"
+)
+
+// QueuedMessage is a message dumped from a queue, as a JSON line
+type QueuedMessage struct {
+	Queue    string          `json:"queue"`
+	Priority uint8           `json:"priority"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// DumpOptions determine which queues are dumped and whether messages remain
+type DumpOptions struct {
+	Queues []string // Queues to dump, all crawler queues when empty
+	Remove bool     // Remove dumped messages from the queues
+}
+
+// dumpQueue writes all messages ready in a queue to encoder, holding them
+// unacknowledged, and returns the deliveries
+func dumpQueue(q *queue.Queue, encoder *json.Encoder) ([]amqp.Delivery, error) {
+	var deliveries []amqp.Delivery
+
+	for {
+		d, ok, err := q.Channel.Get(q.Name, false)
+		if err != nil {
+			return deliveries, err
+		}
+		if !ok {
+			// Queue is empty
+			return deliveries, nil
+		}
+
+		deliveries = append(deliveries, d)
+
+		if !json.Valid(d.Body) {
+			return deliveries, fmt.Errorf("message in %s is not JSON: %q", q.Name, d.Body)
+		}
+
+		err = encoder.Encode(&QueuedMessage{
+			Queue:    q.Name,
+			Priority: d.Priority,
+			Body:     d.Body,
+		})
+		if err != nil {
+			return deliveries, err
+		}
+	}
+}
+
+// DumpQueues writes the messages in the crawler's queues to w as JSON lines,
+// for replaying later or into another broker. Messages are requeued, unless
+// options.Remove is set and all of them have been written. Messages are
+// dumped as ready; those being crawled meanwhile are not included.
+func DumpQueues(cfg *config.Config, options *DumpOptions, w io.Writer) (int, error) {
+	names := options.Queues
+	if len(names) == 0 {
+		names = crawlerQueues(cfg)
+	}
+
+	conn, err := getConnection(cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	encoder := json.NewEncoder(w)
+
+	var (
+		channels   []*queue.Channel
+		deliveries []amqp.Delivery
+	)
+
+	// Closing channels requeues messages not acknowledged, e.g. on errors
+	defer func() {
+		for _, ch := range channels {
+			ch.Close()
+		}
+	}()
+
+	for _, name := range names {
+		q, err := conn.NewChannelQueue(name)
+		if err != nil {
+			return 0, err
+		}
+		if q.Channel == nil {
+			return 0, fmt.Errorf("dumping queues is only supported on AMQP brokers")
+		}
+		channels = append(channels, q.Channel)
+
+		var dumped []amqp.Delivery
+		dumped, err = dumpQueue(q, encoder)
+		deliveries = append(deliveries, dumped...)
+
+		if err != nil {
+			return 0, err
+		}
+
+		log.WithField("queue", name).Infof("Dumped %d messages", len(dumped))
+	}
+
+	for _, d := range deliveries {
+		if options.Remove {
+			err = d.Ack(false)
+		} else {
+			err = d.Nack(false, true)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// publisher publishes messages to their queues, opening channels as needed
+type publisher struct {
+	conn   *queue.Connection
+	queues map[string]*queue.Queue
+}
+
+// newPublisher returns a publisher for connection
+func newPublisher(conn *queue.Connection) *publisher {
+	return &publisher{
+		conn:   conn,
+		queues: make(map[string]*queue.Queue),
+	}
+}
+
+// publish publishes a message to its queue with its priority
+func (p *publisher) publish(msg *QueuedMessage) error {
+	q, ok := p.queues[msg.Queue]
+	if !ok {
+		var err error
+		q, err = p.conn.NewChannelQueue(msg.Queue)
+		if err != nil {
+			return err
+		}
+		p.queues[msg.Queue] = q
+	}
+
+	// Messages published before tasks were introduced are wrapped
+	task, err := queue.ParseTask(msg.Body)
+	if err != nil {
+		return err
+	}
+	task.Priority = msg.Priority
+
+	return q.PublishTask(context.Background(), task)
+}
+
+// ReplayQueues publishes messages dumped by DumpQueues, read from r, to
+// their original queues with their original priority
+func ReplayQueues(cfg *config.Config, r io.Reader) (int, error) {
+	conn, err := getConnection(cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	p := newPublisher(conn)
+
+	replayed := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		msg := &QueuedMessage{}
+		if err := json.Unmarshal(scanner.Bytes(), msg); err != nil {
+			return replayed, fmt.Errorf("line %d: %v", replayed+1, err)
+		}
+
+		if err := p.publish(msg); err != nil {
+			return replayed, err
+		}
+
+		replayed++
+	}
+
+	return replayed, scanner.Err()
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/extractor"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// recrawlPriority is below that of new items, which are more likely to
+// be available
+const recrawlPriority = 1
+
+// queueStale queues a batch of stale items for recrawling, returning the
+// number of items queued
+func queueStale(ctx context.Context, cfg *config.Config, i *indexer.Indexer, q *queue.Queue) (int, error) {
+	now := time.Now()
+
+	stale, err := i.Stale(ctx, now.Add(-cfg.Recrawl.Staleness), cfg.Recrawl.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range stale {
+		err := q.Publish(&crawler.Args{
+			Hash:    d.Hash,
+			Recrawl: true,
+		}, recrawlPriority)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(stale), i.MarkRecrawl(ctx, stale, now)
+}
+
+// queueOutdated queues a batch of files for extraction with capabilities
+// added to the extractor registry after they were indexed, returning the
+// number of files queued
+func queueOutdated(ctx context.Context, cfg *config.Config, i *indexer.Indexer, q *queue.Queue) (int, error) {
+	queued := 0
+
+	for _, c := range extractor.Capabilities {
+		if c.Version <= 1 || queued >= cfg.Recrawl.BatchSize {
+			continue
+		}
+
+		outdated, err := i.Outdated(ctx, c.MimeTypes, c.Version, cfg.Recrawl.BatchSize-queued)
+		if err != nil {
+			return queued, err
+		}
+
+		for _, d := range outdated {
+			err := q.Publish(&crawler.Args{
+				Hash:    d.Hash,
+				Recrawl: true,
+			}, recrawlPriority)
+			if err != nil {
+				return queued, err
+			}
+		}
+
+		if err := i.MarkExtract(ctx, outdated, c.Version); err != nil {
+			return queued, err
+		}
+		queued += len(outdated)
+	}
+
+	return queued, nil
+}
+
+// Recrawl periodically queues items which have not been seen within the
+// staleness window for crawling again, until the context is cancelled.
+// Recrawling refreshes metadata of available items; items which remain
+// unavailable keep their last seen date. Files of content types extractors
+// gained capabilities for since they were indexed are queued as well.
+func Recrawl(ctx context.Context, cfg *config.Config) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := getConnection(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	q, err := conn.NewChannelQueue("hashes")
+	if err != nil {
+		return err
+	}
+
+	for {
+		n, err := queueStale(ctx, cfg, i, q)
+		if err != nil {
+			return err
+		}
+
+		log.WithField("items", n).Info("Queued stale items for recrawling")
+
+		n, err = queueOutdated(ctx, cfg, i, q)
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			log.WithField("files", n).Info("Queued files for extraction with new capabilities")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cfg.Recrawl.Interval):
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/relevance"
+	"io"
+)
+
+// EvaluateRelevance runs the judged queries in judgementsFile against the
+// index and writes nDCG@k and recall@k per query and on average to w
+func EvaluateRelevance(ctx context.Context, cfg *config.Config, judgementsFile string, k int, w io.Writer) error {
+	if k < 1 {
+		return fmt.Errorf("k should be at least 1, got %d", k)
+	}
+
+	judgements, err := relevance.ReadJudgements(judgementsFile)
+	if err != nil {
+		return err
+	}
+
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	results := make([]*relevance.Result, 0, len(judgements))
+
+	fmt.Fprintf(w, "%-8s %-8s %s\n", "ndcg", "recall", "query")
+
+	for _, j := range judgements {
+		result, err := i.Search(ctx, j.Query, &indexer.SearchOptions{Size: k})
+		if err != nil {
+			return fmt.Errorf("error searching '%s': %v", j.Query, err)
+		}
+
+		hashes := make([]string, len(result.Documents))
+		for n, d := range result.Documents {
+			hashes[n] = d.Hash
+		}
+
+		r := j.Evaluate(hashes, k)
+		results = append(results, r)
+
+		fmt.Fprintf(w, "%-8.4f %-8.4f %s\n", r.NDCG, r.Recall, r.Query)
+	}
+
+	mean := relevance.Mean(results)
+	fmt.Fprintf(w, "%-8.4f %-8.4f (mean over %d queries, k=%d)\n", mean.NDCG, mean.Recall, len(results), k)
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ipfs-search/ipfs-search/config"
+	"io"
+)
+
+// SampleOptions determine which and how many documents are sampled
+type SampleOptions struct {
+	Type  string // Only sample documents of this type
+	Query string // Only sample documents matching this query string
+	Size  int    // Amount of documents to return
+	Seed  int64  // Seed for reproducible samples, random when 0
+}
+
+// Sample writes a random sample of indexed documents as JSON lines to w
+func Sample(ctx context.Context, cfg *config.Config, options *SampleOptions, w io.Writer) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	documents, err := i.Sample(ctx, options.Type, options.Query, options.Size, options.Seed)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, d := range documents {
+		if err := encoder.Encode(d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/snapshot"
+	"time"
+)
+
+// Snapshot publishes snapshots of the index to IPFS under an IPNS key,
+// periodically until the context is cancelled or only once, in which case
+// the root hash is written to stdout. Periodic snapshots are differential
+// in between full snapshots; the first one is always full.
+func Snapshot(ctx context.Context, cfg *config.Config, once bool) error {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		return err
+	}
+
+	sh := getShell(cfg)
+
+	p := &snapshot.Publisher{
+		Indexer:      i,
+		Shell:        sh,
+		Key:          cfg.Snapshot.Key,
+		Lifetime:     cfg.Snapshot.Lifetime,
+		ShardSize:    cfg.Snapshot.ShardSize,
+		FullInterval: cfg.Snapshot.FullInterval,
+	}
+
+	for {
+		root, err := p.Publish(ctx)
+		if err != nil {
+			return err
+		}
+
+		if once {
+			fmt.Println(root)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cfg.Snapshot.Interval):
+		}
+	}
+}
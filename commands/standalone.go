@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"golang.org/x/sync/errgroup"
+	"io"
+)
+
+// UseMemoryQueues configures queues within this process as broker, holding
+// up to size messages each, instead of any configured broker
+func UseMemoryQueues(cfg *config.Config, size int) {
+	cfg.RedisStreams.URL = ""
+	cfg.JetStream.URL = ""
+	cfg.Kafka.URL = ""
+	cfg.AMQP = config.AMQP{AMQPURL: queue.MemoryURL(size)}
+}
+
+// Standalone crawls with in-memory queues, queueing hashes given in
+// options. Unless the index backend is typeless, the API is served as
+// well, so lookups of unindexed hashes queue them. Queued messages are lost
+// on exit.
+func Standalone(ctx context.Context, cfg *config.Config, options *AddOptions, out io.Writer) error {
+	errg, ctx := errgroup.WithContext(ctx)
+
+	errg.Go(func() error { return Crawl(ctx, cfg) })
+
+	if !indexer.IsTypeless(cfg.ElasticSearch.Backend) {
+		errg.Go(func() error { return API(ctx, cfg) })
+	}
+
+	if len(options.Hashes) > 0 || len(options.Sources) > 0 {
+		errg.Go(func() error { return AddHashes(ctx, cfg, options, out) })
+	}
+
+	return errg.Wait()
+}
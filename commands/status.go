@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/c2h5oh/datasize"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs/go-ipfs-api"
+	"io"
+	"sort"
+	"time"
+)
+
+// statusTimeout limits the time spent on contacting each component
+const statusTimeout = 10 * time.Second
+
+// queueNames are the queues reported on, including dead letter queues
+var queueNames = []string{"hashes", "files", "hashes-dead", "files-dead"}
+
+// crawlerQueues returns queueNames along with the queues of configured
+// routes and their dead letter queues
+func crawlerQueues(cfg *config.Config) []string {
+	names := append([]string{}, queueNames...)
+
+	for _, r := range cfg.CrawlerConfig().Routes {
+		names = append(names, r.Queue(), r.Queue()+"-dead")
+	}
+
+	return names
+}
+
+// QueuesStatus describes the state of the AMQP broker
+type QueuesStatus struct {
+	Queues []*queue.State `json:"queues,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// IndexStatus describes the state of Elasticsearch
+type IndexStatus struct {
+	Health    string            `json:"health,omitempty"`
+	Documents map[string]int64  `json:"documents,omitempty"`
+	Capacity  *indexer.Capacity `json:"capacity,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// IPFSStatus describes the state of the IPFS daemon
+type IPFSStatus struct {
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Status describes the state of all components
+type Status struct {
+	Queues QueuesStatus `json:"amqp"`
+	Index  IndexStatus  `json:"elasticsearch"`
+	IPFS   IPFSStatus   `json:"ipfs"`
+}
+
+// inspectQueues returns the state of the crawler's queues, with their
+// trends once estimator has several samples of them
+func inspectQueues(conn *queue.Connection, names []string, estimator *queue.Estimator) ([]*queue.State, error) {
+	states := make([]*queue.State, 0, len(names))
+
+	for _, name := range names {
+		state, err := conn.Inspect(name)
+		if err != nil {
+			return nil, err
+		}
+
+		state.Trend = estimator.Add(name, time.Now(), state.Messages)
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// getQueuesStatus returns the state of the queues; with a sample period,
+// queues are inspected again after it to estimate their trends
+func getQueuesStatus(ctx context.Context, cfg *config.Config, sample time.Duration) (s QueuesStatus) {
+	conn, err := getConnection(cfg)
+	if err != nil {
+		s.Error = err.Error()
+		return
+	}
+	defer conn.Close()
+
+	names := crawlerQueues(cfg)
+	estimator := queue.NewEstimator(sample)
+
+	s.Queues, err = inspectQueues(conn, names, estimator)
+	if err != nil || sample <= 0 {
+		if err != nil {
+			s.Error = err.Error()
+		}
+		return
+	}
+
+	select {
+	case <-time.After(sample):
+	case <-ctx.Done():
+		return
+	}
+
+	s.Queues, err = inspectQueues(conn, names, estimator)
+	if err != nil {
+		s.Error = err.Error()
+	}
+
+	return
+}
+
+func getIndexStatus(ctx context.Context, cfg *config.Config) (s IndexStatus) {
+	i, err := getIndexer(cfg)
+	if err != nil {
+		s.Error = err.Error()
+		return
+	}
+
+	s.Health, err = i.Health(ctx)
+	if err != nil {
+		s.Error = err.Error()
+		return
+	}
+
+	s.Documents, err = i.CountByType(ctx)
+	if err != nil {
+		s.Error = err.Error()
+		return
+	}
+
+	s.Capacity, err = i.Capacity(ctx, cfg.Capacity.Window)
+	if err != nil {
+		s.Error = err.Error()
+	}
+
+	return
+}
+
+func getIPFSStatus(cfg *config.Config) (s IPFSStatus) {
+	sh := shell.NewShell(cfg.IPFS.IpfsAPI)
+	sh.SetTimeout(statusTimeout)
+
+	version, _, err := sh.Version()
+	if err != nil {
+		s.Error = err.Error()
+		return
+	}
+
+	s.Version = version
+	return
+}
+
+// GetStatus returns the state of broker, index and IPFS daemon; errors
+// contacting a component are reported in its status. Queue trends are
+// estimated from their depths during the sample period, if any.
+func GetStatus(ctx context.Context, cfg *config.Config, sample time.Duration) *Status {
+	queues := getQueuesStatus(ctx, cfg, sample)
+
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+
+	return &Status{
+		Queues: queues,
+		Index:  getIndexStatus(ctx, cfg),
+		IPFS:   getIPFSStatus(cfg),
+	}
+}
+
+// write writes a human readable rendering of the status
+func (s *Status) write(w io.Writer) {
+	fmt.Fprintln(w, "AMQP:")
+	for _, q := range s.Queues.Queues {
+		fmt.Fprintf(w, "  %-12s %10d messages %5d consumers", q.Name, q.Messages, q.Consumers)
+		if q.Trend != nil {
+			fmt.Fprintf(w, "  %s", describeTrend(q.Trend))
+		}
+		fmt.Fprintln(w)
+	}
+	if s.Queues.Error != "" {
+		fmt.Fprintf(w, "  error: %s\n", s.Queues.Error)
+	}
+
+	fmt.Fprintln(w, "Elasticsearch:")
+	if s.Index.Health != "" {
+		fmt.Fprintf(w, "  health: %s\n", s.Index.Health)
+	}
+	types := make([]string, 0, len(s.Index.Documents))
+	for t := range s.Index.Documents {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(w, "  %-12s %10d documents\n", t, s.Index.Documents[t])
+	}
+	if c := s.Index.Capacity; c != nil {
+		for _, d := range c.Disks {
+			fmt.Fprintf(w, "  %-12s %10s of %s disk used\n", d.Node, datasize.ByteSize(d.Used).HR(), datasize.ByteSize(d.Total).HR())
+		}
+		fmt.Fprintf(w, "  growth: %s/day\n", datasize.ByteSize(c.Growth*24*60*60).HR())
+		for _, m := range c.Watermarks {
+			fmt.Fprintf(w, "  %-12s %s\n", m.Name+":", describeWatermark(m))
+		}
+	}
+	if s.Index.Error != "" {
+		fmt.Fprintf(w, "  error: %s\n", s.Index.Error)
+	}
+
+	fmt.Fprintln(w, "IPFS:")
+	if s.IPFS.Version != "" {
+		fmt.Fprintf(w, "  version: %s\n", s.IPFS.Version)
+	}
+	if s.IPFS.Error != "" {
+		fmt.Fprintf(w, "  error: %s\n", s.IPFS.Error)
+	}
+}
+
+// describeTrend returns a human readable description of a queue's trend
+func describeTrend(t *queue.Trend) string {
+	switch {
+	case t.ETA == 0:
+		return "empty"
+	case t.Draining():
+		eta := time.Duration(t.ETA) * time.Second
+		return fmt.Sprintf("draining %.1f/s, empty in %s", -t.Rate, eta)
+	case t.Rate > 0:
+		return fmt.Sprintf("growing %.1f/s", t.Rate)
+	}
+
+	return "steady"
+}
+
+// describeWatermark returns a human readable forecast of a disk watermark
+func describeWatermark(m indexer.Watermark) string {
+	switch {
+	case m.ETA == 0:
+		return "hit"
+	case m.ETA < 0:
+		return "not growing"
+	}
+
+	return fmt.Sprintf("hit in %s", (time.Duration(m.ETA) * time.Second).Round(time.Minute))
+}
+
+// healthy returns whether all components could be contacted
+func (s *Status) healthy() bool {
+	return s.Queues.Error == "" && s.Index.Error == "" && s.IPFS.Error == ""
+}
+
+// WriteStatus writes the status of all components to w, as JSON if asJSON
+// is set, with queue trends estimated during the sample period. An error
+// is returned when any of the components is unavailable.
+func WriteStatus(ctx context.Context, cfg *config.Config, sample time.Duration, asJSON bool, w io.Writer) error {
+	status := GetStatus(ctx, cfg, sample)
+
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(status); err != nil {
+			return err
+		}
+	} else {
+		status.write(w)
+	}
+
+	if !status.healthy() {
+		return fmt.Errorf("One or more components unavailable")
+	}
+
+	return nil
+}
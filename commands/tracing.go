@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/tracing"
+	log "github.com/sirupsen/logrus"
+)
+
+// startTracing sets up tracing for service, returning a function flushing
+// remaining spans
+func startTracing(ctx context.Context, cfg *config.Config, service string) (func(), error) {
+	shutdown, err := tracing.Setup(ctx, cfg.TracingConfig(service))
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if err := shutdown(context.Background()); err != nil {
+			log.WithError(err).Warn("Error flushing traces")
+		}
+	}, nil
+}
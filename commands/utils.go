@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/ipfspool"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs/go-ipfs-api"
+)
+
+// getConnection connects to the configured broker, declaring queues with
+// the configured expiry, as the crawler does
+func getConnection(cfg *config.Config) (*queue.Connection, error) {
+	conn, err := queue.NewConnection(cfg.BrokerURL())
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetExpiry(cfg.Expiry())
+	return conn, nil
+}
+
+// getIndexer returns an indexer for commands not requiring the full crawler
+func getIndexer(cfg *config.Config) (*indexer.Indexer, error) {
+	return indexer.New(cfg.ElasticSearchConfig())
+}
+
+// cluster describes the state of the index backend
+type cluster interface {
+	Version(ctx context.Context) (string, error)
+	IndexHealth(ctx context.Context) (string, error)
+}
+
+// getCluster returns the configured index backend, for checking its state
+func getCluster(cfg *config.Config) (cluster, error) {
+	if indexer.IsTypeless(cfg.ElasticSearch.Backend) {
+		return indexer.NewTypeless(cfg.ElasticSearchConfig())
+	}
+
+	return getIndexer(cfg)
+}
+
+// getShell returns an IPFS API shell with configured timeout and
+// concurrency limit, failing over between configured nodes
+func getShell(cfg *config.Config) *shell.Shell {
+	sh, _ := ipfspool.NewShell(cfg.IPFS.URLs(), cfg.IPFS.Concurrency())
+	sh.SetTimeout(cfg.IPFS.IpfsTimeout)
+
+	return sh
+}
@@ -0,0 +1,124 @@
+package concurrency
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// Weights for exponentially weighted moving averages of latency; the
+	// baseline follows improvements fast and degradation slowly
+	latencyWeight         = 0.2
+	baselineWeight        = 0.01
+	baselineImproveWeight = 0.5
+
+	// Latency over baseline by this factor is considered congestion
+	latencyTolerance = 2.0
+
+	// Factor by which the limit is decreased on congestion or errors
+	decreaseFactor = 0.5
+
+	// Minimum time between decreases, so a burst of failures from
+	// requests in flight counts once
+	decreaseCooldown = time.Second
+)
+
+// adaptiveLimiter implements additive increase/multiplicative decrease
+// (AIMD) of the amount of requests in flight, between 1 and max. The limit
+// is halved when requests fail or latency rises well above its baseline,
+// and increases by 1 for every limit successful requests.
+type adaptiveLimiter struct {
+	mu       sync.Mutex
+	max      float64
+	limit    float64
+	inflight int
+	changed  chan struct{} // Closed and replaced when a slot may be available
+
+	latency      float64 // Moving average of recent latency, in seconds
+	baseline     float64 // Moving average of latency without congestion
+	lastDecrease time.Time
+}
+
+func newAdaptiveLimiter(max uint) *adaptiveLimiter {
+	return &adaptiveLimiter{
+		max:     float64(max),
+		limit:   float64(max),
+		changed: make(chan struct{}),
+	}
+}
+
+// notify wakes up waiting requests; must be called with lock held
+func (l *adaptiveLimiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// acquire waits for a slot, or until the context is done
+func (l *adaptiveLimiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inflight < int(l.limit) {
+			l.inflight++
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// release frees a slot, adapting the limit to the outcome of the request
+func (l *adaptiveLimiter) release(latency time.Duration, failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+	defer l.notify()
+
+	if latency == 0 {
+		return
+	}
+
+	seconds := latency.Seconds()
+	if l.baseline == 0 {
+		l.latency = seconds
+		l.baseline = seconds
+	} else {
+		l.latency += latencyWeight * (seconds - l.latency)
+
+		weight := baselineWeight
+		if l.latency < l.baseline {
+			weight = baselineImproveWeight
+		}
+		l.baseline += weight * (l.latency - l.baseline)
+	}
+
+	if failed || l.latency > latencyTolerance*l.baseline {
+		if time.Since(l.lastDecrease) < decreaseCooldown {
+			return
+		}
+
+		l.lastDecrease = time.Now()
+		l.limit = math.Max(1, math.Floor(l.limit*decreaseFactor))
+
+		log.WithFields(log.Fields{
+			"limit":    l.limit,
+			"failed":   failed,
+			"latency":  l.latency,
+			"baseline": l.baseline,
+		}).Debug("Decreasing concurrency")
+
+		return
+	}
+
+	l.limit = math.Min(l.max, l.limit+1/l.limit)
+}
@@ -0,0 +1,98 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveDecrease(t *testing.T) {
+	tests := []struct {
+		name    string
+		latency time.Duration
+		failed  bool
+		limit   float64
+	}{
+		{"success", 10 * time.Millisecond, false, 8},
+		{"failure", 10 * time.Millisecond, true, 4},
+		{"congestion", time.Second, false, 4},
+		{"unmeasured", 0, true, 8},
+	}
+
+	for _, test := range tests {
+		l := newAdaptiveLimiter(8)
+
+		// Establish a baseline latency
+		l.acquire(context.Background())
+		l.release(10*time.Millisecond, false)
+
+		l.acquire(context.Background())
+		l.release(test.latency, test.failed)
+
+		if l.limit != test.limit {
+			t.Errorf("%s: limit = %v, want %v", test.name, l.limit, test.limit)
+		}
+	}
+}
+
+func TestAdaptiveCooldown(t *testing.T) {
+	l := newAdaptiveLimiter(8)
+
+	for i := 0; i < 3; i++ {
+		l.acquire(context.Background())
+		l.release(time.Millisecond, true)
+	}
+
+	if l.limit != 4 {
+		t.Errorf("limit after burst of failures = %v, want 4", l.limit)
+	}
+}
+
+func TestAdaptiveIncrease(t *testing.T) {
+	l := newAdaptiveLimiter(8)
+	l.limit = 2
+
+	// Additive increase by 1 for every limit successes
+	for i := 0; i < 3; i++ {
+		l.acquire(context.Background())
+		l.release(time.Millisecond, false)
+	}
+
+	if l.limit < 3 || l.limit >= 4 {
+		t.Errorf("limit after 3 successes from 2 = %v, want [3, 4)", l.limit)
+	}
+
+	for i := 0; i < 100; i++ {
+		l.acquire(context.Background())
+		l.release(time.Millisecond, false)
+	}
+
+	if l.limit != 8 {
+		t.Errorf("limit after many successes = %v, want max 8", l.limit)
+	}
+}
+
+func TestAdaptiveAcquireWaits(t *testing.T) {
+	l := newAdaptiveLimiter(1)
+
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx); err != context.DeadlineExceeded {
+		t.Errorf("acquire() over limit = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- l.acquire(context.Background())
+	}()
+
+	l.release(0, false)
+
+	if err := <-done; err != nil {
+		t.Errorf("acquire() after release = %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package concurrency
+
+// Config configures the amount of requests in flight to a backend
+type Config struct {
+	Max      uint // Maximum requests in flight, 0 for unlimited
+	Adaptive bool // Adapt the limit to backend latency and errors, up to Max
+
+	Rates map[string]Rate // Request rates by endpoint; others are unlimited
+
+	Faults Faults // Delays and failures injected, for resilience testing
+}
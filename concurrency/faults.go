@@ -0,0 +1,71 @@
+package concurrency
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Faults injects delays and failures into requests to a backend, so
+// retries, circuit breaking and dead lettering can be exercised in staging
+type Faults struct {
+	Delay     float64       // Fraction of requests delayed
+	DelayTime time.Duration // Maximum delay; delays are uniformly distributed up to it
+	Fail      float64       // Fraction of requests failing without being sent
+}
+
+// enabled returns whether any faults are injected
+func (f Faults) enabled() bool {
+	return (f.Delay > 0 && f.DelayTime > 0) || f.Fail > 0
+}
+
+// FaultError is the error of requests failed by fault injection. Like
+// network failures, it is temporary.
+type FaultError struct{}
+
+func (FaultError) Error() string   { return "injected fault" }
+func (FaultError) Timeout() bool   { return false }
+func (FaultError) Temporary() bool { return true }
+
+// FaultTransport delays and fails a fraction of requests through an
+// underlying transport
+type FaultTransport struct {
+	base   http.RoundTripper
+	faults Faults
+	random func() float64 // In [0, 1)
+}
+
+// NewFaultTransport returns a transport injecting faults into requests
+// through base; without faults, base is returned as is.
+func NewFaultTransport(faults Faults, base http.RoundTripper) http.RoundTripper {
+	if !faults.enabled() {
+		return base
+	}
+
+	return &FaultTransport{
+		base:   base,
+		faults: faults,
+		random: rand.Float64,
+	}
+}
+
+// RoundTrip delays the request, or fails it, when chosen to, and otherwise
+// performs it. Delays end early when the request is cancelled.
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.random() < t.faults.Delay {
+		timer := time.NewTimer(time.Duration(t.random() * float64(t.faults.DelayTime)))
+		defer timer.Stop()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	if t.random() < t.faults.Fail {
+		return nil, FaultError{}
+	}
+
+	return t.base.RoundTrip(req)
+}
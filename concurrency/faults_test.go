@@ -0,0 +1,69 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestNewFaultTransportDisabled(t *testing.T) {
+	for _, faults := range []Faults{{}, {Delay: 0.5}} {
+		if transport := NewFaultTransport(faults, http.DefaultTransport); transport != http.DefaultTransport {
+			t.Errorf("NewFaultTransport(%+v) = %T, want base", faults, transport)
+		}
+	}
+}
+
+func TestFaultTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		faults Faults
+		random float64
+		fail   bool
+	}{
+		{"passed", Faults{Fail: 0.1}, 0.5, false},
+		{"failed", Faults{Fail: 0.1}, 0.05, true},
+		{"delayed", Faults{Delay: 0.1, DelayTime: 10 * time.Millisecond}, 0.05, false},
+	}
+
+	for _, test := range tests {
+		transport := NewFaultTransport(test.faults, http.DefaultTransport).(*FaultTransport)
+		transport.random = func() float64 { return test.random }
+		client := &http.Client{Transport: transport}
+
+		resp, err := client.Get(server.URL)
+		if !test.fail {
+			if err != nil {
+				t.Errorf("%s: Get() error %v", test.name, err)
+				continue
+			}
+			resp.Body.Close()
+			continue
+		}
+
+		// Failures are temporary, like network failures
+		var urlErr *url.Error
+		if !errors.As(err, &urlErr) || !urlErr.Temporary() || urlErr.Timeout() {
+			t.Errorf("%s: Get() error %v, want temporary error", test.name, err)
+		}
+	}
+}
+
+func TestFaultTransportCancelled(t *testing.T) {
+	transport := NewFaultTransport(Faults{Delay: 1, DelayTime: time.Hour}, http.DefaultTransport)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost", nil)
+	if _, err := transport.RoundTrip(req.WithContext(ctx)); err != context.DeadlineExceeded {
+		t.Errorf("RoundTrip() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,125 @@
+package concurrency
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// Rate limits the rate of requests to an endpoint
+type Rate struct {
+	PerSecond float64 // Sustained requests per second
+	Burst     uint    // Requests allowed at once after idling; at least 1
+}
+
+// bucket is a token bucket shared by all requests to an endpoint
+type bucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(r Rate) *bucket {
+	burst := math.Max(1, float64(r.Burst))
+
+	return &bucket{
+		rate:   r.PerSecond,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token, returning the time until it is available
+func (b *bucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token
+func (b *bucket) cancel() {
+	b.mu.Lock()
+	b.tokens++
+	b.mu.Unlock()
+}
+
+// wait takes a token, waiting until it is available or the context is done
+func (b *bucket) wait(ctx context.Context) error {
+	delay := b.reserve()
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// AllEndpoints is the endpoint name of a rate applying to all requests
+const AllEndpoints = "*"
+
+// RateTransport limits the rate of requests to endpoints, named by the
+// last element of their path; e.g. ls for IPFS' /api/v0/file/ls. Requests
+// to other endpoints are only limited by the rate for AllEndpoints, if any.
+type RateTransport struct {
+	base    http.RoundTripper
+	buckets map[string]*bucket
+}
+
+// NewRateTransport returns a transport limiting requests through base to
+// rates by endpoint; without rates, base is returned as is.
+func NewRateTransport(rates map[string]Rate, base http.RoundTripper) http.RoundTripper {
+	buckets := make(map[string]*bucket, len(rates))
+	for endpoint, r := range rates {
+		if r.PerSecond > 0 {
+			buckets[endpoint] = newBucket(r)
+		}
+	}
+
+	if len(buckets) == 0 {
+		return base
+	}
+
+	return &RateTransport{
+		base:    base,
+		buckets: buckets,
+	}
+}
+
+// RoundTrip waits for the endpoint's rate limit, or until the request is
+// cancelled, and performs the request
+func (t *RateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, endpoint := range []string{AllEndpoints, path.Base(req.URL.Path)} {
+		if b, ok := t.buckets[endpoint]; ok {
+			if err := b.wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
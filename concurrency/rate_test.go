@@ -0,0 +1,103 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBucketReserve(t *testing.T) {
+	b := newBucket(Rate{PerSecond: 10, Burst: 2})
+
+	tests := []struct {
+		min, max time.Duration
+	}{
+		{0, 0},
+		{0, 0},
+		{90 * time.Millisecond, 100 * time.Millisecond},
+		{190 * time.Millisecond, 200 * time.Millisecond},
+	}
+
+	for n, test := range tests {
+		if delay := b.reserve(); delay < test.min || delay > test.max {
+			t.Errorf("reserve() %d = %s, want [%s, %s]", n, delay, test.min, test.max)
+		}
+	}
+}
+
+func TestBucketWaitCancelled(t *testing.T) {
+	b := newBucket(Rate{PerSecond: 1})
+	b.reserve()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := b.wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("wait() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The cancelled reservation is returned
+	if delay := b.reserve(); delay > time.Second {
+		t.Errorf("reserve() after cancelled wait = %s, want at most 1s", delay)
+	}
+}
+
+func TestRateTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	transport := NewRateTransport(map[string]Rate{
+		"ls": {PerSecond: 1, Burst: 1},
+	}, http.DefaultTransport)
+	client := &http.Client{Transport: transport}
+
+	tests := []struct {
+		path    string
+		limited bool
+	}{
+		{"/api/v0/file/ls", false},
+		{"/api/v0/cat", false},
+		{"/api/v0/cat", false},
+		{"/api/v0/ls", true},
+	}
+
+	for _, test := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		req, _ := http.NewRequest(http.MethodPost, server.URL+test.path, nil)
+
+		resp, err := client.Do(req.WithContext(ctx))
+		cancel()
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		if limited := err != nil; limited != test.limited {
+			t.Errorf("POST %s = %v, want limited %v", test.path, err, test.limited)
+		}
+	}
+
+	all := &http.Client{Transport: NewRateTransport(map[string]Rate{
+		AllEndpoints: {PerSecond: 1, Burst: 1},
+	}, http.DefaultTransport)}
+	for n, limited := range []bool{false, true} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		req, _ := http.NewRequest(http.MethodGet, server.URL+fmt.Sprintf("/%d", n), nil)
+
+		resp, err := all.Do(req.WithContext(ctx))
+		cancel()
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		if (err != nil) != limited {
+			t.Errorf("request %d with rate for all endpoints = %v, want limited %v", n, err, limited)
+		}
+	}
+
+	if NewRateTransport(nil, http.DefaultTransport) != http.DefaultTransport {
+		t.Error("NewRateTransport() without rates wraps base transport")
+	}
+}
@@ -0,0 +1,29 @@
+package concurrency
+
+import (
+	"github.com/ipfs/go-ipfs-api"
+	"net/http"
+)
+
+// NewShellTransport returns the transport used for the IPFS API; like
+// shell.NewShell, keep-alives are disabled.
+func NewShellTransport() *http.Transport {
+	return &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		DisableKeepAlives: true,
+	}
+}
+
+// NewShell returns an IPFS API shell with requests in flight and their rate
+// limited by config.
+func NewShell(url string, config Config) *shell.Shell {
+	return NewShellWithTransport(url, config, NewShellTransport())
+}
+
+// NewShellWithTransport returns an IPFS API shell performing requests
+// through base, with requests in flight and their rate limited by config.
+func NewShellWithTransport(url string, config Config, base http.RoundTripper) *shell.Shell {
+	return shell.NewShellWithClient(url, &http.Client{
+		Transport: NewTransport(config, base),
+	})
+}
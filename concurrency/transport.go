@@ -0,0 +1,112 @@
+// Package concurrency limits concurrent requests to backends such as IPFS,
+// ipfs-tika and Elasticsearch, independent of the amount of workers.
+package concurrency
+
+import (
+	"context"
+	"golang.org/x/sync/semaphore"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// limiter hands out slots for requests
+type limiter interface {
+	acquire(ctx context.Context) error
+	// release frees a slot, given the time to response and whether the
+	// backend failed; latency 0 means the outcome is unknown
+	release(latency time.Duration, failed bool)
+}
+
+// staticLimiter allows a fixed amount of requests in flight
+type staticLimiter struct {
+	sem *semaphore.Weighted
+}
+
+func (l *staticLimiter) acquire(ctx context.Context) error {
+	return l.sem.Acquire(ctx, 1)
+}
+
+func (l *staticLimiter) release(time.Duration, bool) {
+	l.sem.Release(1)
+}
+
+// Transport limits the amount of requests in flight through an underlying
+// transport. Requests hold their slot until the response body is closed,
+// as streaming responses keep the backend busy.
+type Transport struct {
+	base    http.RoundTripper
+	limiter limiter
+}
+
+// NewTransport returns a transport allowing up to config.Max requests in
+// flight through base, at config.Rates, injecting config.Faults; for Max 0,
+// no rates and no faults, base is returned as is.
+func NewTransport(config Config, base http.RoundTripper) http.RoundTripper {
+	// Injected faults take slots, like those of the backend
+	base = NewFaultTransport(config.Faults, base)
+
+	if config.Max == 0 {
+		return NewRateTransport(config.Rates, base)
+	}
+
+	var l limiter
+	if config.Adaptive {
+		l = newAdaptiveLimiter(config.Max)
+	} else {
+		l = &staticLimiter{semaphore.NewWeighted(int64(config.Max))}
+	}
+
+	// Wait for the rate limit before taking a slot
+	return NewRateTransport(config.Rates, &Transport{
+		base:    base,
+		limiter: l,
+	})
+}
+
+// releasingBody releases a slot when closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close closes the body and releases the slot, once
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// RoundTrip waits for a slot, or until the request is cancelled, and
+// performs the request
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if err := t.limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	latency := time.Since(start)
+
+	if err != nil {
+		if ctx.Err() != nil {
+			// Requests cancelled or timed out by the caller say
+			// little about the backend
+			latency = 0
+		}
+
+		t.limiter.release(latency, latency != 0)
+		return nil, err
+	}
+
+	failed := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+	resp.Body = &releasingBody{
+		ReadCloser: resp.Body,
+		release:    func() { t.limiter.release(latency, failed) },
+	}
+
+	return resp, nil
+}
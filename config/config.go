@@ -4,11 +4,30 @@ import (
 	"fmt"
 	env "github.com/Netflix/go-env"
 	"github.com/c2h5oh/datasize"
+	"github.com/ipfs-search/ipfs-search/admin"
+	"github.com/ipfs-search/ipfs-search/api"
+	"github.com/ipfs-search/ipfs-search/classifier"
+	"github.com/ipfs-search/ipfs-search/concurrency"
 	"github.com/ipfs-search/ipfs-search/crawler"
 	"github.com/ipfs-search/ipfs-search/crawler/factory"
+	"github.com/ipfs-search/ipfs-search/dedup"
+	"github.com/ipfs-search/ipfs-search/denylist"
+	"github.com/ipfs-search/ipfs-search/enrich"
+	"github.com/ipfs-search/ipfs-search/extractor/images"
+	"github.com/ipfs-search/ipfs-search/extractor/media"
+	"github.com/ipfs-search/ipfs-search/extractor/pdf"
+	"github.com/ipfs-search/ipfs-search/extractor/tika"
+	"github.com/ipfs-search/ipfs-search/gateway"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs-search/ipfs-search/thumbnail"
+	"github.com/ipfs-search/ipfs-search/tracing"
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
 	yaml "gopkg.in/yaml.v2"
 	"io/ioutil"
-	"log"
+	"path"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -17,55 +36,725 @@ type Tika struct {
 	IpfsTikaURL     string            `yaml:"url" env:"IPFS_TIKA_URL"`
 	IpfsTikaTimeout time.Duration     `yaml:"timeout"`
 	MetadataMaxSize datasize.ByteSize `yaml:"max_size"`
+	PartialSize     datasize.ByteSize `yaml:"partial_size" optional:"true"`
+	TikaServerURL   string            `yaml:"server_url" env:"TIKA_SERVER_URL" optional:"true"`
+	MaxConcurrency  uint              `yaml:"max_concurrency" optional:"true"`
+	Adaptive        bool              `yaml:"adaptive_concurrency" optional:"true"`
+	Faults          Faults            `yaml:"faults" optional:"true"`
+}
+
+// Concurrency returns the limits for ipfs-tika requests in flight
+func (t Tika) Concurrency() concurrency.Config {
+	return concurrency.Config{Max: t.MaxConcurrency, Adaptive: t.Adaptive, Faults: t.Faults.faults()}
+}
+
+// Faults injects delays and failures into requests to a backend, for
+// resilience testing in staging
+type Faults struct {
+	Delay     float64       `yaml:"delay"`
+	DelayTime time.Duration `yaml:"delay_time"`
+	Fail      float64       `yaml:"fail"`
+}
+
+func (f Faults) faults() concurrency.Faults {
+	return concurrency.Faults{Delay: f.Delay, DelayTime: f.DelayTime, Fail: f.Fail}
+}
+
+// check returns an error for fractions outside [0, 1] and delays without
+// delay time
+func (f Faults) check() error {
+	if f.Delay < 0 || f.Delay > 1 || f.Fail < 0 || f.Fail > 1 {
+		return fmt.Errorf("delay and fail are fractions of requests, from 0 to 1")
+	}
+
+	if f.Delay > 0 && f.DelayTime <= 0 {
+		return fmt.Errorf("delay requires delay_time")
+	}
+
+	return nil
+}
+
+// enabled returns whether any faults are injected
+func (f Faults) enabled() bool {
+	return f.Delay > 0 || f.Fail > 0
+}
+
+// RateLimit limits the rate of requests to an endpoint
+type RateLimit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst uint    `yaml:"burst"`
 }
 
 type IPFS struct {
-	IpfsAPI     string        `yaml:"api_url" env:"IPFS_API_URL"`
-	IpfsTimeout time.Duration `yaml:"timeout"`
+	IpfsAPI             string               `yaml:"api_url" env:"IPFS_API_URL"`
+	IpfsAPIs            []string             `yaml:"api_urls" optional:"true"`
+	HealthcheckInterval time.Duration        `yaml:"healthcheck_interval" optional:"true"`
+	IpfsTimeout         time.Duration        `yaml:"timeout"`
+	MaxConcurrency      uint                 `yaml:"max_concurrency" optional:"true"`
+	Adaptive            bool                 `yaml:"adaptive_concurrency" optional:"true"`
+	RateLimits          map[string]RateLimit `yaml:"rate_limits" optional:"true"`
+	Faults              Faults               `yaml:"faults" optional:"true"`
+}
+
+// URLs returns the endpoints of all IPFS nodes
+func (i IPFS) URLs() []string {
+	return append([]string{i.IpfsAPI}, i.IpfsAPIs...)
+}
+
+// Concurrency returns the limits for IPFS API requests in flight and their
+// rates by endpoint
+func (i IPFS) Concurrency() concurrency.Config {
+	rates := make(map[string]concurrency.Rate, len(i.RateLimits))
+	for endpoint, r := range i.RateLimits {
+		rates[endpoint] = concurrency.Rate{PerSecond: r.Rate, Burst: r.Burst}
+	}
+
+	return concurrency.Config{Max: i.MaxConcurrency, Adaptive: i.Adaptive, Rates: rates, Faults: i.Faults.faults()}
+}
+
+// Gateways are public IPFS gateways content is fetched from when the IPFS
+// API times out
+type Gateways struct {
+	URLs    []string      `yaml:"urls" optional:"true"`
+	Timeout time.Duration `yaml:"timeout"`
+	Rate    float64       `yaml:"rate"`
+	Burst   uint          `yaml:"burst"`
+}
+
+type Images struct {
+	Native    bool              `yaml:"native" optional:"true"`
+	MaxHeader datasize.ByteSize `yaml:"max_header"`
+	StripGPS  bool              `yaml:"strip_gps" optional:"true"`
+}
+
+type Media struct {
+	FFprobe    string        `yaml:"ffprobe" optional:"true"`
+	GatewayURL string        `yaml:"gateway_url"`
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+type PDF struct {
+	Fallback bool              `yaml:"fallback" optional:"true"`
+	MaxSize  datasize.ByteSize `yaml:"max_size"`
+}
+
+type Thumbnails struct {
+	Enabled    bool              `yaml:"enabled" optional:"true"`
+	MaxSize    int               `yaml:"max_size"`
+	Quality    int               `yaml:"quality"`
+	MaxImage   datasize.ByteSize `yaml:"max_image"`
+	FFmpeg     string            `yaml:"ffmpeg" optional:"true"`
+	GatewayURL string            `yaml:"gateway_url"`
+	Timeout    time.Duration     `yaml:"timeout"`
+	StoreURL   string            `yaml:"store_url" env:"THUMBNAIL_STORE_URL" optional:"true"`
+}
+
+type Classifier struct {
+	URL       string            `yaml:"url" env:"CLASSIFIER_URL" optional:"true"`
+	Timeout   time.Duration     `yaml:"timeout"`
+	BatchSize int               `yaml:"batch_size"`
+	BatchWait time.Duration     `yaml:"batch_wait"`
+	MaxLength datasize.ByteSize `yaml:"max_length"`
 }
 
 type ElasticSearch struct {
-	ElasticSearchURL string `yaml:"url" env:"ELASTICSEARCH_URL"`
+	Backend             string        `yaml:"backend" optional:"true"`
+	ElasticSearchURL    string        `yaml:"url" env:"ELASTICSEARCH_URL"`
+	URLs                []string      `yaml:"urls" optional:"true"`
+	Username            string        `yaml:"username" env:"ELASTICSEARCH_USERNAME" optional:"true"`
+	Password            string        `yaml:"password" env:"ELASTICSEARCH_PASSWORD" optional:"true"`
+	APIKey              string        `yaml:"api_key" env:"ELASTICSEARCH_API_KEY" optional:"true"`
+	CACert              string        `yaml:"ca_cert" optional:"true"`
+	Sniff               bool          `yaml:"sniff" optional:"true"`
+	HealthcheckInterval time.Duration `yaml:"healthcheck_interval" optional:"true"`
+	MaxConcurrency      uint          `yaml:"max_concurrency" optional:"true"`
+	Adaptive            bool          `yaml:"adaptive_concurrency" optional:"true"`
+	Routing             string        `yaml:"routing" optional:"true"`
+	DocumentIDs         string        `yaml:"document_ids" optional:"true"`
+	Faults              Faults        `yaml:"faults" optional:"true"`
+}
+
+// Concurrency returns the limits for Elasticsearch requests in flight
+func (e ElasticSearch) Concurrency() concurrency.Config {
+	return concurrency.Config{Max: e.MaxConcurrency, Adaptive: e.Adaptive, Faults: e.Faults.faults()}
+}
+
+type Standby struct {
+	URLs     []string `yaml:"urls" optional:"true"`
+	Username string   `yaml:"username" env:"STANDBY_ELASTICSEARCH_USERNAME" optional:"true"`
+	Password string   `yaml:"password" env:"STANDBY_ELASTICSEARCH_PASSWORD" optional:"true"`
+	APIKey   string   `yaml:"api_key" env:"STANDBY_ELASTICSEARCH_API_KEY" optional:"true"`
+	CACert   string   `yaml:"ca_cert" optional:"true"`
 }
 
 type AMQP struct {
-	AMQPURL string `yaml:"url" env:"AMQP_URL"`
+	AMQPURL  string `yaml:"url" env:"AMQP_URL"`
+	Username string `yaml:"username" env:"AMQP_USERNAME" optional:"true"`
+	Password string `yaml:"password" env:"AMQP_PASSWORD" optional:"true"`
+	VHost    string `yaml:"vhost" env:"AMQP_VHOST" optional:"true"`
+
+	// Messages delivered to each consumer ahead of acknowledgement, and
+	// optionally to all consumers of a channel (per connection on brokers
+	// other than RabbitMQ)
+	Prefetch       int `yaml:"prefetch" env:"AMQP_PREFETCH" optional:"true"`
+	GlobalPrefetch int `yaml:"global_prefetch" env:"AMQP_GLOBAL_PREFETCH" optional:"true"`
+}
+
+// RedisStreams configures Redis Streams as broker, instead of AMQP
+type RedisStreams struct {
+	URL string `yaml:"url" env:"REDIS_STREAMS_URL" optional:"true"`
+}
+
+// JetStream configures NATS JetStream as broker, instead of AMQP
+type JetStream struct {
+	URL string `yaml:"url" env:"JETSTREAM_URL" optional:"true"`
+}
+
+// Kafka configures Kafka as broker, instead of AMQP
+type Kafka struct {
+	URL string `yaml:"url" env:"KAFKA_URL" optional:"true"`
+}
+
+type API struct {
+	Listen              string        `yaml:"listen" env:"API_LISTEN"`
+	BeaconFlushInterval time.Duration `yaml:"beacon_flush_interval"`
+	CrawlTimeout        time.Duration `yaml:"crawl_timeout"`
+	CrawlConcurrency    int           `yaml:"crawl_concurrency"`
+	IngestInterval      time.Duration `yaml:"ingest_interval"`
+	IngestBurst         int           `yaml:"ingest_burst"`
+	IngestDifficulty    uint          `yaml:"ingest_difficulty" optional:"true"`
+	TrustedProxies      []string      `yaml:"trusted_proxies" optional:"true"`
+	PublisherSecret     string        `yaml:"publisher_secret" env:"API_PUBLISHER_SECRET" optional:"true"`
+	QueryAnalytics      string        `yaml:"query_analytics" optional:"true"`
+	AnalyticsInterval   time.Duration `yaml:"analytics_interval"`
+	PrivacyEpsilon      float64       `yaml:"privacy_epsilon"`
+	PrivacyThreshold    int64         `yaml:"privacy_threshold" optional:"true"`
+}
+
+type Metrics struct {
+	Listen string `yaml:"listen" env:"METRICS_LISTEN"`
+}
+
+type Tracing struct {
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT" optional:"true"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type Admin struct {
+	Listen string `yaml:"listen" env:"ADMIN_LISTEN" optional:"true"`
+}
+
+type Recrawl struct {
+	Staleness time.Duration `yaml:"staleness"`
+	Interval  time.Duration `yaml:"interval"`
+	BatchSize int           `yaml:"batch_size"`
+}
+
+// Probe configures probing the availability of indexed content on public
+// gateways
+type Probe struct {
+	Gateways   []string      `yaml:"gateways" optional:"true"`
+	Interval   time.Duration `yaml:"interval"`
+	SampleSize int           `yaml:"sample_size"`
+	Timeout    time.Duration `yaml:"timeout"`
+	Rate       float64       `yaml:"rate"`
+	Burst      uint          `yaml:"burst"`
+}
+
+// Capacity configures forecasting when Elasticsearch disk watermarks are
+// hit, and throttling discovery ahead of it
+type Capacity struct {
+	Window         time.Duration `yaml:"window"`
+	Interval       time.Duration `yaml:"interval"`
+	ThrottleWithin time.Duration `yaml:"throttle_within" optional:"true"`
+}
+
+type Snapshot struct {
+	Key          string        `yaml:"key"`
+	Interval     time.Duration `yaml:"interval"`
+	FullInterval time.Duration `yaml:"full_interval" optional:"true"`
+	Lifetime     time.Duration `yaml:"lifetime"`
+	ShardSize    int           `yaml:"shard_size"`
+}
+
+type Denylist struct {
+	Sources         []string      `yaml:"sources" optional:"true"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	ErasureFile     string        `yaml:"erasure_file" optional:"true"`
+}
+
+type Dedup struct {
+	Size     int           `yaml:"size" optional:"true"`
+	TTL      time.Duration `yaml:"ttl"`
+	RedisURL string        `yaml:"redis_url" env:"REDIS_URL" optional:"true"`
+	Tasks    bool          `yaml:"tasks" optional:"true"`
+}
+
+// FileRoute directs files of given content types to a dedicated worker pool
+type FileRoute struct {
+	Name      string   `yaml:"name"`
+	MimeTypes []string `yaml:"mimetypes"`
+	Workers   uint     `yaml:"workers"`
+}
+
+// Enricher is an enrichment service files are sent to after extraction
+type Enricher struct {
+	Name       string        `yaml:"name"`
+	URL        string        `yaml:"url"`
+	MimeTypes  []string      `yaml:"mimetypes"`
+	Fields     []string      `yaml:"fields"`
+	GatewayURL string        `yaml:"gateway_url"`
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+// FilePipeline lists the stages files of particular content types go
+// through
+type FilePipeline struct {
+	MimeTypes []string `yaml:"mimetypes"`
+	Stages    []string `yaml:"stages"`
+}
+
+// TagRule tags and labels items matching all of its conditions, leaving
+// out conditions matches any item
+type TagRule struct {
+	MimeTypes []string          `yaml:"mimetypes"`
+	MinSize   datasize.ByteSize `yaml:"min_size"`
+	MaxSize   datasize.ByteSize `yaml:"max_size"`
+	Names     []string          `yaml:"names"`
+	Roots     []string          `yaml:"roots"`
+	Metadata  map[string]string `yaml:"metadata"`
+	Tags      []string          `yaml:"tags"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+// check returns an error for rules without tags or labels, or with invalid
+// patterns
+func (r *TagRule) check() error {
+	if len(r.Tags) == 0 && len(r.Labels) == 0 {
+		return fmt.Errorf("rules require tags or labels")
+	}
+
+	if r.MaxSize > 0 && r.MaxSize < r.MinSize {
+		return fmt.Errorf("max_size below min_size")
+	}
+
+	for _, pattern := range r.Names {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("name %s: %v", pattern, err)
+		}
+	}
+
+	for field, pattern := range r.Metadata {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("metadata %s: %v", field, err)
+		}
+	}
+
+	return nil
 }
 
 type Crawler struct {
-	RetryWait   time.Duration     `yaml:"retry_wait"`
-	HashWait    time.Duration     `yaml:"hash_wait"`
-	FileWait    time.Duration     `yaml:"file_wait"`
-	PartialSize datasize.ByteSize `yaml:"partial_size"`
-	HashWorkers uint              `yaml:"hash_workers"`
-	FileWorkers uint              `yaml:"file_workers"`
+	RetryWait      time.Duration     `yaml:"retry_wait"`
+	ExtractRetries int               `yaml:"extract_retries" optional:"true"`
+	HashWait       time.Duration     `yaml:"hash_wait"`
+	FileWait       time.Duration     `yaml:"file_wait"`
+	PartialSize    datasize.ByteSize `yaml:"partial_size"`
+	HashWorkers    uint              `yaml:"hash_workers"`
+	FileWorkers    uint              `yaml:"file_workers"`
+	MinHashWorkers uint              `yaml:"min_hash_workers"`
+	MinFileWorkers uint              `yaml:"min_file_workers"`
+	ScaleInterval  time.Duration     `yaml:"scale_interval"`
+	MemoryBudget   datasize.ByteSize `yaml:"memory_budget" optional:"true"`
+	HistorySize    int               `yaml:"history_size" optional:"true"`
+	Routes         []FileRoute       `yaml:"routes" optional:"true"`
+	Rules          []TagRule         `yaml:"rules" optional:"true"`
+	Enrichers      []Enricher        `yaml:"enrichers" optional:"true"`
+	Pipelines      []FilePipeline    `yaml:"pipelines" optional:"true"`
+	Journal        string            `yaml:"journal" optional:"true"`
+	ExactlyOnce    bool              `yaml:"exactly_once" optional:"true"`
+
+	// Maximum age of tasks by queue name, after which they expire, and
+	// whether expired tasks go to the dead letter queue instead of dropped
+	QueueTTLs         map[string]time.Duration `yaml:"queue_ttls" optional:"true"`
+	DeadLetterExpired bool                     `yaml:"dead_letter_expired" optional:"true"`
+
+	// Time after which tasks failing temporarily are tried again, through
+	// a delay queue rather than by waiting in the worker
+	RetryDelay time.Duration `yaml:"retry_delay" optional:"true"`
+
+	// Index directory structure and file names of all hashes, without
+	// fetching file contents
+	StructureOnly bool `yaml:"structure_only" optional:"true"`
 }
 
 type Config struct {
 	Tika          `yaml:"tika"`
 	IPFS          `yaml:"ipfs"`
+	Gateways      `yaml:"gateways"`
+	Images        `yaml:"images"`
+	Media         `yaml:"media"`
+	PDF           `yaml:"pdf"`
+	Thumbnails    `yaml:"thumbnails"`
+	Classifier    `yaml:"classifier"`
 	ElasticSearch `yaml:"elasticsearch"`
+	Standby       `yaml:"standby_elasticsearch"`
 	AMQP          `yaml:"amqp"`
+	RedisStreams  `yaml:"redis_streams"`
+	JetStream     `yaml:"jetstream"`
+	Kafka         `yaml:"kafka"`
 	Crawler       `yaml:"crawler"`
+	Recrawl       `yaml:"recrawl"`
+	Probe         `yaml:"probe"`
+	Capacity      `yaml:"capacity"`
+	Denylist      `yaml:"denylist"`
+	Dedup         `yaml:"dedup"`
+	Snapshot      `yaml:"snapshot"`
+	API           `yaml:"api"`
+	Metrics       `yaml:"metrics"`
+	Admin         `yaml:"admin"`
+	Tracing       `yaml:"tracing"`
 }
 
 func (c *Config) CrawlerConfig() *crawler.Config {
-	return &crawler.Config{
+	cfg := &crawler.Config{
+		RetryWait:      c.Crawler.RetryWait,
+		ExtractRetries: c.Crawler.ExtractRetries,
+		PartialSize:    uint64(c.Crawler.PartialSize),
+
+		// Files up to max_size are extracted entirely, beyond only partial_size
+		MaxExtractSize: uint64(c.Tika.MetadataMaxSize),
+
+		HistorySize: c.Crawler.HistorySize,
+
+		ExactlyOnce: c.Crawler.ExactlyOnce,
+
+		StructureOnly: c.Crawler.StructureOnly,
+
+		DedupTasks: c.Dedup.Tasks,
+	}
+
+	for _, r := range c.Crawler.Routes {
+		cfg.Routes = append(cfg.Routes, crawler.Route{
+			Name:      r.Name,
+			MimeTypes: r.MimeTypes,
+		})
+	}
+
+	for _, p := range c.Crawler.Pipelines {
+		cfg.Pipelines = append(cfg.Pipelines, crawler.Pipeline{
+			MimeTypes: p.MimeTypes,
+			Stages:    p.Stages,
+		})
+	}
+
+	for _, r := range c.Crawler.Rules {
+		rule := crawler.Rule{
+			MimeTypes: r.MimeTypes,
+			MinSize:   uint64(r.MinSize),
+			MaxSize:   uint64(r.MaxSize),
+			Names:     r.Names,
+			Roots:     r.Roots,
+			Metadata:  make(map[string]*regexp.Regexp, len(r.Metadata)),
+			Tags:      r.Tags,
+			Labels:    r.Labels,
+		}
+
+		// Patterns have been checked when reading the configuration
+		for field, pattern := range r.Metadata {
+			rule.Metadata[field] = regexp.MustCompile(pattern)
+		}
+
+		cfg.Rules = append(cfg.Rules, rule)
+	}
+
+	return cfg
+}
+
+func (c *Config) TikaConfig() *tika.Config {
+	return &tika.Config{
 		IpfsTikaURL:     c.Tika.IpfsTikaURL,
 		IpfsTikaTimeout: c.Tika.IpfsTikaTimeout,
+		IpfsAPIs:        c.IPFS.URLs(),
 		MetadataMaxSize: uint64(c.Tika.MetadataMaxSize),
-		RetryWait:       c.Crawler.RetryWait,
-		PartialSize:     uint64(c.Crawler.PartialSize),
+		PartialSize:     uint64(c.Tika.PartialSize),
+		TikaServerURL:   c.Tika.TikaServerURL,
+		Concurrency:     c.Tika.Concurrency(),
+		Gateways:        c.GatewayConfig(),
+	}
+}
+
+// GatewayConfig returns the configuration for fetching content from
+// gateways, nil if none are configured
+func (c *Config) GatewayConfig() *gateway.Config {
+	if len(c.Gateways.URLs) == 0 {
+		return nil
+	}
+
+	return &gateway.Config{
+		URLs:    c.Gateways.URLs,
+		Timeout: c.Gateways.Timeout,
+		Rate:    concurrency.Rate{PerSecond: c.Gateways.Rate, Burst: c.Gateways.Burst},
+	}
+}
+
+// ProbeConfig returns the configuration of the gateways availability is
+// probed on, nil if none are configured
+func (c *Config) ProbeConfig() *gateway.Config {
+	if len(c.Probe.Gateways) == 0 {
+		return nil
+	}
+
+	return &gateway.Config{
+		URLs:    c.Probe.Gateways,
+		Timeout: c.Probe.Timeout,
+		Rate:    concurrency.Rate{PerSecond: c.Probe.Rate, Burst: c.Probe.Burst},
+	}
+}
+
+// ImagesConfig returns the configuration for extracting images in-process,
+// nil if disabled
+func (c *Config) ImagesConfig() *images.Config {
+	if !c.Images.Native {
+		return nil
+	}
+
+	return &images.Config{
+		MaxHeader: uint64(c.Images.MaxHeader),
+		StripGPS:  c.Images.StripGPS,
+	}
+}
+
+// MediaConfig returns the configuration for extracting audio and video
+// with ffprobe, nil if no ffprobe is configured
+func (c *Config) MediaConfig() *media.Config {
+	if c.Media.FFprobe == "" {
+		return nil
+	}
+
+	return &media.Config{
+		FFprobe:    c.Media.FFprobe,
+		GatewayURL: c.Media.GatewayURL,
+		Timeout:    c.Media.Timeout,
+	}
+}
+
+// PDFConfig returns the configuration for extracting PDF text in-process
+// while Tika is unavailable, nil if disabled
+func (c *Config) PDFConfig() *pdf.Config {
+	if !c.PDF.Fallback {
+		return nil
+	}
+
+	return &pdf.Config{
+		MaxSize: uint64(c.PDF.MaxSize),
+	}
+}
+
+// ThumbnailConfig returns the configuration for generating thumbnails, nil
+// if disabled
+func (c *Config) ThumbnailConfig() *thumbnail.Config {
+	if !c.Thumbnails.Enabled {
+		return nil
+	}
+
+	return &thumbnail.Config{
+		MaxSize:    c.Thumbnails.MaxSize,
+		Quality:    c.Thumbnails.Quality,
+		MaxImage:   uint64(c.Thumbnails.MaxImage),
+		FFmpeg:     c.Thumbnails.FFmpeg,
+		GatewayURL: c.Thumbnails.GatewayURL,
+		Timeout:    c.Thumbnails.Timeout,
+		StoreURL:   c.Thumbnails.StoreURL,
+	}
+}
+
+// EnrichConfigs returns the configurations of enrichment services, in
+// order
+func (c *Config) EnrichConfigs() []*enrich.Config {
+	configs := make([]*enrich.Config, len(c.Crawler.Enrichers))
+	for n, e := range c.Crawler.Enrichers {
+		configs[n] = &enrich.Config{
+			Name:       e.Name,
+			URL:        e.URL,
+			MimeTypes:  e.MimeTypes,
+			Fields:     e.Fields,
+			GatewayURL: e.GatewayURL,
+			Timeout:    e.Timeout,
+		}
+	}
+
+	return configs
+}
+
+// ClassifierConfig returns the configuration of the classification
+// service, nil if none is configured
+func (c *Config) ClassifierConfig() *classifier.Config {
+	if c.Classifier.URL == "" {
+		return nil
+	}
+
+	return &classifier.Config{
+		URL:       c.Classifier.URL,
+		Timeout:   c.Classifier.Timeout,
+		BatchSize: c.Classifier.BatchSize,
+		BatchWait: c.Classifier.BatchWait,
+		MaxLength: int(c.Classifier.MaxLength),
+	}
+}
+
+func (c *Config) DenylistConfig() *denylist.Config {
+	return &denylist.Config{
+		Sources:         c.Denylist.Sources,
+		RefreshInterval: c.Denylist.RefreshInterval,
+		ErasureFile:     c.Denylist.ErasureFile,
+	}
+}
+
+func (c *Config) DedupConfig() *dedup.Config {
+	return &dedup.Config{
+		Size:     c.Dedup.Size,
+		TTL:      c.Dedup.TTL,
+		RedisURL: c.Dedup.RedisURL,
+	}
+}
+
+func (c *Config) ElasticSearchConfig() *indexer.Config {
+	return &indexer.Config{
+		Backend:             c.ElasticSearch.Backend,
+		URLs:                append([]string{c.ElasticSearch.ElasticSearchURL}, c.ElasticSearch.URLs...),
+		Username:            c.ElasticSearch.Username,
+		Password:            c.ElasticSearch.Password,
+		APIKey:              c.ElasticSearch.APIKey,
+		CACert:              c.ElasticSearch.CACert,
+		Sniff:               c.ElasticSearch.Sniff,
+		HealthcheckInterval: c.ElasticSearch.HealthcheckInterval,
+		Concurrency:         c.ElasticSearch.Concurrency(),
+		Routing:             c.ElasticSearch.Routing,
+		IDs:                 c.ElasticSearch.DocumentIDs,
+		Standby:             c.StandbyConfig(),
+	}
+}
+
+// StandbyConfig returns the configuration for the standby cluster, nil if
+// there is none. Cluster health, concurrency, routing and document IDs
+// are shared with the primary.
+func (c *Config) StandbyConfig() *indexer.Config {
+	if len(c.Standby.URLs) == 0 {
+		return nil
+	}
+
+	return &indexer.Config{
+		URLs:                c.Standby.URLs,
+		Username:            c.Standby.Username,
+		Password:            c.Standby.Password,
+		APIKey:              c.Standby.APIKey,
+		CACert:              c.Standby.CACert,
+		HealthcheckInterval: c.ElasticSearch.HealthcheckInterval,
+		Concurrency:         c.ElasticSearch.Concurrency(),
+		Routing:             c.ElasticSearch.Routing,
+		IDs:                 c.ElasticSearch.DocumentIDs,
+	}
+}
+
+// BrokerURL returns the Redis Streams, NATS JetStream or Kafka URL when
+// configured, or else the AMQP URL with credentials and vhost applied. Invalid URLs
+// are returned as-is, to be reported when dialing.
+func (c *Config) BrokerURL() string {
+	if c.RedisStreams.URL != "" {
+		return c.RedisStreams.URL
+	}
+	if c.JetStream.URL != "" {
+		return c.JetStream.URL
+	}
+	if c.Kafka.URL != "" {
+		return c.Kafka.URL
+	}
+
+	uri, err := amqp.ParseURI(c.AMQP.AMQPURL)
+	if err != nil {
+		return c.AMQP.AMQPURL
+	}
+
+	if c.AMQP.Username != "" {
+		uri.Username = c.AMQP.Username
+	}
+	if c.AMQP.Password != "" {
+		uri.Password = c.AMQP.Password
+	}
+	if c.AMQP.VHost != "" {
+		uri.Vhost = c.AMQP.VHost
+	}
+
+	return uri.String()
+}
+
+func (c *Config) APIConfig() *api.Config {
+	return &api.Config{
+		Listen:              c.API.Listen,
+		BeaconFlushInterval: c.API.BeaconFlushInterval,
+		CrawlTimeout:        c.API.CrawlTimeout,
+		CrawlConcurrency:    c.API.CrawlConcurrency,
+		IngestInterval:      c.API.IngestInterval,
+		IngestBurst:         c.API.IngestBurst,
+		IngestDifficulty:    c.API.IngestDifficulty,
+		TrustedProxies:      c.API.TrustedProxies,
+		PublisherSecret:     c.API.PublisherSecret,
+		QueryAnalytics:      c.API.QueryAnalytics,
+		AnalyticsInterval:   c.API.AnalyticsInterval,
+		PrivacyEpsilon:      c.API.PrivacyEpsilon,
+		PrivacyThreshold:    c.API.PrivacyThreshold,
+	}
+}
+
+func (c *Config) AdminConfig() *admin.Config {
+	return &admin.Config{
+		Listen: c.Admin.Listen,
+	}
+}
+
+func (c *Config) TracingConfig(service string) *tracing.Config {
+	return &tracing.Config{
+		Endpoint:    c.Tracing.Endpoint,
+		SampleRatio: c.Tracing.SampleRatio,
+		ServiceName: service,
+	}
+}
+
+// Expiry returns when tasks on queues expire, by their configured maximum
+// age, which queues on the broker are declared with as well
+func (c *Config) Expiry() queue.Expiry {
+	return queue.Expiry{
+		TTLs:       c.Crawler.QueueTTLs,
+		DeadLetter: c.Crawler.DeadLetterExpired,
 	}
 }
 
 func (c *Config) FactoryConfig() *factory.Config {
 	return &factory.Config{
-		IpfsAPI:          c.IPFS.IpfsAPI,
-		IpfsTimeout:      c.IPFS.IpfsTimeout,
-		ElasticSearchURL: c.ElasticSearch.ElasticSearchURL,
-		AMQPURL:          c.AMQP.AMQPURL,
+		IpfsAPIs:            c.IPFS.URLs(),
+		IpfsHealthcheck:     c.IPFS.HealthcheckInterval,
+		IpfsTimeout:         c.IPFS.IpfsTimeout,
+		IpfsConcurrency:     c.IPFS.Concurrency(),
+		ElasticSearchConfig: c.ElasticSearchConfig(),
+		AMQPURL:             c.BrokerURL(),
+		QoS: queue.QoS{
+			Prefetch:       c.AMQP.Prefetch,
+			GlobalPrefetch: c.AMQP.GlobalPrefetch,
+		},
+		Expiry:           c.Expiry(),
+		RetryDelay:       c.Crawler.RetryDelay,
 		CrawlerConfig:    c.CrawlerConfig(),
+		TikaConfig:       c.TikaConfig(),
+		ImagesConfig:     c.ImagesConfig(),
+		MediaConfig:      c.MediaConfig(),
+		PDFConfig:        c.PDFConfig(),
+		ThumbnailConfig:  c.ThumbnailConfig(),
+		EnrichConfigs:    c.EnrichConfigs(),
+		ClassifierConfig: c.ClassifierConfig(),
+		DenylistConfig:   c.DenylistConfig(),
+		DedupConfig:      c.DedupConfig(),
+		MemoryBudget:     uint64(c.Crawler.MemoryBudget),
+		JournalPath:      c.Crawler.Journal,
 	}
 }
 
@@ -140,5 +829,62 @@ func Get(configFile string) (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.Crawler.MinHashWorkers > cfg.Crawler.HashWorkers || cfg.Crawler.MinFileWorkers > cfg.Crawler.FileWorkers {
+		return nil, fmt.Errorf("Minimum amount of workers exceeds maximum")
+	}
+
+	routes := make(map[string]bool, len(cfg.Crawler.Routes))
+	for _, r := range cfg.Crawler.Routes {
+		if r.Name == "" || len(r.MimeTypes) == 0 || r.Workers == 0 {
+			return nil, fmt.Errorf("Routes require a name, mimetypes and workers")
+		}
+		if routes[r.Name] {
+			return nil, fmt.Errorf("Duplicate route: %s", r.Name)
+		}
+		routes[r.Name] = true
+	}
+
+	enrichers := make([]string, 0, len(cfg.Crawler.Enrichers))
+	for _, e := range cfg.Crawler.Enrichers {
+		if e.Name == "" || e.URL == "" || len(e.Fields) == 0 || e.Timeout <= 0 {
+			return nil, fmt.Errorf("Enrichers require a name, url, fields and timeout")
+		}
+		if crawler.CheckStages([]string{e.Name}, enrichers) == nil {
+			return nil, fmt.Errorf("Duplicate enricher or stage: %s", e.Name)
+		}
+		enrichers = append(enrichers, e.Name)
+	}
+
+	for n, p := range cfg.Crawler.Pipelines {
+		if err := crawler.CheckStages(p.Stages, enrichers); err != nil {
+			return nil, fmt.Errorf("Invalid pipeline %d: %v", n+1, err)
+		}
+	}
+
+	for name, ttl := range cfg.Crawler.QueueTTLs {
+		if ttl < 0 {
+			return nil, fmt.Errorf("Negative TTL of queue %s", name)
+		}
+	}
+
+	if cfg.Crawler.RetryDelay < 0 {
+		return nil, fmt.Errorf("Negative retry delay")
+	}
+
+	for n, r := range cfg.Crawler.Rules {
+		if err := r.check(); err != nil {
+			return nil, fmt.Errorf("Invalid rule %d: %v", n+1, err)
+		}
+	}
+
+	for backend, f := range map[string]Faults{"ipfs": cfg.IPFS.Faults, "tika": cfg.Tika.Faults, "elasticsearch": cfg.ElasticSearch.Faults} {
+		if err := f.check(); err != nil {
+			return nil, fmt.Errorf("Invalid faults of %s: %v", backend, err)
+		}
+		if f.enabled() {
+			log.WithField("backend", backend).Warn("Injecting faults into requests; never do this in production")
+		}
+	}
+
 	return cfg, nil
 }
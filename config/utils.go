@@ -6,6 +6,7 @@ import (
 )
 
 // findZeroElements returns a slice of all (nested) struct fields with a zero value.
+// Fields tagged `optional:"true"` are allowed to be zero.
 func findZeroElements(s interface{}) []string {
 	var output []string
 
@@ -16,6 +17,10 @@ func findZeroElements(s interface{}) []string {
 		f := v.Field(i)
 		name := v.Type().Field(i).Tag.Get("yaml")
 
+		if v.Type().Field(i).Tag.Get("optional") == "true" {
+			continue
+		}
+
 		switch f.Kind() {
 		case reflect.Struct:
 			// It's a struct - recurse!
@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/archive"
+	"github.com/ipfs/go-ipfs-api"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	// maxArchiveEntries limits the entries of archives indexed
+	maxArchiveEntries = 1000
+
+	// maxArchiveRead limits the bytes read for listing an archive, e.g.
+	// for zip files with very large central directories
+	maxArchiveRead = 4 * 1024 * 1024
+)
+
+// ipfsReaderAt reads parts of a file from IPFS, up to a total amount of
+// bytes
+type ipfsReaderAt struct {
+	ctx       context.Context
+	shell     *shell.Shell
+	path      string
+	remaining int64
+}
+
+// ReadAt reads len(p) bytes from off
+func (r *ipfsReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if int64(len(p)) > r.remaining {
+		return 0, fmt.Errorf("read limit of %d bytes exceeded", maxArchiveRead)
+	}
+	r.remaining -= int64(len(p))
+
+	resp, err := r.shell.Request("cat", r.path).
+		Option("offset", off).
+		Option("length", len(p)).
+		Send(r.ctx)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Error != nil {
+		return 0, resp.Error
+	}
+	defer resp.Close()
+
+	n, err := io.ReadFull(resp.Output, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	// Drain the response, so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Output)
+
+	return n, err
+}
+
+// addArchiveContents sets the entries of zip and tar archives on
+// properties, so files which only exist inside archives can be found.
+// Failure to list an archive does not prevent indexing it.
+func (i *Indexable) addArchiveContents(ctx context.Context, properties metadata) {
+	format := archive.Format(i.head)
+	if format == "" || i.Size == 0 {
+		return
+	}
+
+	r := &ipfsReaderAt{
+		ctx:       ctx,
+		shell:     i.Shell,
+		path:      i.hashURL(),
+		remaining: maxArchiveRead,
+	}
+
+	entries, complete, err := archive.List(r, int64(i.Size), maxArchiveEntries)
+	if err != nil {
+		i.log().WithError(err).WithField("format", format).Info("Error listing archive")
+		return
+	}
+
+	if !complete {
+		i.log().Debugf("Indexing first %d entries of archive", len(entries))
+	}
+
+	properties["archive-format"] = format
+	properties["archive-contents"] = entries
+}
@@ -0,0 +1,96 @@
+package crawler
+
+import (
+	"regexp"
+	"strings"
+)
+
+// authorFields are the extracted metadata fields naming authors
+var authorFields = []string{
+	"author", "meta:author", "dc:creator", "creator", "Author",
+	"xmpDM:artist", "Artist",
+}
+
+const (
+	maxAuthors      = 20  // Authors kept per document
+	maxAuthorLength = 100 // Longer values are not names
+)
+
+// authorSeparator matches separators of authors in a single value
+var authorSeparator = regexp.MustCompile(`\s*(?:;|&|\band\b|\bund\b|\bet\b|\|)\s*`)
+
+// NormalizeAuthor returns the form authors are indexed in: trimmed, with
+// whitespace collapsed and case-folded
+func NormalizeAuthor(author string) string {
+	return strings.ToLower(strings.Join(strings.Fields(author), " "))
+}
+
+// splitAuthors splits a value naming several authors. Commas only
+// separate authors when every part has several words, as in
+// "Jane Doe, John Smith", unlike "Doe, Jane".
+func splitAuthors(value string) []string {
+	parts := authorSeparator.Split(value, -1)
+
+	var authors []string
+	for _, part := range parts {
+		names := strings.Split(part, ",")
+		for _, name := range names {
+			if len(names) > 1 && len(strings.Fields(name)) < 2 {
+				names = []string{part}
+				break
+			}
+		}
+
+		for _, name := range names {
+			authors = append(authors, strings.TrimSpace(name))
+		}
+	}
+
+	return authors
+}
+
+// addAuthors sets the normalized authors named in extracted metadata on
+// properties, so documents can be searched and aggregated by author
+func addAuthors(m metadata) {
+	fields, ok := m["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	seen := make(map[string]bool)
+	var authors []string
+
+	for _, field := range authorFields {
+		var values []interface{}
+		switch v := fields[field].(type) {
+		case string:
+			values = []interface{}{v}
+		case []interface{}:
+			values = v
+		}
+
+		for _, value := range values {
+			s, ok := value.(string)
+			if !ok {
+				continue
+			}
+
+			for _, author := range splitAuthors(s) {
+				author = NormalizeAuthor(author)
+				if author == "" || len(author) > maxAuthorLength || seen[author] {
+					continue
+				}
+				seen[author] = true
+
+				if authors = append(authors, author); len(authors) == maxAuthors {
+					m["authors"] = authors
+					return
+				}
+			}
+		}
+	}
+
+	if len(authors) > 0 {
+		m["authors"] = authors
+	}
+}
@@ -0,0 +1,64 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitAuthors(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"Jane Doe", []string{"Jane Doe"}},
+		{"Doe, Jane", []string{"Doe, Jane"}},
+		{"Jane Doe; John Smith", []string{"Jane Doe", "John Smith"}},
+		{"Jane Doe and John Smith", []string{"Jane Doe", "John Smith"}},
+		{"Jane Doe & Brandon Anderson", []string{"Jane Doe", "Brandon Anderson"}},
+		{"Jane Doe, John Smith", []string{"Jane Doe", "John Smith"}},
+		{"Doe, Jane; Smith, John", []string{"Doe, Jane", "Smith, John"}},
+	}
+
+	for _, test := range tests {
+		if got := splitAuthors(test.value); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitAuthors(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestAddAuthors(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		want   interface{}
+	}{
+		{"none", map[string]interface{}{}, nil},
+		{
+			"normalized",
+			map[string]interface{}{"author": []interface{}{"  Jane   DOE "}},
+			[]string{"jane doe"},
+		},
+		{
+			"deduplicated across fields",
+			map[string]interface{}{
+				"author":     []interface{}{"Jane Doe; John Smith"},
+				"dc:creator": []interface{}{"jane doe"},
+			},
+			[]string{"jane doe", "john smith"},
+		},
+		{"empty", map[string]interface{}{"author": []interface{}{" ; "}}, nil},
+	}
+
+	for _, test := range tests {
+		m := metadata{"metadata": test.fields}
+		addAuthors(m)
+
+		got, ok := m["authors"]
+		if !ok {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: addAuthors() = %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
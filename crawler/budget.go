@@ -0,0 +1,42 @@
+package crawler
+
+import (
+	"context"
+	"github.com/ipfs/go-ipfs-api"
+)
+
+// linkSize estimates the memory held for every directory entry, in the
+// listing and the indexed document
+const linkSize = 512
+
+// reserve acquires n bytes from the memory budget, returning a function
+// releasing them
+func (i *Indexable) reserve(ctx context.Context, n uint64) (func(), error) {
+	if err := i.Budget.Acquire(ctx, n); err != nil {
+		return nil, err
+	}
+
+	return func() { i.Budget.Release(n) }, nil
+}
+
+// extractSize estimates the memory held extracting metadata from the file
+func (i *Indexable) extractSize() uint64 {
+	if i.Config.MaxExtractSize > 0 && i.Size > i.Config.MaxExtractSize {
+		return i.Config.MaxExtractSize
+	}
+
+	return i.Size
+}
+
+// reservedFileList lists the hash within a reservation of its expected
+// size, as the listing is held in memory entirely; once listed, files and
+// directories reserve for their actual size
+func (i *Indexable) reservedFileList(ctx context.Context) (*shell.UnixLsObject, error) {
+	release, err := i.reserve(ctx, i.extractSize())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return i.getFileList(ctx)
+}
@@ -0,0 +1,30 @@
+package crawler
+
+import (
+	"context"
+	"strings"
+)
+
+// addCategories sets the topic categories assigned to extracted text by the
+// classification service on properties. Failure to classify does not
+// prevent indexing.
+func (i *Indexable) addCategories(ctx context.Context, properties metadata) {
+	if i.Classifier == nil {
+		return
+	}
+
+	content, _ := properties["content"].(string)
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	categories, err := i.Classifier.Classify(ctx, content)
+	if err != nil {
+		i.log().WithError(err).Warn("Error classifying content")
+		return
+	}
+
+	if len(categories) > 0 {
+		properties["categories"] = categories
+	}
+}
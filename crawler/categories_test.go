@@ -0,0 +1,45 @@
+package crawler
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/classifier"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddCategories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"categories": [["science", "physics"]]}`))
+	}))
+	defer server.Close()
+
+	c := classifier.New(&classifier.Config{URL: server.URL, Timeout: time.Second, BatchSize: 1})
+
+	tests := []struct {
+		name       string
+		classifier *classifier.Classifier
+		content    interface{}
+		want       interface{}
+	}{
+		{"classified", c, "Quantum mechanics", []string{"science", "physics"}},
+		{"no content", c, " ", nil},
+		{"no classifier", nil, "Quantum mechanics", nil},
+	}
+
+	for _, test := range tests {
+		i := &Indexable{
+			Crawler: &Crawler{Classifier: test.classifier},
+			Args:    &Args{Hash: "hash"},
+		}
+
+		m := metadata{"content": test.content}
+		i.addCategories(context.Background(), m)
+
+		if got := m["categories"]; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: addCategories() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -6,13 +6,25 @@ import (
 
 // Config contains user configurable options for a crawler
 type Config struct {
-	IpfsTikaURL     string        // ipfs-tika endpoint URL
-	IpfsTikaTimeout time.Duration // ipfs-tika request timeout
-
 	RetryWait time.Duration // wait time between retries of failed requests
 
-	MetadataMaxSize uint64 // Don't attempt to get metadata for files over this size
+	ExtractRetries int // Retries of temporary extraction failures before indexing without metadata
+
+	PartialSize uint64 // Size of raw blocks considered partial - this is the default chunker block size
+
+	MaxExtractSize uint64 // Maximum content extracted from a file, for estimating memory use
+
+	HistorySize int // Crawl attempts kept per hash, 0 disables crawl history
+
+	Routes []Route // Queues for files of particular content types
+
+	Rules []Rule // Tags and labels for items matching operator defined conditions
+
+	Pipelines []Pipeline // Stages of files by content type, first match wins; DefaultStages otherwise
+
+	ExactlyOnce bool // Apply index updates once per task, retrying failed ones
+
+	StructureOnly bool // Index names and sizes of files without fetching their contents
 
-	PartialSize uint64 // Size for partial items - this is the default chunker block size
-	// TODO: replace by a sane method of skipping partials
+	DedupTasks bool // Queue tasks for a hash once per deduplication window, whatever their reference
 }
@@ -1,11 +1,21 @@
 package crawler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/ipfs-search/ipfs-search/budget"
+	"github.com/ipfs-search/ipfs-search/classifier"
+	"github.com/ipfs-search/ipfs-search/crawler/crawlerrors"
+	"github.com/ipfs-search/ipfs-search/dedup"
+	"github.com/ipfs-search/ipfs-search/denylist"
+	"github.com/ipfs-search/ipfs-search/enrich"
+	"github.com/ipfs-search/ipfs-search/extractor"
 	"github.com/ipfs-search/ipfs-search/indexer"
 	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs-search/ipfs-search/thumbnail"
 	"github.com/ipfs/go-ipfs-api"
+	"time"
 )
 
 // Args describe a resource to be crawled
@@ -15,36 +25,99 @@ type Args struct {
 	Size       uint64
 	ParentHash string
 	ParentName string // This is legacy, should be removed
+	Recrawl    bool   // Crawl again even when indexed, to refresh and verify availability
+	Depth      uint   // Distance from the root the hash was found through; 0 for roots
+
+	// Index directory structure and file names only, without fetching
+	// file contents; inherited by the items found
+	StructureOnly bool
+
+	Provenance *indexer.Provenance // How the hash was discovered; unset for the sniffer
 }
 
 // Crawler consumes file and hash queues and indexes them
 type Crawler struct {
 	Config *Config
 
-	Shell     *shell.Shell
-	Indexer   *indexer.Indexer
-	FileQueue *queue.Queue
-	HashQueue *queue.Queue
+	Shell      *shell.Shell
+	Indexer    indexer.Index
+	Extractor  extractor.Extractor
+	Images     extractor.Extractor    // Optional, extracts images in-process instead of Extractor
+	Media      extractor.Extractor    // Optional, extracts audio and video with ffprobe instead of Extractor
+	PDF        extractor.Extractor    // Optional, extracts PDF text while Extractor is unavailable
+	Classifier *classifier.Classifier // Optional, nil disables classification
+	Thumbnails *thumbnail.Generator   // Optional, nil generates no thumbnails
+	Enrichers  []*enrich.Enricher     // Optional, run in order on files after extraction
+	FileQueue  *queue.Queue
+	HashQueue  *queue.Queue
+	Denylist   *denylist.Denylist // Optional, nil disables denying
+	Budget     *budget.Budget     // Optional, nil for unlimited memory use
+	Seen       dedup.Cache        // Optional, nil disables skipping recently seen hashes
+
+	RouteQueues map[string]*queue.Queue // Queues of Config.Routes, by name
 }
 
-// IndexableFromJSON returns and Indexable associated with this crawler based on a JSON blob
+// IndexableFromJSON returns and Indexable associated with this crawler based
+// on a JSON task, with Args as payload
 func (c *Crawler) IndexableFromJSON(input []byte) (*Indexable, error) {
-	// Unmarshall message into crawler Args
+	task, err := queue.ParseTask(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshall payload into crawler Args
 	args := &Args{}
-	err := json.Unmarshal(input, args)
+	err = json.Unmarshal(task.Payload, args)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := c.NewIndexable(args)
 	if err != nil {
 		return nil, err
 	}
 
+	i.task = task
+	return i, nil
+}
+
+// NewIndexable returns an Indexable associated with this crawler for args
+func (c *Crawler) NewIndexable(args *Args) (*Indexable, error) {
 	// Later down, we assume this hash is set and we're seeing errors where
 	// this aparently seems not the case.
 	if args.Hash == "" {
-		return nil, fmt.Errorf("Empty hash in JSON: %s", input)
+		return nil, fmt.Errorf("Empty hash in %+v", args)
+	}
+
+	hash, err := NormalizeHash(args.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid hash '%s': %v", args.Hash, err)
+	}
+
+	// Keep original representation as alias
+	var alias string
+	if hash != args.Hash {
+		alias = args.Hash
+		args.Hash = hash
 	}
 
 	return &Indexable{
 		Args:    args,
 		Crawler: c,
+		alias:   alias,
 	}, nil
+}
+
+// RecordPanic indexes the item described by a JSON blob as invalid, with
+// the panic which occurred while crawling it as error, to prevent crawling
+// it again
+func (c *Crawler) RecordPanic(ctx context.Context, input []byte, err error) {
+	i, ierr := c.IndexableFromJSON(input)
+	if ierr != nil {
+		return
+	}
 
+	err = crawlerrors.New(crawlerrors.Panic, err)
+	i.indexInvalid(ctx, err)
+	i.recordAttempt(ctx, time.Now(), err)
 }
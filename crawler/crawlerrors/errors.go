@@ -0,0 +1,145 @@
+// Package crawlerrors categorizes crawler failures, so they can be handled
+// and queried by category rather than by matching error messages.
+package crawlerrors
+
+import (
+	"context"
+	"errors"
+	"github.com/ipfs/go-ipfs-api"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// Category describes the kind of a failure
+type Category string
+
+const (
+	// Temporary failures are expected to succeed on retry
+	Temporary Category = "temporary"
+	// Timeout failures took longer than allowed
+	Timeout Category = "timeout"
+	// Invalid content can't be crawled, ever
+	Invalid Category = "invalid"
+	// NotFound content could not be retrieved
+	NotFound Category = "not-found"
+	// TooLarge content exceeds configured limits
+	TooLarge Category = "too-large"
+	// Unavailable services kept failing temporarily after retrying
+	Unavailable Category = "unavailable"
+	// Panic occurred while crawling content
+	Panic Category = "panic"
+)
+
+// Error is a failure of a particular category
+type Error struct {
+	Category Category
+	Err      error
+}
+
+// New returns an error of given category wrapping err
+func New(category Category, err error) *Error {
+	return &Error{
+		Category: category,
+		Err:      err,
+	}
+}
+
+// Error returns the message of the wrapped error
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CategoryOf returns the category of err, or of an error it wraps, or ""
+// when it is not categorized
+func CategoryOf(err error) Category {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Category
+	}
+
+	return ""
+}
+
+// HasCategory returns whether err is of given category
+func HasCategory(err error, category Category) bool {
+	return CategoryOf(err) == category
+}
+
+// invalidMessages identify IPFS errors for content which can't be crawled
+var invalidMessages = []string{"proto", "unrecognized type", "not a valid merkledag node"}
+
+// classifyShell categorizes errors returned by the IPFS API
+func classifyShell(err *shell.Error) Category {
+	for _, m := range invalidMessages {
+		if strings.Contains(err.Message, m) {
+			return Invalid
+		}
+	}
+
+	if strings.Contains(err.Message, "not found") || strings.Contains(err.Message, "no link named") {
+		return NotFound
+	}
+
+	return ""
+}
+
+// classifyURL categorizes errors from HTTP requests
+func classifyURL(err *url.Error) Category {
+	if err.Timeout() {
+		return Timeout
+	}
+
+	if err.Temporary() {
+		return Temporary
+	}
+
+	// Somehow, the errors below are not temp errors !?
+	var opErr *net.OpError
+	if errors.As(err.Err, &opErr) && (opErr.Op == "dial" || opErr.Op == "read") {
+		return Temporary
+	}
+
+	if errors.Is(err.Err, syscall.ECONNREFUSED) {
+		return Temporary
+	}
+
+	return ""
+}
+
+// Classify returns err categorized, when its category can be determined
+// from IPFS API, HTTP or context errors, also when these are wrapped.
+// Errors which are already categorized, or uncategorizable, are returned
+// as they are.
+func Classify(err error) error {
+	if err == nil || CategoryOf(err) != "" {
+		return err
+	}
+
+	var (
+		category Category
+		shellErr *shell.Error
+		urlErr   *url.Error
+	)
+
+	switch {
+	case errors.As(err, &shellErr):
+		category = classifyShell(shellErr)
+	case errors.As(err, &urlErr):
+		category = classifyURL(urlErr)
+	case errors.Is(err, context.DeadlineExceeded):
+		category = Timeout
+	}
+
+	if category == "" {
+		return err
+	}
+
+	return New(category, err)
+}
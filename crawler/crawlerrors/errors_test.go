@@ -0,0 +1,76 @@
+package crawlerrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ipfs/go-ipfs-api"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// timeoutError is a net.Error timing out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassify(t *testing.T) {
+	refused := &url.Error{
+		Op:  "Post",
+		URL: "http://localhost:8081",
+		Err: &net.OpError{
+			Op:  "dial",
+			Net: "tcp",
+			Err: os.NewSyscallError("connect", syscall.ECONNREFUSED),
+		},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want Category
+	}{
+		{"nil", nil, ""},
+		{"plain", errors.New("plain"), ""},
+		{"categorized", New(TooLarge, errors.New("large")), TooLarge},
+		{"wrapped categorized", fmt.Errorf("crawling: %w", New(Invalid, errors.New("invalid"))), Invalid},
+		{"deadline", context.DeadlineExceeded, Timeout},
+		{"wrapped deadline", fmt.Errorf("reading: %w", context.DeadlineExceeded), Timeout},
+		{"shell invalid", &shell.Error{Message: "proto: required field not set"}, Invalid},
+		{"shell not found", &shell.Error{Message: "no link named \"x\""}, NotFound},
+		{"shell other", &shell.Error{Message: "something else"}, ""},
+		{"wrapped shell", fmt.Errorf("listing: %w", &shell.Error{Message: "merkledag: not found"}), NotFound},
+		{"connection refused", refused, Temporary},
+		{"refused read", &url.Error{Op: "Get", Err: syscall.ECONNREFUSED}, Temporary},
+		{"url timeout", &url.Error{Op: "Get", Err: timeoutError{}}, Timeout},
+		{"url other", &url.Error{Op: "Get", Err: errors.New("bad")}, ""},
+	}
+
+	for _, test := range tests {
+		err := Classify(test.err)
+
+		if got := CategoryOf(err); got != test.want {
+			t.Errorf("Classify(%s) category = %q, want %q", test.name, got, test.want)
+		}
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("Classify(%s) = %v, does not wrap %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestHasCategory(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", New(NotFound, errors.New("missing")))
+
+	if !HasCategory(err, NotFound) {
+		t.Errorf("HasCategory(%v, %s) = false, want true", err, NotFound)
+	}
+	if HasCategory(err, Temporary) {
+		t.Errorf("HasCategory(%v, %s) = true, want false", err, Temporary)
+	}
+}
@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"strings"
+	"time"
+)
+
+// dateFields are the extracted metadata fields holding the creation and
+// modification dates of content, in order of preference
+var dateFields = map[string][]string{
+	"content-created": {
+		"dcterms:created", "meta:creation-date", "Creation-Date", "created",
+		"exif:DateTimeOriginal", "Date/Time Original", "date",
+	},
+	"content-modified": {
+		"dcterms:modified", "meta:save-date", "Last-Save-Date", "Last-Modified", "modified",
+	},
+}
+
+// dateLayouts are the formats dates are extracted in
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006:01:02 15:04:05", // EXIF
+	"D:20060102150405Z07'00'",
+	"D:20060102150405Z",
+	"D:20060102150405",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+	time.ANSIC,
+}
+
+// minDate is the earliest valid content date; earlier dates are bogus
+var minDate = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// maxClockSkew is how far content dates may lie in the future
+const maxClockSkew = 24 * time.Hour
+
+// parseDate parses a date in any of the known formats, returning false
+// when it is unknown or implausible
+func parseDate(s string, now time.Time) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+
+		if t.Before(minDate) || t.After(now.Add(maxClockSkew)) {
+			return time.Time{}, false
+		}
+
+		return t.UTC(), true
+	}
+
+	return time.Time{}, false
+}
+
+// addDates sets normalized creation and modification dates of content on
+// metadata, when extracted
+func addDates(m metadata) {
+	fields, ok := m["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	for field, keys := range dateFields {
+		for _, key := range keys {
+			if t, ok := parseDate(firstValue(fields, key), now); ok {
+				m[field] = t.Format(time.RFC3339)
+				break
+			}
+		}
+	}
+}
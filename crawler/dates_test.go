@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2019-03-04T05:06:07Z", "2019-03-04T05:06:07Z"},
+		{"2019-03-04T05:06:07+02:00", "2019-03-04T03:06:07Z"},
+		{"2019-03-04T05:06:07", "2019-03-04T05:06:07Z"},
+		{"2019-03-04", "2019-03-04T00:00:00Z"},
+		{"2019:03:04 05:06:07", "2019-03-04T05:06:07Z"},
+		{"D:20190304050607Z", "2019-03-04T05:06:07Z"},
+		{"Mon, 04 Mar 2019 05:06:07 +0000", "2019-03-04T05:06:07Z"},
+		{" 2019-03-04 ", "2019-03-04T00:00:00Z"},
+		{"", ""},
+		{"yesterday", ""},
+		{"0000:00:00 00:00:00", ""},
+		{"1601-01-01T00:00:00Z", ""},
+		{"2030-01-01T00:00:00Z", ""},
+	}
+
+	for _, test := range tests {
+		d, ok := parseDate(test.date, now)
+
+		got := ""
+		if ok {
+			got = d.Format(time.RFC3339)
+		}
+
+		if got != test.want {
+			t.Errorf("parseDate(%q) = %q, want %q", test.date, got, test.want)
+		}
+	}
+}
+
+func TestAddDates(t *testing.T) {
+	m := metadata{
+		"metadata": map[string]interface{}{
+			"date":             []interface{}{"2018-01-01"},
+			"dcterms:created":  []interface{}{"invalid"},
+			"meta:save-date":   []interface{}{"2019-03-04T05:06:07Z"},
+			"dcterms:modified": []interface{}{"2019-05-06T07:08:09Z"},
+		},
+	}
+
+	addDates(m)
+
+	want := map[string]interface{}{
+		"content-created":  "2018-01-01T00:00:00Z",
+		"content-modified": "2019-05-06T07:08:09Z",
+	}
+	got := map[string]interface{}{
+		"content-created":  m["content-created"],
+		"content-modified": m["content-modified"],
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("addDates() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/ipfs-search/ipfs-search/queue"
+	log "github.com/sirupsen/logrus"
+)
+
+// seen returns whether key is in the recently seen cache, if any. Cache
+// errors are logged and treated as not seen.
+func (c *Crawler) seen(key string) bool {
+	if c.Seen == nil {
+		return false
+	}
+
+	seen, err := c.Seen.Contains(key)
+	if err != nil {
+		log.WithError(err).WithField("key", key).Warn("Error reading recently seen cache")
+		return false
+	}
+
+	return seen
+}
+
+// markSeen adds key to the recently seen cache, if any
+func (c *Crawler) markSeen(key string) {
+	if c.Seen == nil {
+		return
+	}
+
+	if err := c.Seen.Add(key); err != nil {
+		log.WithError(err).WithField("key", key).Warn("Error writing recently seen cache")
+	}
+}
+
+// seenKey returns the cache key for a hash seen as kind. The reference,
+// i.e. parent and name, is part of it, so that hashes found through
+// another parent are not skipped and their references still get recorded.
+func seenKey(kind string, args *Args) string {
+	return kind + "/" + args.Hash + "/" + args.ParentHash + "/" + args.Name
+}
+
+// messageHash returns the hash identifying tasks crawling the same hash in
+// the same way, whichever parent, name or provenance they were found with
+func messageHash(args *Args) string {
+	b, _ := json.Marshal(struct {
+		Hash          string
+		Recrawl       bool
+		StructureOnly bool
+	}{args.Hash, args.Recrawl, args.StructureOnly})
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// publish queues args as a task derived from this item, unless the hash
+// has recently been queued on q with the same reference. With task
+// deduplication, the task carries its message hash, so the queue skips it
+// when it was recently queued with any reference.
+func (i *Indexable) publish(ctx context.Context, q *queue.Queue, args *Args, priority uint8) error {
+	key := seenKey("queued/"+q.Name, args)
+
+	if !args.Recrawl && i.seen(key) {
+		log.WithField("hash", args.Hash).Debug("Skipping recently queued hash")
+		return nil
+	}
+
+	task, err := i.childTask(args, priority)
+	if err != nil {
+		return err
+	}
+
+	if i.Config.DedupTasks {
+		task.Hash = messageHash(args)
+	}
+
+	if err := q.PublishTask(ctx, task); err != nil {
+		return err
+	}
+
+	i.markSeen(key)
+	return nil
+}
+
+// crawledKey returns the cache key for items crawled as kind
+func (i *Indexable) crawledKey(kind string) string {
+	return seenKey("crawled/"+kind, i.Args)
+}
+
+// recentlyCrawled returns whether the item was recently crawled as kind,
+// hash or file, with the same reference; items to be recrawled are never
+// skipped
+func (i *Indexable) recentlyCrawled(kind string) bool {
+	return !i.Recrawl && i.seen(i.crawledKey(kind))
+}
@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"github.com/ipfs-search/ipfs-search/dedup"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"testing"
+	"time"
+)
+
+func TestRecentlyCrawled(t *testing.T) {
+	crawled := &Args{Hash: "hash", ParentHash: "parent", Name: "name"}
+
+	tests := []struct {
+		name string
+		args *Args
+		want bool
+	}{
+		{"same reference", &Args{Hash: "hash", ParentHash: "parent", Name: "name"}, true},
+		{"other parent", &Args{Hash: "hash", ParentHash: "other", Name: "name"}, false},
+		{"other name", &Args{Hash: "hash", ParentHash: "parent", Name: "other"}, false},
+		{"no parent", &Args{Hash: "hash"}, false},
+		{"other hash", &Args{Hash: "other", ParentHash: "parent", Name: "name"}, false},
+		{"recrawl", &Args{Hash: "hash", ParentHash: "parent", Name: "name", Recrawl: true}, false},
+	}
+
+	c := &Crawler{
+		Seen: dedup.New(&dedup.Config{Size: 10, TTL: time.Hour}),
+	}
+	seen := &Indexable{Crawler: c, Args: crawled}
+	seen.markSeen(seen.crawledKey("file"))
+
+	for _, test := range tests {
+		i := &Indexable{Crawler: c, Args: test.args}
+
+		if got := i.recentlyCrawled("file"); got != test.want {
+			t.Errorf("recentlyCrawled() with %s = %v, want %v", test.name, got, test.want)
+		}
+		if i.recentlyCrawled("hash") {
+			t.Errorf("recentlyCrawled() as hash with %s = true, want false", test.name)
+		}
+	}
+}
+
+func TestRecentlyCrawledWithoutCache(t *testing.T) {
+	i := &Indexable{
+		Crawler: &Crawler{},
+		Args:    &Args{Hash: "hash"},
+	}
+	i.markSeen(i.crawledKey("file"))
+
+	if i.recentlyCrawled("file") {
+		t.Errorf("recentlyCrawled() without cache = true, want false")
+	}
+}
+
+func TestMessageHash(t *testing.T) {
+	hash := messageHash(&Args{Hash: "hash", ParentHash: "parent", Name: "name"})
+
+	tests := []struct {
+		name string
+		args *Args
+		same bool
+	}{
+		{"other parent", &Args{Hash: "hash", ParentHash: "other", Name: "name", Depth: 2}, true},
+		{"provenance", &Args{Hash: "hash", Provenance: &indexer.Provenance{Source: SourceLink}}, true},
+		{"other hash", &Args{Hash: "other", ParentHash: "parent", Name: "name"}, false},
+		{"recrawl", &Args{Hash: "hash", Recrawl: true}, false},
+		{"structure only", &Args{Hash: "hash", StructureOnly: true}, false},
+	}
+
+	for _, test := range tests {
+		if got := messageHash(test.args) == hash; got != test.same {
+			t.Errorf("messageHash() with %s same = %v, want %v", test.name, got, test.same)
+		}
+	}
+}
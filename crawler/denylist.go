@@ -0,0 +1,27 @@
+package crawler
+
+import (
+	"context"
+)
+
+// isDenied returns whether a hash is on the denylist, if any
+func (c *Crawler) isDenied(hash string) bool {
+	return c.Denylist != nil && c.Denylist.Contains(hash)
+}
+
+// denied returns whether this item is on the denylist; denied items which
+// have been indexed before are removed from the index
+func (i *Indexable) denied(ctx context.Context) (bool, error) {
+	if !i.isDenied(i.Hash) {
+		return false, nil
+	}
+
+	i.log().Info("Skipping denied hash")
+
+	deleted, err := i.Indexer.DeleteItem(ctx, i.Hash)
+	if deleted {
+		i.log().Info("Removed denied hash from index")
+	}
+
+	return true, err
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"context"
+	"github.com/ipfs/go-ipfs-api"
+)
+
+// directoryStats returns the number of files and directories listed and
+// the total size of the files
+func directoryStats(list *shell.UnixLsObject) (files, directories int, size uint64) {
+	for _, link := range list.Links {
+		switch link.Type {
+		case "File":
+			files++
+			size += link.Size
+		case "Directory":
+			directories++
+		}
+	}
+
+	return
+}
+
+// cumulativeSize returns the size of the directory and everything in it,
+// recursively, as recorded in the DAG by the sizes of links; only the root
+// node is fetched. It includes the encoding of the DAG itself.
+func (i *Indexable) cumulativeSize(ctx context.Context) (uint64, error) {
+	var stat shell.ObjectStats
+	if err := i.Shell.Request("object/stat", i.hashURL()).Exec(ctx, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.CumulativeSize), nil
+}
+
+// addDirectorySize sets the counts of entries of a directory on properties,
+// as well as the size of the files in it and the cumulative size of its
+// contents, recursively. The latter is left out when IPFS can't tell.
+func (i *Indexable) addDirectorySize(ctx context.Context, properties metadata, list *shell.UnixLsObject) {
+	files, directories, size := directoryStats(list)
+
+	properties["item-count"] = len(list.Links)
+	properties["file-count"] = files
+	properties["directory-count"] = directories
+	properties["files-size"] = size
+
+	total, err := i.cumulativeSize(ctx)
+	if err != nil {
+		i.log().WithError(err).Debug("Error getting cumulative size")
+		return
+	}
+
+	properties["total-size"] = total
+}
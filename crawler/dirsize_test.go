@@ -0,0 +1,23 @@
+package crawler
+
+import (
+	"github.com/ipfs/go-ipfs-api"
+	"testing"
+)
+
+func TestDirectoryStats(t *testing.T) {
+	list := &shell.UnixLsObject{
+		Type: "Directory",
+		Links: []*shell.UnixLsLink{
+			{Name: "a.txt", Type: "File", Size: 10},
+			{Name: "b.txt", Type: "File", Size: 5},
+			{Name: "sub", Type: "Directory"},
+			{Name: "link", Type: "Symlink", Size: 3},
+		},
+	}
+
+	files, directories, size := directoryStats(list)
+	if files != 2 || directories != 1 || size != 15 {
+		t.Errorf("directoryStats() = %d, %d, %d, want 2, 1, 15", files, directories, size)
+	}
+}
@@ -0,0 +1,36 @@
+package crawler
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/enrich"
+)
+
+// enrich sets the properties an enrichment service returns for the file on
+// properties. Failure of the service does not prevent indexing.
+func (i *Indexable) enrich(ctx context.Context, e *enrich.Enricher, properties metadata) {
+	if !e.Supported(i.mimetype) {
+		return
+	}
+
+	fields, err := e.Enrich(ctx, &enrich.File{
+		Hash:     i.Hash,
+		Name:     i.Name,
+		Mimetype: i.mimetype,
+		Size:     i.Size,
+	})
+	if err != nil {
+		i.log().WithError(err).WithField("enricher", e.Name()).Warn("Error enriching file")
+		return
+	}
+
+	for k, v := range fields {
+		properties[k] = v
+	}
+}
+
+// addEnrichments runs all enrichment services on the file, in order
+func (i *Indexable) addEnrichments(ctx context.Context, properties metadata) {
+	for _, e := range i.Enrichers {
+		i.enrich(ctx, e, properties)
+	}
+}
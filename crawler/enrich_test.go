@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ipfs-search/ipfs-search/enrich"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddEnrichments(t *testing.T) {
+	nsfw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"nsfw_score": 0.75})
+	}))
+	defer nsfw.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	enrichers := []*enrich.Enricher{
+		enrich.New(&enrich.Config{Name: "failing", URL: failing.URL, Fields: []string{"score"}, Timeout: time.Second}),
+		enrich.New(&enrich.Config{Name: "nsfw", URL: nsfw.URL, MimeTypes: []string{"image/", "video/"}, Fields: []string{"nsfw_score"}, Timeout: time.Second}),
+	}
+
+	tests := []struct {
+		mimetype string
+		want     metadata
+	}{
+		{"image/jpeg", metadata{"nsfw_score": 0.75}},
+		{"text/plain", metadata{}},
+	}
+
+	for _, test := range tests {
+		i := &Indexable{
+			Crawler:  &Crawler{Config: &Config{}, Enrichers: enrichers},
+			Args:     &Args{Hash: "hash", Size: 10},
+			mimetype: test.mimetype,
+		}
+
+		m := make(metadata)
+		i.addEnrichments(context.Background(), m)
+
+		if !reflect.DeepEqual(m, test.want) {
+			t.Errorf("addEnrichments() for %s = %v, want %v", test.mimetype, m, test.want)
+		}
+	}
+}
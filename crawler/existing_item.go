@@ -3,14 +3,22 @@ package crawler
 import (
 	"context"
 	"github.com/ipfs-search/ipfs-search/indexer"
-	"log"
+	"github.com/ipfs-search/ipfs-search/quality"
 )
 
 type existingItem struct {
 	*Indexable
 	exists     bool
 	references indexer.References
+	aliases    []string
 	itemType   string
+	partial    bool
+	override   *indexer.Override
+	published  *indexer.Override // Override by a verified publisher
+
+	size           uint64
+	contentQuality *float64 // Quality of extracted metadata, when known
+	structureOnly  bool     // Indexed without file contents
 }
 
 // referenceFromIndexable generates a new reference for a given indexable
@@ -30,18 +38,75 @@ func (i *existingItem) updateReferences() {
 		return
 	}
 
-	log.Printf("Adding reference '%v' to %v", newRef, i)
+	i.log().Debugf("Adding reference '%v'", newRef)
 	i.references = append(i.references, *newRef)
 }
 
-// updateItem updates references and last seen date
+// updateAliases adds the original representation of the hash to aliases
+func (i *existingItem) updateAliases() {
+	if i.alias == "" {
+		return
+	}
+
+	for _, a := range i.aliases {
+		if a == i.alias {
+			return
+		}
+	}
+
+	i.log().Debugf("Adding alias '%s'", i.alias)
+	i.aliases = append(i.aliases, i.alias)
+}
+
+// addAliases sets aliases on properties, if any
+func (i *existingItem) addAliases(properties metadata) {
+	if len(i.aliases) > 0 {
+		properties["aliases"] = i.aliases
+	}
+}
+
+// addOverride sets the metadata overrides on properties of new items;
+// existing items already have them
+func (i *existingItem) addOverride(properties metadata) {
+	if i.override != nil {
+		properties["override"] = i.override
+	}
+	if i.published != nil {
+		properties["publisher-override"] = i.published
+	}
+}
+
+// addProvenance sets the provenance on properties of new items; existing
+// items keep how they were first discovered
+func (i *existingItem) addProvenance(properties metadata) {
+	if !i.exists {
+		properties["provenance"] = i.provenance()
+	}
+}
+
+// addSeen sets the last seen date on properties, as well as the first seen
+// date for new items
+func (i *existingItem) addSeen(properties metadata) {
+	now := nowISO()
+
+	if !i.exists {
+		properties["first-seen"] = now
+	}
+	properties["last-seen"] = now
+}
+
+// updateIndex adds references and aliases, updates the last seen date and
+// rescores the item for its current references
 func (i *existingItem) updateIndex(ctx context.Context) error {
 	properties := metadata{
 		"references": i.references,
-		"last-seen":  nowISO(),
 	}
+	i.addSeen(properties)
+	i.addAliases(properties)
+	i.addFilenames(properties)
+	i.addRescore(properties)
 
-	return i.Indexer.IndexItem(ctx, i.itemType, i.Hash, properties)
+	return i.updateItem(ctx, i.itemType, properties)
 }
 
 // update updates existing items (if they in fact do exist)
@@ -50,9 +115,12 @@ func (i *existingItem) update(ctx context.Context) error {
 		// Update references always; this also adds existing to them
 		// I know, this is bad design...
 		i.updateReferences()
+		i.updateAliases()
 
-		if i.exists {
-			log.Printf("Updating %v", i)
+		// Recrawled and enriched items are updated after having been
+		// fetched; being unavailable, they should not seem alive
+		if i.exists && !i.Recrawl && !i.enrich() {
+			i.log().Debug("Updating")
 			return i.updateIndex(ctx)
 		}
 	}
@@ -65,13 +133,13 @@ func (i *existingItem) update(ctx context.Context) error {
 func (i *existingItem) skipItem() bool {
 	// TODO; this is currently called in update() and shouldCrawl and
 	// yields duplicate output. Todo; make this return an error or nil.
-	if i.Size == i.Config.PartialSize && i.ParentHash == "" {
-		log.Printf("Skipping unreferenced partial content for item %v", i)
+	if i.partial {
+		i.log().Debug("Skipping unreferenced partial content")
 		return true
 	}
 
 	if i.itemType == "invalid" {
-		log.Printf("Skipping update of invalid %v", i)
+		i.log().Debug("Skipping update of invalid")
 		return true
 	}
 
@@ -84,26 +152,87 @@ func (i *Indexable) getExistingItem(ctx context.Context) (*existingItem, error)
 		panic("Indexable should not be nil")
 	}
 
-	references, itemType, err := i.Indexer.GetReferences(ctx, i.Hash)
+	indexed, err := i.Indexer.GetItem(ctx, i.Hash)
 	if err != nil {
 		return nil, err
 	}
 
 	item := &existingItem{
 		Indexable:  i,
-		exists:     itemType != "", // itemType == nil -> doesn't exist
-		references: references,
-		itemType:   itemType,
+		exists:     indexed.Type != "", // itemType == nil -> doesn't exist
+		references: indexed.References,
+		aliases:    indexed.Aliases,
+		itemType:   indexed.Type,
+
+		size:           indexed.Size,
+		contentQuality: indexed.ContentQuality,
+		structureOnly:  indexed.StructureOnly,
+	}
+
+	// Only new items which are not referenced from a directory are
+	// inspected, as chunks reach us separately through the sniffer
+	if i.ParentHash == "" && !item.exists {
+		item.partial, err = i.isPartial()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Overrides may be set before an item is indexed
+	if !item.exists {
+		item.override, err = i.Indexer.GetOverride(ctx, i.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		item.published, err = i.Indexer.GetPublisherOverride(ctx, i.Hash)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return item, nil
 }
 
+// qualityItem returns the properties of this item relevant for scoring
+func (i *existingItem) qualityItem(m metadata, size uint64) *quality.Item {
+	return &quality.Item{
+		Name:           i.Name,
+		ReferenceNames: i.references.Names(),
+		Size:           size,
+		Metadata:       m,
+	}
+}
+
+// addQuality sets the quality score for this item with given extracted
+// metadata and size on properties, as well as the score of the metadata
+// by itself, so the item can be rescored without it
+func (i *existingItem) addQuality(properties metadata, m metadata, size uint64) {
+	content := quality.ContentScore(m)
+
+	properties["content-quality"] = content
+	properties["quality"] = quality.Rescore(i.qualityItem(nil, size), content)
+}
+
+// addRescore updates the quality score on properties for the current
+// references; files indexed without a content score keep their score
+func (i *existingItem) addRescore(properties metadata) {
+	content := 1.0
+	switch {
+	case i.contentQuality != nil:
+		content = *i.contentQuality
+	case i.itemType != "directory":
+		return
+	}
+
+	properties["quality"] = quality.Rescore(i.qualityItem(nil, i.size), content)
+}
+
 // shouldCrawl returns whether or not this item should be crawled
 func (i *existingItem) shouldCrawl() bool {
 	if i == nil {
 		panic("Existingitem should not be nil")
 	}
 
-	return !(i.skipItem() || i.exists)
+	return !(i.skipItem() || (i.exists && !i.Recrawl && !i.enrich()))
 }
@@ -1,16 +1,46 @@
 package factory
 
 import (
+	"github.com/ipfs-search/ipfs-search/classifier"
+	"github.com/ipfs-search/ipfs-search/concurrency"
 	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/dedup"
+	"github.com/ipfs-search/ipfs-search/denylist"
+	"github.com/ipfs-search/ipfs-search/enrich"
+	"github.com/ipfs-search/ipfs-search/extractor/images"
+	"github.com/ipfs-search/ipfs-search/extractor/media"
+	"github.com/ipfs-search/ipfs-search/extractor/pdf"
+	"github.com/ipfs-search/ipfs-search/extractor/tika"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs-search/ipfs-search/thumbnail"
 	"time"
 )
 
 // Config defines configuration for a crawler factory
 type Config struct {
-	IpfsAPI          string
-	ElasticSearchURL string
-	AMQPURL          string
-	IpfsTimeout      time.Duration // Timeout for IPFS gateway HTTPS requests
+	IpfsAPIs        []string      // IPFS API endpoints requests are distributed over
+	IpfsHealthcheck time.Duration // Interval of IPFS node health checks, with several nodes
+	AMQPURL         string
+	QoS             queue.QoS          // Prefetch limits of consumers on AMQP brokers
+	Expiry          queue.Expiry       // When consumed tasks expire
+	RetryDelay      time.Duration      // Before tasks failing temporarily are tried again
+	IpfsTimeout     time.Duration      // Timeout for IPFS gateway HTTPS requests
+	IpfsConcurrency concurrency.Config // Limits IPFS API requests in flight
 
-	CrawlerConfig *crawler.Config
+	ElasticSearchConfig *indexer.Config
+
+	CrawlerConfig    *crawler.Config
+	TikaConfig       *tika.Config
+	ImagesConfig     *images.Config     // Optional, nil extracts images with Tika
+	MediaConfig      *media.Config      // Optional, nil extracts audio and video with Tika
+	PDFConfig        *pdf.Config        // Optional, nil disables the PDF fallback
+	ClassifierConfig *classifier.Config // Optional, nil disables classification
+	ThumbnailConfig  *thumbnail.Config  // Optional, nil generates no thumbnails
+	EnrichConfigs    []*enrich.Config   // Enrichment services files are sent to, in order
+	DenylistConfig   *denylist.Config
+	DedupConfig      *dedup.Config
+
+	MemoryBudget uint64 // Maximum bytes held by items in flight, 0 for unlimited
+	JournalPath  string // File tasks in flight are journaled to, empty disables the journal
 }
@@ -2,12 +2,28 @@ package factory
 
 import (
 	"context"
+	"github.com/ipfs-search/ipfs-search/budget"
+	"github.com/ipfs-search/ipfs-search/classifier"
 	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/dedup"
+	"github.com/ipfs-search/ipfs-search/denylist"
+	"github.com/ipfs-search/ipfs-search/enrich"
+	"github.com/ipfs-search/ipfs-search/extractor"
+	"github.com/ipfs-search/ipfs-search/extractor/images"
+	"github.com/ipfs-search/ipfs-search/extractor/media"
+	"github.com/ipfs-search/ipfs-search/extractor/pdf"
+	"github.com/ipfs-search/ipfs-search/extractor/tika"
 	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/ipfspool"
+	"github.com/ipfs-search/ipfs-search/journal"
 	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs-search/ipfs-search/thumbnail"
 	"github.com/ipfs-search/ipfs-search/worker"
 	"github.com/ipfs/go-ipfs-api"
+	log "github.com/sirupsen/logrus"
 	"github.com/streadway/amqp"
+	"sync"
+	"time"
 )
 
 // Factory creates hash and file crawl workers
@@ -16,8 +32,24 @@ type Factory struct {
 	pubConnection *queue.Connection
 	conConnection *queue.Connection
 	errChan       chan<- error
-	indexer       *indexer.Indexer
+	indexer       indexer.Index
+	extractor     extractor.Extractor
+	images        extractor.Extractor
+	media         extractor.Extractor
+	pdf           extractor.Extractor
+	classifier    *classifier.Classifier
+	thumbnails    *thumbnail.Generator
+	enrichers     []*enrich.Enricher
 	shell         *shell.Shell
+	pool          *ipfspool.Pool // With several IPFS nodes
+	healthcheck   time.Duration
+	denylist      *denylist.Denylist
+	budget        *budget.Budget
+	seen          dedup.Cache
+	journal       *journal.Journal // Optional
+
+	mu          sync.Mutex
+	utilization map[string]*worker.Utilization // By queue name
 }
 
 // New creates a new crawl worker factory
@@ -32,18 +64,68 @@ func New(config *Config, errc chan<- error) (*Factory, error) {
 		return nil, err
 	}
 
-	// Create and configure Ipfs shell
-	sh := shell.NewShell(config.IpfsAPI)
+	if err := conConnection.SetQoS(config.QoS); err != nil {
+		return nil, err
+	}
+	pubConnection.SetExpiry(config.Expiry)
+	conConnection.SetExpiry(config.Expiry)
+	conConnection.SetRetryDelay(config.RetryDelay)
+
+	// Tasks are deduplicated by the seen-set of crawling, if any
+	seen := dedup.New(config.DedupConfig)
+	pubConnection.SetSeen(seen)
+
+	// Create and configure Ipfs shell, distributing requests over nodes
+	sh, pool := ipfspool.NewShell(config.IpfsAPIs, config.IpfsConcurrency)
 	sh.SetTimeout(config.IpfsTimeout)
 
-	el, err := getElastic(config.ElasticSearchURL)
+	// Extract images in-process, if enabled
+	var imageExtractor extractor.Extractor
+	if config.ImagesConfig != nil {
+		imageExtractor = images.New(config.ImagesConfig, sh)
+	}
+
+	// Extract audio and video with ffprobe, if configured
+	var mediaExtractor extractor.Extractor
+	if config.MediaConfig != nil {
+		mediaExtractor = media.New(config.MediaConfig)
+	}
+
+	// Extract PDF text in-process while Tika is unavailable, if enabled
+	var pdfExtractor extractor.Extractor
+	if config.PDFConfig != nil {
+		pdfExtractor = pdf.New(config.PDFConfig, sh)
+	}
+
+	enrichers := make([]*enrich.Enricher, len(config.EnrichConfigs))
+	for n, c := range config.EnrichConfigs {
+		enrichers[n] = enrich.New(c)
+	}
+
+	// Create elasticsearch indexer
+	id, err := getIndexer(config.ElasticSearchConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load denylist before crawling anything
+	dl := denylist.New(config.DenylistConfig)
+	err = dl.Load(context.TODO())
 	if err != nil {
 		return nil, err
 	}
 
-	// Create elasticsearch indexer
-	id := &indexer.Indexer{
-		ElasticSearch: el,
+	// Journal tasks in flight, if configured
+	var j *journal.Journal
+	if config.JournalPath != "" {
+		j, err = journal.Open(config.JournalPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if pending := len(j.Pending()); pending > 0 {
+			log.WithField("journal", config.JournalPath).Warnf("%d tasks were in flight when the crawler stopped; requeue them with `ipfs-search journal --requeue`", pending)
+		}
 	}
 
 	return &Factory{
@@ -52,10 +134,55 @@ func New(config *Config, errc chan<- error) (*Factory, error) {
 		conConnection: conConnection,
 		errChan:       errc,
 		shell:         sh,
+		pool:          pool,
+		healthcheck:   config.IpfsHealthcheck,
 		indexer:       id,
+		extractor:     tika.New(config.TikaConfig),
+		images:        imageExtractor,
+		media:         mediaExtractor,
+		pdf:           pdfExtractor,
+		classifier:    classifier.New(config.ClassifierConfig),
+		thumbnails:    thumbnail.New(config.ThumbnailConfig, sh),
+		enrichers:     enrichers,
+		denylist:      dl,
+		budget:        budget.New(config.MemoryBudget),
+		seen:          seen,
+		journal:       j,
+		utilization:   make(map[string]*worker.Utilization),
 	}, nil
 }
 
+// Utilization returns the utilization of the workers consuming a queue,
+// shared by all workers created for it
+func (f *Factory) Utilization(queueName string) *worker.Utilization {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	u, ok := f.utilization[queueName]
+	if !ok {
+		u = new(worker.Utilization)
+		f.utilization[queueName] = u
+	}
+
+	return u
+}
+
+// WatchDenylist periodically reloads the denylist until the context is
+// cancelled
+func (f *Factory) WatchDenylist(ctx context.Context) {
+	f.denylist.Watch(ctx)
+}
+
+// WatchIPFS periodically checks the health of IPFS nodes, when there are
+// several, until the context is cancelled
+func (f *Factory) WatchIPFS(ctx context.Context) {
+	if f.pool == nil || f.healthcheck <= 0 {
+		return
+	}
+
+	f.pool.Watch(ctx, f.healthcheck)
+}
+
 func (f *Factory) newCrawler() (*crawler.Crawler, error) {
 	fileQueue, err := f.pubConnection.NewChannelQueue("files")
 	if err != nil {
@@ -67,12 +194,31 @@ func (f *Factory) newCrawler() (*crawler.Crawler, error) {
 		return nil, err
 	}
 
+	routeQueues := make(map[string]*queue.Queue, len(f.crawlerConfig.Routes))
+	for _, r := range f.crawlerConfig.Routes {
+		routeQueues[r.Name], err = f.pubConnection.NewChannelQueue(r.Queue())
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &crawler.Crawler{
-		Config:    f.crawlerConfig,
-		Shell:     f.shell,
-		Indexer:   f.indexer,
-		FileQueue: fileQueue,
-		HashQueue: hashQueue,
+		Config:      f.crawlerConfig,
+		Shell:       f.shell,
+		Indexer:     f.indexer,
+		Extractor:   f.extractor,
+		Images:      f.images,
+		Media:       f.media,
+		PDF:         f.pdf,
+		Classifier:  f.classifier,
+		Thumbnails:  f.thumbnails,
+		Enrichers:   f.enrichers,
+		FileQueue:   fileQueue,
+		HashQueue:   hashQueue,
+		RouteQueues: routeQueues,
+		Denylist:    f.denylist,
+		Budget:      f.budget,
+		Seen:        f.seen,
 	}, nil
 }
 
@@ -90,16 +236,46 @@ func (f *Factory) newWorker(queueName string, crawl CrawlFunc) (worker.Worker, e
 		return nil, err
 	}
 
-	// A MessageWorkerFactory generates a worker for every message in a queue
+	utilization := f.Utilization(queueName)
+
+	// A MessageWorkerFactory generates a worker for every message in a
+	// queue, busy while crawling it
 	messageWorkerFactory := func(msg *amqp.Delivery) worker.Worker {
-		return &Worker{
+		return utilization.Track(&Worker{
 			Crawler:   c,
 			Delivery:  msg,
 			CrawlFunc: crawl,
-		}
+			Journal:   f.journal,
+			Queue:     queueName,
+		})
 	}
 
-	return queue.NewWorker(f.errChan, conQueue, messageWorkerFactory), nil
+	// Record panics in the index, so items are not crawled again
+	onPanic := func(msg *amqp.Delivery, err error) {
+		c.RecordPanic(context.Background(), msg.Body, err)
+	}
+
+	return queue.NewWorker(f.errChan, conQueue, messageWorkerFactory, onPanic), nil
+}
+
+// queueLoad returns a LoadFunc reporting the depth of a queue
+func (f *Factory) queueLoad(queueName string) (worker.LoadFunc, error) {
+	q, err := f.conConnection.NewChannelQueue(queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Depth, nil
+}
+
+// HashLoad returns a LoadFunc for the hash queue
+func (f *Factory) HashLoad() (worker.LoadFunc, error) {
+	return f.queueLoad("hashes")
+}
+
+// FileLoad returns a LoadFunc for the file queue
+func (f *Factory) FileLoad() (worker.LoadFunc, error) {
+	return f.queueLoad("files")
 }
 
 // NewHashWorker returns a new hash crawl worker
@@ -115,3 +291,18 @@ func (f *Factory) NewFileWorker() (worker.Worker, error) {
 		return i.CrawlFile
 	})
 }
+
+// RouteLoad returns a LoadFunc for the queue of a route
+func (f *Factory) RouteLoad(r crawler.Route) (worker.LoadFunc, error) {
+	return f.queueLoad(r.Queue())
+}
+
+// RouteWorkerFactory returns a worker.Factory for workers crawling files
+// received through a route
+func (f *Factory) RouteWorkerFactory(r crawler.Route) worker.Factory {
+	return func() (worker.Worker, error) {
+		return f.newWorker(r.Queue(), func(i *crawler.Indexable) func(context.Context) error {
+			return i.CrawlRoutedFile
+		})
+	}
+}
@@ -1,25 +1,25 @@
 package factory
 
 import (
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
-	"gopkg.in/olivere/elastic.v5"
-	"log"
 )
 
-func getElastic(url string) (*elastic.Client, error) {
-	el, err := elastic.NewClient(elastic.SetSniff(false), elastic.SetURL(url))
+// getIndexer returns an index for given configuration, creating the
+// indices if they don't exist
+func getIndexer(config *indexer.Config) (indexer.Index, error) {
+	id, err := indexer.NewIndex(config)
 	if err != nil {
 		return nil, err
 	}
-	exists, err := el.IndexExists("ipfs").Do(context.TODO())
+
+	_, err = id.CreateIndex(context.TODO())
 	if err != nil {
 		return nil, err
 	}
-	if !exists {
-		// Index does not exist yet, create
-		el.CreateIndex("ipfs")
-	}
-	log.Printf("Connected to ElasticSearch")
 
-	return el, nil
+	log.Info("Connected to ElasticSearch")
+
+	return id, nil
 }
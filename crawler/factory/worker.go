@@ -3,6 +3,9 @@ package factory
 import (
 	"context"
 	"github.com/ipfs-search/ipfs-search/crawler"
+	"github.com/ipfs-search/ipfs-search/journal"
+	"github.com/ipfs-search/ipfs-search/queue"
+	log "github.com/sirupsen/logrus"
 	"github.com/streadway/amqp"
 )
 
@@ -16,17 +19,49 @@ type Worker struct {
 
 	Delivery  *amqp.Delivery
 	CrawlFunc CrawlFunc
+	Journal   *journal.Journal // Optional, records the task while in flight
+	Queue     string           // Queue the message was received from
+}
+
+// journal records the task as started, returning a function recording it
+// as done. Journal errors are logged, rather than failing the task.
+func (c *Worker) journal() func() {
+	id, err := c.Journal.Start(c.Queue, c.Delivery.Priority, c.Delivery.Body)
+	if err != nil {
+		log.WithError(err).Warn("Error journaling task")
+		return func() {}
+	}
+
+	return func() {
+		if err := c.Journal.Done(id); err != nil {
+			log.WithError(err).Warn("Error journaling completed task")
+		}
+	}
 }
 
 // Work takes a message with JSON body, converts it to a crawlable and
-// calls CrawlFunc on it.
+// calls CrawlFunc on it. While the memory budget is exhausted, crawling
+// waits; as only a few messages are prefetched, consumption pauses.
 func (c *Worker) Work(ctx context.Context) error {
+	if err := c.Budget.Wait(ctx); err != nil {
+		return err
+	}
+
+	defer c.journal()()
+
 	// Create an Indexable from the message's body
 	i, err := c.IndexableFromJSON(c.Delivery.Body)
 	if err != nil {
 		return err
 	}
 
-	// Call crawler function with context
+	if i.Expired() {
+		log.WithField("hash", i.Hash).Info("Dropping task past its deadline")
+		return nil
+	}
+
+	// Call crawler function with context, continuing the trace of the
+	// publisher
+	ctx = queue.ContextFromDelivery(ctx, c.Delivery)
 	return c.CrawlFunc(i)(ctx)
 }
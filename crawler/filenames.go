@@ -0,0 +1,49 @@
+package crawler
+
+import (
+	"path"
+	"strings"
+)
+
+// maxExtensionLength is the length above which the part of a name after
+// the last dot is not considered an extension
+const maxExtensionLength = 10
+
+// extension returns the lowercased extension of a name, without dot, or
+// an empty string if it has none
+func extension(name string) string {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	if ext == "" || len(ext) > maxExtensionLength || ext == strings.ToLower(strings.TrimPrefix(name, ".")) {
+		return ""
+	}
+
+	return ext
+}
+
+// addFilenames sets the names the item is referenced by and their
+// extensions on properties, so items can be searched by filename
+func (i *existingItem) addFilenames(properties metadata) {
+	var names, extensions []string
+	seenNames := make(map[string]bool)
+	seenExtensions := make(map[string]bool)
+
+	for _, name := range i.references.Names() {
+		if name == "" || seenNames[name] {
+			continue
+		}
+		seenNames[name] = true
+		names = append(names, name)
+
+		if ext := extension(name); ext != "" && !seenExtensions[ext] {
+			seenExtensions[ext] = true
+			extensions = append(extensions, ext)
+		}
+	}
+
+	if len(names) > 0 {
+		properties["filename"] = names
+	}
+	if len(extensions) > 0 {
+		properties["extension"] = extensions
+	}
+}
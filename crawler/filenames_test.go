@@ -0,0 +1,55 @@
+package crawler
+
+import (
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"reflect"
+	"testing"
+)
+
+func TestExtension(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"paper.pdf", "pdf"},
+		{"Photo.JPG", "jpg"},
+		{"archive.tar.gz", "gz"},
+		{"README", ""},
+		{".bashrc", ""},
+		{"name.", ""},
+		{"backup.0123456789abcdef", ""},
+	}
+
+	for _, test := range tests {
+		if got := extension(test.name); got != test.want {
+			t.Errorf("extension(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAddFilenames(t *testing.T) {
+	i := &existingItem{
+		references: indexer.References{
+			{ParentHash: "a", Name: "paper.pdf"},
+			{ParentHash: "b", Name: "Paper.PDF"},
+			{ParentHash: "c", Name: "paper.pdf"},
+			{ParentHash: "d", Name: ""},
+		},
+	}
+
+	properties := make(metadata)
+	i.addFilenames(properties)
+
+	if want := []string{"paper.pdf", "Paper.PDF"}; !reflect.DeepEqual(properties["filename"], want) {
+		t.Errorf("filename = %v, want %v", properties["filename"], want)
+	}
+	if want := []string{"pdf"}; !reflect.DeepEqual(properties["extension"], want) {
+		t.Errorf("extension = %v, want %v", properties["extension"], want)
+	}
+
+	empty := make(metadata)
+	(&existingItem{}).addFilenames(empty)
+	if len(empty) != 0 {
+		t.Errorf("addFilenames() without references = %v", empty)
+	}
+}
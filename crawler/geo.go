@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dmsCoordinate matches EXIF coordinates in degrees, minutes and seconds,
+// e.g. 52° 22' 11.52"
+var dmsCoordinate = regexp.MustCompile(`^(\d+(?:\.\d+)?)°\s*(?:(\d+(?:\.\d+)?)'\s*(?:(\d+(?:\.\d+)?)")?)?$`)
+
+// parseCoordinate parses a decimal coordinate or one in degrees, minutes
+// and seconds, negated for the southern or western reference
+func parseCoordinate(value, ref string) (float64, error) {
+	value = strings.TrimSpace(value)
+
+	c, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		match := dmsCoordinate.FindStringSubmatch(value)
+		if match == nil {
+			return 0, fmt.Errorf("invalid coordinate %q", value)
+		}
+
+		c, _ = strconv.ParseFloat(match[1], 64)
+		for n, divisor := range []float64{60, 3600} {
+			if part := match[n+2]; part != "" {
+				f, _ := strconv.ParseFloat(part, 64)
+				c += f / divisor
+			}
+		}
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(ref)) {
+	case "S", "W":
+		c = -c
+	}
+
+	return c, nil
+}
+
+// location returns the point at which a file was created according to its
+// extracted metadata, from Tika's geo properties or else raw EXIF GPS tags
+func location(m metadata) (map[string]float64, bool) {
+	fields, ok := m["metadata"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	keys := [][4]string{
+		{"geo:lat", "", "geo:long", ""},
+		{"GPS Latitude", "GPS Latitude Ref", "GPS Longitude", "GPS Longitude Ref"},
+	}
+
+	for _, k := range keys {
+		lat, latRef, lon, lonRef := firstValue(fields, k[0]), firstValue(fields, k[1]), firstValue(fields, k[2]), firstValue(fields, k[3])
+		if lat == "" || lon == "" {
+			continue
+		}
+
+		latitude, err := parseCoordinate(lat, latRef)
+		if err != nil || latitude < -90 || latitude > 90 {
+			return nil, false
+		}
+
+		longitude, err := parseCoordinate(lon, lonRef)
+		if err != nil || longitude < -180 || longitude > 180 {
+			return nil, false
+		}
+
+		// Cameras without fix often write 0, 0
+		if latitude == 0 && longitude == 0 {
+			return nil, false
+		}
+
+		return map[string]float64{"lat": latitude, "lon": longitude}, true
+	}
+
+	return nil, false
+}
+
+// addLocation sets the location of geotagged files on properties
+func addLocation(m metadata) {
+	if l, ok := location(m); ok {
+		m["location"] = l
+	}
+}
@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		want   map[string]float64
+	}{
+		{"none", map[string]interface{}{}, nil},
+		{
+			"tika",
+			map[string]interface{}{"geo:lat": []interface{}{"52.5"}, "geo:long": []interface{}{"-4.25"}},
+			map[string]float64{"lat": 52.5, "lon": -4.25},
+		},
+		{
+			"exif",
+			map[string]interface{}{
+				"GPS Latitude":      []interface{}{`52° 30' 36"`},
+				"GPS Latitude Ref":  []interface{}{"S"},
+				"GPS Longitude":     []interface{}{`4° 15'`},
+				"GPS Longitude Ref": []interface{}{"E"},
+			},
+			map[string]float64{"lat": -52.51, "lon": 4.25},
+		},
+		{"missing longitude", map[string]interface{}{"geo:lat": "52.5"}, nil},
+		{"out of range", map[string]interface{}{"geo:lat": "95", "geo:long": "4"}, nil},
+		{"no fix", map[string]interface{}{"geo:lat": "0", "geo:long": "0.0"}, nil},
+		{"garbage", map[string]interface{}{"geo:lat": "north", "geo:long": "4"}, nil},
+	}
+
+	for _, test := range tests {
+		got, ok := location(metadata{"metadata": test.fields})
+		if ok != (test.want != nil) {
+			t.Errorf("%s: location() = %v, %v", test.name, got, ok)
+			continue
+		}
+
+		for k, v := range test.want {
+			if math.Abs(got[k]-v) > 1e-9 {
+				t.Errorf("%s: location() = %v, want %v", test.name, got, test.want)
+			}
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: location() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/cids"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// NormalizeHash returns the canonical representation of a CID, such that
+// the same content yields the same document regardless of CID version or
+// multibase encoding. Content expressible as CIDv0 (dag-pb, sha2-256) is
+// represented as such, matching existing documents. Anything else is
+// represented as base32 CIDv1.
+func NormalizeHash(hash string) (string, error) {
+	c, err := cids.Decode(hash)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := c.Prefix()
+	if prefix.Codec == cid.DagProtobuf && prefix.MhType == mh.SHA2_256 && prefix.MhLength == 32 {
+		return cid.NewCidV0(c.Hash()).String(), nil
+	}
+
+	return cid.NewCidV1(prefix.Codec, c.Hash()).StringOfBase(multibase.Base32)
+}
+
+// ChecksumHashes returns the normalized hashes of content with a hex
+// SHA-256 checksum: stored as a single raw block, or as the root of a
+// dag-pb file or directory, i.e. the checksum of that block
+func ChecksumHashes(checksum string) ([]string, error) {
+	digest, err := hex.DecodeString(checksum)
+	if err != nil || len(digest) != 32 {
+		return nil, fmt.Errorf("invalid SHA-256 checksum '%s'", checksum)
+	}
+
+	multihash, err := mh.Encode(digest, mh.SHA2_256)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, 2)
+	for _, codec := range []uint64{cid.DagProtobuf, cid.Raw} {
+		hash, err := NormalizeHash(cid.NewCidV1(codec, multihash).String())
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, nil
+}
+
+// partitionBytes is the length of the digest prefix partitioning tasks
+const partitionBytes = 4
+
+// partitionKey returns the partition key of tasks for a hash: a prefix of
+// its digest in hex, so tasks for all forms of a CID share a partition.
+// Hashes which can't be decoded are their own key.
+func partitionKey(hash string) string {
+	c, err := cids.Decode(hash)
+	if err != nil {
+		return hash
+	}
+
+	decoded, err := mh.Decode(c.Hash())
+	if err != nil || len(decoded.Digest) < partitionBytes {
+		return hash
+	}
+
+	return hex.EncodeToString(decoded.Digest[:partitionBytes])
+}
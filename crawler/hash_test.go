@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeHash(t *testing.T) {
+	const v0 = "QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX"
+	const raw = "bafkreibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq"
+
+	tests := []struct {
+		hash       string
+		normalized string
+		valid      bool
+	}{
+		// CIDv0 is kept as is
+		{v0, v0, true},
+		// dag-pb sha2-256 CIDv1 becomes CIDv0
+		{"bafybeibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq", v0, true},
+		// Other content is base32 CIDv1, regardless of multibase
+		{raw, raw, true},
+		{"zb2rhZQtvTZNtTy2q2E5fsG7tm6QGxudcE51HosNR1acaYDYF", raw, true},
+		// Hash functions unknown to the multihash library, e.g. blake3
+		{"bafkr4ihkr4ld3m4gqkjf4reryxsy2s5tkbxprqkow6fin2iiyvreuzzab4", "bafkr4ihkr4ld3m4gqkjf4reryxsy2s5tkbxprqkow6fin2iiyvreuzzab4", true},
+		{"", "", false},
+		{"notahash", "", false},
+		{v0 + "x", "", false},
+	}
+
+	for _, test := range tests {
+		normalized, err := NormalizeHash(test.hash)
+		if (err == nil) != test.valid {
+			t.Errorf("NormalizeHash(%q) error = %v, valid %v", test.hash, err, test.valid)
+			continue
+		}
+
+		if normalized != test.normalized {
+			t.Errorf("NormalizeHash(%q) = %q, want %q", test.hash, normalized, test.normalized)
+		}
+	}
+}
+
+func TestChecksumHashes(t *testing.T) {
+	hashes, err := ChecksumHashes("2925542245b849106798db4474fccf01b36680ec88c35399928fc8a1bd7053cc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX",
+		"bafkreibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq",
+	}
+	if !reflect.DeepEqual(hashes, want) {
+		t.Errorf("ChecksumHashes() = %v, want %v", hashes, want)
+	}
+
+	for _, invalid := range []string{"", "xyz", "2925542246e1"} {
+		if _, err := ChecksumHashes(invalid); err == nil {
+			t.Errorf("ChecksumHashes(%q) accepted", invalid)
+		}
+	}
+}
+
+func TestPartitionKey(t *testing.T) {
+	const v0 = "QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX"
+
+	key := partitionKey(v0)
+	if len(key) != 2*partitionBytes {
+		t.Fatalf("partitionKey(%q) = %q, want %d hex digits", v0, key, 2*partitionBytes)
+	}
+
+	// All forms of the same digest share a partition
+	for _, hash := range []string{
+		"bafybeibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq",
+		"bafkreibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq",
+		"zb2rhZQtvTZNtTy2q2E5fsG7tm6QGxudcE51HosNR1acaYDYF",
+	} {
+		if got := partitionKey(hash); got != key {
+			t.Errorf("partitionKey(%q) = %q, want %q", hash, got, key)
+		}
+	}
+
+	if got := partitionKey("notahash"); got != "notahash" {
+		t.Errorf("partitionKey(\"notahash\") = %q, want the hash itself", got)
+	}
+}
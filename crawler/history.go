@@ -0,0 +1,38 @@
+package crawler
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/crawler/crawlerrors"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"time"
+)
+
+// Outcomes of crawl attempts
+const (
+	outcomeIndexed = "indexed"
+	outcomeFailed  = "failed"
+)
+
+// recordAttempt adds a crawl attempt, which started at start and returned
+// err, to the history of this item; failure to do so is only logged
+func (i *Indexable) recordAttempt(ctx context.Context, start time.Time, err error) {
+	if i.Config.HistorySize <= 0 || ctx.Err() != nil {
+		return
+	}
+
+	attempt := &indexer.Attempt{
+		Time:     start.UTC(),
+		Outcome:  outcomeIndexed,
+		Duration: int64(time.Since(start) / time.Millisecond),
+	}
+
+	if err != nil {
+		attempt.Outcome = outcomeFailed
+		attempt.Category = string(crawlerrors.CategoryOf(err))
+		attempt.Error = err.Error()
+	}
+
+	if rerr := i.Indexer.RecordAttempt(ctx, i.Hash, attempt, i.Config.HistorySize); rerr != nil {
+		i.log().WithError(rerr).Warn("Error recording crawl attempt")
+	}
+}
@@ -0,0 +1,28 @@
+package crawler
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+)
+
+// updateItem updates the item in the index with properties. With exactly
+// once indexing, the idempotency key of the task is included, so the
+// update is applied once however often the task is performed, and failed
+// updates are retried rather than dropping the task.
+func (i *Indexable) updateItem(ctx context.Context, doctype string, properties metadata) error {
+	if !i.Config.ExactlyOnce {
+		return i.Indexer.UpdateItem(ctx, doctype, i.Hash, properties)
+	}
+
+	if i.task != nil && i.task.Key != "" {
+		properties[indexer.TaskKey] = i.task.Key
+	}
+
+	err := i.Indexer.UpdateItem(ctx, doctype, i.Hash, properties)
+	if err != nil && ctx.Err() == nil {
+		return &queue.RetryError{Err: err}
+	}
+
+	return err
+}
@@ -0,0 +1,66 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"testing"
+)
+
+// updateIndex records updates, failing with err
+type updateIndex struct {
+	indexer.Index
+	properties map[string]interface{}
+	err        error
+}
+
+// UpdateItem records properties
+func (u *updateIndex) UpdateItem(ctx context.Context, doctype string, hash string, properties map[string]interface{}) error {
+	u.properties = properties
+	return u.err
+}
+
+func TestUpdateItem(t *testing.T) {
+	tests := []struct {
+		name        string
+		exactlyOnce bool
+		task        *queue.Task
+		err         error
+		key         interface{}
+		retry       bool
+	}{
+		{"default", false, &queue.Task{Key: "k"}, nil, nil, false},
+		{"default failing", false, &queue.Task{Key: "k"}, fmt.Errorf("conflict"), nil, false},
+		{"exactly once", true, &queue.Task{Key: "k"}, nil, "k", false},
+		{"exactly once failing", true, &queue.Task{Key: "k"}, fmt.Errorf("conflict"), "k", true},
+		{"exactly once without key", true, &queue.Task{}, nil, nil, false},
+		{"exactly once without task", true, nil, nil, nil, false},
+	}
+
+	for _, test := range tests {
+		index := &updateIndex{err: test.err}
+		i := &Indexable{
+			Crawler: &Crawler{
+				Config:  &Config{ExactlyOnce: test.exactlyOnce},
+				Indexer: index,
+			},
+			Args: &Args{Hash: "hash"},
+			task: test.task,
+		}
+
+		err := i.updateItem(context.Background(), "file", metadata{"size": 1})
+
+		if (err != nil) != (test.err != nil) {
+			t.Errorf("%s: updateItem() error %v, want %v", test.name, err, test.err)
+		}
+		var retryErr *queue.RetryError
+		if errors.As(err, &retryErr) != test.retry {
+			t.Errorf("%s: updateItem() error %v, retry %v", test.name, err, test.retry)
+		}
+		if index.properties[indexer.TaskKey] != test.key {
+			t.Errorf("%s: updated with key %v, want %v", test.name, index.properties[indexer.TaskKey], test.key)
+		}
+	}
+}
@@ -3,14 +3,11 @@ package crawler
 import (
 	"context"
 	"fmt"
-	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/crawler/crawlerrors"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs-search/ipfs-search/tracing"
 	"github.com/ipfs/go-ipfs-api"
-	"log"
-	"math/rand"
-	"net"
-	"net/url"
-	"strings"
-	"syscall"
+	log "github.com/sirupsen/logrus"
 	"time"
 )
 
@@ -18,6 +15,16 @@ import (
 type Indexable struct {
 	*Crawler
 	*Args
+
+	alias string      // Original representation of Hash, if different
+	task  *queue.Task // Task the item was received in, if any
+
+	routed   bool   // Received through a route
+	mimetype string // Sniffed content type, once detected
+	head     []byte // First bytes of the file, once its content type is detected
+
+	inline        []byte // Content of files held by their root block, once fetched
+	inlineFetched bool   // Whether the root block has been fetched for inline content
 }
 
 // String returns '<hash>' (<name>)
@@ -28,55 +35,41 @@ func (i *Indexable) String() string {
 	return fmt.Sprintf("'%s' (Unnamed)", i.Hash)
 }
 
-// handleShellError handles IPFS shell errors; returns try again bool and original error
+// log returns a logger with fields identifying this Indexable
+func (i *Indexable) log() *log.Entry {
+	fields := log.Fields{
+		"hash": i.Hash,
+	}
+	if i.Name != "" {
+		fields["name"] = i.Name
+	}
+	if i.task != nil {
+		fields["task"] = i.task.ID
+	}
+
+	return log.WithFields(fields)
+}
+
+// handleShellError handles IPFS shell errors; returns try again bool and categorized error
 func (i *Indexable) handleShellError(ctx context.Context, err error) (bool, error) {
-	if _, ok := err.(*shell.Error); ok && (strings.Contains(err.Error(), "proto") ||
-		strings.Contains(err.Error(), "unrecognized type") ||
-		strings.Contains(err.Error(), "not a valid merkledag node")) {
+	tryAgain, err := i.handleError(err)
 
+	if crawlerrors.HasCategory(err, crawlerrors.Invalid) {
 		// Attempt to index invalid to prevent re-indexing
 		i.indexInvalid(ctx, err)
-
-		// Don't try again, return error
-		return false, err
 	}
 
-	// Different error, attempt handling as URL error
-	return i.handleURLError(err)
+	return tryAgain, err
 }
 
-// handleURLError handles HTTP errors graceously, returns try again bool and original error
-func (i *Indexable) handleURLError(err error) (bool, error) {
-	if uerr, ok := err.(*url.Error); ok {
-		if uerr.Timeout() {
-			// Fail on timeouts
-			return false, err
-		}
+// handleError categorizes errors, returns try again bool and categorized
+// error; temporary errors are retried
+func (i *Indexable) handleError(err error) (bool, error) {
+	err = crawlerrors.Classify(err)
 
-		if uerr.Temporary() {
-			// Retry on other temp errors
-			log.Printf("Temporary URL error: %v", uerr)
-			return true, nil
-		}
-
-		// Somehow, the errors below are not temp errors !?
-		switch t := uerr.Err.(type) {
-		case *net.OpError:
-			if t.Op == "dial" {
-				log.Printf("Unknown host %v", t)
-				return true, nil
-
-			} else if t.Op == "read" {
-				log.Printf("Connection refused %v", t)
-				return true, nil
-			}
-
-		case syscall.Errno:
-			if t == syscall.ECONNREFUSED {
-				log.Printf("Connection refused %v", t)
-				return true, nil
-			}
-		}
+	if crawlerrors.HasCategory(err, crawlerrors.Temporary) {
+		i.log().WithError(err).Warn("Temporary error")
+		return true, err
 	}
 
 	return false, err
@@ -87,23 +80,38 @@ func (i *Indexable) hashURL() string {
 	return fmt.Sprintf("/ipfs/%s", i.Hash)
 }
 
-// getFileList return list of files and/or type of item (directory/file)
+// getFileList return list of files and/or type of item (directory/file).
+// Temporary errors of items received in tasks are returned as RetryError,
+// so the task is tried again later rather than holding on to the worker;
+// other items are tried again after waiting.
 func (i *Indexable) getFileList(ctx context.Context) (list *shell.UnixLsObject, err error) {
+	ctx, span := i.startSpan(ctx, "FileList")
+	defer func() { tracing.End(span, err) }()
+
+	if data, ok := i.identityData(); ok {
+		return i.inlineList(data), nil
+	}
+
 	url := i.hashURL()
 
-	tryAgain := true
-	for tryAgain {
+	for {
 		list, err = i.Shell.FileList(url)
 
+		var tryAgain bool
 		tryAgain, err = i.handleShellError(ctx, err)
+		if !tryAgain {
+			return
+		}
 
-		if tryAgain {
-			log.Printf("Retrying in %s", i.Config.RetryWait)
-			time.Sleep(i.Config.RetryWait)
+		if i.task != nil {
+			return nil, &queue.RetryError{Err: err}
 		}
-	}
 
-	return
+		i.log().Debugf("Retrying in %s", i.Config.RetryWait)
+		if err = sleep(ctx, i.Config.RetryWait); err != nil {
+			return nil, err
+		}
+	}
 }
 
 // indexInvalid indexes invalid files to prevent indexing again
@@ -113,37 +121,55 @@ func (i *Indexable) indexInvalid(ctx context.Context, err error) {
 		"error": err.Error(),
 	}
 
+	if category := crawlerrors.CategoryOf(err); category != "" {
+		m["error-category"] = category
+	}
+
+	now := nowISO()
+	m["first-seen"] = now
+	m["last-seen"] = now
+	m["provenance"] = i.provenance()
+
 	i.Indexer.IndexItem(ctx, "invalid", i.Hash, m)
 }
 
 // queueList queues any items in a given list/directory
 func (i *Indexable) queueList(ctx context.Context, list *shell.UnixLsObject) (err error) {
 	for _, link := range list.Links {
+		if i.isDenied(link.Hash) {
+			i.log().WithField("link", link.Hash).Debug("Skipping denied link")
+			continue
+		}
+
 		dirArgs := &Args{
 			Hash:       link.Hash,
 			Name:       link.Name,
 			Size:       link.Size,
 			ParentHash: i.Hash,
-		}
+			Depth:      i.Depth + 1,
+			Provenance: i.childProvenance(SourceDirectory),
 
-		// Generate random lower priority for items in this directory
-		// Rationale; directories might have different availability but
-		// within a directory, items are likely to have similar availability.
-		// We want consumers to get a varied mixture of availability, for
-		// consistent overall indexing load.
-
-		priority := uint8(1 + rand.Intn(7))
+			StructureOnly: i.StructureOnly,
+		}
 
+		// Items deeper down get lower priority, keeping the index fresh
+		// with a large backlog of directory contents
 		switch link.Type {
 		case "File":
-			// Add file to crawl queue, with lower priority
-			err = i.FileQueue.Publish(dirArgs, priority)
+			// Add file to crawl queue, valuable files first; only this
+			// directory is known to reference it
+			err = i.publish(ctx, i.FileQueue, dirArgs, dirArgs.FilePriority(1))
 		case "Directory":
 			// Add directory to crawl queue, with lower priority
-			err = i.HashQueue.Publish(dirArgs, priority)
+			err = i.publish(ctx, i.HashQueue, dirArgs, dirArgs.Priority())
 		default:
-			log.Printf("Type '%s' skipped for %s", link.Type, i)
-			i.indexInvalid(ctx, fmt.Errorf("Unknown type: %s", link.Type))
+			i.log().Infof("Type '%s' skipped for link %s", link.Type, link.Hash)
+			i.indexInvalid(ctx, crawlerrors.New(crawlerrors.Invalid, fmt.Errorf("Unknown type: %s", link.Type)))
+		}
+
+		if err != nil {
+			// Fail the directory, so it is retried as a whole
+			return err
 		}
 	}
 
@@ -151,21 +177,31 @@ func (i *Indexable) queueList(ctx context.Context, list *shell.UnixLsObject) (er
 }
 
 // processList processes and indexes a file listing
-func (i *Indexable) processList(ctx context.Context, list *shell.UnixLsObject, references []indexer.Reference) (err error) {
-	now := nowISO()
-
+func (i *Indexable) processList(ctx context.Context, list *shell.UnixLsObject, existing *existingItem) (err error) {
 	switch list.Type {
 	case "File":
-		// Add to file crawl queue with high priority
+		// Add to file crawl queue with the priority for its depth and value
 		fileArgs := &Args{
 			Hash:       i.Hash,
 			Name:       i.Name,
 			Size:       list.Size,
 			ParentHash: i.ParentHash,
+			Recrawl:    i.Recrawl,
+			Depth:      i.Depth,
+			Provenance: i.Provenance,
+
+			StructureOnly: i.StructureOnly,
 		}
 
-		err = i.FileQueue.Publish(fileArgs, 9)
+		err = i.publish(ctx, i.FileQueue, fileArgs, fileArgs.FilePriority(len(existing.references)))
 	case "Directory":
+		var release func()
+		release, err = i.reserve(ctx, uint64(len(list.Links))*linkSize)
+		if err != nil {
+			return err
+		}
+		defer release()
+
 		// Queue indexing of linked items
 		err = i.queueList(ctx, list)
 		if err != nil {
@@ -176,37 +212,67 @@ func (i *Indexable) processList(ctx context.Context, list *shell.UnixLsObject, r
 		m := metadata{
 			"links":      list.Links,
 			"size":       list.Size,
-			"references": references,
-			"first-seen": now,
-			"last-seen":  now,
+			"references": existing.references,
 		}
-
-		err = i.Indexer.IndexItem(ctx, "directory", i.Hash, m)
+		existing.addQuality(m, nil, list.Size)
+		existing.addTags(m, nil, list.Size)
+		existing.addSeen(m)
+		existing.addAliases(m)
+		existing.addFilenames(m)
+		existing.addOverride(m)
+		existing.addProvenance(m)
+		existing.addStructureOnly(m)
+		i.addDirectorySize(ctx, m, list)
+
+		err = i.updateItem(ctx, "directory", m)
 	default:
-		log.Printf("Type '%s' skipped for %s", list.Type, i)
+		i.log().Infof("Type '%s' skipped", list.Type)
 	}
 
 	return
 }
 
-// processList processes and indexes a single file
-func (i *Indexable) processFile(ctx context.Context, references []indexer.Reference) error {
-	now := nowISO()
-
+// processList processes and indexes a single file; in structure-only
+// mode, without fetching its contents
+func (i *Indexable) processFile(ctx context.Context, existing *existingItem) error {
 	m := make(metadata)
+	extracted := m
 
-	err := i.getMetadata(&m)
-	if err != nil {
-		return err
+	if i.skipContents() {
+		extracted = nil
+	} else {
+		release, err := i.reserve(ctx, i.extractSize())
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		err = i.runPipeline(ctx, m)
+		if err != nil {
+			return err
+		}
+
+		err = i.queueLinks(ctx, m)
+		if err != nil {
+			return err
+		}
 	}
 
+	// Score before adding our own properties
+	existing.addQuality(m, extracted, i.Size)
+	existing.addTags(m, extracted, i.Size)
+
 	// Add previously found references now
 	m["size"] = i.Size
-	m["references"] = references
-	m["first-seen"] = now
-	m["last-seen"] = now
-
-	return i.Indexer.IndexItem(ctx, "file", i.Hash, m)
+	m["references"] = existing.references
+	existing.addSeen(m)
+	existing.addAliases(m)
+	existing.addFilenames(m)
+	existing.addOverride(m)
+	existing.addProvenance(m)
+	existing.addStructureOnly(m)
+
+	return i.updateItem(ctx, "file", m)
 }
 
 // preCrawl checks for and returns existing item and conditionally updates it
@@ -219,49 +285,146 @@ func (i *Indexable) preCrawl(ctx context.Context) (*existingItem, error) {
 	return e, e.update(ctx)
 }
 
+// crawlList lists and processes a hash
+func (i *Indexable) crawlList(ctx context.Context, existing *existingItem) error {
+	list, err := i.reservedFileList(ctx)
+	if err != nil {
+		return err
+	}
+
+	return i.processList(ctx, list, existing)
+}
+
+// crawlSync lists a hash and processes it as a file or directory
+func (i *Indexable) crawlSync(ctx context.Context, existing *existingItem) error {
+	list, err := i.reservedFileList(ctx)
+	if err != nil {
+		return err
+	}
+
+	if list.Type == "File" {
+		i.Size = list.Size
+		return i.processFile(ctx, existing)
+	}
+
+	return i.processList(ctx, list, existing)
+}
+
 // CrawlHash crawls a particular hash (file or directory)
-func (i *Indexable) CrawlHash(ctx context.Context) error {
-	existing, err := i.preCrawl(ctx)
+func (i *Indexable) CrawlHash(ctx context.Context) (err error) {
+	start := time.Now()
 
-	if err != nil || !existing.shouldCrawl() {
-		log.Printf("Skipping hash %s", i)
+	ctx, span := i.startSpan(ctx, "CrawlHash")
+	defer func() { tracing.End(span, err) }()
+
+	if denied, err := i.denied(ctx); denied || err != nil {
 		return err
 	}
 
-	log.Printf("Crawling hash %s", i)
+	if i.recentlyCrawled("hash") {
+		i.log().Debug("Skipping recently crawled hash")
+		return nil
+	}
 
-	list, err := i.getFileList(ctx)
+	existing, err := i.preCrawl(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = i.processList(ctx, list, existing.references)
+	if !existing.shouldCrawl() {
+		i.log().Debug("Skipping hash")
+		i.markSeen(i.crawledKey("hash"))
+		return nil
+	}
+
+	i.log().Debug("Crawling hash")
+
+	err = i.crawlList(ctx, existing)
+	i.recordAttempt(ctx, start, err)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Finished hash %s", i)
+	i.markSeen(i.crawledKey("hash"))
+	i.log().WithField("duration", time.Since(start)).Info("Finished hash")
 
 	return nil
 }
 
-// CrawlFile crawls a single object, known to be a file
-func (i *Indexable) CrawlFile(ctx context.Context) error {
+// Crawl crawls a hash synchronously; unlike CrawlHash, files are indexed
+// directly instead of being queued. Directory entries are still queued.
+func (i *Indexable) Crawl(ctx context.Context) (err error) {
+	start := time.Now()
+
+	ctx, span := i.startSpan(ctx, "Crawl")
+	defer func() { tracing.End(span, err) }()
+
+	if denied, err := i.denied(ctx); denied || err != nil {
+		return err
+	}
+
 	existing, err := i.preCrawl(ctx)
 
 	if err != nil || !existing.shouldCrawl() {
-		log.Printf("Skipping file %s", i)
+		i.log().Debug("Skipping hash")
+		return err
+	}
+
+	i.log().Debug("Crawling hash synchronously")
+
+	err = i.crawlSync(ctx, existing)
+	i.recordAttempt(ctx, start, err)
+	if err != nil {
+		return err
+	}
+
+	i.log().WithField("duration", time.Since(start)).Info("Finished hash")
+
+	return nil
+}
+
+// CrawlFile crawls a single object, known to be a file
+func (i *Indexable) CrawlFile(ctx context.Context) (err error) {
+	start := time.Now()
+
+	ctx, span := i.startSpan(ctx, "CrawlFile")
+	defer func() { tracing.End(span, err) }()
+
+	if denied, err := i.denied(ctx); denied || err != nil {
+		return err
+	}
+
+	if i.recentlyCrawled("file") {
+		i.log().Debug("Skipping recently crawled file")
+		return nil
+	}
+
+	existing, err := i.preCrawl(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !existing.shouldCrawl() {
+		i.log().Debug("Skipping file")
+		i.markSeen(i.crawledKey("file"))
+		return nil
+	}
+
+	routed, err := i.route(ctx, existing)
+	if routed || err != nil {
 		return err
 	}
 
-	log.Printf("Crawling file %s", i)
+	i.log().Debug("Crawling file")
 
-	i.processFile(ctx, existing.references)
+	err = i.processFile(ctx, existing)
+	i.recordAttempt(ctx, start, err)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Finished file %s", i)
+	i.markSeen(i.crawledKey("file"))
+	i.log().WithField("duration", time.Since(start)).Info("Finished file")
 
 	return nil
 }
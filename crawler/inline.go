@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"github.com/ipfs-search/ipfs-search/cids"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-ipfs-api"
+	"strings"
+	"unicode/utf8"
+)
+
+// inlineMaxSize is the size of the largest files held by their root block
+// entirely: the default chunk size of IPFS
+const inlineMaxSize = 256 * 1024
+
+// identityData returns the content of files with raw identity CIDs, which
+// the CID holds itself, so neither IPFS nor extractors are asked for it.
+// Identity CIDs of other codecs hold a block to be interpreted by IPFS,
+// which resolves them without fetching either.
+func (i *Indexable) identityData() ([]byte, bool) {
+	c, err := cids.Decode(i.Hash)
+	if err != nil || c.Type() != cid.Raw {
+		return nil, false
+	}
+
+	return cids.Inline(c)
+}
+
+// inlineData returns the content of files held entirely by their root
+// block, which is indexed without fetching the file through the gateway
+// and extracting it: that of raw identity CIDs, and otherwise of small raw
+// blocks and of dag-pb nodes without links holding the data of a file, as
+// fetched from IPFS. The block is fetched once.
+func (i *Indexable) inlineData() ([]byte, bool) {
+	if data, ok := i.identityData(); ok {
+		return data, true
+	}
+
+	if i.inlineFetched || i.Size == 0 || i.Size > inlineMaxSize {
+		return i.inline, i.inline != nil
+	}
+	i.inlineFetched = true
+
+	c, err := cids.Decode(i.Hash)
+	if err != nil || (c.Type() != cid.Raw && c.Type() != cid.DagProtobuf) {
+		return nil, false
+	}
+
+	block, err := i.Shell.BlockGet(i.Hash)
+	if err != nil {
+		i.log().WithError(err).Debug("Error getting root block, fetching file")
+		return nil, false
+	}
+
+	data := block
+	if c.Type() == cid.DagProtobuf {
+		var ok bool
+		if data, ok = unixfsFileData(block); !ok {
+			return nil, false
+		}
+	}
+
+	// Chunked files have a root block smaller than their size
+	if uint64(len(data)) != i.Size {
+		return nil, false
+	}
+
+	i.inline = data
+	return data, true
+}
+
+// inlineList returns the listing of a file with inline content, as IPFS
+// would list it
+func (i *Indexable) inlineList(data []byte) *shell.UnixLsObject {
+	return &shell.UnixLsObject{
+		Hash: i.Hash,
+		Size: uint64(len(data)),
+		Type: "File",
+	}
+}
+
+// addInlineContent adds inline content of textual files as their content,
+// like it is extracted from files fetched from IPFS
+func addInlineContent(m metadata, mimetype string, data []byte) {
+	if strings.HasPrefix(mimetype, "text/") && utf8.Valid(data) {
+		m["content"] = string(data)
+	}
+}
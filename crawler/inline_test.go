@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"bytes"
+	"testing"
+)
+
+// field returns a length-delimited protobuf field
+func field(number byte, data []byte) []byte {
+	return append([]byte{number<<3 | 2, byte(len(data))}, data...)
+}
+
+// fileBlock returns a dag-pb block of a UnixFS file with content inline,
+// optionally linking to a chunk
+func fileBlock(content string, link bool) []byte {
+	unixfs := append([]byte{unixfsKind << 3, unixfsFile}, field(unixfsData, []byte(content))...)
+
+	var block []byte
+	if link {
+		block = field(pbLinks, field(1, []byte("hash")))
+	}
+
+	return append(block, field(pbData, unixfs)...)
+}
+
+func TestUnixfsFileData(t *testing.T) {
+	data, ok := unixfsFileData(fileBlock("ipfs-search", false))
+	if !ok || string(data) != "ipfs-search" {
+		t.Errorf("unixfsFileData() = %q, %v, want ipfs-search", data, ok)
+	}
+
+	if _, ok := unixfsFileData(fileBlock("ipfs-search", true)); ok {
+		t.Error("unixfsFileData() of block with links returned data")
+	}
+
+	directory := field(pbData, []byte{unixfsKind << 3, 1})
+	if _, ok := unixfsFileData(directory); ok {
+		t.Error("unixfsFileData() of directory returned data")
+	}
+
+	if kind, err := unixfsType(fileBlock("", true)); err != nil || kind != unixfsFile {
+		t.Errorf("unixfsType() = %d, %v, want file", kind, err)
+	}
+
+	if _, ok := unixfsFileData(bytes.Repeat([]byte{0xff}, 4)); ok {
+		t.Error("unixfsFileData() of malformed block returned data")
+	}
+}
+
+func TestIdentityData(t *testing.T) {
+	tests := []struct {
+		hash   string
+		inline string
+		ok     bool
+	}{
+		// Raw identity CID of "ipfs-search"
+		{"bafkqac3jobthglltmvqxey3i", "ipfs-search", true},
+		// Identity CIDs of dag-pb blocks are listed by IPFS
+		{"bafyaablimvwgy3y", "", false},
+		{"QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX", "", false},
+		{"notahash", "", false},
+	}
+
+	for _, test := range tests {
+		i := &Indexable{Args: &Args{Hash: test.hash}}
+
+		data, ok := i.identityData()
+		if ok != test.ok || string(data) != test.inline {
+			t.Errorf("identityData() of %s = %q, %v", test.hash, data, ok)
+		}
+	}
+}
+
+func TestAddInlineContent(t *testing.T) {
+	m := make(metadata)
+	addInlineContent(m, "text/plain; charset=utf-8", []byte("ipfs-search"))
+	if m["content"] != "ipfs-search" {
+		t.Errorf("content = %v, want ipfs-search", m["content"])
+	}
+
+	m = make(metadata)
+	addInlineContent(m, "application/octet-stream", []byte{0xff, 0xfe})
+	if _, ok := m["content"]; ok {
+		t.Error("content added for binary data")
+	}
+}
@@ -0,0 +1,30 @@
+package crawler
+
+import (
+	"github.com/ipfs-search/ipfs-search/language"
+)
+
+// addLanguage detects the language of extracted content when the extractor
+// did not, e.g. for content extracted by the Tika server, and sets it on
+// properties in the form ipfs-tika uses
+func addLanguage(m metadata) {
+	if l, ok := m["language"].(map[string]interface{}); ok && l["language"] != "" && l["language"] != nil {
+		return
+	}
+
+	content, ok := m["content"].(string)
+	if !ok {
+		return
+	}
+
+	code, confidence, score := language.Detect(content)
+	if code == "" {
+		return
+	}
+
+	m["language"] = map[string]interface{}{
+		"language":   code,
+		"confidence": confidence,
+		"rawScore":   score,
+	}
+}
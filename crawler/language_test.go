@@ -0,0 +1,32 @@
+package crawler
+
+import (
+	"testing"
+)
+
+func TestAddLanguage(t *testing.T) {
+	content := "The quick brown fox jumps over the lazy dog and this is the story of that fox."
+
+	tests := []struct {
+		name string
+		m    metadata
+		want interface{}
+	}{
+		{"detected", metadata{"content": content}, "en"},
+		{"extractor detected", metadata{"content": content, "language": map[string]interface{}{"language": "de"}}, "de"},
+		{"extractor undetected", metadata{"content": content, "language": map[string]interface{}{"language": ""}}, "en"},
+		{"no content", metadata{}, nil},
+	}
+
+	for _, test := range tests {
+		addLanguage(test.m)
+
+		var got interface{}
+		if l, ok := test.m["language"].(map[string]interface{}); ok {
+			got = l["language"]
+		}
+		if got != test.want {
+			t.Errorf("%s: addLanguage() language = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
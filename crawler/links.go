@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"context"
+	"regexp"
+)
+
+// maxLinks limits the amount of links queued for a single document
+const maxLinks = 100
+
+// linkPriority is the queue priority for hashes linked from documents,
+// which are less likely to be available than directory entries
+const linkPriority = 1
+
+// ipfsLink matches IPFS paths, ipfs:// URLs and subdomain gateway URLs,
+// capturing the CID. The root is linked to, as the name of the item a
+// path within it refers to is not known without resolving.
+var ipfsLink = regexp.MustCompile(`(?:/ipfs/|ipfs://)([A-Za-z0-9]+)|//([a-z0-9]+)\.ipfs\.`)
+
+// findLinks returns crawl arguments for IPFS links found in extracted
+// metadata, with the document as referencing parent
+func (i *Indexable) findLinks(m metadata) []*Args {
+	var texts []string
+
+	if urls, ok := m["urls"].([]interface{}); ok {
+		for _, u := range urls {
+			if s, ok := u.(string); ok {
+				texts = append(texts, s)
+			}
+		}
+	}
+
+	if content, ok := m["content"].(string); ok {
+		texts = append(texts, content)
+	}
+
+	seen := make(map[string]bool)
+	var links []*Args
+
+	for _, text := range texts {
+		for _, match := range ipfsLink.FindAllStringSubmatch(text, -1) {
+			hash := match[1]
+			if hash == "" {
+				hash = match[2]
+			}
+
+			hash, err := NormalizeHash(hash)
+			if err != nil || hash == i.Hash || seen[hash] || i.isDenied(hash) {
+				continue
+			}
+			seen[hash] = true
+
+			links = append(links, &Args{
+				Hash:       hash,
+				ParentHash: i.Hash,
+				Depth:      i.Depth + 1,
+				Provenance: i.childProvenance(SourceLink),
+			})
+
+			if len(links) == maxLinks {
+				return links
+			}
+		}
+	}
+
+	return links
+}
+
+// queueLinks queues IPFS links found in extracted metadata for crawling
+func (i *Indexable) queueLinks(ctx context.Context, m metadata) error {
+	links := i.findLinks(m)
+
+	for _, link := range links {
+		if err := i.publish(ctx, i.HashQueue, link, linkPriority); err != nil {
+			return err
+		}
+	}
+
+	if len(links) > 0 {
+		i.log().WithField("links", len(links)).Debug("Queued links from content")
+	}
+
+	return nil
+}
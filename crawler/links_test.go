@@ -0,0 +1,43 @@
+package crawler
+
+import (
+	"testing"
+)
+
+func TestFindLinks(t *testing.T) {
+	const v0 = "QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX"
+	const v1 = "bafybeibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq"
+	const raw = "bafkreibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq"
+
+	tests := []struct {
+		name string
+		m    metadata
+		want []string
+	}{
+		{"none", metadata{"content": "no links here"}, nil},
+		{"path", metadata{"content": "see /ipfs/" + raw + "/index.html"}, []string{raw}},
+		{"url", metadata{"urls": []interface{}{"ipfs://" + raw}}, []string{raw}},
+		{"subdomain", metadata{"content": "https://" + raw + ".ipfs.dweb.link/"}, []string{raw}},
+		{"normalized duplicate", metadata{"content": "/ipfs/" + v0 + " /ipfs/" + v1}, []string{v0}},
+		{"invalid", metadata{"content": "/ipfs/notahash"}, nil},
+	}
+
+	for _, test := range tests {
+		i := &Indexable{
+			Crawler: &Crawler{},
+			Args:    &Args{Hash: "hash", Depth: 1},
+		}
+
+		links := i.findLinks(test.m)
+
+		if len(links) != len(test.want) {
+			t.Errorf("%s: findLinks() = %d links, want %v", test.name, len(links), test.want)
+			continue
+		}
+		for n, link := range links {
+			if link.Hash != test.want[n] || link.ParentHash != "hash" || link.Depth != 2 || link.Provenance.Source != SourceLink {
+				t.Errorf("%s: findLinks() link %d = %+v, want %s", test.name, n, link, test.want[n])
+			}
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package crawler
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
-	"net/http"
-	"time"
+	"github.com/ipfs-search/ipfs-search/crawler/crawlerrors"
+	"github.com/ipfs-search/ipfs-search/extractor"
+	"github.com/ipfs-search/ipfs-search/extractor/images"
+	"github.com/ipfs-search/ipfs-search/extractor/media"
+	"github.com/ipfs-search/ipfs-search/extractor/pdf"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/ipfs-search/ipfs-search/simhash"
+	"github.com/ipfs-search/ipfs-search/tracing"
 )
 
 type metadata map[string]interface{}
@@ -22,85 +27,148 @@ func (i *Indexable) getFilenameURL() (path string) {
 	return fmt.Sprintf("/ipfs/%s", i.Hash)
 }
 
-func (i *Indexable) retryingGet(url string) (resp *http.Response, err error) {
-	client := http.Client{
-		Timeout: i.Config.IpfsTikaTimeout,
-	}
+// retryingExtract calls the extractor, retrying temporary errors up to
+// the configured amount of times, after which the extractor is considered
+// unavailable. Items received in tasks are retried by returning
+// RetryError, so the task is tried again later rather than holding on to
+// the worker; attempts of the task count as retries.
+func (i *Indexable) retryingExtract(ctx context.Context, path string) (map[string]interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		m, err := i.Extractor.Extract(ctx, path, i.Size)
+
+		err = crawlerrors.Classify(err)
+		if !crawlerrors.HasCategory(err, crawlerrors.Temporary) {
+			return m, err
+		}
 
-	tryAgain := true
-	for tryAgain {
-		log.Printf("Fetching metadata from '%s'", url)
-		resp, err = client.Get(url)
+		if i.task != nil {
+			attempt = i.task.Attempts
+		}
 
-		tryAgain, err = i.handleURLError(err)
+		if attempt >= i.Config.ExtractRetries {
+			return nil, crawlerrors.New(crawlerrors.Unavailable, fmt.Errorf("extraction failed %d times: %w", attempt+1, err))
+		}
 
-		if tryAgain {
-			log.Printf("Retrying in %s", i.Config.RetryWait)
-			time.Sleep(i.Config.RetryWait)
+		if i.task != nil {
+			return nil, &queue.RetryError{Err: err}
 		}
+
+		i.log().WithError(err).Warnf("Temporary error, retrying in %s", i.Config.RetryWait)
+		if err := sleep(ctx, i.Config.RetryWait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// dedicatedExtractor returns the extractor dedicated to the sniffed content
+// type of the file, if any
+func (i *Indexable) dedicatedExtractor() extractor.Extractor {
+	switch {
+	case i.Images != nil && images.Supported(i.mimetype):
+		return i.Images
+	case i.Media != nil && media.Supported(i.mimetype):
+		return i.Media
 	}
 
-	return
+	return nil
 }
 
-// getTika requests IPFS path from IPFS-TIKA and writes returned metadata
-func (i *Indexable) getTika(m *metadata) error {
-	resp, err := i.retryingGet(i.Config.IpfsTikaURL + i.getFilenameURL())
+// fallbackExtract extracts the text of PDF files in-process when the
+// extractor is unavailable, so they still get their content indexed,
+// returning the extractor's error otherwise
+func (i *Indexable) fallbackExtract(ctx context.Context, path string, err error) (map[string]interface{}, error) {
+	if i.PDF == nil || !crawlerrors.HasCategory(err, crawlerrors.Unavailable) || !pdf.Supported(i.mimetype, i.Name) {
+		return nil, err
+	}
 
-	if err != nil {
-		return err
+	m, pdfErr := i.PDF.Extract(ctx, path, i.Size)
+	if pdfErr != nil {
+		i.log().WithError(pdfErr).Debug("Error in PDF fallback")
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("undesired status '%s' from ipfs-tika", resp.Status)
+	i.log().WithError(err).Info("Extractor unavailable, extracted PDF text in-process")
+	return m, nil
+}
+
+// extract returns the metadata of images and media from their dedicated
+// extractors, when configured, falling back to the extractor for other
+// files and those the dedicated extractor fails on
+func (i *Indexable) extract(ctx context.Context, path string) (map[string]interface{}, error) {
+	if e := i.dedicatedExtractor(); e != nil {
+		m, err := e.Extract(ctx, path, i.Size)
+		if err == nil {
+			return m, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		i.log().WithError(err).WithField("mimetype", i.mimetype).Debug("Error in dedicated extractor, using extractor")
 	}
 
-	// Parse resulting JSON
-	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
-		return err
+	m, err := i.retryingExtract(ctx, path)
+	if err != nil {
+		return i.fallbackExtract(ctx, path, err)
 	}
 
-	return err
+	return m, nil
 }
 
 // getMatadata sets metdata for file with args or returns error
-func (i *Indexable) getMetadata(m *metadata) error {
-	var err error
+func (i *Indexable) getMetadata(ctx context.Context, m *metadata) (err error) {
+	ctx, span := i.startSpan(ctx, "ExtractMetadata")
+	defer func() { tracing.End(span, err) }()
+
+	if data, ok := i.inlineData(); ok {
+		mimetype, _ := i.detectMimetype(ctx)
+		addInlineContent(*m, mimetype, data)
+		return nil
+	}
 
 	if i.Args.Size > 0 {
-		if i.Args.Size > i.Config.MetadataMaxSize {
-			// Fail hard for really large files, for now
-			return fmt.Errorf("%s too large, not indexing (for now)", i)
-		}
-
-		err = i.getTika(m)
+		extracted, err := i.extract(ctx, i.getFilenameURL())
 		if err != nil {
 			return err
 		}
 
-		// Check for IPFS links in content
-		/*
-		   for raw_url := range metadata.urls {
-		       url, err := URL.Parse(raw_url)
-
-		       if err != nil {
-		           return err
-		       }
-
-		       if strings.HasPrefix(url.Path, "/ipfs/") {
-		           // Found IPFS link!
-		           args := crawlerArgs{
-		               Hash:       link.Hash,
-		               Name:       link.Name,
-		               Size:       link.Size,
-		               ParentHash: hash,
-		           }
-
-		       }
-		   }
-		*/
+		for k, v := range extracted {
+			(*m)[k] = v
+		}
 	}
 
 	return nil
 }
+
+// firstValue returns the first textual value of an extracted metadata
+// field, which ipfs-tika returns as a list of strings
+func firstValue(fields map[string]interface{}, key string) string {
+	switch v := fields[key].(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			s, _ := v[0].(string)
+			return s
+		}
+	}
+
+	return ""
+}
+
+// addSignature sets the simhash of extracted text on metadata, along with
+// its bands, so near-duplicates can be looked up
+func addSignature(m metadata) {
+	content, ok := m["content"].(string)
+	if !ok {
+		return
+	}
+
+	signature, ok := simhash.Signature(content)
+	if !ok {
+		return
+	}
+
+	m["simhash"] = simhash.Format(signature)
+	m["simhash-bands"] = simhash.Bands(signature)
+}
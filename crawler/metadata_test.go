@@ -0,0 +1,245 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/crawler/crawlerrors"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// failingExtractor fails with connection refused a number of times before
+// returning metadata
+type failingExtractor struct {
+	failures int
+	calls    int
+}
+
+func (e *failingExtractor) Extract(ctx context.Context, path string, size uint64) (map[string]interface{}, error) {
+	e.calls++
+	if e.calls <= e.failures {
+		return nil, &url.Error{Op: "Get", URL: "http://localhost:8081" + path, Err: syscall.ECONNREFUSED}
+	}
+
+	return map[string]interface{}{"content": "text"}, nil
+}
+
+func TestRetryingExtract(t *testing.T) {
+	tests := []struct {
+		failures int
+		retries  int
+		calls    int
+		category crawlerrors.Category
+	}{
+		{0, 0, 1, ""},
+		{1, 0, 1, crawlerrors.Unavailable},
+		{2, 3, 3, ""},
+		{3, 3, 4, ""},
+		{4, 3, 4, crawlerrors.Unavailable},
+	}
+
+	for _, test := range tests {
+		e := &failingExtractor{failures: test.failures}
+		i := &Indexable{
+			Crawler: &Crawler{
+				Config:    &Config{ExtractRetries: test.retries},
+				Extractor: e,
+			},
+			Args: &Args{Hash: "hash", Size: 10},
+		}
+
+		m, err := i.retryingExtract(context.Background(), "/ipfs/hash")
+
+		if category := crawlerrors.CategoryOf(err); category != test.category {
+			t.Errorf("retryingExtract() failing %d times with %d retries: error %v, want category %q", test.failures, test.retries, err, test.category)
+		}
+		if err == nil && m["content"] != "text" {
+			t.Errorf("retryingExtract() failing %d times with %d retries = %v", test.failures, test.retries, m)
+		}
+		if e.calls != test.calls {
+			t.Errorf("retryingExtract() failing %d times with %d retries: %d calls, want %d", test.failures, test.retries, e.calls, test.calls)
+		}
+	}
+}
+
+func TestRetryingExtractTask(t *testing.T) {
+	tests := []struct {
+		attempts int
+		retry    bool
+	}{
+		{0, true},
+		{2, true},
+		{3, false},
+	}
+
+	for _, test := range tests {
+		e := &failingExtractor{failures: 10}
+		i := &Indexable{
+			Crawler: &Crawler{
+				Config:    &Config{ExtractRetries: 3, RetryWait: time.Hour},
+				Extractor: e,
+			},
+			Args: &Args{Hash: "hash", Size: 10},
+			task: &queue.Task{Attempts: test.attempts},
+		}
+
+		_, err := i.retryingExtract(context.Background(), "/ipfs/hash")
+
+		var retry *queue.RetryError
+		if errors.As(err, &retry) != test.retry {
+			t.Errorf("retryingExtract() of task tried %d times = %v, want retry %v", test.attempts, err, test.retry)
+		}
+		if !test.retry && !crawlerrors.HasCategory(err, crawlerrors.Unavailable) {
+			t.Errorf("retryingExtract() of task tried %d times = %v, want unavailable", test.attempts, err)
+		}
+		if e.calls != 1 {
+			t.Errorf("retryingExtract() of task: %d calls, want 1", e.calls)
+		}
+	}
+}
+
+func TestRetryingExtractCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	i := &Indexable{
+		Crawler: &Crawler{
+			Config:    &Config{ExtractRetries: 3, RetryWait: time.Hour},
+			Extractor: &failingExtractor{failures: 10},
+		},
+		Args: &Args{Hash: "hash", Size: 10},
+	}
+
+	if _, err := i.retryingExtract(ctx, "/ipfs/hash"); err != context.Canceled {
+		t.Errorf("retryingExtract() with cancelled context = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAddMimetype(t *testing.T) {
+	tests := []struct {
+		size     uint64
+		mimetype string
+		want     interface{}
+	}{
+		{0, "text/plain", nil},
+		{10, "text/plain", "text/plain"},
+	}
+
+	for _, test := range tests {
+		i := &Indexable{
+			Args:     &Args{Hash: "hash", Size: test.size},
+			mimetype: test.mimetype,
+		}
+
+		m := make(metadata)
+		i.addMimetype(context.Background(), m)
+
+		if got := m["mimetype"]; got != test.want {
+			t.Errorf("addMimetype() for size %d = %v, want %v", test.size, got, test.want)
+		}
+	}
+}
+
+// staticExtractor returns fixed metadata or error
+type staticExtractor struct {
+	m     map[string]interface{}
+	err   error
+	calls int
+}
+
+func (e *staticExtractor) Extract(ctx context.Context, path string, size uint64) (map[string]interface{}, error) {
+	e.calls++
+	return e.m, e.err
+}
+
+func TestExtractDedicated(t *testing.T) {
+	image := map[string]interface{}{"source": "images"}
+	media := map[string]interface{}{"source": "media"}
+	tika := map[string]interface{}{"source": "tika"}
+
+	tests := []struct {
+		name     string
+		mimetype string
+		err      error
+		want     string
+	}{
+		{"image", "image/jpeg", nil, "images"},
+		{"other", "text/plain", nil, "tika"},
+		{"image failing", "image/png", fmt.Errorf("invalid png"), "tika"},
+		{"video", "video/mp4", nil, "media"},
+		{"audio", "audio/mpeg", nil, "media"},
+		{"media failing", "video/webm", fmt.Errorf("ffprobe failed"), "tika"},
+	}
+
+	for _, test := range tests {
+		i := &Indexable{
+			Crawler: &Crawler{
+				Config:    &Config{},
+				Extractor: &staticExtractor{m: tika},
+				Images:    &staticExtractor{m: image, err: test.err},
+				Media:     &staticExtractor{m: media, err: test.err},
+			},
+			Args:     &Args{Hash: "hash", Size: 10},
+			mimetype: test.mimetype,
+		}
+
+		m, err := i.extract(context.Background(), "/ipfs/hash")
+		if err != nil {
+			t.Fatalf("%s: extract() error %v", test.name, err)
+		}
+		if m["source"] != test.want {
+			t.Errorf("%s: extract() from %v, want %s", test.name, m["source"], test.want)
+		}
+	}
+}
+
+func TestExtractPDFFallback(t *testing.T) {
+	text := map[string]interface{}{"content": "pdf text"}
+
+	tests := []struct {
+		name     string
+		mimetype string
+		filename string
+		failures int
+		pdfErr   error
+		want     string
+		wantErr  bool
+	}{
+		{"available", "application/pdf", "", 0, nil, "text", false},
+		{"unavailable", "application/pdf", "", 1, nil, "pdf text", false},
+		{"unavailable by name", "application/octet-stream", "paper.pdf", 1, nil, "pdf text", false},
+		{"unavailable other", "text/plain", "notes.txt", 1, nil, "", true},
+		{"unavailable failing", "application/pdf", "", 1, fmt.Errorf("encrypted PDF"), "", true},
+	}
+
+	for _, test := range tests {
+		i := &Indexable{
+			Crawler: &Crawler{
+				Config:    &Config{},
+				Extractor: &failingExtractor{failures: test.failures},
+				PDF:       &staticExtractor{m: text, err: test.pdfErr},
+			},
+			Args:     &Args{Hash: "hash", Name: test.filename, Size: 10},
+			mimetype: test.mimetype,
+		}
+
+		m, err := i.extract(context.Background(), "/ipfs/hash")
+		if test.wantErr {
+			if !crawlerrors.HasCategory(err, crawlerrors.Unavailable) {
+				t.Errorf("%s: extract() error %v, want unavailable", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: extract() error %v", test.name, err)
+			continue
+		}
+		if m["content"] != test.want {
+			t.Errorf("%s: extract() content %v, want %s", test.name, m["content"], test.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// sniffLength is the amount of bytes used to detect content types
+const sniffLength = 512
+
+// detectMimetype returns the content type of a file based on its first
+// bytes, independent of metadata extraction
+func (i *Indexable) detectMimetype(ctx context.Context) (string, error) {
+	if i.mimetype != "" {
+		return i.mimetype, nil
+	}
+
+	if data, ok := i.inlineData(); ok {
+		if len(data) > sniffLength {
+			data = data[:sniffLength]
+		}
+
+		i.head = data
+		i.mimetype = http.DetectContentType(data)
+		return i.mimetype, nil
+	}
+
+	resp, err := i.Shell.Request("cat", i.hashURL()).
+		Option("length", sniffLength).
+		Send(ctx)
+	if err != nil {
+		return "", err
+	}
+	if resp.Error != nil {
+		return "", resp.Error
+	}
+	defer resp.Close()
+
+	head, err := ioutil.ReadAll(io.LimitReader(resp.Output, sniffLength))
+	if err != nil {
+		return "", err
+	}
+
+	i.head = head
+	i.mimetype = http.DetectContentType(head)
+	return i.mimetype, nil
+}
+
+// addMimetype sets the detected content type on properties of non-empty
+// files; failure to detect it does not prevent indexing
+func (i *Indexable) addMimetype(ctx context.Context, properties metadata) {
+	if i.Size == 0 {
+		return
+	}
+
+	mimetype, err := i.detectMimetype(ctx)
+	if err != nil {
+		i.log().WithError(err).Warn("Error detecting content type")
+		return
+	}
+
+	properties["mimetype"] = mimetype
+}
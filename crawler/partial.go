@@ -0,0 +1,168 @@
+package crawler
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/ipfs-search/ipfs-search/cids"
+	"github.com/ipfs/go-cid"
+)
+
+// UnixFS data type of a chunk of a file which is not a file by itself
+const unixfsRaw = 0
+
+var errMalformed = errors.New("malformed protobuf")
+
+// walkProtobuf calls visit for the varint and length-delimited fields of a
+// protobuf message, until visit returns false
+func walkProtobuf(msg []byte, visit func(number, value uint64, data []byte) bool) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errMalformed
+		}
+		msg = msg[n:]
+
+		value, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errMalformed
+		}
+		msg = msg[n:]
+
+		var data []byte
+		switch key & 7 {
+		case 0: // varint
+		case 2: // length-delimited
+			if uint64(len(msg)) < value {
+				return errMalformed
+			}
+			data, msg = msg[:value], msg[value:]
+		default:
+			return errMalformed
+		}
+
+		if !visit(key>>3, value, data) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Fields of dag-pb nodes (PBNode) and of the UnixFS Data message they hold
+const (
+	pbData     = 1
+	pbLinks    = 2
+	unixfsKind = 1 // Type
+	unixfsData = 2
+
+	unixfsFile = 2
+)
+
+// pbNodeData returns the Data field of a dag-pb block, a UnixFS Data
+// message, and whether the block links to others
+func pbNodeData(block []byte) ([]byte, bool, error) {
+	var data []byte
+	links := false
+	err := walkProtobuf(block, func(number, _ uint64, d []byte) bool {
+		switch number {
+		case pbData:
+			data = d
+		case pbLinks:
+			links = true
+		}
+		return true
+	})
+	if err != nil || data == nil {
+		return nil, false, errMalformed
+	}
+
+	return data, links, nil
+}
+
+// unixfsType returns the UnixFS data type of a dag-pb block
+func unixfsType(block []byte) (uint64, error) {
+	data, _, err := pbNodeData(block)
+	if err != nil {
+		return 0, err
+	}
+
+	t := uint64(unixfsRaw)
+	err = walkProtobuf(data, func(number, value uint64, _ []byte) bool {
+		if number == unixfsKind {
+			t = value
+			return false
+		}
+		return true
+	})
+
+	return t, err
+}
+
+// unixfsFileData returns the content of a dag-pb block holding a file
+// entirely, without links to chunks
+func unixfsFileData(block []byte) ([]byte, bool) {
+	data, links, err := pbNodeData(block)
+	if err != nil || links {
+		return nil, false
+	}
+
+	t := uint64(unixfsRaw)
+	var content []byte
+	err = walkProtobuf(data, func(number, value uint64, d []byte) bool {
+		switch number {
+		case unixfsKind:
+			t = value
+		case unixfsData:
+			content = d
+		}
+		return true
+	})
+	if err != nil || (t != unixfsFile && t != unixfsRaw) {
+		return nil, false
+	}
+
+	return content, true
+}
+
+// isPartial returns whether the item is a chunk of a larger file rather
+// than a file by itself.
+// Chunks wrapped in UnixFS are marked as raw data. Raw blocks without
+// UnixFS wrapping are chunks or single-block files alike; these are only
+// considered partial when they are of the default chunk size, as single
+// block files usually are not.
+func (i *Indexable) isPartial() (bool, error) {
+	c, err := cids.Decode(i.Hash)
+	if err != nil {
+		return false, err
+	}
+
+	// Blocks held by the CID are small
+	if _, ok := cids.Inline(c); ok {
+		return false, nil
+	}
+
+	switch c.Type() {
+	case cid.Raw:
+		_, size, err := i.Shell.BlockStat(i.Hash)
+		if err != nil {
+			return false, err
+		}
+
+		return uint64(size) == i.Config.PartialSize, nil
+	case cid.DagProtobuf:
+		block, err := i.Shell.BlockGet(i.Hash)
+		if err != nil {
+			return false, err
+		}
+
+		t, err := unixfsType(block)
+		if err != nil {
+			// Not UnixFS; let crawling report the error
+			return false, nil
+		}
+
+		return t == unixfsRaw, nil
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,147 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/crawler/crawlerrors"
+	"github.com/ipfs-search/ipfs-search/extractor"
+)
+
+// stage adds properties to the metadata of a file; errors fail the file
+type stage func(i *Indexable, ctx context.Context, m metadata) error
+
+// infallible adapts stages which log rather than return errors
+func infallible(f func(*Indexable, context.Context, metadata)) stage {
+	return func(i *Indexable, ctx context.Context, m metadata) error {
+		f(i, ctx, m)
+		return nil
+	}
+}
+
+// derived adapts stages which derive properties from extracted metadata
+func derived(f func(metadata)) stage {
+	return func(i *Indexable, ctx context.Context, m metadata) error {
+		f(m)
+		return nil
+	}
+}
+
+// stages are the built-in stages files go through, by name. Enrichment
+// services are stages named after them.
+var stages = map[string]stage{
+	"detect-type":     infallible((*Indexable).addMimetype),
+	"extract":         (*Indexable).extractMetadata,
+	"signature":       derived(addSignature),
+	"location":        derived(addLocation),
+	"dates":           derived(addDates),
+	"authors":         derived(addAuthors),
+	"detect-language": derived(addLanguage),
+	"archive":         infallible((*Indexable).addArchiveContents),
+	"classify":        infallible((*Indexable).addCategories),
+	"thumbnail":       infallible((*Indexable).addThumbnail),
+	"enrich":          infallible((*Indexable).addEnrichments),
+}
+
+// DefaultStages are the stages of files no pipeline matches, in order; all
+// enrichment services run in the enrich stage
+var DefaultStages = []string{
+	"detect-type", "extract",
+	"signature", "location", "dates", "authors", "detect-language",
+	"archive", "classify", "thumbnail", "enrich",
+}
+
+// Pipeline lists the stages files of particular content types go through,
+// so enrichments can be added or left out without code changes. Stages
+// deriving properties from extracted metadata should follow extract.
+type Pipeline struct {
+	MimeTypes []string // Prefixes of sniffed content types, any file when empty
+	Stages    []string // Names of built-in stages or enrichment services, in order
+}
+
+// matches returns whether files of a content type go through the pipeline
+func (p *Pipeline) matches(mimetype string) bool {
+	return len(p.MimeTypes) == 0 || anyPrefix(mimetype, p.MimeTypes)
+}
+
+// CheckStages returns an error for stages which are neither built in nor
+// one of the named enrichment services
+func CheckStages(names []string, enrichers []string) error {
+	for _, name := range names {
+		if _, ok := stages[name]; ok {
+			continue
+		}
+
+		found := false
+		for _, e := range enrichers {
+			found = found || e == name
+		}
+		if !found {
+			return fmt.Errorf("unknown stage %s", name)
+		}
+	}
+
+	return nil
+}
+
+// extractMetadata adds extracted metadata, with the version of the
+// extractor capability registry it was extracted with, indexing files
+// without it when they are too large or extraction is unavailable
+func (i *Indexable) extractMetadata(ctx context.Context, m metadata) error {
+	err := i.getMetadata(ctx, &m)
+	switch {
+	case err == nil:
+		m["extractor-version"] = extractor.Version(i.mimetype)
+	case crawlerrors.HasCategory(err, crawlerrors.TooLarge):
+		// Index without extracted metadata
+		i.log().WithError(err).Info("Skipping metadata extraction")
+	case crawlerrors.HasCategory(err, crawlerrors.Unavailable):
+		// Index with the sniffed mimetype only, rather than retrying forever
+		i.log().WithError(err).Warn("Metadata extraction unavailable")
+	default:
+		return err
+	}
+
+	return nil
+}
+
+// stages returns the stages of the first pipeline matching the file's
+// sniffed content type, or the default stages
+func (i *Indexable) stages(ctx context.Context) []string {
+	if len(i.Config.Pipelines) == 0 {
+		return DefaultStages
+	}
+
+	var mimetype string
+	if i.Size > 0 {
+		// Files which can't be sniffed only match pipelines for any file
+		mimetype, _ = i.detectMimetype(ctx)
+	}
+
+	for _, p := range i.Config.Pipelines {
+		if p.matches(mimetype) {
+			return p.Stages
+		}
+	}
+
+	return DefaultStages
+}
+
+// runPipeline adds the properties of the file's stages to metadata
+func (i *Indexable) runPipeline(ctx context.Context, m metadata) error {
+	for _, name := range i.stages(ctx) {
+		if s, ok := stages[name]; ok {
+			if err := s(i, ctx, m); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, e := range i.Enrichers {
+			if e.Name() == name {
+				i.enrich(ctx, e, m)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ipfs-search/ipfs-search/enrich"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckStages(t *testing.T) {
+	tests := []struct {
+		stages []string
+		valid  bool
+	}{
+		{DefaultStages, true},
+		{[]string{"detect-type", "extract", "nsfw"}, true},
+		{[]string{"extract", "ocr"}, false},
+	}
+
+	for _, test := range tests {
+		if err := CheckStages(test.stages, []string{"nsfw"}); (err == nil) != test.valid {
+			t.Errorf("CheckStages(%v) error = %v, valid %v", test.stages, err, test.valid)
+		}
+	}
+}
+
+func TestPipelineStages(t *testing.T) {
+	pipelines := []Pipeline{
+		{MimeTypes: []string{"image/", "video/"}, Stages: []string{"detect-type", "thumbnail"}},
+		{MimeTypes: []string{"text/"}, Stages: []string{"extract"}},
+	}
+
+	tests := []struct {
+		pipelines []Pipeline
+		mimetype  string
+		want      []string
+	}{
+		{nil, "image/png", DefaultStages},
+		{pipelines, "image/png", []string{"detect-type", "thumbnail"}},
+		{pipelines, "text/plain; charset=utf-8", []string{"extract"}},
+		{pipelines, "application/pdf", DefaultStages},
+		{append(pipelines, Pipeline{Stages: []string{"detect-type"}}), "application/pdf", []string{"detect-type"}},
+	}
+
+	for _, test := range tests {
+		i := &Indexable{
+			Crawler:  &Crawler{Config: &Config{Pipelines: test.pipelines}},
+			Args:     &Args{Hash: "hash", Size: 10},
+			mimetype: test.mimetype,
+		}
+
+		if got := i.stages(context.Background()); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("stages() for %s = %v, want %v", test.mimetype, got, test.want)
+		}
+	}
+}
+
+func TestRunPipeline(t *testing.T) {
+	var order []string
+	newEnricher := func(name string) *enrich.Enricher {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, name)
+			json.NewEncoder(w).Encode(map[string]interface{}{name + "_score": 0.5})
+		}))
+		t.Cleanup(server.Close)
+
+		return enrich.New(&enrich.Config{Name: name, URL: server.URL, Fields: []string{name + "_score"}, Timeout: time.Second})
+	}
+
+	i := &Indexable{
+		Crawler: &Crawler{
+			Config: &Config{
+				Pipelines: []Pipeline{{Stages: []string{"dates", "nsfw", "spam"}}},
+			},
+			Enrichers: []*enrich.Enricher{newEnricher("spam"), newEnricher("nsfw"), newEnricher("unused")},
+		},
+		Args:     &Args{Hash: "hash", Size: 10},
+		mimetype: "image/jpeg",
+	}
+
+	m := metadata{
+		"metadata": map[string]interface{}{"dcterms:created": []interface{}{"2019-05-04T10:11:12Z"}},
+	}
+	if err := i.runPipeline(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(order, []string{"nsfw", "spam"}) {
+		t.Errorf("enrichers ran %v, want nsfw, spam", order)
+	}
+
+	for _, key := range []string{"content-created", "nsfw_score", "spam_score"} {
+		if _, ok := m[key]; !ok {
+			t.Errorf("%s not set by pipeline: %v", key, m)
+		}
+	}
+	if _, ok := m["mimetype"]; ok {
+		t.Errorf("mimetype set without detect-type stage: %v", m)
+	}
+}
@@ -0,0 +1,15 @@
+package crawler
+
+// rootPriority is the queue priority for roots, i.e. hashes at depth 0.
+// Descendants are queued with a priority decreasing by depth, down to 1,
+// so freshly announced hashes are crawled before deep directory contents.
+const rootPriority = 9
+
+// Priority returns the queue priority for the resource, based on its depth
+func (a *Args) Priority() uint8 {
+	if a.Depth >= rootPriority-1 {
+		return 1
+	}
+
+	return uint8(rootPriority - a.Depth)
+}
@@ -0,0 +1,25 @@
+package crawler
+
+import (
+	"testing"
+)
+
+func TestPriority(t *testing.T) {
+	tests := []struct {
+		depth uint
+		want  uint8
+	}{
+		{0, rootPriority},
+		{1, rootPriority - 1},
+		{rootPriority - 2, 2},
+		{rootPriority - 1, 1},
+		{100, 1},
+	}
+
+	for _, test := range tests {
+		a := &Args{Depth: test.depth}
+		if got := a.Priority(); got != test.want {
+			t.Errorf("Priority() at depth %d = %d, want %d", test.depth, got, test.want)
+		}
+	}
+}
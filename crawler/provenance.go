@@ -0,0 +1,49 @@
+package crawler
+
+import (
+	"github.com/ipfs-search/ipfs-search/indexer"
+)
+
+// Sources of hashes, recorded in provenance
+const (
+	SourceSniffer   = "sniffer"   // Seen on the DHT; the sniffer doesn't record provenance
+	SourceDirectory = "directory" // Entry of a crawled directory
+	SourceLink      = "link"      // Linked from the content of a crawled file
+	SourceAdd       = "add"       // Added by an operator
+	SourceAPI       = "api"       // Requested through the API
+	SourceIngest    = "ingest"    // Submitted anonymously through the API
+)
+
+// maxRoots limits the length of the chain of roots; the submitted root is
+// always kept, along with the closest ancestors
+const maxRoots = 32
+
+// provenance returns how this item was discovered
+func (i *Indexable) provenance() *indexer.Provenance {
+	if i.Provenance == nil {
+		return &indexer.Provenance{Source: SourceSniffer}
+	}
+
+	return i.Provenance
+}
+
+// childProvenance returns the provenance of items discovered through this
+// one, inheriting submitter and job and extending the chain of roots
+func (i *Indexable) childProvenance(source string) *indexer.Provenance {
+	p := i.provenance()
+
+	roots := make([]string, 0, len(p.Roots)+1)
+	roots = append(roots, p.Roots...)
+	roots = append(roots, i.Hash)
+
+	if len(roots) > maxRoots {
+		roots = append(roots[:1], roots[len(roots)-maxRoots+1:]...)
+	}
+
+	return &indexer.Provenance{
+		Source:    source,
+		Submitter: p.Submitter,
+		Job:       p.Job,
+		Roots:     roots,
+	}
+}
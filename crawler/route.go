@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"context"
+)
+
+// Route directs files of particular content types to a separate queue,
+// consumed by a dedicated pool of workers, so slow formats don't hold up
+// extraction of other files
+type Route struct {
+	Name      string   // Name of the route; its queue is files-<Name>
+	MimeTypes []string // Prefixes of sniffed content types, e.g. video/
+}
+
+// Queue returns the name of the queue for the route
+func (r *Route) Queue() string {
+	return "files-" + r.Name
+}
+
+// matches returns whether a content type is routed
+func (r *Route) matches(mimetype string) bool {
+	return anyPrefix(mimetype, r.MimeTypes)
+}
+
+// route queues the file for the route matching its content type, if any;
+// returns whether it has been routed. Files received through a route, or
+// by crawlers without route queues, are crawled where they are, as are
+// files in structure-only mode, as their content type is not sniffed.
+func (i *Indexable) route(ctx context.Context, existing *existingItem) (bool, error) {
+	if i.routed || len(i.RouteQueues) == 0 || i.Size == 0 || i.skipContents() {
+		return false, nil
+	}
+
+	mimetype, err := i.detectMimetype(ctx)
+	if err != nil {
+		// Crawl here, rather than failing the file
+		i.log().WithError(err).Warn("Error detecting content type, not routing")
+		return false, nil
+	}
+
+	for _, r := range i.Config.Routes {
+		if !r.matches(mimetype) {
+			continue
+		}
+
+		i.log().WithField("route", r.Name).Debug("Routing file")
+
+		priority := i.FilePriority(len(existing.references))
+		return true, i.publish(ctx, i.RouteQueues[r.Name], i.Args, priority)
+	}
+
+	return false, nil
+}
+
+// CrawlRoutedFile crawls a file received through a route
+func (i *Indexable) CrawlRoutedFile(ctx context.Context) error {
+	i.routed = true
+	return i.CrawlFile(ctx)
+}
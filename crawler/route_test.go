@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"testing"
+)
+
+func TestRouteMatches(t *testing.T) {
+	r := &Route{Name: "media", MimeTypes: []string{"video/", "audio/"}}
+
+	tests := []struct {
+		mimetype string
+		want     bool
+	}{
+		{"video/mp4", true},
+		{"audio/mpeg", true},
+		{"text/html", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := r.matches(test.mimetype); got != test.want {
+			t.Errorf("matches(%q) = %v, want %v", test.mimetype, got, test.want)
+		}
+	}
+
+	if q := r.Queue(); q != "files-media" {
+		t.Errorf("Queue() = %q, want files-media", q)
+	}
+}
+
+func TestRouteSkipped(t *testing.T) {
+	queues := map[string]*queue.Queue{"media": nil}
+
+	tests := []struct {
+		name   string
+		queues map[string]*queue.Queue
+		size   uint64
+		routed bool
+	}{
+		{"no route queues", nil, 10, false},
+		{"empty file", queues, 0, false},
+		{"received through route", queues, 10, true},
+	}
+
+	for _, test := range tests {
+		i := &Indexable{
+			Crawler: &Crawler{RouteQueues: test.queues},
+			Args:    &Args{Hash: "hash", Size: test.size},
+			routed:  test.routed,
+		}
+
+		routed, err := i.route(context.Background(), &existingItem{})
+		if routed || err != nil {
+			t.Errorf("%s: route() = %v, %v, want not routed", test.name, routed, err)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package crawler
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule tags items matching all of its conditions at index time, so
+// operators can categorize content without code changes, e.g. tag
+// everything under a root as "dataset". Empty conditions match any item.
+type Rule struct {
+	MimeTypes []string                  // Prefixes of content types, e.g. text/
+	MinSize   uint64                    // Minimum size in bytes
+	MaxSize   uint64                    // Maximum size in bytes, 0 for none
+	Names     []string                  // Patterns on names of the item, e.g. *.csv
+	Roots     []string                  // Hashes of directories the item was found under
+	Metadata  map[string]*regexp.Regexp // Patterns on values of extracted metadata fields
+
+	Tags   []string          // Tags added to matching items
+	Labels map[string]string // Fields set under labels on matching items, e.g. category: data
+}
+
+// ruleItem contains the properties of an item rules are evaluated on
+type ruleItem struct {
+	mimetype string
+	size     uint64
+	names    []string
+	roots    map[string]bool
+	metadata map[string]interface{}
+}
+
+// anyPrefix returns whether s has any of prefixes
+func anyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesName returns whether any of names matches any of the patterns
+func matchesName(names, patterns []string) bool {
+	for _, name := range names {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchesMetadata returns whether every field has a value matching its
+// pattern
+func matchesMetadata(fields map[string]interface{}, patterns map[string]*regexp.Regexp) bool {
+	for field, pattern := range patterns {
+		var values []interface{}
+		switch v := fields[field].(type) {
+		case string:
+			values = []interface{}{v}
+		case []interface{}:
+			values = v
+		}
+
+		matched := false
+		for _, value := range values {
+			if s, ok := value.(string); ok && pattern.MatchString(s) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matches returns whether an item satisfies all conditions of the rule
+func (r *Rule) matches(item *ruleItem) bool {
+	if len(r.MimeTypes) > 0 && !anyPrefix(item.mimetype, r.MimeTypes) {
+		return false
+	}
+
+	if item.size < r.MinSize || (r.MaxSize > 0 && item.size > r.MaxSize) {
+		return false
+	}
+
+	if len(r.Names) > 0 && !matchesName(item.names, r.Names) {
+		return false
+	}
+
+	if len(r.Roots) > 0 {
+		found := false
+		for _, root := range r.Roots {
+			if item.roots[root] {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return matchesMetadata(item.metadata, r.Metadata)
+}
+
+// ruleItem returns the properties of this item rules are evaluated on,
+// with given extracted metadata and size
+func (i *existingItem) ruleItem(m metadata, size uint64) *ruleItem {
+	item := &ruleItem{
+		size:  size,
+		names: i.references.Names(),
+		roots: make(map[string]bool),
+	}
+
+	if i.Name != "" {
+		item.names = append(item.names, i.Name)
+	}
+
+	if i.ParentHash != "" {
+		item.roots[i.ParentHash] = true
+	}
+	for _, r := range i.references {
+		item.roots[r.ParentHash] = true
+	}
+	for _, root := range i.provenance().Roots {
+		item.roots[root] = true
+	}
+
+	item.metadata, _ = m["metadata"].(map[string]interface{})
+
+	item.mimetype, _ = m["mimetype"].(string)
+	if item.mimetype == "" {
+		item.mimetype = firstValue(item.metadata, "Content-Type")
+	}
+
+	return item
+}
+
+// addTags sets the tags and labels of the rules matching this item, with
+// given extracted metadata and size, on properties
+func (i *existingItem) addTags(properties metadata, m metadata, size uint64) {
+	if len(i.Config.Rules) == 0 {
+		return
+	}
+
+	item := i.ruleItem(m, size)
+
+	seen := make(map[string]bool)
+	tags := []string{}
+	labels := make(map[string]string)
+
+	for n := range i.Config.Rules {
+		r := &i.Config.Rules[n]
+		if !r.matches(item) {
+			continue
+		}
+
+		for _, tag := range r.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+
+		for k, v := range r.Labels {
+			labels[k] = v
+		}
+	}
+
+	sort.Strings(tags)
+
+	// Set even when empty, removing tags of rules no longer matching
+	properties["tags"] = tags
+	properties["labels"] = labels
+}
@@ -0,0 +1,85 @@
+package crawler
+
+import (
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestRuleMatches(t *testing.T) {
+	item := &ruleItem{
+		mimetype: "text/csv",
+		size:     2000,
+		names:    []string{"data.csv"},
+		roots:    map[string]bool{"root": true},
+		metadata: map[string]interface{}{
+			"Content-Language": []interface{}{"en"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{"empty", Rule{}, true},
+		{"mimetype", Rule{MimeTypes: []string{"text/"}}, true},
+		{"other mimetype", Rule{MimeTypes: []string{"video/"}}, false},
+		{"size", Rule{MinSize: 1000, MaxSize: 3000}, true},
+		{"too small", Rule{MinSize: 3000}, false},
+		{"too large", Rule{MaxSize: 1000}, false},
+		{"name", Rule{Names: []string{"*.json", "*.csv"}}, true},
+		{"other name", Rule{Names: []string{"*.json"}}, false},
+		{"root", Rule{Roots: []string{"other", "root"}}, true},
+		{"other root", Rule{Roots: []string{"other"}}, false},
+		{"metadata", Rule{Metadata: map[string]*regexp.Regexp{"Content-Language": regexp.MustCompile("^en")}}, true},
+		{"other metadata", Rule{Metadata: map[string]*regexp.Regexp{"Content-Language": regexp.MustCompile("^de")}}, false},
+		{"missing metadata", Rule{Metadata: map[string]*regexp.Regexp{"title": regexp.MustCompile("")}}, false},
+		{"all", Rule{MimeTypes: []string{"text/"}, Names: []string{"*.csv"}, Roots: []string{"other"}}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.rule.matches(item); got != test.want {
+			t.Errorf("%s: matches() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAddTags(t *testing.T) {
+	rules := []Rule{
+		{Roots: []string{"root"}, Tags: []string{"dataset"}, Labels: map[string]string{"category": "data"}},
+		{Names: []string{"*.csv"}, Tags: []string{"table", "dataset"}},
+		{MimeTypes: []string{"video/"}, Tags: []string{"video"}},
+	}
+
+	i := &existingItem{
+		Indexable: &Indexable{
+			Crawler: &Crawler{Config: &Config{Rules: rules}},
+			Args: &Args{
+				Hash:       "hash",
+				Name:       "data.csv",
+				ParentHash: "parent",
+				Provenance: &indexer.Provenance{Roots: []string{"root", "parent"}},
+			},
+		},
+	}
+
+	properties := make(metadata)
+	i.addTags(properties, metadata{"mimetype": "text/csv"}, 10)
+
+	if tags := properties["tags"]; !reflect.DeepEqual(tags, []string{"dataset", "table"}) {
+		t.Errorf("addTags() tags = %v, want [dataset table]", tags)
+	}
+	if labels := properties["labels"]; !reflect.DeepEqual(labels, map[string]string{"category": "data"}) {
+		t.Errorf("addTags() labels = %v, want category: data", labels)
+	}
+
+	// Without rules, tags are left alone
+	i.Config.Rules = nil
+	properties = make(metadata)
+	i.addTags(properties, nil, 10)
+	if _, ok := properties["tags"]; ok {
+		t.Errorf("addTags() without rules = %v", properties)
+	}
+}
@@ -0,0 +1,26 @@
+package crawler
+
+// skipContents returns whether files are indexed by name and size only,
+// without fetching their contents: in structure-only mode, set globally
+// or for the task the item was found through
+func (i *Indexable) skipContents() bool {
+	return i.Config.StructureOnly || i.StructureOnly
+}
+
+// enrich returns whether the item was indexed in structure-only mode and
+// is now crawled in full, so its contents are indexed after all
+func (i *existingItem) enrich() bool {
+	return i.structureOnly && !i.skipContents()
+}
+
+// addStructureOnly marks new items indexed in structure-only mode on
+// properties, and clears the mark of items crawled in full. Items indexed
+// in full before keep their contents, so they are not marked.
+func (i *existingItem) addStructureOnly(properties metadata) {
+	switch {
+	case i.skipContents() && !i.exists:
+		properties["structure-only"] = true
+	case i.enrich():
+		properties["structure-only"] = false
+	}
+}
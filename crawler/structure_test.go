@@ -0,0 +1,44 @@
+package crawler
+
+import (
+	"testing"
+)
+
+func TestStructureOnly(t *testing.T) {
+	tests := []struct {
+		global, task  bool // Structure-only mode of the crawler and the task
+		exists        bool
+		structureOnly bool // Indexed in structure-only mode before
+		crawl         bool
+		mark          interface{} // structure-only property set, if any
+	}{
+		{false, false, false, false, true, nil},
+		{false, true, false, false, true, true},
+		{true, false, false, false, true, true},
+		{false, true, true, false, false, nil},
+		{false, true, true, true, false, nil},
+		{false, false, true, true, true, false},
+		{false, false, true, false, false, nil},
+	}
+
+	for n, test := range tests {
+		i := &existingItem{
+			Indexable: &Indexable{
+				Crawler: &Crawler{Config: &Config{StructureOnly: test.global}},
+				Args:    &Args{Hash: "a", StructureOnly: test.task},
+			},
+			exists:        test.exists,
+			structureOnly: test.structureOnly,
+		}
+
+		if got := i.shouldCrawl(); got != test.crawl {
+			t.Errorf("%d: shouldCrawl() = %v, want %v", n, got, test.crawl)
+		}
+
+		properties := make(metadata)
+		i.addStructureOnly(properties)
+		if got := properties["structure-only"]; got != test.mark {
+			t.Errorf("%d: structure-only = %v, want %v", n, got, test.mark)
+		}
+	}
+}
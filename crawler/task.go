@@ -0,0 +1,70 @@
+package crawler
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"time"
+)
+
+// taskSource returns the source of tasks for args, from their provenance
+func taskSource(args *Args) string {
+	if args.Provenance == nil {
+		return ""
+	}
+
+	return args.Provenance.Source
+}
+
+// newTask returns a new task for crawling args, with their provenance as
+// source, partitioned by their hash
+func newTask(args *Args, priority uint8) (*queue.Task, error) {
+	task, err := queue.NewTask(args, priority, taskSource(args))
+	if err != nil {
+		return nil, err
+	}
+
+	task.Partition = partitionKey(args.Hash)
+	return task, nil
+}
+
+// Publish queues args on q in a new task, continuing the trace in ctx
+func Publish(ctx context.Context, q *queue.Queue, args *Args, priority uint8) error {
+	return PublishWithin(ctx, q, args, priority, 0)
+}
+
+// PublishWithin queues args like Publish, with a deadline of ttl from now
+// for the task and those created while performing it; 0 sets none
+func PublishWithin(ctx context.Context, q *queue.Queue, args *Args, priority uint8, ttl time.Duration) error {
+	task, err := newTask(args, priority)
+	if err != nil {
+		return err
+	}
+
+	if ttl > 0 {
+		task.SetTTL(ttl)
+	}
+
+	return q.PublishTask(ctx, task)
+}
+
+// childTask returns the task for crawling args found while crawling this
+// item, sharing its correlation ID and deadline
+func (i *Indexable) childTask(args *Args, priority uint8) (*queue.Task, error) {
+	if i.task == nil {
+		return newTask(args, priority)
+	}
+
+	task, err := i.task.Child(args, priority, taskSource(args))
+	if err != nil {
+		return nil, err
+	}
+
+	task.Partition = partitionKey(args.Hash)
+	return task, nil
+}
+
+// Expired returns whether the deadline of the task the item was received
+// in has passed
+func (i *Indexable) Expired() bool {
+	return i.task != nil && i.task.Expired()
+}
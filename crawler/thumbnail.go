@@ -0,0 +1,23 @@
+package crawler
+
+import (
+	"context"
+)
+
+// addThumbnail sets the stored thumbnail of images and videos on
+// properties. Failure to generate one does not prevent indexing.
+func (i *Indexable) addThumbnail(ctx context.Context, properties metadata) {
+	if !i.Thumbnails.Supported(i.mimetype) {
+		return
+	}
+
+	t, err := i.Thumbnails.Generate(ctx, i.Hash, i.mimetype, i.Size)
+	if err != nil {
+		i.log().WithError(err).Warn("Error generating thumbnail")
+		return
+	}
+
+	if t != nil {
+		properties["thumbnail"] = t
+	}
+}
@@ -0,0 +1,23 @@
+package crawler
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts a span for an operation on this item
+func (i *Indexable) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		attribute.String("hash", i.Hash),
+		attribute.Int64("size", int64(i.Size)),
+		attribute.Int("depth", int(i.Depth)),
+	}
+
+	if i.task != nil {
+		attributes = append(attributes, attribute.String("task", i.task.ID))
+	}
+
+	return tracing.Start(ctx, name, attributes...)
+}
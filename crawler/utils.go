@@ -1,6 +1,7 @@
 package crawler
 
 import (
+	"context"
 	"time"
 )
 
@@ -8,3 +9,13 @@ import (
 func nowISO() string {
 	return time.Now().UTC().Format(time.RFC3339)
 }
+
+// sleep waits for d, returning the context's error when it is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"mime"
+	"path"
+	"strings"
+)
+
+// largeFile is the size above which extraction is considered expensive
+const largeFile = 100 * 1024 * 1024
+
+// valuableTypes are content types most useful to search for, by prefix
+var valuableTypes = []string{
+	"text/html",
+	"application/pdf",
+	"application/epub",
+	"application/msword",
+	"application/vnd.openxmlformats-officedocument",
+	"application/vnd.oasis.opendocument",
+	"text/",
+}
+
+// blobTypes are content types yielding little besides a file name, by prefix
+var blobTypes = []string{
+	"application/octet-stream",
+	"application/zip",
+	"application/x-",
+	"video/",
+}
+
+// hasPrefix returns whether s starts with any of prefixes
+func hasPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// value estimates how valuable extracting a file is, from -2 to 3, based
+// on signals available before fetching it: the content type suggested by
+// its name, its size, how it was found and how often it is referenced
+func (a *Args) value(references int) int {
+	value := 0
+
+	contentType := mime.TypeByExtension(path.Ext(a.Name))
+	switch {
+	case contentType == "":
+		// Unnamed or unknown; most likely a blob
+		value--
+	case hasPrefix(contentType, valuableTypes):
+		value++
+	case hasPrefix(contentType, blobTypes):
+		value--
+	}
+
+	if a.Size > largeFile {
+		value--
+	}
+
+	if references > 1 {
+		value++
+	}
+
+	if a.Provenance != nil {
+		switch a.Provenance.Source {
+		case SourceAdd, SourceAPI, SourceIngest:
+			// Explicitly requested
+			value++
+		}
+	}
+
+	return value
+}
+
+// FilePriority returns the queue priority for extracting a file: its
+// priority by depth, raised or lowered by its expected value, so valuable
+// documents are extracted first when there is a backlog
+func (a *Args) FilePriority(references int) uint8 {
+	priority := int(a.Priority()) + a.value(references)
+
+	if priority < 1 {
+		return 1
+	}
+	if priority > rootPriority {
+		return rootPriority
+	}
+
+	return uint8(priority)
+}
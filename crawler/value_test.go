@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"testing"
+)
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       Args
+		references int
+		want       int
+	}{
+		{"unnamed", Args{}, 0, -1},
+		{"document", Args{Name: "paper.pdf"}, 0, 1},
+		{"blob", Args{Name: "archive.zip"}, 0, -1},
+		{"large blob", Args{Name: "archive.zip", Size: largeFile + 1}, 0, -2},
+		{"referenced", Args{Name: "index.html"}, 2, 2},
+		{"sniffed", Args{Name: "index.html", Provenance: &indexer.Provenance{Source: SourceSniffer}}, 0, 1},
+		{"requested", Args{Name: "index.html", Provenance: &indexer.Provenance{Source: SourceAPI}}, 2, 3},
+	}
+
+	for _, test := range tests {
+		if got := test.args.value(test.references); got != test.want {
+			t.Errorf("%s: value(%d) = %d, want %d", test.name, test.references, got, test.want)
+		}
+	}
+}
+
+func TestFilePriority(t *testing.T) {
+	tests := []struct {
+		name string
+		args Args
+		want uint8
+	}{
+		{"root document", Args{Name: "paper.pdf", Provenance: &indexer.Provenance{Source: SourceAdd}}, rootPriority},
+		{"shallow document", Args{Name: "paper.pdf", Depth: 2}, 8},
+		{"deep blob", Args{Depth: 20}, 1},
+	}
+
+	for _, test := range tests {
+		if got := test.args.FilePriority(0); got != test.want {
+			t.Errorf("%s: FilePriority() = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
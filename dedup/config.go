@@ -0,0 +1,12 @@
+package dedup
+
+import (
+	"time"
+)
+
+// Config contains user configurable options for the recently seen cache
+type Config struct {
+	Size     int           // Maximum amount of keys kept in memory, 0 disables the local cache
+	TTL      time.Duration // Time a key is remembered
+	RedisURL string        // Redis server shared between crawlers, used instead of the local cache if set
+}
@@ -0,0 +1,24 @@
+// Package dedup remembers recently queued and crawled hashes, so duplicates
+// can be skipped without a round-trip to the index.
+package dedup
+
+// Cache holds keys seen recently, until they expire
+type Cache interface {
+	// Contains returns whether key has been added and not yet expired
+	Contains(key string) (bool, error)
+	// Add marks key as seen
+	Add(key string) error
+}
+
+// New returns the cache for config, or nil when none is configured
+func New(config *Config) Cache {
+	if config.RedisURL != "" {
+		return newRedis(config.RedisURL, config.TTL)
+	}
+
+	if config.Size > 0 {
+		return newLRU(config.Size, config.TTL)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package dedup
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru is an in-memory cache evicting the least recently added keys
+type lru struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Front is most recently added
+}
+
+type lruEntry struct {
+	key     string
+	expires time.Time
+}
+
+func newLRU(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// remove removes an element; must be called with lock held
+func (c *lru) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*lruEntry).key)
+}
+
+// Contains returns whether key has been added and not yet expired
+func (c *lru) Contains(key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return false, nil
+	}
+
+	if time.Now().After(e.Value.(*lruEntry).expires) {
+		c.remove(e)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Add marks key as seen, evicting the oldest key when full
+func (c *lru) Add(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruEntry).expires = expires
+		c.order.MoveToFront(e)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, expires: expires})
+
+	if c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	tests := []struct {
+		name     string
+		added    []string
+		ttl      time.Duration
+		key      string
+		contains bool
+	}{
+		{"empty", nil, time.Hour, "a", false},
+		{"added", []string{"a"}, time.Hour, "a", true},
+		{"other", []string{"a"}, time.Hour, "b", false},
+		{"evicted", []string{"a", "b", "c"}, time.Hour, "a", false},
+		{"kept", []string{"a", "b", "c"}, time.Hour, "b", true},
+		{"re-added", []string{"a", "b", "a", "c"}, time.Hour, "a", true},
+		{"evicted after re-adding other", []string{"a", "b", "a", "c"}, time.Hour, "b", false},
+		{"expired", []string{"a"}, -time.Second, "a", false},
+	}
+
+	for _, test := range tests {
+		c := newLRU(2, test.ttl)
+		for _, key := range test.added {
+			if err := c.Add(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		contains, err := c.Contains(test.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contains != test.contains {
+			t.Errorf("%s: Contains(%s) = %v, want %v", test.name, test.key, contains, test.contains)
+		}
+		if len(c.entries) != c.order.Len() || len(c.entries) > 2 {
+			t.Errorf("%s: %d entries, %d ordered", test.name, len(c.entries), c.order.Len())
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		config Config
+		want   string
+	}{
+		{Config{}, "none"},
+		{Config{Size: 10, TTL: time.Hour}, "lru"},
+		{Config{Size: 10, RedisURL: "redis://localhost:6379/0"}, "redis"},
+	}
+
+	for _, test := range tests {
+		got := "none"
+		switch New(&test.config).(type) {
+		case *lru:
+			got = "lru"
+		case *redisCache:
+			got = "redis"
+		}
+
+		if got != test.want {
+			t.Errorf("New(%+v) = %s, want %s", test.config, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package dedup
+
+import (
+	"github.com/gomodule/redigo/redis"
+	"time"
+)
+
+// keyPrefix namespaces keys in a shared Redis database
+const keyPrefix = "ipfs-search:seen:"
+
+// redisCache keeps keys in Redis, shared between crawlers, expiring them
+// server side
+type redisCache struct {
+	pool *redis.Pool
+	ttl  time.Duration
+}
+
+func newRedis(url string, ttl time.Duration) *redisCache {
+	return &redisCache{
+		pool: &redis.Pool{
+			MaxIdle:     10,
+			IdleTimeout: 5 * time.Minute,
+			Dial: func() (redis.Conn, error) {
+				return redis.DialURL(url)
+			},
+		},
+		ttl: ttl,
+	}
+}
+
+// Contains returns whether key has been added and not yet expired
+func (c *redisCache) Contains(key string) (bool, error) {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("EXISTS", keyPrefix+key))
+}
+
+// Add marks key as seen
+func (c *redisCache) Add(key string) error {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("SET", keyPrefix+key, 1, "PX", c.ttl.Nanoseconds()/int64(time.Millisecond))
+	return err
+}
@@ -0,0 +1,12 @@
+package denylist
+
+import (
+	"time"
+)
+
+// Config contains user configurable options for the denylist
+type Config struct {
+	Sources         []string      // Files or HTTP(S) URLs of denylists
+	RefreshInterval time.Duration // Time between reloading sources
+	ErasureFile     string        // Local file erased CIDs are added to, read as a source as well; optional
+}
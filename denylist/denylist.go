@@ -0,0 +1,298 @@
+// Package denylist blocks CIDs from being crawled and indexed, e.g. for
+// DMCA or abuse compliance.
+//
+// Sources are files or HTTP(S) URLs, either in Bad Bits JSON format, an
+// array of objects with an "anchor" (hex SHA-256 of "<base32 CIDv1>/"),
+// or text with a CID or anchor prefixed by // on every line.
+package denylist
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/cids"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const fetchTimeout = time.Minute
+
+// Denylist holds denied CIDs, plainly or as anchors
+type Denylist struct {
+	config *Config
+
+	mu      sync.RWMutex
+	hashes  map[string]bool // Base32 CIDv1 representation
+	anchors map[string]bool
+}
+
+// New returns an empty denylist for given configuration; sources are
+// read by Load
+func New(config *Config) *Denylist {
+	return &Denylist{
+		config:  config,
+		hashes:  make(map[string]bool),
+		anchors: make(map[string]bool),
+	}
+}
+
+// v1 returns the base32 CIDv1 representation of a hash, which is
+// independent of the version and encoding it was given in
+func v1(hash string) (string, error) {
+	c, err := cids.Decode(hash)
+	if err != nil {
+		return "", err
+	}
+
+	return cid.NewCidV1(c.Type(), c.Hash()).StringOfBase(multibase.Base32)
+}
+
+// anchor returns the Bad Bits anchor for a base32 CIDv1
+func anchor(v1 string) string {
+	sum := sha256.Sum256([]byte(v1 + "/"))
+	return hex.EncodeToString(sum[:])
+}
+
+// Contains returns whether a hash is denied, regardless of its CID version
+// or encoding
+func (d *Denylist) Contains(hash string) bool {
+	key, err := v1(hash)
+	if err != nil {
+		return false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.hashes[key] || d.anchors[anchor(key)]
+}
+
+// Hashes returns the plainly listed CIDs; anchored entries can only be
+// matched against known CIDs
+func (d *Denylist) Hashes() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	hashes := make([]string, 0, len(d.hashes))
+	for hash := range d.hashes {
+		hashes = append(hashes, hash)
+	}
+
+	return hashes
+}
+
+// entries holds parsed denylist entries
+type entries struct {
+	hashes  map[string]bool
+	anchors map[string]bool
+}
+
+// add adds an entry: an anchor prefixed by //, or a CID optionally
+// prefixed by /ipfs/
+func (e *entries) add(entry string) error {
+	if strings.HasPrefix(entry, "//") {
+		e.anchors[strings.ToLower(strings.TrimPrefix(entry, "//"))] = true
+		return nil
+	}
+
+	hash, err := v1(strings.TrimPrefix(entry, "/ipfs/"))
+	if err != nil {
+		return fmt.Errorf("invalid entry '%s': %v", entry, err)
+	}
+
+	e.hashes[hash] = true
+	return nil
+}
+
+// parse reads entries in Bad Bits JSON or text format
+func (e *entries) parse(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	first, err := br.Peek(1)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if first[0] == '[' {
+		var items []struct {
+			Anchor string `json:"anchor"`
+		}
+		if err := json.NewDecoder(br).Decode(&items); err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			e.anchors[strings.ToLower(item.Anchor)] = true
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(br)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := e.add(line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// open returns a reader for a file or HTTP(S) source
+func open(ctx context.Context, source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status fetching %s: %s", source, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// sources returns the configured sources and the erasure file, if any
+func (d *Denylist) sources() []string {
+	if d.config.ErasureFile == "" {
+		return d.config.Sources
+	}
+
+	return append(append([]string{}, d.config.Sources...), d.config.ErasureFile)
+}
+
+// Deny adds hash to the erasure file, with a comment, unless already
+// denied. Crawlers deny it once they reload their denylist.
+func (d *Denylist) Deny(hash string, comment string) error {
+	if d.config.ErasureFile == "" {
+		return fmt.Errorf("no denylist erasure file configured")
+	}
+
+	key, err := v1(hash)
+	if err != nil {
+		return fmt.Errorf("invalid hash '%s': %v", hash, err)
+	}
+
+	if d.Contains(hash) {
+		return nil
+	}
+
+	f, err := os.OpenFile(d.config.ErasureFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	line := key + "\n"
+	if comment != "" {
+		line = "# " + strings.Replace(comment, "\n", " ", -1) + "\n" + line
+	}
+
+	if _, err := f.WriteString(line); err != nil {
+		f.Close()
+		return err
+	}
+
+	// Sync, as the content is removed from the index once denied
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.hashes[key] = true
+	d.mu.Unlock()
+
+	return nil
+}
+
+// Load reads all sources, replacing the current entries only when all of
+// them could be read
+func (d *Denylist) Load(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	e := &entries{
+		hashes:  make(map[string]bool),
+		anchors: make(map[string]bool),
+	}
+
+	for _, source := range d.sources() {
+		r, err := open(ctx, source)
+		if os.IsNotExist(err) && source == d.config.ErasureFile {
+			// Nothing has been erased yet
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		err = e.parse(r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("error reading denylist %s: %v", source, err)
+		}
+	}
+
+	d.mu.Lock()
+	d.hashes, d.anchors = e.hashes, e.anchors
+	d.mu.Unlock()
+
+	log.WithFields(log.Fields{
+		"hashes":  len(e.hashes),
+		"anchors": len(e.anchors),
+	}).Info("Loaded denylist")
+
+	return nil
+}
+
+// Watch reloads sources periodically until the context is cancelled.
+// On errors, the previous entries are kept.
+func (d *Denylist) Watch(ctx context.Context) {
+	if len(d.sources()) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(d.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Load(ctx); err != nil {
+				log.WithError(err).Error("Error reloading denylist")
+			}
+		}
+	}
+}
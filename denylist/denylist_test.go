@@ -0,0 +1,52 @@
+package denylist
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeny(t *testing.T) {
+	const v0 = "QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX"
+	const v1 = "bafybeibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq"
+
+	path := filepath.Join(t.TempDir(), "erased")
+	d := New(&Config{ErasureFile: path})
+
+	// A missing erasure file is empty
+	if err := d.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Deny(v0, "request 1\nby mail"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deny(v1, "again"); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Contains(v1) {
+		t.Errorf("Contains(%s) = false after Deny()", v1)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# request 1 by mail\n" + v1 + "\n"; string(data) != want {
+		t.Errorf("erasure file %q, want %q", data, want)
+	}
+
+	reloaded := New(&Config{ErasureFile: path})
+	if err := reloaded.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.Contains(v0) {
+		t.Errorf("Contains(%s) = false after reloading", v0)
+	}
+
+	if err := New(&Config{}).Deny(v0, ""); err == nil || !strings.Contains(err.Error(), "erasure file") {
+		t.Errorf("Deny() without erasure file error %v", err)
+	}
+}
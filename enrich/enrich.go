@@ -0,0 +1,123 @@
+/*
+Package enrich adds properties to files from external enrichment services,
+e.g. an nsfw_score from an image classifier, after metadata extraction.
+Services are plugged in through configuration.
+
+A service receives POST requests with a JSON body describing a file:
+
+	{"hash": "Qm...", "name": "cat.jpg", "mimetype": "image/jpeg", "size": 1234,
+	 "path": "/ipfs/Qm...", "url": "http://localhost:8080/ipfs/Qm..."}
+
+and responds with the properties to set, e.g. {"nsfw_score": 0.02}. Only
+the properties configured for a service are kept, so it can't overwrite
+others.
+*/
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Config configures an enrichment service
+type Config struct {
+	Name       string        // Identifies the service, e.g. nsfw
+	URL        string        // Endpoint of the service
+	MimeTypes  []string      // Prefixes of content types of files sent, all files when empty
+	Fields     []string      // Properties the service may set
+	GatewayURL string        // IPFS gateway files are linked through, e.g. http://localhost:8080; optional
+	Timeout    time.Duration // Time the service has to respond
+}
+
+// File describes a file to enrich
+type File struct {
+	Hash     string `json:"hash"`
+	Name     string `json:"name,omitempty"`
+	Mimetype string `json:"mimetype,omitempty"`
+	Size     uint64 `json:"size"`
+	Path     string `json:"path"`
+	URL      string `json:"url,omitempty"`
+}
+
+// Enricher requests properties of files from a service
+type Enricher struct {
+	config *Config
+	client *http.Client
+}
+
+// New returns an enricher for configuration
+func New(config *Config) *Enricher {
+	return &Enricher{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Name returns the name of the service
+func (e *Enricher) Name() string {
+	return e.config.Name
+}
+
+// Supported returns whether files of a content type are enriched
+func (e *Enricher) Supported(mimetype string) bool {
+	if len(e.config.MimeTypes) == 0 {
+		return true
+	}
+
+	for _, prefix := range e.config.MimeTypes {
+		if strings.HasPrefix(mimetype, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Enrich returns the properties the service sets for f, leaving out those
+// not configured
+func (e *Enricher) Enrich(ctx context.Context, f *File) (map[string]interface{}, error) {
+	f.Path = "/ipfs/" + f.Hash
+	if e.config.GatewayURL != "" {
+		f.URL = strings.TrimSuffix(e.config.GatewayURL, "/") + f.Path
+	}
+
+	body, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("undesired status '%s' from %s", resp.Status, e.config.Name)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %v", e.config.Name, err)
+	}
+
+	properties := make(map[string]interface{})
+	for _, field := range e.config.Fields {
+		if v, ok := response[field]; ok && v != nil {
+			properties[field] = v
+		}
+	}
+
+	return properties, nil
+}
@@ -0,0 +1,82 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnrich(t *testing.T) {
+	var received File
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err)
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"nsfw_score": 0.25,
+			"size":       1,
+		})
+	}))
+	defer server.Close()
+
+	e := New(&Config{
+		Name:       "nsfw",
+		URL:        server.URL,
+		Fields:     []string{"nsfw_score", "labels"},
+		GatewayURL: "http://localhost:8080/",
+		Timeout:    time.Second,
+	})
+
+	properties, err := e.Enrich(context.Background(), &File{Hash: "QmHash", Mimetype: "image/png", Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := File{Hash: "QmHash", Mimetype: "image/png", Size: 10, Path: "/ipfs/QmHash", URL: "http://localhost:8080/ipfs/QmHash"}
+	if received != want {
+		t.Errorf("service received %+v, want %+v", received, want)
+	}
+
+	// Unconfigured properties are left out
+	if !reflect.DeepEqual(properties, map[string]interface{}{"nsfw_score": 0.25}) {
+		t.Errorf("Enrich() = %v", properties)
+	}
+}
+
+func TestEnrichError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	e := New(&Config{Name: "nsfw", URL: server.URL, Fields: []string{"nsfw_score"}, Timeout: time.Second})
+
+	if properties, err := e.Enrich(context.Background(), &File{Hash: "QmHash"}); err == nil {
+		t.Errorf("Enrich() = %v, want error", properties)
+	}
+}
+
+func TestSupported(t *testing.T) {
+	tests := []struct {
+		mimetypes []string
+		mimetype  string
+		want      bool
+	}{
+		{nil, "text/plain", true},
+		{[]string{"image/", "video/"}, "image/jpeg", true},
+		{[]string{"image/", "video/"}, "video/mp4", true},
+		{[]string{"image/", "video/"}, "application/pdf", false},
+	}
+
+	for _, test := range tests {
+		e := New(&Config{MimeTypes: test.mimetypes})
+		if got := e.Supported(test.mimetype); got != test.want {
+			t.Errorf("Supported(%q) with %v = %v, want %v", test.mimetype, test.mimetypes, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package extractor
+
+import (
+	"strings"
+)
+
+// Capability records that an extractor handles content types, as of a
+// version of the capability registry
+type Capability struct {
+	Version   int      // Of the registry the capability was added in
+	Extractor string   // Name of the extractor handling the content types
+	MimeTypes []string // Prefixes of content types handled; any when empty
+}
+
+// Capabilities is the registry of content types handled by extractors.
+// Support for new content types, or extracting more from them, is
+// registered with the next version of the registry; files of those types
+// indexed with an earlier version are then queued for extraction again.
+// Files indexed before the registry existed count as version 1.
+var Capabilities = []Capability{
+	{1, "tika", nil},
+	{1, "images", []string{"image/"}},
+	{1, "media", []string{"audio/", "video/", "application/ogg"}},
+	{1, "pdf", []string{"application/pdf"}},
+}
+
+// Handles returns whether the capability handles a content type
+func (c *Capability) Handles(mimetype string) bool {
+	if len(c.MimeTypes) == 0 {
+		return true
+	}
+
+	for _, prefix := range c.MimeTypes {
+		if strings.HasPrefix(mimetype, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Version returns the version of the registry files of a content type are
+// extracted with: that of the latest capability handling it
+func Version(mimetype string) int {
+	version := 1
+	for _, c := range Capabilities {
+		if c.Version > version && c.Handles(mimetype) {
+			version = c.Version
+		}
+	}
+
+	return version
+}
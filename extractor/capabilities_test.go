@@ -0,0 +1,32 @@
+package extractor
+
+import (
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	registered := Capabilities
+	defer func() { Capabilities = registered }()
+
+	Capabilities = append(Capabilities,
+		Capability{2, "tika", []string{"application/epub+zip"}},
+		Capability{3, "images", []string{"image/heic"}},
+	)
+
+	tests := []struct {
+		mimetype string
+		want     int
+	}{
+		{"text/plain", 1},
+		{"", 1},
+		{"application/epub+zip", 2},
+		{"image/heic", 3},
+		{"image/png", 1},
+	}
+
+	for _, test := range tests {
+		if got := Version(test.mimetype); got != test.want {
+			t.Errorf("Version(%q) = %d, want %d", test.mimetype, got, test.want)
+		}
+	}
+}
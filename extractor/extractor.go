@@ -0,0 +1,13 @@
+/*
+Package extractor defines the interface for metadata extraction from IPFS resources.
+*/
+package extractor
+
+import (
+	"context"
+)
+
+// Extractor extracts metadata for a resource at an IPFS path of given size
+type Extractor interface {
+	Extract(ctx context.Context, path string, size uint64) (map[string]interface{}, error)
+}
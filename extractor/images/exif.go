@@ -0,0 +1,236 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// EXIF tags read from images
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+)
+
+// typeSizes are the sizes in bytes of TIFF field types
+var typeSizes = map[uint16]uint32{
+	1:  1, // BYTE
+	2:  1, // ASCII
+	3:  2, // SHORT
+	4:  4, // LONG
+	5:  8, // RATIONAL
+	7:  1, // UNDEFINED
+	9:  4, // SLONG
+	10: 8, // SRATIONAL
+}
+
+// maxEntries limits the entries read from an IFD, as corrupt files may
+// claim any amount
+const maxEntries = 512
+
+// exifHeader precedes TIFF data in JPEG APP1 segments and some WebP files
+var exifHeader = []byte("Exif\x00\x00")
+
+// field is a value in an IFD
+type field struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// tiff is the TIFF structure holding EXIF data
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// newTIFF returns the TIFF structure in data, and the offset of its first
+// IFD
+func newTIFF(data []byte) (*tiff, uint32, error) {
+	data = bytes.TrimPrefix(data, exifHeader)
+	if len(data) < 8 {
+		return nil, 0, fmt.Errorf("EXIF data too short")
+	}
+
+	t := &tiff{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, 0, fmt.Errorf("invalid EXIF byte order")
+	}
+
+	if t.order.Uint16(data[2:]) != 42 {
+		return nil, 0, fmt.Errorf("invalid TIFF header")
+	}
+
+	return t, t.order.Uint32(data[4:]), nil
+}
+
+// ifd returns the fields of the IFD at offset, by tag
+func (t *tiff) ifd(offset uint32) (map[uint16]field, error) {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil, fmt.Errorf("IFD offset out of range")
+	}
+
+	count := int(t.order.Uint16(t.data[offset:]))
+	if count > maxEntries {
+		return nil, fmt.Errorf("IFD with %d entries", count)
+	}
+
+	fields := make(map[uint16]field, count)
+	for n := 0; n < count; n++ {
+		start := uint64(offset) + 2 + uint64(n)*12
+		if start+12 > uint64(len(t.data)) {
+			break
+		}
+		entry := t.data[start : start+12]
+
+		f := field{
+			typ:   t.order.Uint16(entry[2:]),
+			count: t.order.Uint32(entry[4:]),
+		}
+
+		size, ok := typeSizes[f.typ]
+		if !ok {
+			continue
+		}
+
+		length := uint64(size) * uint64(f.count)
+		if length <= 4 {
+			f.value = entry[8 : 8+length]
+		} else {
+			at := uint64(t.order.Uint32(entry[8:]))
+			if at+length > uint64(len(t.data)) {
+				continue
+			}
+			f.value = t.data[at : at+length]
+		}
+
+		fields[t.order.Uint16(entry)] = f
+	}
+
+	return fields, nil
+}
+
+// str returns the value of an ASCII field
+func (t *tiff) str(f field) string {
+	if f.typ != 2 {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.TrimRight(string(f.value), "\x00"))
+}
+
+// long returns the value of a SHORT or LONG field
+func (t *tiff) long(f field) (uint32, bool) {
+	switch {
+	case f.typ == 3 && len(f.value) >= 2:
+		return uint32(t.order.Uint16(f.value)), true
+	case f.typ == 4 && len(f.value) >= 4:
+		return t.order.Uint32(f.value), true
+	}
+
+	return 0, false
+}
+
+// rationals returns the values of a RATIONAL field
+func (t *tiff) rationals(f field) []float64 {
+	if f.typ != 5 {
+		return nil
+	}
+
+	values := make([]float64, 0, f.count)
+	for n := 0; n+8 <= len(f.value); n += 8 {
+		num, denom := t.order.Uint32(f.value[n:]), t.order.Uint32(f.value[n+4:])
+		if denom == 0 {
+			return nil
+		}
+		values = append(values, float64(num)/float64(denom))
+	}
+
+	return values
+}
+
+// coordinate returns a GPS coordinate from degrees, minutes and seconds,
+// negated for the southern or western reference
+func (t *tiff) coordinate(value, ref field) (float64, bool) {
+	dms := t.rationals(value)
+	if len(dms) != 3 {
+		return 0, false
+	}
+
+	c := dms[0] + dms[1]/60 + dms[2]/3600
+	switch t.str(ref) {
+	case "S", "W":
+		c = -c
+	}
+
+	return c, true
+}
+
+// exifDate converts an EXIF date, e.g. 2019:05:04 10:11:12, into the form
+// Tika uses, 2019-05-04T10:11:12
+func exifDate(s string) string {
+	if len(s) != len("2006:01:02 15:04:05") || s[4] != ':' || s[7] != ':' || s[10] != ' ' {
+		return ""
+	}
+
+	return s[:4] + "-" + s[5:7] + "-" + s[8:10] + "T" + s[11:]
+}
+
+// exif returns metadata fields named like Tika's from EXIF data: camera,
+// dates and, unless stripGPS, location
+func exif(data []byte, stripGPS bool) (map[string]string, error) {
+	t, offset, err := newTIFF(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ifd0, err := t.ifd(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			result[key] = value
+		}
+	}
+
+	set("tiff:Make", t.str(ifd0[tagMake]))
+	set("tiff:Model", t.str(ifd0[tagModel]))
+	set("dcterms:modified", exifDate(t.str(ifd0[tagDateTime])))
+
+	if offset, ok := t.long(ifd0[tagExifIFD]); ok {
+		if ifd, err := t.ifd(offset); err == nil {
+			set("exif:DateTimeOriginal", exifDate(t.str(ifd[tagDateTimeOriginal])))
+		}
+	}
+
+	if offset, ok := t.long(ifd0[tagGPSIFD]); ok && !stripGPS {
+		if gps, err := t.ifd(offset); err == nil {
+			lat, latOK := t.coordinate(gps[tagGPSLatitude], gps[tagGPSLatitudeRef])
+			lon, lonOK := t.coordinate(gps[tagGPSLongitude], gps[tagGPSLongitudeRef])
+			if latOK && lonOK {
+				set("geo:lat", fmt.Sprintf("%.6f", lat))
+				set("geo:long", fmt.Sprintf("%.6f", lon))
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,223 @@
+/*
+Package images extracts metadata of JPEG, PNG, GIF and WebP images
+in-process: their dimensions, and camera, dates and location from EXIF.
+Images are the most common files on IPFS, and only their first bytes are
+needed for this, so they don't require a round-trip through Tika.
+*/
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/ipfs/go-ipfs-api"
+	"image"
+	_ "image/gif"  // Register decoder for DecodeConfig
+	_ "image/jpeg" // Register decoder for DecodeConfig
+	_ "image/png"  // Register decoder for DecodeConfig
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// Config configures the extraction of image metadata
+type Config struct {
+	MaxHeader uint64 // Bytes read from the start of images
+	StripGPS  bool   // Leave out locations, for privacy
+}
+
+// Extractor extracts metadata of images read from IPFS
+type Extractor struct {
+	config *Config
+	shell  *shell.Shell
+}
+
+// New returns an extractor for configuration reading images through sh
+func New(config *Config, sh *shell.Shell) *Extractor {
+	return &Extractor{
+		config: config,
+		shell:  sh,
+	}
+}
+
+// mimetypes are the content types of supported images
+var mimetypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Supported returns whether metadata of images of a content type can be
+// extracted
+func Supported(mimetype string) bool {
+	return mimetypes[mimetype]
+}
+
+// head returns the first MaxHeader bytes of the file at path
+func (e *Extractor) head(ctx context.Context, path string) ([]byte, error) {
+	resp, err := e.shell.Request("cat", path).
+		Option("length", e.config.MaxHeader).
+		Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	defer resp.Close()
+
+	return ioutil.ReadAll(io.LimitReader(resp.Output, int64(e.config.MaxHeader)))
+}
+
+// Extract returns the metadata of the image at path, in the form ipfs-tika
+// returns it
+func (e *Extractor) Extract(ctx context.Context, path string, size uint64) (map[string]interface{}, error) {
+	head, err := e.head(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return extract(head, e.config.StripGPS)
+}
+
+// jpegEXIF returns the EXIF data in the APP1 segment of a JPEG image
+func jpegEXIF(data []byte) []byte {
+	for n := 2; n+4 <= len(data); {
+		if data[n] != 0xff {
+			return nil
+		}
+
+		marker := data[n+1]
+		length := int(binary.BigEndian.Uint16(data[n+2:]))
+		if marker == 0xda || n+2+length > len(data) {
+			// Start of scan, after which there is image data only
+			return nil
+		}
+
+		segment := data[n+4 : n+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, exifHeader) {
+			return segment
+		}
+
+		n += 2 + length
+	}
+
+	return nil
+}
+
+// pngEXIF returns the data of the eXIf chunk of a PNG image
+func pngEXIF(data []byte) []byte {
+	for n := 8; n+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[n:]))
+		if length < 0 || n+12+length > len(data) {
+			return nil
+		}
+
+		switch string(data[n+4 : n+8]) {
+		case "eXIf":
+			return data[n+8 : n+8+length]
+		case "IDAT", "IEND":
+			return nil
+		}
+
+		n += 12 + length
+	}
+
+	return nil
+}
+
+// webp returns the dimensions and EXIF data of a WebP image
+func webp(data []byte) (image.Config, []byte, error) {
+	var config image.Config
+	var exif []byte
+
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return config, nil, fmt.Errorf("invalid WebP header")
+	}
+
+	for n := 12; n+8 <= len(data); {
+		length := int(binary.LittleEndian.Uint32(data[n+4:]))
+		if length < 0 || n+8+length > len(data) {
+			break
+		}
+		chunk := data[n+8 : n+8+length]
+
+		switch string(data[n : n+4]) {
+		case "VP8X":
+			if len(chunk) >= 10 {
+				config.Width = int(uint32(chunk[4])|uint32(chunk[5])<<8|uint32(chunk[6])<<16) + 1
+				config.Height = int(uint32(chunk[7])|uint32(chunk[8])<<8|uint32(chunk[9])<<16) + 1
+			}
+		case "VP8 ":
+			if config.Width == 0 && len(chunk) >= 10 {
+				config.Width = int(binary.LittleEndian.Uint16(chunk[6:]) & 0x3fff)
+				config.Height = int(binary.LittleEndian.Uint16(chunk[8:]) & 0x3fff)
+			}
+		case "VP8L":
+			if config.Width == 0 && len(chunk) >= 5 {
+				bits := binary.LittleEndian.Uint32(chunk[1:])
+				config.Width = int(bits&0x3fff) + 1
+				config.Height = int(bits>>14&0x3fff) + 1
+			}
+		case "EXIF":
+			exif = chunk
+		}
+
+		// Chunks are padded to an even length
+		n += 8 + length + length%2
+	}
+
+	if config.Width == 0 {
+		return config, nil, fmt.Errorf("WebP dimensions not found")
+	}
+
+	return config, exif, nil
+}
+
+// extract returns the metadata of an image from its first bytes
+func extract(head []byte, stripGPS bool) (map[string]interface{}, error) {
+	mimetype := http.DetectContentType(head)
+
+	var config image.Config
+	var exifData []byte
+	var err error
+
+	switch mimetype {
+	case "image/webp":
+		config, exifData, err = webp(head)
+	case "image/jpeg":
+		exifData = jpegEXIF(head)
+		config, _, err = image.DecodeConfig(bytes.NewReader(head))
+	case "image/png":
+		exifData = pngEXIF(head)
+		config, _, err = image.DecodeConfig(bytes.NewReader(head))
+	case "image/gif":
+		config, _, err = image.DecodeConfig(bytes.NewReader(head))
+	default:
+		return nil, fmt.Errorf("unsupported image type %s", mimetype)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", mimetype, err)
+	}
+
+	fields := map[string]interface{}{
+		"Content-Type":     []interface{}{mimetype},
+		"tiff:ImageWidth":  []interface{}{strconv.Itoa(config.Width)},
+		"tiff:ImageLength": []interface{}{strconv.Itoa(config.Height)},
+	}
+
+	if exifData != nil {
+		// Images with invalid EXIF are indexed with their dimensions
+		values, _ := exif(exifData, stripGPS)
+		for k, v := range values {
+			fields[k] = []interface{}{v}
+		}
+	}
+
+	return map[string]interface{}{
+		"metadata": fields,
+	}, nil
+}
@@ -0,0 +1,197 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+// ifdEntry is an entry of a test IFD
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte // Stored at the end of the data when longer than 4 bytes
+}
+
+// buildTIFF returns little endian TIFF data with IFD0, an EXIF and a GPS
+// IFD; pointers to the latter are added to IFD0
+func buildTIFF(ifd0, exifIFD, gps []ifdEntry) []byte {
+	le := binary.LittleEndian
+	var data []byte
+	data = append(data, 'I', 'I', 42, 0, 8, 0, 0, 0)
+
+	ifdSize := func(entries []ifdEntry) int { return 2 + 12*len(entries) + 4 }
+
+	// Layout: IFD0, EXIF IFD, GPS IFD, then values
+	exifOffset := 8 + ifdSize(ifd0) + 12*2
+	gpsOffset := exifOffset + ifdSize(exifIFD)
+	valueOffset := gpsOffset + ifdSize(gps)
+
+	long := func(v int) []byte {
+		b := make([]byte, 4)
+		le.PutUint32(b, uint32(v))
+		return b
+	}
+	ifd0 = append(ifd0, ifdEntry{tagExifIFD, 4, 1, long(exifOffset)}, ifdEntry{tagGPSIFD, 4, 1, long(gpsOffset)})
+
+	var values []byte
+	for _, entries := range [][]ifdEntry{ifd0, exifIFD, gps} {
+		b := make([]byte, 2)
+		le.PutUint16(b, uint16(len(entries)))
+		data = append(data, b...)
+
+		for _, e := range entries {
+			entry := make([]byte, 12)
+			le.PutUint16(entry, e.tag)
+			le.PutUint16(entry[2:], e.typ)
+			le.PutUint32(entry[4:], e.count)
+			if len(e.value) <= 4 {
+				copy(entry[8:], e.value)
+			} else {
+				le.PutUint32(entry[8:], uint32(valueOffset+len(values)))
+				values = append(values, e.value...)
+			}
+			data = append(data, entry...)
+		}
+		data = append(data, 0, 0, 0, 0)
+	}
+
+	return append(data, values...)
+}
+
+// ascii returns an ASCII entry
+func ascii(tag uint16, s string) ifdEntry {
+	return ifdEntry{tag, 2, uint32(len(s) + 1), append([]byte(s), 0)}
+}
+
+// rational returns a RATIONAL entry of degrees, minutes and seconds
+func rational(tag uint16, d, m, s uint32) ifdEntry {
+	b := make([]byte, 24)
+	for n, v := range []uint32{d, m, s * 100} {
+		binary.LittleEndian.PutUint32(b[n*8:], v)
+		binary.LittleEndian.PutUint32(b[n*8+4:], 1)
+	}
+	binary.LittleEndian.PutUint32(b[20:], 100)
+	return ifdEntry{tag, 5, 3, b}
+}
+
+// testEXIF returns EXIF data of a camera, with date and location
+func testEXIF() []byte {
+	return buildTIFF(
+		[]ifdEntry{ascii(tagMake, "Canon"), ascii(tagModel, "EOS 5D"), ascii(tagDateTime, "2019:05:06 07:08:09")},
+		[]ifdEntry{ascii(tagDateTimeOriginal, "2019:05:04 10:11:12")},
+		[]ifdEntry{
+			ascii(tagGPSLatitudeRef, "N"), rational(tagGPSLatitude, 52, 22, 12),
+			ascii(tagGPSLongitudeRef, "W"), rational(tagGPSLongitude, 4, 54, 0),
+		},
+	)
+}
+
+// testJPEG returns a JPEG image with an APP1 segment with EXIF data
+func testJPEG(t *testing.T) []byte {
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, image.NewRGBA(image.Rect(0, 0, 40, 30)), nil); err != nil {
+		t.Fatal(err)
+	}
+	encoded := b.Bytes()
+
+	segment := append(append([]byte{}, exifHeader...), testEXIF()...)
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+
+	var result []byte
+	result = append(result, encoded[:2]...)
+	result = append(result, app1...)
+	result = append(result, segment...)
+	return append(result, encoded[2:]...)
+}
+
+// encoded returns an image encoded by encode
+func encoded(t *testing.T, encode func(*bytes.Buffer, image.Image) error) []byte {
+	var b bytes.Buffer
+	if err := encode(&b, image.NewPaletted(image.Rect(0, 0, 20, 10), color.Palette{color.Black, color.White})); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// testWebP returns the header of a lossless WebP image
+func testWebP() []byte {
+	chunk := []byte{0x2f, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(chunk[1:], uint32(99)|uint32(49)<<14)
+
+	data := []byte("RIFF\x00\x00\x00\x00WEBPVP8L\x05\x00\x00\x00")
+	return append(data, chunk...)
+}
+
+func TestExtract(t *testing.T) {
+	pngData := encoded(t, func(b *bytes.Buffer, i image.Image) error { return png.Encode(b, i) })
+	gifData := encoded(t, func(b *bytes.Buffer, i image.Image) error { return gif.Encode(b, i, nil) })
+
+	tests := []struct {
+		name     string
+		head     []byte
+		stripGPS bool
+		want     map[string]string
+	}{
+		{"jpeg", testJPEG(t), false, map[string]string{
+			"Content-Type": "image/jpeg", "tiff:ImageWidth": "40", "tiff:ImageLength": "30",
+			"tiff:Make": "Canon", "tiff:Model": "EOS 5D",
+			"dcterms:modified": "2019-05-06T07:08:09", "exif:DateTimeOriginal": "2019-05-04T10:11:12",
+			"geo:lat": "52.370000", "geo:long": "-4.900000",
+		}},
+		{"jpeg without GPS", testJPEG(t), true, map[string]string{
+			"Content-Type": "image/jpeg", "tiff:ImageWidth": "40", "tiff:ImageLength": "30",
+			"tiff:Make": "Canon", "tiff:Model": "EOS 5D",
+			"dcterms:modified": "2019-05-06T07:08:09", "exif:DateTimeOriginal": "2019-05-04T10:11:12",
+		}},
+		{"png", pngData, false, map[string]string{
+			"Content-Type": "image/png", "tiff:ImageWidth": "20", "tiff:ImageLength": "10",
+		}},
+		{"gif", gifData, false, map[string]string{
+			"Content-Type": "image/gif", "tiff:ImageWidth": "20", "tiff:ImageLength": "10",
+		}},
+		{"webp", testWebP(), false, map[string]string{
+			"Content-Type": "image/webp", "tiff:ImageWidth": "100", "tiff:ImageLength": "50",
+		}},
+	}
+
+	for _, test := range tests {
+		m, err := extract(test.head, test.stripGPS)
+		if err != nil {
+			t.Errorf("%s: extract() error %v", test.name, err)
+			continue
+		}
+
+		want := make(map[string]interface{}, len(test.want))
+		for k, v := range test.want {
+			want[k] = []interface{}{v}
+		}
+		if !reflect.DeepEqual(m["metadata"], want) {
+			t.Errorf("%s: extract() = %v, want %v", test.name, m["metadata"], want)
+		}
+	}
+}
+
+func TestExtractInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+	}{
+		{"text", []byte("plain text")},
+		{"truncated jpeg", testJPEG(t)[:100]},
+	}
+
+	for _, test := range tests {
+		if m, err := extract(test.head, false); err == nil {
+			t.Errorf("%s: extract() = %v, want error", test.name, m)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Package media extracts metadata of audio and video files with ffprobe:
+their duration, bitrate, container format, codecs, resolution and embedded
+tags. ffprobe reads files through an IPFS gateway, so only the parts of
+files holding metadata are transferred.
+*/
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config configures the extraction of media metadata
+type Config struct {
+	FFprobe    string        // Path of the ffprobe executable
+	GatewayURL string        // IPFS gateway files are read from, e.g. http://localhost:8080
+	Timeout    time.Duration // Time ffprobe has to probe a file
+}
+
+// runFunc runs a command, returning its output
+type runFunc func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// run runs a command, returning its output or its error output in errors
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	return out, err
+}
+
+// Extractor extracts metadata of media files with ffprobe
+type Extractor struct {
+	config *Config
+	run    runFunc
+}
+
+// New returns an extractor for configuration
+func New(config *Config) *Extractor {
+	return &Extractor{
+		config: config,
+		run:    run,
+	}
+}
+
+// Supported returns whether metadata of files of a content type can be
+// extracted
+func Supported(mimetype string) bool {
+	return strings.HasPrefix(mimetype, "audio/") || strings.HasPrefix(mimetype, "video/") || mimetype == "application/ogg"
+}
+
+// tagFields map lowercased ffprobe tags to metadata fields named like Tika's
+var tagFields = map[string]string{
+	"title":         "title",
+	"artist":        "xmpDM:artist",
+	"album_artist":  "xmpDM:albumArtist",
+	"album":         "xmpDM:album",
+	"genre":         "xmpDM:genre",
+	"composer":      "xmpDM:composer",
+	"comment":       "description",
+	"description":   "description",
+	"date":          "xmpDM:releaseDate",
+	"creation_time": "dcterms:created",
+}
+
+// probe is the output of ffprobe
+type probe struct {
+	Format struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		BitRate    string            `json:"bit_rate"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType  string            `json:"codec_type"`
+		CodecName  string            `json:"codec_name"`
+		Width      int               `json:"width"`
+		Height     int               `json:"height"`
+		SampleRate string            `json:"sample_rate"`
+		Channels   int               `json:"channels"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"streams"`
+}
+
+// Video describes the first video stream of a file
+type Video struct {
+	Codec  string `json:"codec,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// Audio describes the first audio stream of a file
+type Audio struct {
+	Codec      string `json:"codec,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Channels   int    `json:"channels,omitempty"`
+}
+
+// Media describes the streams of a media file
+type Media struct {
+	Format   string  `json:"format,omitempty"`
+	Duration float64 `json:"duration,omitempty"` // In seconds
+	BitRate  uint64  `json:"bitrate,omitempty"`  // In bits per second
+	Video    *Video  `json:"video,omitempty"`
+	Audio    *Audio  `json:"audio,omitempty"`
+}
+
+// parse returns the media description and metadata fields from ffprobe
+// output
+func parse(output []byte) (*Media, map[string]interface{}, error) {
+	var p probe
+	if err := json.Unmarshal(output, &p); err != nil {
+		return nil, nil, fmt.Errorf("invalid ffprobe output: %v", err)
+	}
+
+	if p.Format.FormatName == "" {
+		return nil, nil, fmt.Errorf("no media format detected")
+	}
+
+	m := &Media{Format: p.Format.FormatName}
+	m.Duration, _ = strconv.ParseFloat(p.Format.Duration, 64)
+	m.BitRate, _ = strconv.ParseUint(p.Format.BitRate, 10, 64)
+
+	fields := make(map[string]interface{})
+	addTags := func(tags map[string]string) {
+		// Sorted, so the same tag wins when several map to a field
+		names := make([]string, 0, len(tags))
+		for tag := range tags {
+			names = append(names, tag)
+		}
+		sort.Strings(names)
+
+		for _, tag := range names {
+			value := tags[tag]
+			field, ok := tagFields[strings.ToLower(tag)]
+			if !ok || strings.TrimSpace(value) == "" {
+				continue
+			}
+			if _, exists := fields[field]; !exists {
+				fields[field] = []interface{}{strings.TrimSpace(value)}
+			}
+		}
+	}
+	addTags(p.Format.Tags)
+
+	for _, s := range p.Streams {
+		switch {
+		case s.CodecType == "video" && m.Video == nil:
+			m.Video = &Video{Codec: s.CodecName, Width: s.Width, Height: s.Height}
+		case s.CodecType == "audio" && m.Audio == nil:
+			m.Audio = &Audio{Codec: s.CodecName, Channels: s.Channels}
+			m.Audio.SampleRate, _ = strconv.Atoi(s.SampleRate)
+		}
+
+		addTags(s.Tags)
+	}
+
+	if m.Duration > 0 {
+		fields["xmpDM:duration"] = []interface{}{strconv.FormatFloat(m.Duration, 'f', -1, 64)}
+	}
+
+	return m, fields, nil
+}
+
+// Extract returns the metadata of the media file at path, with the media
+// description as media
+func (e *Extractor) Extract(ctx context.Context, path string, size uint64) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	url := strings.TrimSuffix(e.config.GatewayURL, "/") + path
+
+	output, err := e.run(ctx, e.config.FFprobe,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format", "-show_streams",
+		url,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe %s: %v", path, err)
+	}
+
+	m, fields, err := parse(output)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"metadata": fields,
+		"media":    m,
+	}, nil
+}
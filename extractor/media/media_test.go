@@ -0,0 +1,106 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testOutput = `{
+	"streams": [
+		{"codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080},
+		{"codec_type": "audio", "codec_name": "aac", "sample_rate": "48000", "channels": 2, "tags": {"language": "eng"}}
+	],
+	"format": {
+		"format_name": "mov,mp4,m4a,3gp,3g2,mj2",
+		"duration": "61.500000",
+		"bit_rate": "4500000",
+		"tags": {"title": "Holiday", "ARTIST": "Jane Doe", "creation_time": "2019-05-04T10:11:12.000000Z", "encoder": "Lavf58"}
+	}
+}`
+
+func TestExtract(t *testing.T) {
+	var args []string
+	e := &Extractor{
+		config: &Config{FFprobe: "ffprobe", GatewayURL: "http://localhost:8080/", Timeout: time.Second},
+		run: func(ctx context.Context, name string, a ...string) ([]byte, error) {
+			args = a
+			return []byte(testOutput), nil
+		},
+	}
+
+	result, err := e.Extract(context.Background(), "/ipfs/hash/video.mp4", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if url := args[len(args)-1]; url != "http://localhost:8080/ipfs/hash/video.mp4" {
+		t.Errorf("Extract() probed %s", url)
+	}
+
+	want := &Media{
+		Format:   "mov,mp4,m4a,3gp,3g2,mj2",
+		Duration: 61.5,
+		BitRate:  4500000,
+		Video:    &Video{Codec: "h264", Width: 1920, Height: 1080},
+		Audio:    &Audio{Codec: "aac", SampleRate: 48000, Channels: 2},
+	}
+	if !reflect.DeepEqual(result["media"], want) {
+		t.Errorf("Extract() media = %+v, want %+v", result["media"], want)
+	}
+
+	fields := map[string]interface{}{
+		"title":           []interface{}{"Holiday"},
+		"xmpDM:artist":    []interface{}{"Jane Doe"},
+		"dcterms:created": []interface{}{"2019-05-04T10:11:12.000000Z"},
+		"xmpDM:duration":  []interface{}{"61.5"},
+	}
+	if !reflect.DeepEqual(result["metadata"], fields) {
+		t.Errorf("Extract() metadata = %v, want %v", result["metadata"], fields)
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		err    error
+	}{
+		{"failing", "", fmt.Errorf("exit status 1")},
+		{"invalid output", "not json", nil},
+		{"no format", `{"streams": [], "format": {}}`, nil},
+	}
+
+	for _, test := range tests {
+		e := &Extractor{
+			config: &Config{Timeout: time.Second},
+			run: func(ctx context.Context, name string, a ...string) ([]byte, error) {
+				return []byte(test.output), test.err
+			},
+		}
+
+		if m, err := e.Extract(context.Background(), "/ipfs/hash", 10); err == nil {
+			t.Errorf("%s: Extract() = %v, want error", test.name, m)
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	tests := []struct {
+		mimetype string
+		want     bool
+	}{
+		{"video/mp4", true},
+		{"audio/mpeg", true},
+		{"application/ogg", true},
+		{"image/jpeg", false},
+	}
+
+	for _, test := range tests {
+		if got := Supported(test.mimetype); got != test.want {
+			t.Errorf("Supported(%q) = %v, want %v", test.mimetype, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+)
+
+// maxDepth limits following references and page tree levels, as corrupt
+// files may have cycles
+const maxDepth = 32
+
+// name is a PDF name, without slash
+type name string
+
+// keyword is a bare word, e.g. a content stream operator
+type keyword string
+
+// ref is an indirect reference to an object
+type ref struct {
+	num int
+	gen int
+}
+
+// dict is a PDF dictionary, by names without slash
+type dict map[string]interface{}
+
+// array is a PDF array
+type array []interface{}
+
+// object is an indirect object, with its decoded stream if it has one
+type object struct {
+	value  interface{}
+	stream []byte
+}
+
+// document holds the objects of a PDF file
+type document struct {
+	objects  map[int]*object
+	trailers []dict // Trailer dictionaries, in file order
+	fonts    map[int]*font
+}
+
+// parseValue parses the value starting with t, returning strings as
+// []byte, numbers as float64 and keywords other than true, false and null
+// as keyword
+func parseValue(l *lexer, t token) interface{} {
+	switch t.kind {
+	case tokenDictStart:
+		d := make(dict)
+		for {
+			k := l.next()
+			if k.kind == tokenDictEnd || k.kind == tokenEOF {
+				return d
+			}
+			if k.kind != tokenName {
+				continue
+			}
+			d[string(k.value)] = parseValue(l, l.next())
+		}
+	case tokenArrayStart:
+		var a array
+		for {
+			v := l.next()
+			if v.kind == tokenArrayEnd || v.kind == tokenEOF {
+				return a
+			}
+			a = append(a, parseValue(l, v))
+		}
+	case tokenName:
+		return name(t.value)
+	case tokenString:
+		return t.value
+	case tokenNumber:
+		// References are two integers followed by R
+		pos := l.pos
+		gen := l.next()
+		if gen.kind == tokenNumber {
+			if r := l.next(); r.kind == tokenKeyword && string(r.value) == "R" {
+				return ref{int(t.number), int(gen.number)}
+			}
+		}
+		l.pos = pos
+		return t.number
+	case tokenKeyword:
+		switch string(t.value) {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return keyword(t.value)
+	}
+
+	return nil
+}
+
+// decode returns stream data decoded by the filters in d
+func decode(d dict, data []byte) ([]byte, error) {
+	var filters []interface{}
+	switch f := d["Filter"].(type) {
+	case name:
+		filters = []interface{}{f}
+	case array:
+		filters = f
+	}
+
+	for _, f := range filters {
+		switch f {
+		case name("FlateDecode"), name("Fl"):
+			data = inflate(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter %v", f)
+		}
+	}
+
+	return data, nil
+}
+
+// inflate decompresses zlib data, returning what could be read from
+// damaged or truncated streams
+func inflate(data []byte) []byte {
+	var r io.Reader
+	if z, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		r = z
+	} else {
+		// Some writers leave out the zlib header
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+
+	decoded, _ := ioutil.ReadAll(r)
+	return decoded
+}
+
+// objectPattern matches the start of indirect objects
+var objectPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// streamData returns the data of the stream starting at pos, with dict d,
+// and the position after it
+func streamData(data []byte, pos int, d dict) ([]byte, int) {
+	// The keyword is followed by CRLF or LF
+	if bytes.HasPrefix(data[pos:], []byte("\r\n")) {
+		pos += 2
+	} else if pos < len(data) && (data[pos] == '\n' || data[pos] == '\r') {
+		pos++
+	}
+
+	if length, ok := d["Length"].(float64); ok && length >= 0 && pos+int(length) <= len(data) {
+		end := pos + int(length)
+		rest := bytes.TrimLeft(data[end:], "\r\n \t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return data[pos:end], end
+		}
+	}
+
+	// Indirect or wrong lengths
+	end := bytes.Index(data[pos:], []byte("endstream"))
+	if end < 0 {
+		// Truncated file
+		return data[pos:], len(data)
+	}
+
+	return bytes.TrimRight(data[pos:pos+end], "\r\n"), pos + end
+}
+
+// parseDocument reads the objects of a PDF file, which may be truncated.
+// Objects are found by scanning rather than through cross-reference
+// tables, which are at the end of files and often damaged.
+func parseDocument(data []byte) (*document, error) {
+	header := bytes.Index(data, []byte("%PDF-"))
+	if header < 0 || header > 1024 {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+
+	doc := &document{
+		objects: make(map[int]*object),
+		fonts:   make(map[int]*font),
+	}
+
+	next := 0
+	for _, m := range objectPattern.FindAllSubmatchIndex(data, -1) {
+		if m[0] < next {
+			// Within the stream of the previous object
+			continue
+		}
+
+		var num int
+		fmt.Sscan(string(data[m[2]:m[3]]), &num)
+
+		l := &lexer{data: data, pos: m[1]}
+		o := &object{value: parseValue(l, l.next())}
+		next = l.pos
+
+		l.skipSpace()
+		if d, ok := o.value.(dict); ok && bytes.HasPrefix(data[l.pos:], []byte("stream")) {
+			var raw []byte
+			raw, next = streamData(data, l.pos+len("stream"), d)
+
+			// Streams with unsupported filters, e.g. images, are left out
+			o.stream, _ = decode(d, raw)
+
+			if d["Type"] == name("XRef") {
+				doc.trailers = append(doc.trailers, d)
+			}
+		}
+
+		doc.objects[num] = o
+	}
+
+	for pos := 0; ; {
+		n := bytes.Index(data[pos:], []byte("trailer"))
+		if n < 0 {
+			break
+		}
+		l := &lexer{data: data, pos: pos + n + len("trailer")}
+		if d, ok := parseValue(l, l.next()).(dict); ok {
+			doc.trailers = append(doc.trailers, d)
+		}
+		pos = l.pos
+	}
+
+	if doc.encrypted() {
+		return nil, fmt.Errorf("encrypted PDF")
+	}
+
+	doc.readObjectStreams()
+
+	return doc, nil
+}
+
+// encrypted returns whether strings and streams in the document are
+// encrypted
+func (doc *document) encrypted() bool {
+	for _, t := range doc.trailers {
+		if _, ok := t["Encrypt"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readObjectStreams adds the objects compressed in object streams, unless
+// defined directly
+func (doc *document) readObjectStreams() {
+	nums := make([]int, 0, len(doc.objects))
+	for num := range doc.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	for _, num := range nums {
+		o := doc.objects[num]
+		d, ok := o.value.(dict)
+		if !ok || d["Type"] != name("ObjStm") || o.stream == nil {
+			continue
+		}
+
+		count, _ := d["N"].(float64)
+		first, _ := d["First"].(float64)
+
+		l := &lexer{data: o.stream}
+		offsets := make(map[int]int, int(count))
+		for n := 0; n < int(count); n++ {
+			objNum, offset := l.next(), l.next()
+			if objNum.kind != tokenNumber || offset.kind != tokenNumber {
+				break
+			}
+			offsets[int(objNum.number)] = int(first + offset.number)
+		}
+
+		for objNum, offset := range offsets {
+			if _, exists := doc.objects[objNum]; exists || offset < 0 || offset >= len(o.stream) {
+				continue
+			}
+
+			l := &lexer{data: o.stream, pos: offset}
+			doc.objects[objNum] = &object{value: parseValue(l, l.next())}
+		}
+	}
+}
+
+// resolve follows references to the value of the object they refer to
+func (doc *document) resolve(v interface{}) interface{} {
+	for n := 0; n < maxDepth; n++ {
+		r, ok := v.(ref)
+		if !ok {
+			return v
+		}
+
+		o, ok := doc.objects[r.num]
+		if !ok {
+			return nil
+		}
+		v = o.value
+	}
+
+	return nil
+}
+
+// dict returns the dictionary v is or refers to, or nil
+func (doc *document) dict(v interface{}) dict {
+	d, _ := doc.resolve(v).(dict)
+	return d
+}
+
+// streams returns the decoded data of the streams v refers to, directly or
+// in an array
+func (doc *document) streams(v interface{}) [][]byte {
+	switch x := v.(type) {
+	case ref:
+		if o, ok := doc.objects[x.num]; ok {
+			if o.stream != nil {
+				return [][]byte{o.stream}
+			}
+			if a, ok := o.value.(array); ok {
+				return doc.streams(a)
+			}
+		}
+	case array:
+		var streams [][]byte
+		for _, element := range x {
+			if r, ok := element.(ref); ok {
+				streams = append(streams, doc.streams(r)...)
+			}
+		}
+		return streams
+	}
+
+	return nil
+}
+
+// trailerValue returns the value of key in the last trailer having it
+func (doc *document) trailerValue(key string) interface{} {
+	for n := len(doc.trailers) - 1; n >= 0; n-- {
+		if v, ok := doc.trailers[n][key]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// pages returns the pages of the document in order, or those found when
+// the page tree is missing, e.g. from truncated files
+func (doc *document) pages() []dict {
+	var pages []dict
+
+	var walk func(node dict, depth int)
+	walk = func(node dict, depth int) {
+		if node == nil || depth > maxDepth {
+			return
+		}
+
+		kids, isTree := doc.resolve(node["Kids"]).(array)
+		if !isTree {
+			pages = append(pages, node)
+			return
+		}
+
+		for _, kid := range kids {
+			walk(doc.dict(kid), depth+1)
+		}
+	}
+
+	if root := doc.dict(doc.trailerValue("Root")); root != nil {
+		walk(doc.dict(root["Pages"]), 0)
+	}
+
+	if len(pages) > 0 {
+		return pages
+	}
+
+	nums := make([]int, 0, len(doc.objects))
+	for num, o := range doc.objects {
+		if d, ok := o.value.(dict); ok && d["Type"] == name("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+
+	for _, num := range nums {
+		pages = append(pages, doc.objects[num].value.(dict))
+	}
+
+	return pages
+}
+
+// inherited returns the value of key on a page or the nearest ancestor
+// having it, e.g. Resources
+func (doc *document) inherited(page dict, key string) interface{} {
+	node := page
+	for n := 0; node != nil && n < maxDepth; n++ {
+		if v, ok := node[key]; ok {
+			return v
+		}
+		node = doc.dict(node["Parent"])
+	}
+
+	return nil
+}
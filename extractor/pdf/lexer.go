@@ -0,0 +1,215 @@
+package pdf
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// tokenKind is the kind of a lexical token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenDictStart
+	tokenDictEnd
+	tokenArrayStart
+	tokenArrayEnd
+	tokenName
+	tokenString
+	tokenNumber
+	tokenKeyword // Including content stream operators
+)
+
+// token is a lexical token of PDF syntax
+type token struct {
+	kind   tokenKind
+	value  []byte // Decoded strings, names without slash, keywords
+	number float64
+}
+
+// lexer splits PDF syntax into tokens
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+// isSpace returns whether c is PDF whitespace
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+// isDelimiter returns whether c is a PDF delimiter
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// skipSpace skips whitespace and comments
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// regular returns the run of regular characters at the position
+func (l *lexer) regular() []byte {
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return l.data[start:l.pos]
+}
+
+// next returns the next token
+func (l *lexer) next() token {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return token{kind: tokenEOF}
+	}
+
+	switch c := l.data[l.pos]; c {
+	case '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return token{kind: tokenDictStart}
+		}
+		return token{kind: tokenString, value: l.hexString()}
+	case '>':
+		l.pos++
+		if l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+		}
+		return token{kind: tokenDictEnd}
+	case '[':
+		l.pos++
+		return token{kind: tokenArrayStart}
+	case ']':
+		l.pos++
+		return token{kind: tokenArrayEnd}
+	case '(':
+		return token{kind: tokenString, value: l.literalString()}
+	case '/':
+		l.pos++
+		return token{kind: tokenName, value: l.regular()}
+	case ')', '{', '}':
+		// Stray delimiters
+		l.pos++
+		return l.next()
+	}
+
+	word := l.regular()
+	if n, err := strconv.ParseFloat(string(word), 64); err == nil {
+		return token{kind: tokenNumber, number: n, value: word}
+	}
+
+	return token{kind: tokenKeyword, value: word}
+}
+
+// hexString reads a string in angle brackets
+func (l *lexer) hexString() []byte {
+	l.pos++ // <
+
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	s := make([]byte, 0, len(digits)/2)
+	for n := 0; n < len(digits); n += 2 {
+		b, err := strconv.ParseUint(string(digits[n:n+2]), 16, 8)
+		if err != nil {
+			break
+		}
+		s = append(s, byte(b))
+	}
+
+	return s
+}
+
+// escapes are the characters escaped by backslashes in literal strings
+var escapes = map[byte]byte{
+	'n': '\n', 'r': '\r', 't': '\t', 'b': '\b', 'f': '\f',
+	'(': '(', ')': ')', '\\': '\\',
+}
+
+// literalString reads a string in balanced parentheses
+func (l *lexer) literalString() []byte {
+	l.pos++ // (
+
+	var s bytes.Buffer
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s.Bytes()
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return s.Bytes()
+			}
+			e := l.data[l.pos]
+			l.pos++
+
+			if r, ok := escapes[e]; ok {
+				s.WriteByte(r)
+				continue
+			}
+
+			if e >= '0' && e <= '7' {
+				// Octal character code of up to three digits
+				code := int(e - '0')
+				for n := 0; n < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; n++ {
+					code = code*8 + int(l.data[l.pos]-'0')
+					l.pos++
+				}
+				s.WriteByte(byte(code))
+				continue
+			}
+
+			if e == '\r' && l.pos < len(l.data) && l.data[l.pos] == '\n' {
+				l.pos++
+			}
+			if e != '\n' && e != '\r' {
+				// Unknown escapes are ignored, line breaks continue the line
+				s.WriteByte(e)
+			}
+			continue
+		}
+
+		s.WriteByte(c)
+	}
+
+	return s.Bytes()
+}
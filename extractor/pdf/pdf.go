@@ -0,0 +1,119 @@
+/*
+Package pdf extracts the text and document information of PDF files
+in-process. It serves as a fallback while the extractor is unavailable, so
+documents still get their content indexed.
+
+Objects are found by scanning files rather than through their
+cross-reference tables, so damaged and truncated files can be read. Only
+Flate compressed streams are supported and text in form XObjects is left
+out; encrypted files are rejected.
+*/
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs/go-ipfs-api"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Config configures the extraction of PDF text
+type Config struct {
+	MaxSize uint64 // Bytes read from the start of files, and of text extracted
+}
+
+// Extractor extracts the text of PDF files read from IPFS
+type Extractor struct {
+	config *Config
+	shell  *shell.Shell
+}
+
+// New returns an extractor for configuration reading files through sh
+func New(config *Config, sh *shell.Shell) *Extractor {
+	return &Extractor{
+		config: config,
+		shell:  sh,
+	}
+}
+
+// Supported returns whether the text of a file with a content type or name
+// can be extracted
+func Supported(mimetype, name string) bool {
+	return mimetype == "application/pdf" || strings.HasSuffix(strings.ToLower(name), ".pdf")
+}
+
+// read returns the first MaxSize bytes of the file at path
+func (e *Extractor) read(ctx context.Context, path string) ([]byte, error) {
+	resp, err := e.shell.Request("cat", path).
+		Option("length", e.config.MaxSize).
+		Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	defer resp.Close()
+
+	return ioutil.ReadAll(io.LimitReader(resp.Output, int64(e.config.MaxSize)))
+}
+
+// Extract returns the text and metadata of the PDF file at path, in the
+// form ipfs-tika returns them
+func (e *Extractor) Extract(ctx context.Context, path string, size uint64) (map[string]interface{}, error) {
+	data, err := e.read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return extract(data, int(e.config.MaxSize))
+}
+
+// infoFields map document information entries to metadata fields named
+// like Tika's
+var infoFields = map[string]string{
+	"Title":        "title",
+	"Author":       "dc:creator",
+	"Subject":      "dc:subject",
+	"Keywords":     "meta:keyword",
+	"Creator":      "xmp:CreatorTool",
+	"Producer":     "pdf:producer",
+	"CreationDate": "dcterms:created",
+	"ModDate":      "dcterms:modified",
+}
+
+// extract returns the text of a PDF file, up to maxText bytes, and its
+// metadata
+func extract(data []byte, maxText int) (map[string]interface{}, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages found")
+	}
+
+	fields := map[string]interface{}{
+		"Content-Type":  []interface{}{"application/pdf"},
+		"xmpTPg:NPages": []interface{}{strconv.Itoa(len(pages))},
+	}
+
+	info := doc.dict(doc.trailerValue("Info"))
+	for entry, field := range infoFields {
+		if s, ok := doc.resolve(info[entry]).([]byte); ok {
+			if value := strings.TrimSpace(textString(s)); value != "" {
+				fields[field] = []interface{}{value}
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"content":  doc.text(maxText),
+		"metadata": fields,
+	}, nil
+}
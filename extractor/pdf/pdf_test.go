@@ -0,0 +1,141 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// compressed returns zlib compressed data
+func compressed(s string) string {
+	var b bytes.Buffer
+	z := zlib.NewWriter(&b)
+	z.Write([]byte(s))
+	z.Close()
+	return b.String()
+}
+
+// stream returns an object with a stream of data and dictionary entries
+func stream(num int, entries, data string) string {
+	return fmt.Sprintf("%d 0 obj\n<< %s /Length %d >>\nstream\n%s\nendstream\nendobj\n", num, entries, len(data), data)
+}
+
+// testPDF returns a PDF file with two pages: the first with a simple
+// font, the second with a composite font mapped by a ToUnicode CMap. The
+// catalog and page tree are in an object stream.
+func testPDF() []byte {
+	page1 := "BT /F1 12 Tf 72 720 Td (Hello) Tj 0 -14 Td [(W) 30 (orld) -250 (again)] TJ 0 -14 Td (Caf\\351) Tj ET"
+	page2 := "BT /F2 12 Tf <00010002> Tj T* [<0003>] TJ ET BI /W 1 /H 1 ID \x00\xffEI x EI Q"
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"1 beginbfchar <0001> <0050> endbfchar\n" +
+		"1 beginbfrange <0002> <0003> <0044> endbfrange\n" +
+		"endcmap end end"
+
+	catalog := "<< /Type /Catalog /Pages 2 0 R >>"
+	pages := "<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R >> >> >>"
+	header := fmt.Sprintf("1 0 2 %d ", len(catalog)+1)
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n%\xe2\xe3\xcf\xd3\n")
+	b.WriteString(stream(10, fmt.Sprintf("/Type /ObjStm /N 2 /First %d /Filter /FlateDecode", len(header)), compressed(header+catalog+" "+pages)))
+	b.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 6 0 R >>\nendobj\n")
+	b.WriteString("4 0 obj\n<< /Type /Page /Parent 2 0 R /Contents [7 0 R] /Resources << /Font << /F2 8 0 R >> >> >>\nendobj\n")
+	b.WriteString("5 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>\nendobj\n")
+	b.WriteString(stream(6, "/Filter /FlateDecode", compressed(page1)))
+	b.WriteString(stream(7, "", page2))
+	b.WriteString("8 0 obj\n<< /Type /Font /Subtype /Type0 /ToUnicode 9 0 R >>\nendobj\n")
+	b.WriteString(stream(9, "/Filter [/FlateDecode]", compressed(cmap)))
+	b.WriteString("11 0 obj\n<< /Title <FEFF00540065007300740020D83DDCC4> /Author (Jane Doe) /CreationDate (D:20190504101112Z) >>\nendobj\n")
+	b.WriteString("trailer\n<< /Root 1 0 R /Info 11 0 R >>\n%%EOF\n")
+
+	return b.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	data := testPDF()
+
+	tests := []struct {
+		name    string
+		data    []byte
+		maxText int
+		content string
+		pages   string
+	}{
+		{"complete", data, 1024, "Hello\nWorld again\nCaf\u00e9\nPD\nE", "2"},
+		{"limited", data, 8, "Hello\nWo", "2"},
+		{"truncated", data[:bytes.Index(data, []byte("7 0 obj"))], 1024, "Hello\nWorld again\nCaf\u00e9", "2"},
+	}
+
+	for _, test := range tests {
+		m, err := extract(test.data, test.maxText)
+		if err != nil {
+			t.Errorf("%s: extract() error %v", test.name, err)
+			continue
+		}
+
+		if m["content"] != test.content {
+			t.Errorf("%s: content = %q, want %q", test.name, m["content"], test.content)
+		}
+
+		fields := m["metadata"].(map[string]interface{})
+		if got := fields["xmpTPg:NPages"]; !reflect.DeepEqual(got, []interface{}{test.pages}) {
+			t.Errorf("%s: pages = %v, want %s", test.name, got, test.pages)
+		}
+	}
+}
+
+func TestExtractInfo(t *testing.T) {
+	m, err := extract(testPDF(), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"Content-Type":    []interface{}{"application/pdf"},
+		"xmpTPg:NPages":   []interface{}{"2"},
+		"title":           []interface{}{"Test \U0001f4c4"},
+		"dc:creator":      []interface{}{"Jane Doe"},
+		"dcterms:created": []interface{}{"D:20190504101112Z"},
+	}
+	if !reflect.DeepEqual(m["metadata"], want) {
+		t.Errorf("metadata = %v, want %v", m["metadata"], want)
+	}
+}
+
+func TestExtractInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"text", []byte("plain text")},
+		{"encrypted", []byte("%PDF-1.4\n1 0 obj << /Type /Page >> endobj\ntrailer << /Encrypt 2 0 R >>")},
+		{"no pages", []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj")},
+	}
+
+	for _, test := range tests {
+		if m, err := extract(test.data, 1024); err == nil {
+			t.Errorf("%s: extract() = %v, want error", test.name, m)
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	tests := []struct {
+		mimetype string
+		name     string
+		want     bool
+	}{
+		{"application/pdf", "", true},
+		{"application/octet-stream", "Paper.PDF", true},
+		{"text/plain", "notes.txt", false},
+	}
+
+	for _, test := range tests {
+		if got := Supported(test.mimetype, test.name); got != test.want {
+			t.Errorf("Supported(%q, %q) = %v, want %v", test.mimetype, test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package pdf
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// maxRange limits the codes mapped by a single bfrange, as corrupt CMaps
+// may claim any amount
+const maxRange = 1 << 16
+
+// font decodes strings shown in a font into text
+type font struct {
+	cmap       map[string]string // Text by character code, from ToUnicode
+	codeLength int               // Bytes per character code
+}
+
+// winAnsi maps the bytes where WinAnsiEncoding differs from Latin-1
+var winAnsi = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†',
+	0x87: '‡', 0x88: 'ˆ', 0x89: '‰', 0x8a: 'Š', 0x8b: '‹', 0x8c: 'Œ',
+	0x8e: 'Ž', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•',
+	0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™', 0x9a: 'š', 0x9b: '›',
+	0x9c: 'œ', 0x9e: 'ž', 0x9f: 'Ÿ',
+}
+
+// singleByte decodes codes of simple fonts without ToUnicode, which mostly
+// use standard or WinAnsi encodings
+func singleByte(s []byte) string {
+	var b strings.Builder
+	for _, c := range s {
+		if r, ok := winAnsi[c]; ok {
+			b.WriteRune(r)
+		} else if c >= 0x20 {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// utf16Text decodes UTF-16BE
+func utf16Text(s []byte) string {
+	units := make([]uint16, 0, len(s)/2)
+	for n := 0; n+1 < len(s); n += 2 {
+		units = append(units, uint16(s[n])<<8|uint16(s[n+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// textString decodes text strings, e.g. in the document information, which
+// are UTF-16BE with a byte order mark or PDFDocEncoding
+func textString(s []byte) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		return utf16Text(s[2:])
+	}
+	return singleByte(s)
+}
+
+// decode returns the text of a string shown in f
+func (f *font) decode(s []byte) string {
+	if f == nil {
+		return singleByte(s)
+	}
+
+	if f.cmap == nil {
+		if f.codeLength == 1 {
+			return singleByte(s)
+		}
+		// Multibyte codes are glyph IDs without a known mapping to text
+		return ""
+	}
+
+	var b strings.Builder
+	for n := 0; n+f.codeLength <= len(s); n += f.codeLength {
+		code := s[n : n+f.codeLength]
+		if t, ok := f.cmap[string(code)]; ok {
+			b.WriteString(t)
+		} else if f.codeLength == 1 {
+			b.WriteString(singleByte(code))
+		}
+	}
+	return b.String()
+}
+
+// codeValue returns a big endian character code as a number
+func codeValue(code []byte) int {
+	v := 0
+	for _, c := range code {
+		v = v<<8 | int(c)
+	}
+	return v
+}
+
+// codeBytes returns character code v in length bytes
+func codeBytes(v, length int) string {
+	b := make([]byte, length)
+	for n := length - 1; n >= 0; n-- {
+		b[n] = byte(v)
+		v >>= 8
+	}
+	return string(b)
+}
+
+// parseCMap returns the text by character code of a ToUnicode CMap, and
+// the length of its codes or 0 when it doesn't define them
+func parseCMap(data []byte) (map[string]string, int) {
+	cmap := make(map[string]string)
+	codeLength := 0
+
+	l := &lexer{data: data}
+	for {
+		t := l.next()
+		if t.kind == tokenEOF {
+			break
+		}
+		if t.kind != tokenKeyword {
+			continue
+		}
+
+		switch string(t.value) {
+		case "begincodespacerange":
+			if lo := l.next(); lo.kind == tokenString && codeLength == 0 {
+				codeLength = len(lo.value)
+			}
+		case "beginbfchar":
+			for {
+				src := l.next()
+				if src.kind != tokenString {
+					break
+				}
+				if dst := l.next(); dst.kind == tokenString {
+					cmap[string(src.value)] = utf16Text(dst.value)
+				}
+			}
+		case "beginbfrange":
+			for {
+				lo := l.next()
+				if lo.kind != tokenString {
+					break
+				}
+				hi := l.next()
+				if hi.kind != tokenString || len(hi.value) != len(lo.value) {
+					break
+				}
+
+				first, last := codeValue(lo.value), codeValue(hi.value)
+				if last < first || last-first >= maxRange {
+					last = first
+				}
+
+				switch dst := l.next(); dst.kind {
+				case tokenString:
+					// Consecutive codes map to consecutive text
+					for n := 0; n <= last-first && len(dst.value) >= 2; n++ {
+						text := append([]byte{}, dst.value...)
+						v := codeValue(text[len(text)-2:]) + n
+						text[len(text)-2], text[len(text)-1] = byte(v>>8), byte(v)
+						cmap[codeBytes(first+n, len(lo.value))] = utf16Text(text)
+					}
+				case tokenArrayStart:
+					for n := 0; ; n++ {
+						s := l.next()
+						if s.kind != tokenString {
+							break
+						}
+						cmap[codeBytes(first+n, len(lo.value))] = utf16Text(s.value)
+					}
+				}
+			}
+		}
+	}
+
+	if codeLength == 0 {
+		for code := range cmap {
+			codeLength = len(code)
+			break
+		}
+	}
+
+	return cmap, codeLength
+}
+
+// font returns the font described by the font dictionary v refers to
+func (doc *document) font(v interface{}) *font {
+	r, isRef := v.(ref)
+	if isRef {
+		if f, ok := doc.fonts[r.num]; ok {
+			return f
+		}
+	}
+
+	d := doc.dict(v)
+	if d == nil {
+		return nil
+	}
+
+	f := &font{codeLength: 1}
+	if d["Subtype"] == name("Type0") {
+		// Composite fonts mostly use two byte Identity encodings
+		f.codeLength = 2
+	}
+
+	if streams := doc.streams(d["ToUnicode"]); len(streams) > 0 {
+		cmap, codeLength := parseCMap(streams[0])
+		if len(cmap) > 0 {
+			f.cmap = cmap
+			if codeLength > 0 {
+				f.codeLength = codeLength
+			}
+		}
+	}
+
+	if isRef {
+		doc.fonts[r.num] = f
+	}
+
+	return f
+}
+
+// textWriter collects text up to a maximum length, separating lines and
+// words
+type textWriter struct {
+	b   strings.Builder
+	max int
+}
+
+// full returns whether the maximum length has been reached
+func (w *textWriter) full() bool {
+	return w.b.Len() >= w.max
+}
+
+// last returns the last byte written, or a newline when empty
+func (w *textWriter) last() byte {
+	s := w.b.String()
+	if s == "" {
+		return '\n'
+	}
+	return s[len(s)-1]
+}
+
+// write adds text
+func (w *textWriter) write(s string) {
+	if room := w.max - w.b.Len(); len(s) > room {
+		s = strings.ToValidUTF8(s[:room], "")
+	}
+	w.b.WriteString(s)
+}
+
+// space separates words
+func (w *textWriter) space() {
+	if c := w.last(); c != ' ' && c != '\n' {
+		w.write(" ")
+	}
+}
+
+// newline separates lines
+func (w *textWriter) newline() {
+	if w.last() == ' ' {
+		s := strings.TrimRight(w.b.String(), " ")
+		w.b.Reset()
+		w.b.WriteString(s)
+	}
+	if w.last() != '\n' {
+		w.write("\n")
+	}
+}
+
+// String returns the text written
+func (w *textWriter) String() string {
+	return strings.TrimSpace(w.b.String())
+}
+
+// skipInlineImage skips the data of an inline image, after BI
+func skipInlineImage(l *lexer) {
+	for {
+		t := l.next()
+		if t.kind == tokenEOF {
+			return
+		}
+		if t.kind == tokenKeyword && string(t.value) == "ID" {
+			break
+		}
+	}
+
+	// Image data is binary; it ends with EI after whitespace
+	for l.pos+2 < len(l.data) {
+		if isSpace(l.data[l.pos]) && l.data[l.pos+1] == 'E' && l.data[l.pos+2] == 'I' &&
+			(l.pos+3 == len(l.data) || isSpace(l.data[l.pos+3]) || isDelimiter(l.data[l.pos+3])) {
+			l.pos += 3
+			return
+		}
+		l.pos++
+	}
+	l.pos = len(l.data)
+}
+
+// contentText writes the text shown by the operators of a content stream
+// with fonts in its resources
+func (doc *document) contentText(data []byte, fonts dict, w *textWriter) {
+	var (
+		operands []interface{}
+		current  *font
+	)
+
+	show := func(v interface{}) {
+		if s, ok := v.([]byte); ok {
+			w.write(current.decode(s))
+		}
+	}
+
+	l := &lexer{data: data}
+	for !w.full() {
+		t := l.next()
+		if t.kind == tokenEOF {
+			return
+		}
+
+		v := parseValue(l, t)
+		op, ok := v.(keyword)
+		if !ok {
+			operands = append(operands, v)
+			continue
+		}
+
+		var last interface{}
+		if len(operands) > 0 {
+			last = operands[len(operands)-1]
+		}
+
+		switch op {
+		case "BI":
+			skipInlineImage(l)
+		case "Tf":
+			if len(operands) >= 2 {
+				if n, ok := operands[len(operands)-2].(name); ok {
+					current = doc.font(fonts[string(n)])
+				}
+			}
+		case "Tj":
+			show(last)
+		case "'", "\"":
+			w.newline()
+			show(last)
+		case "TJ":
+			elements, _ := last.(array)
+			for _, e := range elements {
+				// Large negative adjustments move to the next word
+				if n, ok := e.(float64); ok && n < -200 {
+					w.space()
+				}
+				show(e)
+			}
+		case "Td", "TD":
+			if ty, ok := last.(float64); ok && ty != 0 {
+				w.newline()
+			} else {
+				w.space()
+			}
+		case "T*", "Tm", "ET":
+			w.newline()
+		}
+
+		operands = operands[:0]
+	}
+}
+
+// text returns the text of the pages of the document, up to max bytes
+func (doc *document) text(max int) string {
+	w := &textWriter{max: max}
+
+	for _, page := range doc.pages() {
+		if w.full() {
+			break
+		}
+
+		resources := doc.dict(doc.inherited(page, "Resources"))
+		fonts := doc.dict(resources["Font"])
+
+		for _, content := range doc.streams(page["Contents"]) {
+			doc.contentText(content, fonts, w)
+			w.newline()
+		}
+		w.newline()
+	}
+
+	return w.String()
+}
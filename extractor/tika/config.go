@@ -0,0 +1,22 @@
+package tika
+
+import (
+	"github.com/ipfs-search/ipfs-search/concurrency"
+	"github.com/ipfs-search/ipfs-search/gateway"
+	"time"
+)
+
+// Config contains user configurable options for the ipfs-tika extractor
+type Config struct {
+	IpfsTikaURL     string        // ipfs-tika endpoint URL
+	IpfsTikaTimeout time.Duration // ipfs-tika request timeout
+	IpfsAPIs        []string      // IPFS APIs, for reading partial content
+	TikaServerURL   string        // Tika server URL, for extracting partial content
+
+	MetadataMaxSize uint64 // Only extract from the first PartialSize bytes of files over this size
+	PartialSize     uint64 // Amount of bytes extracted from large files, 0 to skip these
+
+	Concurrency concurrency.Config // Limits ipfs-tika requests in flight
+
+	Gateways *gateway.Config // Fallback for content IPFS times out on; optional
+}
@@ -0,0 +1,198 @@
+/*
+Package tika implements metadata extraction through ipfs-tika.
+*/
+package tika
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/concurrency"
+	"github.com/ipfs-search/ipfs-search/crawler/crawlerrors"
+	"github.com/ipfs-search/ipfs-search/gateway"
+	"github.com/ipfs-search/ipfs-search/ipfspool"
+	"github.com/ipfs/go-ipfs-api"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Tika extracts metadata using an ipfs-tika server
+type Tika struct {
+	config   *Config
+	client   *http.Client
+	shell    *shell.Shell
+	gateways *gateway.Gateways // Optional
+}
+
+// New returns a new Tika extractor
+func New(config *Config) *Tika {
+	sh, _ := ipfspool.NewShell(config.IpfsAPIs, concurrency.Config{})
+	sh.SetTimeout(config.IpfsTikaTimeout)
+
+	return &Tika{
+		config: config,
+		client: &http.Client{
+			Timeout:   config.IpfsTikaTimeout,
+			Transport: concurrency.NewTransport(config.Concurrency, http.DefaultTransport),
+		},
+		shell:    sh,
+		gateways: gateway.New(config.Gateways),
+	}
+}
+
+// request performs a request to ipfs-tika or the Tika server and returns
+// the resulting JSON
+func (t *Tika) request(ctx context.Context, req *http.Request) (map[string]interface{}, error) {
+	req.Header.Set("Accept", "application/json")
+
+	log.WithField("url", req.URL).WithField("method", req.Method).Debug("Fetching metadata")
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		err := fmt.Errorf("undesired status '%s' from %s", resp.Status, req.URL.Host)
+
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, crawlerrors.New(crawlerrors.NotFound, err)
+		}
+
+		return nil, err
+	}
+
+	// Parse resulting JSON
+	m := make(map[string]interface{})
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// tikaContent is the field holding extracted text in Tika server output
+const tikaContent = "X-TIKA:content"
+
+// fromServer converts Tika server output to the form of ipfs-tika, with
+// metadata values as lists and the text in content
+func fromServer(result map[string]interface{}) map[string]interface{} {
+	metadata := make(map[string]interface{}, len(result))
+	for k, v := range result {
+		if k == tikaContent {
+			continue
+		}
+
+		if _, ok := v.([]interface{}); !ok {
+			v = []interface{}{v}
+		}
+		metadata[k] = v
+	}
+
+	m := map[string]interface{}{
+		"metadata": metadata,
+	}
+	if content, ok := result[tikaContent].(string); ok {
+		m["content"] = content
+	}
+
+	return m
+}
+
+// upload sends content of the resource at path to the Tika server, which
+// unlike ipfs-tika extracts from uploaded content
+func (t *Tika) upload(ctx context.Context, p string, content io.Reader) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodPut, strings.TrimSuffix(t.config.TikaServerURL, "/")+"/tika", content)
+	if err != nil {
+		return nil, err
+	}
+	// The name helps type detection
+	req.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(p)))
+
+	result, err := t.request(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromServer(result), nil
+}
+
+// canFallback returns whether extraction failing with err should be
+// retried with content from gateways: when IPFS timed out, rather than
+// the crawl itself
+func (t *Tika) canFallback(ctx context.Context, err error) bool {
+	return t.gateways != nil && t.config.TikaServerURL != "" && ctx.Err() == nil &&
+		crawlerrors.HasCategory(crawlerrors.Classify(err), crawlerrors.Timeout)
+}
+
+// extractFromGateways extracts from the first length bytes of the
+// resource at path, fetched from gateways
+func (t *Tika) extractFromGateways(ctx context.Context, p string, length uint64) (map[string]interface{}, error) {
+	log.WithField("path", p).Info("IPFS timed out, extracting from gateways")
+
+	content, err := t.gateways.Fetch(ctx, p, length)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return t.upload(ctx, p, content)
+}
+
+// extractPartial streams the first bytes of the resource at path to the
+// Tika server, for type detection and metadata in the file headers
+func (t *Tika) extractPartial(ctx context.Context, p string) (m map[string]interface{}, err error) {
+	resp, err := t.shell.Request("cat", p).
+		Option("length", t.config.PartialSize).
+		Send(ctx)
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+
+	switch {
+	case t.canFallback(ctx, err):
+		m, err = t.extractFromGateways(ctx, p, t.config.PartialSize)
+	case err != nil:
+		return nil, err
+	default:
+		defer resp.Close()
+		m, err = t.upload(ctx, p, resp.Output)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m["metadata-partial"] = true
+
+	return m, nil
+}
+
+// Extract requests IPFS path from ipfs-tika and returns the resulting
+// metadata. For files over the maximum size, only the first bytes are
+// extracted by the Tika server, if configured. When IPFS times out on the
+// file, its content is fetched from gateways for the Tika server instead,
+// if configured.
+func (t *Tika) Extract(ctx context.Context, path string, size uint64) (map[string]interface{}, error) {
+	if size > t.config.MetadataMaxSize {
+		if t.config.PartialSize == 0 || t.config.TikaServerURL == "" {
+			return nil, crawlerrors.New(crawlerrors.TooLarge, fmt.Errorf("%s too large, not extracting metadata", path))
+		}
+
+		return t.extractPartial(ctx, path)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, t.config.IpfsTikaURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := t.request(ctx, req)
+	if t.canFallback(ctx, err) {
+		return t.extractFromGateways(ctx, path, size)
+	}
+
+	return m, err
+}
@@ -0,0 +1,106 @@
+package tika
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/crawler/crawlerrors"
+	"github.com/ipfs-search/ipfs-search/gateway"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFromServer(t *testing.T) {
+	tests := []struct {
+		result map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			map[string]interface{}{},
+			map[string]interface{}{"metadata": map[string]interface{}{}},
+		},
+		{
+			map[string]interface{}{
+				"Content-Type":   "application/pdf",
+				"dc:creator":     []interface{}{"a", "b"},
+				"X-TIKA:content": "text",
+			},
+			map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"Content-Type": []interface{}{"application/pdf"},
+					"dc:creator":   []interface{}{"a", "b"},
+				},
+				"content": "text",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if got := fromServer(test.result); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("fromServer(%v) = %v, want %v", test.result, got, test.want)
+		}
+	}
+}
+
+func TestExtractTooLarge(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"no partial size", Config{MetadataMaxSize: 10, TikaServerURL: "http://localhost:9998"}},
+		{"no Tika server", Config{MetadataMaxSize: 10, PartialSize: 5}},
+	}
+
+	for _, test := range tests {
+		_, err := New(&test.config).Extract(context.Background(), "/ipfs/hash", 11)
+		if !crawlerrors.HasCategory(err, crawlerrors.TooLarge) {
+			t.Errorf("Extract() with %s = %v, want too large", test.name, err)
+		}
+	}
+}
+
+func TestExtractFromGateways(t *testing.T) {
+	ipfsTika := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ipfsTika.Close()
+
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer gw.Close()
+
+	var uploaded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		uploaded = string(body)
+		w.Write([]byte(`{"Content-Type": "text/plain", "X-TIKA:content": "content"}`))
+	}))
+	defer server.Close()
+
+	config := &Config{
+		IpfsTikaURL:     ipfsTika.URL,
+		IpfsTikaTimeout: 10 * time.Millisecond,
+		MetadataMaxSize: 100,
+		TikaServerURL:   server.URL,
+		Gateways:        &gateway.Config{URLs: []string{gw.URL}},
+	}
+
+	m, err := New(config).Extract(context.Background(), "/ipfs/hash", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m["content"] != "content" || uploaded != "content" {
+		t.Errorf("Extract() = %v, uploaded %q", m, uploaded)
+	}
+
+	// Without gateways, the timeout is returned
+	config.Gateways = nil
+	_, err = New(config).Extract(context.Background(), "/ipfs/hash", 7)
+	if !crawlerrors.HasCategory(crawlerrors.Classify(err), crawlerrors.Timeout) {
+		t.Errorf("Extract() without gateways = %v, want timeout", err)
+	}
+}
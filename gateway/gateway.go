@@ -0,0 +1,169 @@
+/*
+Package gateway fetches content through public IPFS HTTP gateways, as a
+fallback for when the local IPFS daemon can't retrieve it in time, and
+probes whether gateways can retrieve content.
+*/
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/concurrency"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config configures the gateways content is fetched from
+type Config struct {
+	URLs    []string         // Gateway base URLs, tried in order, e.g. https://ipfs.io
+	Timeout time.Duration    // Time a gateway has to deliver content
+	Rate    concurrency.Rate // Rate of requests to each gateway; unlimited when zero
+}
+
+// gateway is a single gateway with its own rate limit
+type gateway struct {
+	url    string
+	client *http.Client
+}
+
+// Gateways fetches content from the first gateway delivering it
+type Gateways struct {
+	gateways []*gateway
+}
+
+// New returns gateways for configuration, or nil if none are configured
+func New(config *Config) *Gateways {
+	if config == nil || len(config.URLs) == 0 {
+		return nil
+	}
+
+	g := new(Gateways)
+	for _, url := range config.URLs {
+		g.gateways = append(g.gateways, &gateway{
+			url: strings.TrimSuffix(url, "/"),
+			client: &http.Client{
+				Timeout: config.Timeout,
+				Transport: concurrency.NewRateTransport(map[string]concurrency.Rate{
+					concurrency.AllEndpoints: config.Rate,
+				}, http.DefaultTransport),
+			},
+		})
+	}
+
+	return g
+}
+
+// limitedBody closes the response body after reading up to its limit
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// fetch requests up to length bytes of the content at path from a gateway
+func (g *gateway) fetch(ctx context.Context, path string, length uint64) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, g.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", length-1))
+
+	resp, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("undesired status '%s' from %s", resp.Status, g.url)
+	}
+
+	// Gateways may ignore the range
+	return &limitedBody{
+		Reader: io.LimitReader(resp.Body, int64(length)),
+		Closer: resp.Body,
+	}, nil
+}
+
+// Fetch returns up to length bytes of the content at an IPFS path, e.g.
+// /ipfs/<hash>/<name>, from the first gateway delivering it
+func (g *Gateways) Fetch(ctx context.Context, path string, length uint64) (io.ReadCloser, error) {
+	if length == 0 {
+		return nil, errors.New("nothing to fetch")
+	}
+
+	err := errors.New("no gateways configured")
+	for _, gw := range g.gateways {
+		var body io.ReadCloser
+		body, err = gw.fetch(ctx, path, length)
+		if err == nil {
+			return body, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		log.WithError(err).WithFields(log.Fields{
+			"gateway": gw.url,
+			"path":    path,
+		}).Debug("Error fetching from gateway")
+	}
+
+	return nil, fmt.Errorf("fetching %s from gateways: %w", path, err)
+}
+
+// probe returns whether a gateway delivers the first byte of the content
+// at path
+func (g *gateway) probe(ctx context.Context, path string) bool {
+	body, err := g.fetch(ctx, path, 1)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"gateway": g.url,
+			"path":    path,
+		}).Debug("Content not retrievable from gateway")
+		return false
+	}
+	defer body.Close()
+
+	n, _ := body.Read(make([]byte, 1))
+	return n == 1
+}
+
+// Probe requests the content at an IPFS path from all gateways at once,
+// returning the URLs of those delivering it, in configured order
+func (g *Gateways) Probe(ctx context.Context, path string) []string {
+	results := make([]bool, len(g.gateways))
+
+	var wg sync.WaitGroup
+	for n, gw := range g.gateways {
+		wg.Add(1)
+		go func(n int, gw *gateway) {
+			defer wg.Done()
+			results[n] = gw.probe(ctx, path)
+		}(n, gw)
+	}
+	wg.Wait()
+
+	var retrievable []string
+	for n, ok := range results {
+		if ok {
+			retrievable = append(retrievable, g.gateways[n].url)
+		}
+	}
+
+	return retrievable
+}
+
+// URLs returns the URLs of the gateways, in configured order
+func (g *Gateways) URLs() []string {
+	urls := make([]string, len(g.gateways))
+	for n, gw := range g.gateways {
+		urls[n] = gw.url
+	}
+	return urls
+}
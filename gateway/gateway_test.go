@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFetch(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "timeout", http.StatusGatewayTimeout)
+	}))
+	defer failing.Close()
+
+	var rangeHeader string
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ipfs/hash/name.txt" {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Ignores the range
+		rangeHeader = r.Header.Get("Range")
+		w.Write([]byte("0123456789"))
+	}))
+	defer working.Close()
+
+	g := New(&Config{URLs: []string{failing.URL, working.URL + "/"}})
+
+	body, err := g.Fetch(context.Background(), "/ipfs/hash/name.txt", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "0123" {
+		t.Errorf("Fetch() = %q, want 0123", content)
+	}
+	if rangeHeader != "bytes=0-3" {
+		t.Errorf("Range = %q, want bytes=0-3", rangeHeader)
+	}
+
+	if _, err := g.Fetch(context.Background(), "/ipfs/missing", 4); err == nil {
+		t.Error("Fetch() of missing content succeeded")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if g := New(&Config{}); g != nil {
+		t.Errorf("New() without URLs = %v, want nil", g)
+	}
+	if g := New(nil); g != nil {
+		t.Errorf("New(nil) = %v, want nil", g)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "timeout", http.StatusGatewayTimeout)
+	}))
+	defer failing.Close()
+
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ipfs/hash" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("0"))
+	}))
+	defer working.Close()
+
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer empty.Close()
+
+	g := New(&Config{URLs: []string{failing.URL, working.URL, empty.URL}})
+
+	if got := g.Probe(context.Background(), "/ipfs/hash"); !reflect.DeepEqual(got, []string{working.URL}) {
+		t.Errorf("Probe() = %v, want %s", got, working.URL)
+	}
+	if got := g.Probe(context.Background(), "/ipfs/missing"); len(got) != 0 {
+		t.Errorf("Probe() of missing content = %v", got)
+	}
+}
@@ -0,0 +1,58 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/olivere/elastic.v5"
+)
+
+// unavailableWeight scales the scores of documents no probed gateway
+// could retrieve, so retrievable content ranks higher
+const unavailableWeight = 0.5
+
+// Availability is the retrievability of a document through public
+// gateways, as last probed
+type Availability struct {
+	Checked  string   `json:"checked"`  // Time of the probe
+	Gateways []string `json:"gateways"` // Gateways the document was retrieved from
+	Ratio    float64  `json:"ratio"`    // Fraction of probed gateways retrieving it
+}
+
+// SetAvailability stores the availability of documents, by hash
+func (i *Indexer) SetAvailability(ctx context.Context, documents []Document, availability map[string]*Availability) error {
+	requests := make([]elastic.BulkableRequest, 0, len(documents))
+	for _, d := range documents {
+		a, ok := availability[d.Hash]
+		if !ok {
+			continue
+		}
+
+		alias, err := typeAlias(d.Type)
+		if err != nil {
+			return err
+		}
+
+		requests = append(requests, elastic.NewBulkUpdateRequest().
+			Index(alias).Type(d.Type).
+			Id(i.id(d.Hash)).
+			Routing(i.route(d.Hash)).
+			Doc(map[string]interface{}{
+				"availability": a,
+			}))
+	}
+
+	if len(requests) == 0 {
+		return nil
+	}
+
+	result, err := i.bulk(ctx, requests)
+	if err != nil {
+		return err
+	}
+
+	if failed := result.Failed(); len(failed) > 0 {
+		return fmt.Errorf("failed setting availability of %d documents, first: %s", len(failed), failed[0].Id)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+)
+
+// Backends supported for the index; Elasticsearch 5 is the default
+const (
+	BackendElasticSearch5 = "elasticsearch5"
+	BackendElasticSearch  = "elasticsearch" // Elasticsearch 7 and later
+	BackendOpenSearch     = "opensearch"
+)
+
+// Index is the part of the indexer used for crawling. It is implemented by
+// Indexer for Elasticsearch 5 and by Typeless for Elasticsearch 7 and later
+// and OpenSearch, which have no mapping types.
+type Index interface {
+	CreateIndex(ctx context.Context) (bool, error)
+	IndexItem(ctx context.Context, doctype string, hash string, properties map[string]interface{}) error
+	UpdateItem(ctx context.Context, doctype string, hash string, properties map[string]interface{}) error
+	GetItem(ctx context.Context, hash string) (*Item, error)
+	DeleteItem(ctx context.Context, hash string) (bool, error)
+	GetOverride(ctx context.Context, hash string) (*Override, error)
+	GetPublisherOverride(ctx context.Context, hash string) (*Override, error)
+	RecordAttempt(ctx context.Context, hash string, attempt *Attempt, size int) error
+}
+
+// IsTypeless returns whether a backend has no mapping types, and is only
+// supported for crawling
+func IsTypeless(backend string) bool {
+	return backend == BackendElasticSearch || backend == BackendOpenSearch
+}
+
+// checkBackend returns an error for backends not supported by Indexer
+func checkBackend(backend string) error {
+	switch {
+	case backend == "" || backend == BackendElasticSearch5:
+		return nil
+	case IsTypeless(backend):
+		return fmt.Errorf("index backend %q only supports crawling and creating indices, use %s for this", backend, BackendElasticSearch5)
+	default:
+		return fmt.Errorf("unsupported index backend %q", backend)
+	}
+}
+
+// NewIndex returns the index used for crawling with the configured backend
+func NewIndex(config *Config) (Index, error) {
+	if IsTypeless(config.Backend) {
+		return NewTypeless(config)
+	}
+
+	return New(config)
+}
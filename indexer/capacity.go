@@ -0,0 +1,286 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/olivere/elastic.v5"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// watermarkSetting prefixes the cluster settings of disk watermarks
+const watermarkSetting = "cluster.routing.allocation.disk.watermark."
+
+// watermarkNames are the disk watermarks of Elasticsearch, from low to high
+var watermarkNames = []string{"low", "high", "flood_stage"}
+
+// defaultWatermarks are the watermarks of Elasticsearch unless configured
+var defaultWatermarks = map[string]string{
+	"low":         "85%",
+	"high":        "90%",
+	"flood_stage": "95%",
+}
+
+// byteUnits are the multipliers of byte size units in cluster settings
+var byteUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"pb", 1 << 50}, {"tb", 1 << 40}, {"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10}, {"b", 1},
+}
+
+// threshold is a disk watermark: a ratio of disk used or an amount of
+// bytes left free
+type threshold struct {
+	ratio float64
+	free  int64
+}
+
+// parseWatermark parses a watermark setting, e.g. 85%, 0.85 or 50gb
+func parseWatermark(s string) (threshold, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	if strings.HasSuffix(s, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		return threshold{ratio: percent / 100}, err
+	}
+
+	if ratio, err := strconv.ParseFloat(s, 64); err == nil {
+		return threshold{ratio: ratio}, nil
+	}
+
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(s, unit.suffix), 64)
+			return threshold{free: int64(value * unit.multiplier)}, err
+		}
+	}
+
+	return threshold{}, fmt.Errorf("invalid watermark '%s'", s)
+}
+
+// limit returns the bytes used on a disk of total bytes at which the
+// watermark is hit
+func (t threshold) limit(total int64) int64 {
+	if t.free > 0 {
+		return total - t.free
+	}
+
+	return int64(t.ratio * float64(total))
+}
+
+// Disk is the disk usage of a data node
+type Disk struct {
+	Node  string `json:"node"`
+	Used  int64  `json:"used_bytes"`
+	Total int64  `json:"total_bytes"`
+}
+
+// Watermark is the forecast of when a disk watermark is hit
+type Watermark struct {
+	Name string `json:"name"`
+
+	// ETA is the time until the first node hits the watermark, in
+	// seconds; 0 when it has been hit and -1 when the index is not growing
+	ETA float64 `json:"eta_seconds"`
+}
+
+// Capacity is the disk usage of the cluster, with a forecast of when its
+// watermarks are hit at current crawl throughput
+type Capacity struct {
+	Disks      []Disk      `json:"disks"`
+	Growth     float64     `json:"growth"` // In bytes per second
+	Watermarks []Watermark `json:"watermarks"`
+}
+
+// Within returns whether the named watermark is forecast to be hit within
+// d, or has been hit
+func (c *Capacity) Within(name string, d time.Duration) bool {
+	for _, w := range c.Watermarks {
+		if w.Name == name {
+			return w.ETA >= 0 && w.ETA < d.Seconds()
+		}
+	}
+
+	return false
+}
+
+// forecast returns when watermarks are hit, assuming growth is spread over
+// disks by their size
+func forecast(disks []Disk, thresholds map[string]threshold, growth float64) []Watermark {
+	var total int64
+	for _, d := range disks {
+		total += d.Total
+	}
+
+	watermarks := make([]Watermark, 0, len(watermarkNames))
+	for _, name := range watermarkNames {
+		t, ok := thresholds[name]
+		if !ok {
+			continue
+		}
+
+		w := Watermark{Name: name, ETA: -1}
+		for _, d := range disks {
+			left := t.limit(d.Total) - d.Used
+			if left <= 0 {
+				w.ETA = 0
+				break
+			}
+
+			if growth <= 0 {
+				continue
+			}
+
+			eta := float64(left) / (growth * float64(d.Total) / float64(total))
+			if w.ETA < 0 || eta < w.ETA {
+				w.ETA = eta
+			}
+		}
+
+		w.ETA = math.Round(w.ETA)
+		watermarks = append(watermarks, w)
+	}
+
+	return watermarks
+}
+
+// disks returns the disk usage of the data nodes
+func (i *Indexer) disks(ctx context.Context) ([]Disk, error) {
+	stats, err := i.ElasticSearch.NodesStats().Metric("fs").Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := make([]Disk, 0, len(stats.Nodes))
+	for _, node := range stats.Nodes {
+		if node.FS == nil || node.FS.Total == nil {
+			continue
+		}
+		if len(node.Roles) > 0 && !contains(node.Roles, "data") {
+			continue
+		}
+
+		fs := node.FS.Total
+		disks = append(disks, Disk{
+			Node:  node.Name,
+			Used:  fs.TotalInBytes - fs.AvailableInBytes,
+			Total: fs.TotalInBytes,
+		})
+	}
+
+	sort.Slice(disks, func(a, b int) bool { return disks[a].Node < disks[b].Node })
+
+	return disks, nil
+}
+
+// contains returns whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watermarks returns the disk watermarks of the cluster; transient
+// settings override persistent ones, which override the defaults
+func (i *Indexer) watermarks(ctx context.Context) (map[string]threshold, error) {
+	resp, err := i.ElasticSearch.PerformRequest(ctx, "GET", "/_cluster/settings", url.Values{"flat_settings": {"true"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings struct {
+		Persistent map[string]interface{} `json:"persistent"`
+		Transient  map[string]interface{} `json:"transient"`
+	}
+	if err := json.Unmarshal(resp.Body, &settings); err != nil {
+		return nil, err
+	}
+
+	thresholds := make(map[string]threshold, len(watermarkNames))
+	for _, name := range watermarkNames {
+		value := defaultWatermarks[name]
+		for _, s := range []map[string]interface{}{settings.Persistent, settings.Transient} {
+			if v, ok := s[watermarkSetting+name].(string); ok {
+				value = v
+			}
+		}
+
+		thresholds[name], err = parseWatermark(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return thresholds, nil
+}
+
+// growth returns the rate at which the index grows in bytes per second, as
+// the documents first seen within window times their average size
+func (i *Indexer) growth(ctx context.Context, window time.Duration) (float64, error) {
+	sizes, err := i.IndexSizes(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	counts, err := i.CountByType(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var size, count int64
+	for _, s := range sizes {
+		size += s
+	}
+	for _, c := range counts {
+		count += c
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	recent, err := i.ElasticSearch.Count(searchAlias).
+		Query(elastic.NewRangeQuery("first-seen").
+			Gte(time.Now().Add(-window).UTC().Format(time.RFC3339))).
+		Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(recent) * float64(size) / float64(count) / window.Seconds(), nil
+}
+
+// Capacity returns the disk usage of the data nodes and forecasts when
+// disk watermarks are hit, given the growth of the index by the documents
+// first seen within window
+func (i *Indexer) Capacity(ctx context.Context, window time.Duration) (*Capacity, error) {
+	disks, err := i.disks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	thresholds, err := i.watermarks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	growth, err := i.growth(ctx, window)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Capacity{
+		Disks:      disks,
+		Growth:     growth,
+		Watermarks: forecast(disks, thresholds, growth),
+	}, nil
+}
@@ -0,0 +1,68 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWatermark(t *testing.T) {
+	tests := []struct {
+		value string
+		want  threshold
+		valid bool
+	}{
+		{"85%", threshold{ratio: 0.85}, true},
+		{"0.9", threshold{ratio: 0.9}, true},
+		{"50gb", threshold{free: 50 << 30}, true},
+		{"512MB", threshold{free: 512 << 20}, true},
+		{"lots", threshold{}, false},
+	}
+
+	for _, test := range tests {
+		got, err := parseWatermark(test.value)
+		if (err == nil) != test.valid {
+			t.Errorf("parseWatermark(%s) error = %v, valid %v", test.value, err, test.valid)
+			continue
+		}
+		if test.valid && got != test.want {
+			t.Errorf("parseWatermark(%s) = %+v, want %+v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestForecast(t *testing.T) {
+	thresholds := map[string]threshold{
+		"low":         {ratio: 0.5},
+		"high":        {ratio: 0.9},
+		"flood_stage": {free: 50},
+	}
+	disks := []Disk{
+		{Node: "a", Used: 400, Total: 1000},
+		{Node: "b", Used: 200, Total: 1000},
+	}
+
+	// Each node grows 1 byte per second
+	watermarks := forecast(disks, thresholds, 2)
+	want := []Watermark{{"low", 100}, {"high", 500}, {"flood_stage", 550}}
+	if len(watermarks) != len(want) {
+		t.Fatalf("forecast() = %v, want %v", watermarks, want)
+	}
+	for n, w := range want {
+		if watermarks[n] != w {
+			t.Errorf("forecast() = %v, want %v", watermarks, want)
+			break
+		}
+	}
+
+	c := &Capacity{Watermarks: watermarks}
+	if !c.Within("low", 2*time.Minute) || c.Within("high", 2*time.Minute) || c.Within("unknown", time.Hour) {
+		t.Errorf("Within() of %v incorrect", watermarks)
+	}
+
+	// Hit watermarks are reported regardless of growth
+	disks[0].Used = 600
+	watermarks = forecast(disks, thresholds, 0)
+	if watermarks[0].ETA != 0 || watermarks[1].ETA != -1 {
+		t.Errorf("forecast() without growth = %v, want low hit and high not", watermarks)
+	}
+}
@@ -0,0 +1,130 @@
+package indexer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/concurrency"
+	"gopkg.in/olivere/elastic.v5"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Config contains options for connecting to Elasticsearch
+type Config struct {
+	Backend             string        // Index backend, elasticsearch5 if empty
+	URLs                []string      // Nodes to connect to
+	Username            string        // For basic authentication, if set
+	Password            string        // For basic authentication
+	APIKey              string        // Sent as ApiKey authorization, if set
+	CACert              string        // PEM file with certificate authorities for TLS, system pool if empty
+	Sniff               bool          // Discover other nodes in the cluster
+	HealthcheckInterval time.Duration // Time between checking node health, 0 disables
+	Concurrency         concurrency.Config
+	Routing             string // Routing of item documents, RoutingID if empty
+	IDs                 string // Scheme of document IDs, IDsCID if empty
+
+	Standby *Config // Cluster receiving document writes as well, e.g. in another region; optional
+}
+
+// apiKeyTransport adds ApiKey authorization to requests
+type apiKeyTransport struct {
+	base http.RoundTripper
+	key  string
+}
+
+// RoundTrip performs the request with authorization header
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers should not modify requests
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "ApiKey "+t.key)
+
+	return t.base.RoundTrip(r)
+}
+
+// transport returns the HTTP transport for configured TLS, authorization
+// and concurrency
+func (c *Config) transport() (http.RoundTripper, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if c.CACert != "" {
+		pem, err := ioutil.ReadFile(c.CACert)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CACert)
+		}
+
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	var rt http.RoundTripper = t
+	if c.APIKey != "" {
+		rt = &apiKeyTransport{base: rt, key: c.APIKey}
+	}
+
+	return concurrency.NewTransport(c.Concurrency, rt), nil
+}
+
+// NewClient returns an Elasticsearch client for given configuration
+func NewClient(config *Config) (*elastic.Client, error) {
+	transport, err := config.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	options := []elastic.ClientOptionFunc{
+		elastic.SetURL(config.URLs...),
+		elastic.SetHttpClient(&http.Client{Transport: transport}),
+		elastic.SetSniff(config.Sniff),
+		elastic.SetHealthcheck(config.HealthcheckInterval > 0),
+	}
+
+	if config.HealthcheckInterval > 0 {
+		options = append(options, elastic.SetHealthcheckInterval(config.HealthcheckInterval))
+	}
+
+	if config.Username != "" {
+		options = append(options, elastic.SetBasicAuth(config.Username, config.Password))
+	}
+
+	return elastic.NewClient(options...)
+}
+
+// New returns an indexer for given configuration, writing to the standby
+// cluster as well if configured
+func New(config *Config) (*Indexer, error) {
+	if err := checkBackend(config.Backend); err != nil {
+		return nil, err
+	}
+	if err := checkRouting(config.Routing); err != nil {
+		return nil, err
+	}
+	if err := checkIDs(config.IDs); err != nil {
+		return nil, err
+	}
+
+	el, err := NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Indexer{
+		ElasticSearch: el,
+		routing:       config.Routing,
+		ids:           config.IDs,
+	}
+
+	if config.Standby != nil {
+		i.Standby, err = NewClient(config.Standby)
+		if err != nil {
+			return nil, fmt.Errorf("standby cluster: %v", err)
+		}
+	}
+
+	return i, nil
+}
@@ -0,0 +1,168 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/olivere/elastic.v5"
+	"regexp"
+	"strings"
+)
+
+// metaIndex holds operator managed data, separate from indexed items
+const metaIndex = "ipfs-meta"
+
+// pinBoost is the score given to pinned results, ranking them above all others
+const pinBoost = 1e6
+
+// Curation boosts or pins a document for queries matching Pattern
+type Curation struct {
+	ID      string  `json:"-"`
+	Pattern string  `json:"pattern"` // Case insensitive regular expression matched against queries
+	Hash    string  `json:"hash"`    // Document to boost or pin
+	Boost   float64 `json:"boost"`   // Factor multiplied into relevance
+	Pinned  bool    `json:"pinned"`  // Pinned results are ranked above all others
+}
+
+// String returns a human readable description of the curation
+func (c *Curation) String() string {
+	if c.Pinned {
+		return fmt.Sprintf("%s: pin %s for /%s/", c.ID, c.Hash, c.Pattern)
+	}
+	return fmt.Sprintf("%s: boost %s by %g for /%s/", c.ID, c.Hash, c.Boost, c.Pattern)
+}
+
+// matches returns whether the curation applies to query
+func (c *Curation) matches(query string) bool {
+	re, err := regexp.Compile("(?i)" + c.Pattern)
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(strings.TrimSpace(query))
+}
+
+// Validate returns an error when the pattern or boost is invalid
+func (c *Curation) Validate() error {
+	if _, err := regexp.Compile(c.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern '%s': %v", c.Pattern, err)
+	}
+
+	if !c.Pinned && c.Boost <= 0 {
+		return fmt.Errorf("boost should be positive, got %g", c.Boost)
+	}
+
+	return nil
+}
+
+// AddCuration stores a curation in the meta index, returning its ID. A
+// curation with an ID replaces the one stored under it, if any.
+func (i *Indexer) AddCuration(ctx context.Context, c *Curation) (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+
+	result, err := i.ElasticSearch.Index().
+		Index(metaIndex).
+		Type("curation").
+		Id(c.ID).
+		BodyJson(c).
+		Refresh("true").
+		Do(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return result.Id, nil
+}
+
+// RemoveCuration removes a curation by ID
+func (i *Indexer) RemoveCuration(ctx context.Context, id string) error {
+	_, err := i.ElasticSearch.Delete().
+		Index(metaIndex).
+		Type("curation").
+		Id(id).
+		Refresh("true").
+		Do(ctx)
+
+	return err
+}
+
+// maxCurations limits the amount of curations retrieved
+const maxCurations = 1000
+
+// Curations returns all stored curations
+func (i *Indexer) Curations(ctx context.Context) ([]Curation, error) {
+	exists, err := i.ElasticSearch.IndexExists(metaIndex).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	result, err := i.ElasticSearch.Search(metaIndex).
+		Type("curation").
+		Size(maxCurations).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	curations := make([]Curation, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		var c Curation
+		if err := json.Unmarshal(*hit.Source, &c); err != nil {
+			return nil, err
+		}
+		c.ID = hit.Id
+
+		curations = append(curations, c)
+	}
+
+	return curations, nil
+}
+
+// matchingCurations returns the curations applying to a query, with the
+// IDs of the curated documents as hash
+func (i *Indexer) matchingCurations(ctx context.Context, query string) ([]Curation, error) {
+	curations, err := i.Curations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []Curation
+	for _, c := range curations {
+		if c.matches(query) {
+			// Curated documents are found by ID
+			c.Hash = i.id(c.Hash)
+			matching = append(matching, c)
+		}
+	}
+
+	return matching, nil
+}
+
+// curate applies boosts to a function score query and wraps it such that
+// pinned documents are ranked first, in the order the curations are given
+func curate(q *elastic.FunctionScoreQuery, curations []Curation) elastic.Query {
+	var pins []elastic.Query
+
+	for _, c := range curations {
+		if c.Pinned {
+			boost := pinBoost * float64(len(curations)-len(pins))
+			pins = append(pins, elastic.NewConstantScoreQuery(elastic.NewIdsQuery().Ids(c.Hash)).Boost(boost))
+		} else {
+			q.Add(elastic.NewIdsQuery().Ids(c.Hash), elastic.NewWeightFactorFunction(c.Boost))
+		}
+	}
+
+	if len(pins) == 0 {
+		return q
+	}
+
+	return elastic.NewBoolQuery().
+		Should(q).
+		Should(pins...).
+		MinimumNumberShouldMatch(1)
+}
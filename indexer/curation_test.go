@@ -0,0 +1,74 @@
+package indexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCurationMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		query   string
+		matches bool
+	}{
+		{"whitepaper", "IPFS Whitepaper", true},
+		{"^ipfs$", " ipfs ", true},
+		{"^ipfs$", "ipfs whitepaper", false},
+		{"(", "(", false},
+	}
+
+	for _, test := range tests {
+		c := &Curation{Pattern: test.pattern}
+		if matches := c.matches(test.query); matches != test.matches {
+			t.Errorf("/%s/.matches(%q) = %v, want %v", test.pattern, test.query, matches, test.matches)
+		}
+	}
+}
+
+func TestCurationValidate(t *testing.T) {
+	tests := []struct {
+		curation Curation
+		valid    bool
+	}{
+		{Curation{Pattern: "ipfs", Boost: 2}, true},
+		{Curation{Pattern: "ipfs", Pinned: true}, true},
+		{Curation{Pattern: "ipfs"}, false},
+		{Curation{Pattern: "ipfs", Boost: -1}, false},
+		{Curation{Pattern: "(", Boost: 2}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.curation.Validate(); (err == nil) != test.valid {
+			t.Errorf("%+v.Validate() = %v, valid %v", test.curation, err, test.valid)
+		}
+	}
+}
+
+func TestCurateWithoutPins(t *testing.T) {
+	q := searchQuery("test", []Curation{{Hash: "a", Boost: 2}}, nil)
+
+	source := querySource(t, q)
+	if !strings.HasPrefix(source, `{"function_score"`) {
+		t.Errorf("boosts without pins should keep the function score query, got %s", source)
+	}
+}
+
+func TestCuratePinOrder(t *testing.T) {
+	q := searchQuery("test", []Curation{
+		{Hash: "first", Pinned: true},
+		{Hash: "boosted", Boost: 2},
+		{Hash: "second", Pinned: true},
+	}, nil)
+
+	source := querySource(t, q)
+
+	// The first pin gets the highest boost
+	first := `{"constant_score":{"boost":3000000,"filter":{"ids":{"values":["first"]}}}}`
+	second := `{"constant_score":{"boost":2000000,"filter":{"ids":{"values":["second"]}}}}`
+
+	for _, pin := range []string{first, second} {
+		if !strings.Contains(source, pin) {
+			t.Errorf("query %s does not contain pin %s", source, pin)
+		}
+	}
+}
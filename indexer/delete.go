@@ -0,0 +1,49 @@
+package indexer
+
+import (
+	"context"
+	"gopkg.in/olivere/elastic.v5"
+)
+
+// DeleteItem removes the document for a hash from the indices of all
+// document types, returning whether any document was deleted
+func (i *Indexer) DeleteItem(ctx context.Context, hash string) (bool, error) {
+	var deleted bool
+
+	err := i.write(func(c *elastic.Client) error {
+		d, err := deleteItem(ctx, c, i.id(hash), i.route(hash))
+		if c == i.ElasticSearch {
+			deleted = d
+		}
+
+		return err
+	})
+
+	return deleted, err
+}
+
+// deleteItem removes the document with an ID, routed by routing, from a
+// cluster
+func deleteItem(ctx context.Context, c *elastic.Client, id, routing string) (bool, error) {
+	deleted := false
+
+	for _, doctype := range docTypes {
+		_, err := c.Delete().
+			Index(typeAliases[doctype]).
+			Type(doctype).
+			Id(id).
+			Routing(routing).
+			Do(ctx)
+
+		if err != nil {
+			if elastic.IsNotFound(err) {
+				continue
+			}
+			return deleted, err
+		}
+
+		deleted = true
+	}
+
+	return deleted, nil
+}
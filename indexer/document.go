@@ -0,0 +1,43 @@
+package indexer
+
+import (
+	"context"
+	"gopkg.in/olivere/elastic.v5"
+)
+
+// GetDocument returns the indexed document for a hash, or nil when it
+// has not been indexed
+func (i *Indexer) GetDocument(ctx context.Context, hash string) (*Document, error) {
+	documents, err := i.GetDocuments(ctx, hash)
+	if err != nil || len(documents) == 0 {
+		return nil, err
+	}
+
+	return &documents[0], nil
+}
+
+// GetDocuments returns the indexed documents for hashes, with metadata
+// overrides applied; hashes which have not been indexed are left out
+func (i *Indexer) GetDocuments(ctx context.Context, hashes ...string) ([]Document, error) {
+	found, err := i.multiGet(ctx, elastic.NewFetchSourceContext(true), hashes...)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]Document, 0, len(found))
+	for _, result := range found {
+		source, err := mergeOverride(result.Source)
+		if err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, Document{
+			Hash:      result.Id,
+			Type:      result.Type,
+			Permalink: permalink(result.Id),
+			Source:    source,
+		})
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,104 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ipfs-search/ipfs-search/simhash"
+	"gopkg.in/olivere/elastic.v5"
+	"sort"
+)
+
+// Duplicate is a file with text similar to that of another file
+type Duplicate struct {
+	Hash     string `json:"hash"`
+	Distance int    `json:"distance"` // Differing bits of the simhashes
+}
+
+// signatureSource holds the simhash of an indexed file
+type signatureSource struct {
+	Simhash string `json:"simhash"`
+}
+
+// parseSignature returns the simhash in a document source, and false when
+// it has none
+func parseSignature(source *json.RawMessage) (uint64, bool) {
+	if source == nil {
+		return 0, false
+	}
+
+	var s signatureSource
+	if err := json.Unmarshal(*source, &s); err != nil || s.Simhash == "" {
+		return 0, false
+	}
+
+	signature, err := simhash.Parse(s.Simhash)
+	if err != nil {
+		return 0, false
+	}
+
+	return signature, true
+}
+
+// NearDuplicates returns up to size files with extracted text differing
+// from that of the file with given hash by at most simhash.MaxDistance
+// bits, closest first. It returns nil if the file has no simhash.
+func (i *Indexer) NearDuplicates(ctx context.Context, hash string, size int) ([]Duplicate, error) {
+	result, err := i.ElasticSearch.Get().
+		Index(typeAliases["file"]).
+		Type("file").
+		Id(i.id(hash)).
+		Routing(i.route(hash)).
+		FetchSourceContext(elastic.NewFetchSourceContext(true).Include("simhash")).
+		Do(ctx)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	signature, ok := parseSignature(result.Source)
+	if !ok {
+		return nil, nil
+	}
+
+	bands := simhash.Bands(signature)
+	terms := make([]interface{}, len(bands))
+	for n, band := range bands {
+		terms[n] = band
+	}
+
+	// Candidates share a band; the exact distance is computed here
+	search, err := i.ElasticSearch.Search(typeAliases["file"]).
+		Query(elastic.NewBoolQuery().
+			Filter(elastic.NewTermsQuery("simhash-bands", terms...)).
+			MustNot(elastic.NewIdsQuery().Ids(i.id(hash)))).
+		FetchSourceContext(elastic.NewFetchSourceContext(true).Include("simhash")).
+		Size(size * len(bands)).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := []Duplicate{}
+	for _, hit := range search.Hits.Hits {
+		candidate, ok := parseSignature(hit.Source)
+		if !ok {
+			continue
+		}
+
+		if d := simhash.Distance(signature, candidate); d <= simhash.MaxDistance {
+			duplicates = append(duplicates, Duplicate{Hash: hit.Id, Distance: d})
+		}
+	}
+
+	sort.SliceStable(duplicates, func(a, b int) bool {
+		return duplicates[a].Distance < duplicates[b].Distance
+	})
+
+	if len(duplicates) > size {
+		duplicates = duplicates[:size]
+	}
+
+	return duplicates, nil
+}
@@ -0,0 +1,87 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"gopkg.in/olivere/elastic.v5"
+	"time"
+)
+
+// Erasure is an audit entry for content erased from the index
+type Erasure struct {
+	Time         time.Time `json:"time"`
+	Operator     string    `json:"operator"`
+	Reason       string    `json:"reason"`
+	Request      string    `json:"request"`                // CID or checksum the erasure was requested for
+	Hashes       []string  `json:"hashes"`                 // Denied hashes
+	Removed      []string  `json:"removed"`                // Documents removed, by the run completing the erasure only
+	Unlinked     []string  `json:"unlinked,omitempty"`     // Directories links were removed from
+	Dereferenced []string  `json:"dereferenced,omitempty"` // Entries references were removed from
+}
+
+// RecordErasure adds an erasure to the audit log in the meta index
+func (i *Indexer) RecordErasure(ctx context.Context, e *Erasure) error {
+	return i.write(func(c *elastic.Client) error {
+		_, err := c.Index().
+			Index(metaIndex).
+			Type("erasure").
+			BodyJson(e).
+			Refresh("true").
+			Do(ctx)
+
+		return err
+	})
+}
+
+// Erasures returns the audit log of erasures, most recent first
+func (i *Indexer) Erasures(ctx context.Context, size int) ([]*Erasure, error) {
+	exists, err := i.ElasticSearch.IndexExists(metaIndex).Do(ctx)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	result, err := i.ElasticSearch.Search(metaIndex).
+		Type("erasure").
+		SortBy(elastic.NewFieldSort("time").Desc().UnmappedType("date")).
+		Size(size).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	erasures := make([]*Erasure, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		e := &Erasure{}
+		if err := json.Unmarshal(*hit.Source, e); err != nil {
+			return nil, err
+		}
+		erasures = append(erasures, e)
+	}
+
+	return erasures, nil
+}
+
+// DeleteMeta removes the crawl history and overrides of a hash from the
+// meta index, e.g. when it is erased; missing ones are skipped
+func (i *Indexer) DeleteMeta(ctx context.Context, hash string) error {
+	for _, kind := range []string{"history", operatorOverride, publisherOverride} {
+		err := i.write(func(c *elastic.Client) error {
+			_, err := c.Delete().
+				Index(metaIndex).
+				Type(kind).
+				Id(i.id(hash)).
+				Do(ctx)
+
+			if elastic.IsNotFound(err) {
+				return nil
+			}
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
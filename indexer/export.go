@@ -0,0 +1,98 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/olivere/elastic.v5"
+	"io"
+	"time"
+)
+
+// exportFields are the fields of exported documents; content and
+// directory listings are left out to keep exports compact, submitters as
+// they may identify clients
+var exportFields = []string{
+	"size", "first-seen", "last-seen", "references", "reference-count", "first-referenced", "aliases",
+	"quality", "popularity", "availability", "language.language", "override", "publisher-override", "metadata-partial", "mimetype", "simhash", "location",
+	"content-created", "content-modified", "authors", "filename", "extension", "tags", "labels", "archive-format", "categories", "nsfw_score", "media", "thumbnail",
+	"provenance.source", "provenance.job", "provenance.roots",
+	"item-count", "file-count", "directory-count", "files-size", "total-size",
+	"metadata.Content-Type", "metadata.title", "metadata.description", "metadata.keywords",
+}
+
+// exportBatchSize is the amount of documents retrieved at once when exporting
+const exportBatchSize = 1000
+
+// Export calls fn for indexed files and directories with the most
+// relevant fields, with metadata overrides applied. When since is set,
+// only documents seen since then are exported. Exporting stops at the
+// first error returned by fn.
+func (i *Indexer) Export(ctx context.Context, since time.Time, fn func(*Document) error) error {
+	fsc := elastic.NewFetchSourceContext(true).Include(exportFields...)
+
+	var query elastic.Query = elastic.NewMatchAllQuery()
+	if !since.IsZero() {
+		query = elastic.NewRangeQuery("last-seen").Gte(since.UTC().Format(time.RFC3339))
+	}
+
+	scroll := i.ElasticSearch.Scroll(typeAliases["file"], typeAliases["directory"]).
+		Query(query).
+		FetchSourceContext(fsc).
+		Sort("_doc", true).
+		Size(exportBatchSize)
+	defer scroll.Clear(context.Background())
+
+	for {
+		result, err := scroll.Do(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, d := range hitsToDocuments(result.Hits.Hits) {
+			d.Source, err = mergeOverride(d.Source)
+			if err != nil {
+				return err
+			}
+
+			if err := fn(&d); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Import indexes documents as they are, replacing existing documents with
+// the same hash and type
+func (i *Indexer) Import(ctx context.Context, documents []Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	requests := make([]elastic.BulkableRequest, 0, len(documents))
+	for _, d := range documents {
+		alias, err := typeAlias(d.Type)
+		if err != nil {
+			return err
+		}
+
+		requests = append(requests, elastic.NewBulkIndexRequest().
+			Index(alias).Type(d.Type).
+			Id(i.id(d.Hash)).
+			Routing(i.route(d.Hash)).
+			Doc(d.Source))
+	}
+
+	result, err := i.bulk(ctx, requests)
+	if err != nil {
+		return err
+	}
+
+	if failed := result.Failed(); len(failed) > 0 {
+		return fmt.Errorf("failed importing %d documents, first: %s", len(failed), failed[0].Id)
+	}
+
+	return nil
+}
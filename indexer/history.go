@@ -0,0 +1,72 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"gopkg.in/olivere/elastic.v5"
+	"time"
+)
+
+// Attempt describes a single attempt to crawl a hash
+type Attempt struct {
+	Time     time.Time `json:"time"`
+	Outcome  string    `json:"outcome"`
+	Category string    `json:"error-category,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Duration int64     `json:"duration"` // Milliseconds
+}
+
+// historyScript appends an attempt, keeping the last params.size attempts
+const historyScript = `
+ctx._source.attempts.add(params.attempt);
+while (ctx._source.attempts.size() > params.size) {
+	ctx._source.attempts.remove(0);
+}`
+
+// RecordAttempt adds a crawl attempt to the history of a hash in the meta
+// index, keeping the last size attempts
+func (i *Indexer) RecordAttempt(ctx context.Context, hash string, attempt *Attempt, size int) error {
+	script := elastic.NewScriptInline(historyScript).
+		Lang("painless").
+		Param("attempt", attempt).
+		Param("size", size)
+
+	return i.write(func(c *elastic.Client) error {
+		_, err := c.Update().
+			Index(metaIndex).
+			Type("history").
+			Id(i.id(hash)).
+			Script(script).
+			Upsert(map[string]interface{}{
+				"attempts": []*Attempt{attempt},
+			}).
+			RetryOnConflict(3).
+			Do(ctx)
+
+		return err
+	})
+}
+
+// History returns the recorded crawl attempts for a hash, oldest first
+func (i *Indexer) History(ctx context.Context, hash string) ([]Attempt, error) {
+	result, err := i.ElasticSearch.Get().
+		Index(metaIndex).
+		Type("history").
+		Id(i.id(hash)).
+		Do(ctx)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	history := struct {
+		Attempts []Attempt `json:"attempts"`
+	}{}
+	if err := json.Unmarshal(*result.Source, &history); err != nil {
+		return nil, err
+	}
+
+	return history.Attempts, nil
+}
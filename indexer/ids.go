@@ -0,0 +1,196 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/cids"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/olivere/elastic.v5"
+	"io"
+	"net/http"
+)
+
+// Schemes of document IDs
+const (
+	// IDsCID identifies documents by the CID as crawled; the default
+	IDsCID = "cid"
+
+	// IDsMultihash identifies documents by the base58 multihash of their
+	// CID, which is the CIDv0 of dag-pb content
+	IDsMultihash = "multihash"
+
+	// IDsCIDv1 identifies documents by their canonical CID, the base32
+	// CIDv1, like permalinks
+	IDsCIDv1 = "cidv1"
+)
+
+// checkIDs returns an error for unsupported document ID schemes
+func checkIDs(scheme string) error {
+	switch scheme {
+	case "", IDsCID, IDsMultihash, IDsCIDv1:
+		return nil
+	default:
+		return fmt.Errorf("unsupported document IDs %q, use %s, %s or %s", scheme, IDsCID, IDsMultihash, IDsCIDv1)
+	}
+}
+
+// documentID returns the ID of the documents for hash under an ID scheme.
+// Invalid hashes are their own ID. As the scheme only depends on the
+// content hashed, IDs of IDs are the same.
+func documentID(scheme, hash string) string {
+	switch scheme {
+	case IDsMultihash:
+		if c, err := cids.Decode(hash); err == nil {
+			return c.Hash().B58String()
+		}
+	case IDsCIDv1:
+		if id := permalink(hash); id != "" {
+			return id
+		}
+	}
+
+	return hash
+}
+
+// id returns the ID of the documents for hash
+func (i *Indexer) id(hash string) string {
+	return documentID(i.ids, hash)
+}
+
+// idBatchSize is the amount of documents checked at once when migrating
+// document IDs
+const idBatchSize = 1000
+
+// IDMigration counts the documents checked and changed by MigrateIDs
+type IDMigration struct {
+	Checked int `json:"checked"`
+	Moved   int `json:"moved"`  // Stored under their new ID
+	Merged  int `json:"merged"` // Removed, as a document with their new ID existed
+}
+
+// MigrateIDs moves documents with IDs not following the configured scheme
+// to their new ID, on the primary and standby cluster: item documents and
+// the crawl history and overrides in the meta index. When a document with
+// the new ID exists, e.g. for another version of the same CID, it is kept
+// and the duplicate removed. Crawlers can keep running, as long as they
+// use the new scheme.
+func (i *Indexer) MigrateIDs(ctx context.Context) (*IDMigration, error) {
+	if i.ids == "" || i.ids == IDsCID {
+		return nil, fmt.Errorf("documents are identified by CIDs as crawled, which can't be restored")
+	}
+
+	m := new(IDMigration)
+	for _, doctype := range docTypes {
+		if err := i.migrateIDs(ctx, m, typeAliases[doctype], doctype, true); err != nil {
+			return m, err
+		}
+	}
+
+	exists, err := i.ElasticSearch.IndexExists(metaIndex).Do(ctx)
+	if err != nil || !exists {
+		return m, err
+	}
+
+	for _, kind := range []string{"history", operatorOverride, publisherOverride} {
+		if err := i.migrateIDs(ctx, m, metaIndex, kind, false); err != nil {
+			return m, err
+		}
+	}
+
+	return m, nil
+}
+
+// migrateIDs moves the documents of a type in index to their new ID;
+// routed documents are item documents, routed by their hash
+func (i *Indexer) migrateIDs(ctx context.Context, m *IDMigration, index, doctype string, routed bool) error {
+	scroll := i.ElasticSearch.Scroll(index).
+		Type(doctype).
+		Sort("_doc", true).
+		Size(idBatchSize)
+	defer scroll.Clear(context.Background())
+
+	for {
+		result, err := scroll.Do(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := i.moveDocuments(ctx, m, result.Hits.Hits, routed); err != nil {
+			return err
+		}
+	}
+}
+
+// moveDocuments stores documents under their new ID, if it differs, and
+// removes them under their old ID once stored or found to exist already
+func (i *Indexer) moveDocuments(ctx context.Context, m *IDMigration, hits []*elastic.SearchHit, routed bool) error {
+	routing := func(hit *elastic.SearchHit) string {
+		if !routed {
+			return ""
+		}
+		return i.route(hit.Id)
+	}
+
+	var moving []*elastic.SearchHit
+	var creates []elastic.BulkableRequest
+	for _, hit := range hits {
+		m.Checked++
+
+		id := i.id(hit.Id)
+		if id == hit.Id {
+			continue
+		}
+
+		moving = append(moving, hit)
+		creates = append(creates, elastic.NewBulkIndexRequest().
+			Index(hit.Index).Type(hit.Type).
+			Id(id).
+			Routing(routing(hit)).
+			OpType("create").
+			Doc(hit.Source))
+	}
+	if len(creates) == 0 {
+		return nil
+	}
+
+	result, err := i.bulk(ctx, creates)
+	if err != nil {
+		return err
+	}
+
+	var deletes []elastic.BulkableRequest
+	for n, item := range result.Items {
+		for _, r := range item {
+			switch {
+			case r.Status == http.StatusConflict:
+				m.Merged++
+			case r.Status >= 200 && r.Status < 300:
+				m.Moved++
+			default:
+				log.WithField("id", moving[n].Id).Warnf("Error moving document: status %d", r.Status)
+				continue
+			}
+
+			deletes = append(deletes, elastic.NewBulkDeleteRequest().
+				Index(moving[n].Index).Type(moving[n].Type).
+				Id(moving[n].Id).
+				Routing(routing(moving[n])))
+		}
+	}
+	if len(deletes) == 0 {
+		return nil
+	}
+
+	result, err = i.bulk(ctx, deletes)
+	if err != nil {
+		return err
+	}
+	if failed := result.Failed(); len(failed) > 0 {
+		return fmt.Errorf("failed removing %d documents under their old ID, first: %s", len(failed), failed[0].Id)
+	}
+
+	return nil
+}
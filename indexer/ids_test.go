@@ -0,0 +1,43 @@
+package indexer
+
+import (
+	"testing"
+)
+
+func TestDocumentID(t *testing.T) {
+	const (
+		v0  = "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+		v1  = "bafybeie5nqv6kd3qnfjupgvz34woh3oksc3iau6abmyajn7qvtf6d2ho34"
+		raw = "bafkreibm6jg3ux5qumhcn2b3flc3tyu6dmlb4xa7u5bf44yegnrjhc4yeq"
+	)
+
+	tests := []struct {
+		scheme string
+		hash   string
+		want   string
+	}{
+		{"", v1, v1},
+		{IDsCID, v0, v0},
+		{IDsCIDv1, v0, v1},
+		{IDsCIDv1, v1, v1},
+		{IDsMultihash, v1, v0},
+		{IDsMultihash, v0, v0},
+		{IDsMultihash, raw, "QmRN6wdp1S2A5EtjW9A3M1vKSBuQQGcgvuhoMUoEz4iiT5"},
+		{IDsCIDv1, "invalid", "invalid"},
+	}
+
+	for _, test := range tests {
+		got := documentID(test.scheme, test.hash)
+		if got != test.want {
+			t.Errorf("documentID(%q, %s) = %s, want %s", test.scheme, test.hash, got, test.want)
+		}
+
+		if again := documentID(test.scheme, got); again != got {
+			t.Errorf("documentID(%q, %s) = %s, want the ID itself", test.scheme, got, again)
+		}
+	}
+
+	if err := checkIDs("sha256"); err == nil {
+		t.Error("checkIDs(sha256) returned no error")
+	}
+}
@@ -0,0 +1,244 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+// legacyIndex returns whether documents are stored in the single index
+// used before every type got its own, which has the name of the search alias
+func (i *Indexer) legacyIndex(ctx context.Context) (bool, error) {
+	exists, err := i.ElasticSearch.IndexExists(searchAlias).Do(ctx)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	indices, err := i.ElasticSearch.IndexGet(searchAlias).Do(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// For aliases, the result is keyed by the indices they refer to
+	_, ok := indices[searchAlias]
+	return ok, nil
+}
+
+// createTypeIndex creates the index for a document type with settings,
+// mapping and given aliases, if it does not exist yet. It returns whether
+// the index was created.
+func (i *Indexer) createTypeIndex(ctx context.Context, doctype string, aliases ...string) (bool, error) {
+	name := indexName(doctype)
+
+	exists, err := i.ElasticSearch.IndexExists(name).Do(ctx)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	body, err := indexBody(doctype, aliases...)
+	if err != nil {
+		return false, err
+	}
+
+	log.WithField("index", name).Info("Creating index")
+
+	_, err = i.ElasticSearch.CreateIndex(name).BodyJson(body).Do(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CreateIndex creates the indices for all document types with settings
+// and mapping, if they do not exist yet. It returns whether any index was
+// created. Data in the legacy single index has to be migrated first.
+func (i *Indexer) CreateIndex(ctx context.Context) (bool, error) {
+	legacy, err := i.legacyIndex(ctx)
+	if err != nil {
+		return false, err
+	}
+	if legacy {
+		return false, fmt.Errorf("legacy index '%s' found, migrate it to per-type indices first", searchAlias)
+	}
+
+	var created bool
+	for _, doctype := range docTypes {
+		c, err := i.createTypeIndex(ctx, doctype, typeAliases[doctype], searchAlias)
+		if err != nil {
+			return created, err
+		}
+		created = created || c
+	}
+
+	return created, nil
+}
+
+// properties returns the properties of a mapping or object field, if any
+func properties(m map[string]interface{}) map[string]interface{} {
+	p, _ := m["properties"].(map[string]interface{})
+	return p
+}
+
+// fieldType returns the type of a field mapping; objects have no explicit type
+func fieldType(field map[string]interface{}) string {
+	if t, ok := field["type"].(string); ok {
+		return t
+	}
+	return "object"
+}
+
+// conflicts returns the (nested) fields which are mapped differently in
+// actual than in expected
+func conflicts(expected, actual map[string]interface{}, prefix string) []string {
+	var result []string
+
+	for name, e := range properties(expected) {
+		a, ok := properties(actual)[name].(map[string]interface{})
+		if !ok {
+			// Field not mapped yet; can be added
+			continue
+		}
+		e := e.(map[string]interface{})
+
+		if fieldType(e) != fieldType(a) {
+			result = append(result, fmt.Sprintf("%s%s (%s instead of %s)", prefix, name, fieldType(a), fieldType(e)))
+			continue
+		}
+
+		result = append(result, conflicts(e, a, prefix+name+".")...)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// ensureMapping verifies the mapping of the index for a document type,
+// adding missing fields. It returns fields which are mapped differently.
+func (i *Indexer) ensureMapping(ctx context.Context, doctype string) ([]string, error) {
+	expected, err := typeMapping(doctype)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := i.ElasticSearch.GetMapping().Index(indexName(doctype)).Type(doctype).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index, _ := result[indexName(doctype)].(map[string]interface{})
+	types, _ := index["mappings"].(map[string]interface{})
+	actual, _ := types[doctype].(map[string]interface{})
+
+	c := conflicts(expected, actual, doctype+".")
+	if len(c) > 0 {
+		return c, nil
+	}
+
+	log.WithField("type", doctype).Info("Updating mapping")
+
+	_, err = i.ElasticSearch.PutMapping().
+		Index(indexName(doctype)).
+		Type(doctype).
+		BodyJson(expected).
+		Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error updating mapping for %s: %v", doctype, err)
+	}
+
+	return nil, nil
+}
+
+// hasAnalyzers returns whether index settings define all analyzers
+func hasAnalyzers(settings map[string]interface{}) bool {
+	index, _ := settings["index"].(map[string]interface{})
+	actual, _ := index["analysis"].(map[string]interface{})
+	analyzers, _ := actual["analyzer"].(map[string]interface{})
+
+	for name := range mustParse(analysis)["analyzer"].(map[string]interface{}) {
+		if _, ok := analyzers[name]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ensureAnalysis adds missing analyzers to the index for a document type.
+// Analysis settings can only be changed on closed indices, so the index
+// is briefly closed.
+func (i *Indexer) ensureAnalysis(ctx context.Context, doctype string) error {
+	name := indexName(doctype)
+
+	result, err := i.ElasticSearch.IndexGetSettings(name).Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	if r, ok := result[name]; ok && hasAnalyzers(r.Settings) {
+		return nil
+	}
+
+	log.WithField("index", name).Warn("Closing index to update analysis settings")
+
+	if _, err := i.ElasticSearch.CloseIndex(name).Do(ctx); err != nil {
+		return err
+	}
+
+	_, err = i.ElasticSearch.IndexPutSettings(name).
+		BodyJson(map[string]interface{}{"analysis": mustParse(analysis)}).
+		Do(ctx)
+
+	// Reopen regardless
+	if _, openErr := i.ElasticSearch.OpenIndex(name).Do(ctx); openErr != nil && err == nil {
+		err = openErr
+	}
+	if err != nil {
+		return fmt.Errorf("error updating analysis settings for %s: %v", name, err)
+	}
+
+	return nil
+}
+
+// EnsureIndex creates the indices when they don't exist and otherwise
+// verifies their mappings, adding missing fields and analyzers.
+// Fields mapped with a different type can't be changed in place; these
+// are returned in the error as they require reindexing.
+func (i *Indexer) EnsureIndex(ctx context.Context) error {
+	if i.Standby != nil {
+		standby := &Indexer{ElasticSearch: i.Standby, routing: i.routing, ids: i.ids}
+		if err := standby.EnsureIndex(ctx); err != nil {
+			return fmt.Errorf("standby cluster: %v", err)
+		}
+	}
+
+	if _, err := i.CreateIndex(ctx); err != nil {
+		return err
+	}
+
+	var allConflicts []string
+	for _, doctype := range docTypes {
+		if err := i.ensureAnalysis(ctx, doctype); err != nil {
+			return err
+		}
+
+		c, err := i.ensureMapping(ctx, doctype)
+		if err != nil {
+			return err
+		}
+		allConflicts = append(allConflicts, c...)
+	}
+
+	if len(allConflicts) > 0 {
+		sort.Strings(allConflicts)
+		return fmt.Errorf("mapping conflicts, reindex required: %s", strings.Join(allConflicts, ", "))
+	}
+
+	return nil
+}
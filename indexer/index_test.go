@@ -0,0 +1,40 @@
+package indexer
+
+import (
+	"testing"
+)
+
+func TestHasAnalyzers(t *testing.T) {
+	body, err := indexBody("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := map[string]interface{}{
+		"index": map[string]interface{}{
+			"analysis": body["settings"].(map[string]interface{})["analysis"],
+		},
+	}
+
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		want     bool
+	}{
+		{"created", created, true},
+		{"none", map[string]interface{}{}, false},
+		{"other analyzers", map[string]interface{}{
+			"index": map[string]interface{}{
+				"analysis": map[string]interface{}{
+					"analyzer": map[string]interface{}{"filename": map[string]interface{}{}},
+				},
+			},
+		}, false},
+	}
+
+	for _, test := range tests {
+		if got := hasAnalyzers(test.settings); got != test.want {
+			t.Errorf("hasAnalyzers(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
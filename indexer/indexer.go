@@ -3,74 +3,161 @@ package indexer
 import (
 	"context"
 	"encoding/json"
+	"github.com/ipfs-search/ipfs-search/tracing"
+	log "github.com/sirupsen/logrus"
 	"gopkg.in/olivere/elastic.v5"
-	"log"
 )
 
 // Indexer performs indexing of items and its references using ElasticCloud
 type Indexer struct {
 	ElasticSearch *elastic.Client
+
+	// Standby receives writes of documents and their overrides as well,
+	// keeping a search cluster elsewhere in sync; optional
+	Standby *elastic.Client
+
+	routing string // Routing of item documents, RoutingID if empty
+	ids     string // Scheme of document IDs, IDsCID if empty
+}
+
+// write performs a write on the primary cluster and, when it succeeds, on
+// the standby. Standby failures are logged rather than returned, so an
+// unavailable standby does not stop indexing.
+func (i *Indexer) write(fn func(*elastic.Client) error) error {
+	if err := fn(i.ElasticSearch); err != nil {
+		return err
+	}
+
+	if i.Standby != nil {
+		if err := fn(i.Standby); err != nil {
+			log.WithError(err).Warn("Error writing to standby cluster")
+		}
+	}
+
+	return nil
+}
+
+// bulk performs bulk requests on the primary cluster and, when they
+// succeed, on the standby; it returns the response of the primary
+func (i *Indexer) bulk(ctx context.Context, requests []elastic.BulkableRequest) (*elastic.BulkResponse, error) {
+	var result *elastic.BulkResponse
+
+	err := i.write(func(c *elastic.Client) error {
+		r, err := c.Bulk().Add(requests...).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		if c == i.ElasticSearch {
+			result = r
+		} else if failed := r.Failed(); len(failed) > 0 {
+			log.Warnf("Failed %d bulk requests on standby cluster, first: %s", len(failed), failed[0].Id)
+		}
+
+		return nil
+	})
+
+	return result, err
 }
 
 // IndexItem adds or updates an IPFS item with arbitrary properties
-func (i *Indexer) IndexItem(ctx context.Context, doctype string, hash string, properties map[string]interface{}) error {
-	_, err := i.ElasticSearch.Update().
-		Index("ipfs").
-		Type(doctype).
-		Id(hash).
-		Doc(properties).
-		DocAsUpsert(true).
-		Do(ctx)
+func (i *Indexer) IndexItem(ctx context.Context, doctype string, hash string, properties map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, "IndexItem", doctype, hash)
+	defer func() { tracing.End(span, err) }()
 
+	alias, err := typeAlias(doctype)
 	if err != nil {
-		// Handle error
 		return err
 	}
 
-	return nil
+	return i.write(func(c *elastic.Client) error {
+		_, err := c.Update().
+			Index(alias).
+			Type(doctype).
+			Id(i.id(hash)).
+			Routing(i.route(hash)).
+			Doc(properties).
+			DocAsUpsert(true).
+			Do(ctx)
+
+		return err
+	})
 }
 
-// extractRefrences reads the refernces from the JSON response from ElasticSearch
-func extractReferences(result *elastic.GetResult) (References, error) {
-	var parsedResult map[string]References
+// multiGet returns the documents found for hashes in the indices of all
+// document types. Unlike searches, gets are realtime.
+func (i *Indexer) multiGet(ctx context.Context, fsc *elastic.FetchSourceContext, hashes ...string) ([]*elastic.GetResult, error) {
+	mget := i.ElasticSearch.MultiGet()
+	for _, hash := range hashes {
+		for _, doctype := range docTypes {
+			mget.Add(elastic.NewMultiGetItem().
+				Index(typeAliases[doctype]).Type(doctype).
+				Id(i.id(hash)).
+				Routing(i.route(hash)).
+				FetchSource(fsc))
+		}
+	}
 
-	err := json.Unmarshal(*result.Source, &parsedResult)
+	result, err := mget.Do(ctx)
 	if err != nil {
-		log.Printf("can't unmarshal references JSON: %s", *result.Source)
 		return nil, err
 	}
 
-	references := parsedResult["references"]
+	var found []*elastic.GetResult
+	for _, doc := range result.Docs {
+		if doc.Found {
+			found = append(found, doc)
+		}
+	}
 
-	return references, nil
+	return found, nil
 }
 
-// GetReferences returns existing references and the type for an object.
+// Item represents the indexed state of an existing object
+type Item struct {
+	Type       string     `json:"-"`
+	References References `json:"references"`
+	Aliases    []string   `json:"aliases"`
+	Size       uint64     `json:"size"`
+
+	// Quality score of extracted metadata, nil when not (yet) known
+	ContentQuality *float64 `json:"content-quality"`
+
+	// Indexed without file contents, to be crawled in full later
+	StructureOnly bool `json:"structure-only"`
+}
+
+// extractItem reads the references, aliases, size, content quality and structure-only mark from the JSON response from ElasticSearch
+func extractItem(result *elastic.GetResult) (*Item, error) {
+	item := new(Item)
+
+	err := json.Unmarshal(*result.Source, item)
+	if err != nil {
+		log.WithField("hash", result.Id).Errorf("can't unmarshal item JSON: %s", *result.Source)
+		return nil, err
+	}
+
+	item.Type = result.Type
+
+	return item, nil
+}
+
+// GetItem returns existing references, aliases and the type for an object.
 // When no object is found an empty list of references is returned, the
 // type is "" and no error is set.
-func (i *Indexer) GetReferences(ctx context.Context, hash string) (References, string, error) {
+func (i *Indexer) GetItem(ctx context.Context, hash string) (*Item, error) {
 	fsc := elastic.NewFetchSourceContext(true)
-	fsc.Include("references")
-
-	result, err := i.ElasticSearch.
-		Get().
-		Index("ipfs").Type("_all").
-		FetchSourceContext(fsc).
-		Id(hash).
-		Do(ctx)
+	fsc.Include("references", "aliases", "size", "content-quality", "structure-only")
 
+	found, err := i.multiGet(ctx, fsc, hash)
 	if err != nil {
-		if elastic.IsNotFound(err) {
-			// Initialize empty references when none have been found
-			return []Reference{}, "", nil
-		}
-		return nil, "", err
+		return nil, err
 	}
 
-	references, err := extractReferences(result)
-	if err != nil {
-		return nil, "", err
+	if len(found) == 0 {
+		// Initialize empty references when none have been found
+		return &Item{References: []Reference{}}, nil
 	}
 
-	return references, result.Type, nil
+	return extractItem(found[0])
 }
@@ -0,0 +1,37 @@
+package indexer
+
+import (
+	"fmt"
+)
+
+// searchAlias refers to the indices of all document types, for searching
+const searchAlias = "ipfs"
+
+// indexVersion suffixes index names, so new indices can be created next
+// to the current ones when reindexing is required
+const indexVersion = "v1"
+
+// docTypes are the document types, each of which has its own index
+var docTypes = []string{"file", "directory", "invalid"}
+
+// typeAliases map document types to the alias of their index
+var typeAliases = map[string]string{
+	"file":      "ipfs_files",
+	"directory": "ipfs_directories",
+	"invalid":   "ipfs_invalids",
+}
+
+// typeAlias returns the alias for the index of a document type
+func typeAlias(doctype string) (string, error) {
+	alias, ok := typeAliases[doctype]
+	if !ok {
+		return "", fmt.Errorf("unknown document type: %s", doctype)
+	}
+
+	return alias, nil
+}
+
+// indexName returns the name of the index for a document type
+func indexName(doctype string) string {
+	return typeAliases[doctype] + "_" + indexVersion
+}
@@ -0,0 +1,69 @@
+package indexer
+
+import (
+	"context"
+	"gopkg.in/olivere/elastic.v5"
+	"time"
+)
+
+// JobStatus describes the items indexed for a crawl job, as recorded in
+// their provenance
+type JobStatus struct {
+	Job      string           `json:"job"`
+	Counts   map[string]int64 `json:"counts"`    // Indexed items by type, invalid ones included
+	LastSeen time.Time        `json:"last_seen"` // Of the most recently indexed item
+}
+
+// Jobs returns the status of up to size crawl jobs, by items indexed for
+// them most recently first
+func (i *Indexer) Jobs(ctx context.Context, size int) ([]JobStatus, error) {
+	jobs := elastic.NewTermsAggregation().
+		Field("provenance.job").
+		Size(size).
+		OrderByAggregation("last", false).
+		SubAggregation("types", elastic.NewTermsAggregation().Field("_type")).
+		SubAggregation("last", elastic.NewMaxAggregation().Field("last-seen"))
+
+	result, err := i.ElasticSearch.Search(typeAliases["file"], typeAliases["directory"], typeAliases["invalid"]).
+		Size(0).
+		Aggregation("jobs", jobs).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []JobStatus
+
+	buckets, ok := result.Aggregations.Terms("jobs")
+	if !ok {
+		return statuses, nil
+	}
+
+	for _, bucket := range buckets.Buckets {
+		job, ok := bucket.Key.(string)
+		if !ok {
+			continue
+		}
+
+		status := JobStatus{
+			Job:    job,
+			Counts: make(map[string]int64),
+		}
+
+		if types, ok := bucket.Terms("types"); ok {
+			for _, t := range types.Buckets {
+				if doctype, ok := t.Key.(string); ok {
+					status.Counts[doctype] = t.DocCount
+				}
+			}
+		}
+
+		if last, ok := bucket.Max("last"); ok && last.Value != nil {
+			status.LastSeen = time.Unix(0, int64(*last.Value)*int64(time.Millisecond)).UTC()
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
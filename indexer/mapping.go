@@ -0,0 +1,496 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// settings are shared by the indices of all document types
+const settings = `{
+	"index.mapping.total_fields.limit": 1000,
+	"analysis": ` + analysis + `
+}`
+
+// analysis defines the analyzers for filenames, which split names into
+// their components at punctuation and case changes, e.g. "my_Holiday.jpg"
+// into "my", "holiday" and "jpg". Prefixes of components are indexed
+// separately, for search as you type.
+const analysis = `{
+	"analyzer": {
+		"filename": {
+			"type": "custom",
+			"tokenizer": "filename",
+			"filter": ["lowercase", "asciifolding"]
+		},
+		"filename_prefix": {
+			"type": "custom",
+			"tokenizer": "filename",
+			"filter": ["lowercase", "asciifolding", "filename_edge_ngram"]
+		}
+	},
+	"tokenizer": {
+		"filename": {
+			"type": "pattern",
+			"pattern": "[^\\p{L}\\p{N}]+|(?<=\\p{Ll})(?=\\p{Lu})"
+		}
+	},
+	"filter": {
+		"filename_edge_ngram": {
+			"type": "edge_ngram",
+			"min_gram": 1,
+			"max_gram": 20
+		}
+	}
+}`
+
+// commonMapping defines explicit types for the fields all document types
+// have in common. Without it, Elasticsearch guesses types from the first
+// document it sees, which makes range queries on size and dates unreliable.
+// Labels set by tagging rules are named by operators, and always keywords.
+const commonMapping = `{
+	"dynamic_templates": [
+		{
+			"labels": {
+				"path_match": "labels.*",
+				"mapping": {
+					"type": "keyword"
+				}
+			}
+		}
+	],
+	"properties": {
+		"size": {
+			"type": "long"
+		},
+		"first-seen": {
+			"type": "date",
+			"format": "strict_date_time_no_millis"
+		},
+		"last-seen": {
+			"type": "date",
+			"format": "strict_date_time_no_millis"
+		},
+		"last-recrawl": {
+			"type": "date",
+			"format": "strict_date_time_no_millis"
+		},
+		"aliases": {
+			"type": "keyword"
+		},
+		"task-keys": {
+			"type": "keyword",
+			"index": false
+		},
+		"quality": {
+			"type": "float"
+		},
+		"content-quality": {
+			"type": "float",
+			"index": false
+		},
+		"structure-only": {
+			"type": "boolean"
+		},
+		"popularity": {
+			"type": "long"
+		},
+		"reference-count": {
+			"type": "integer"
+		},
+		"first-referenced": {
+			"type": "date",
+			"format": "strict_date_time_no_millis"
+		},
+		"availability": {
+			"properties": {
+				"checked": {
+					"type": "date",
+					"format": "strict_date_time_no_millis"
+				},
+				"gateways": {
+					"type": "keyword"
+				},
+				"ratio": {
+					"type": "float"
+				}
+			}
+		},
+		"provenance": {
+			"properties": {
+				"source": {
+					"type": "keyword"
+				},
+				"submitter": {
+					"type": "keyword"
+				},
+				"job": {
+					"type": "keyword"
+				},
+				"roots": {
+					"type": "keyword"
+				}
+			}
+		},
+		"override": {
+			"properties": {
+				"title": {
+					"type": "text"
+				},
+				"description": {
+					"type": "text"
+				},
+				"tags": {
+					"type": "keyword"
+				},
+				"publisher": {
+					"type": "keyword"
+				}
+			}
+		},
+		"publisher-override": {
+			"properties": {
+				"title": {
+					"type": "text"
+				},
+				"description": {
+					"type": "text"
+				},
+				"tags": {
+					"type": "keyword"
+				},
+				"publisher": {
+					"type": "keyword"
+				}
+			}
+		},
+		"filename": {
+			"type": "text",
+			"analyzer": "filename",
+			"fields": {
+				"prefix": {
+					"type": "text",
+					"analyzer": "filename_prefix",
+					"search_analyzer": "filename"
+				},
+				"keyword": {
+					"type": "keyword",
+					"ignore_above": 256
+				}
+			}
+		},
+		"extension": {
+			"type": "keyword"
+		},
+		"tags": {
+			"type": "keyword"
+		},
+		"labels": {
+			"type": "object"
+		},
+		"references": {
+			"properties": {
+				"parent_hash": {
+					"type": "keyword"
+				},
+				"name": {
+					"type": "text",
+					"fields": {
+						"keyword": {
+							"type": "keyword",
+							"ignore_above": 256
+						}
+					}
+				}
+			}
+		}
+	}
+}`
+
+// typeMappings define the fields specific to each document type, as every
+// type lives in its own index. Extracted metadata is not mapped
+// dynamically; only listed fields are indexed, all of them remain
+// available in the source.
+var typeMappings = map[string]string{
+	"file": `{
+		"properties": {
+			"content": {
+				"type": "text"
+			},
+			"urls": {
+				"type": "keyword"
+			},
+			"metadata-partial": {
+				"type": "boolean"
+			},
+			"mimetype": {
+				"type": "keyword"
+			},
+			"extractor-version": {
+				"type": "integer"
+			},
+			"extractor-queued": {
+				"type": "integer"
+			},
+			"simhash": {
+				"type": "keyword"
+			},
+			"simhash-bands": {
+				"type": "keyword"
+			},
+			"location": {
+				"type": "geo_point"
+			},
+			"content-created": {
+				"type": "date",
+				"format": "strict_date_time_no_millis"
+			},
+			"content-modified": {
+				"type": "date",
+				"format": "strict_date_time_no_millis"
+			},
+			"authors": {
+				"type": "keyword"
+			},
+			"thumbnail": {
+				"properties": {
+					"hash": {
+						"type": "keyword"
+					},
+					"url": {
+						"type": "keyword",
+						"index": false
+					},
+					"width": {
+						"type": "integer"
+					},
+					"height": {
+						"type": "integer"
+					}
+				}
+			},
+			"media": {
+				"properties": {
+					"format": {
+						"type": "keyword"
+					},
+					"duration": {
+						"type": "float"
+					},
+					"bitrate": {
+						"type": "long"
+					},
+					"video": {
+						"properties": {
+							"codec": {
+								"type": "keyword"
+							},
+							"width": {
+								"type": "integer"
+							},
+							"height": {
+								"type": "integer"
+							}
+						}
+					},
+					"audio": {
+						"properties": {
+							"codec": {
+								"type": "keyword"
+							},
+							"sample_rate": {
+								"type": "integer"
+							},
+							"channels": {
+								"type": "integer"
+							}
+						}
+					}
+				}
+			},
+			"categories": {
+				"type": "keyword"
+			},
+			"nsfw_score": {
+				"type": "float"
+			},
+			"archive-format": {
+				"type": "keyword"
+			},
+			"archive-contents": {
+				"properties": {
+					"name": {
+						"type": "text",
+						"analyzer": "filename",
+						"fields": {
+							"keyword": {
+								"type": "keyword",
+								"ignore_above": 256
+							}
+						}
+					},
+					"size": {
+						"type": "long"
+					},
+					"type": {
+						"type": "keyword"
+					}
+				}
+			},
+			"language": {
+				"properties": {
+					"language": {
+						"type": "keyword"
+					},
+					"confidence": {
+						"type": "keyword"
+					},
+					"rawScore": {
+						"type": "float"
+					}
+				}
+			},
+			"metadata": {
+				"dynamic": false,
+				"properties": {
+					"Content-Type": {
+						"type": "keyword"
+					},
+					"title": {
+						"type": "text"
+					},
+					"dc:title": {
+						"type": "text"
+					},
+					"author": {
+						"type": "text"
+					},
+					"dc:creator": {
+						"type": "text"
+					},
+					"description": {
+						"type": "text"
+					},
+					"dc:description": {
+						"type": "text"
+					},
+					"keywords": {
+						"type": "text"
+					},
+					"meta:keyword": {
+						"type": "text"
+					},
+					"subject": {
+						"type": "text"
+					},
+					"tiff:ImageWidth": {
+						"type": "integer"
+					},
+					"tiff:ImageLength": {
+						"type": "integer"
+					},
+					"tiff:Make": {
+						"type": "keyword"
+					},
+					"tiff:Model": {
+						"type": "keyword"
+					}
+				}
+			}
+		}
+	}`,
+	"directory": `{
+		"properties": {
+			"links": {
+				"properties": {
+					"Hash": {
+						"type": "keyword"
+					},
+					"Name": {
+						"type": "text"
+					},
+					"Size": {
+						"type": "long"
+					},
+					"Type": {
+						"type": "keyword"
+					}
+				}
+			},
+			"item-count": {
+				"type": "integer"
+			},
+			"file-count": {
+				"type": "integer"
+			},
+			"directory-count": {
+				"type": "integer"
+			},
+			"files-size": {
+				"type": "long"
+			},
+			"total-size": {
+				"type": "long"
+			}
+		}
+	}`,
+	"invalid": `{
+		"properties": {
+			"error": {
+				"type": "text"
+			},
+			"error-category": {
+				"type": "keyword"
+			}
+		}
+	}`,
+}
+
+// mustParse parses a mapping constant; failure is a programming error
+func mustParse(s string) map[string]interface{} {
+	var m map[string]interface{}
+
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+// typeMapping returns the mapping for a document type, consisting of the
+// common fields and those specific to the type
+func typeMapping(doctype string) (map[string]interface{}, error) {
+	specific, ok := typeMappings[doctype]
+	if !ok {
+		return nil, fmt.Errorf("unknown document type: %s", doctype)
+	}
+
+	m := mustParse(commonMapping)
+	p := properties(m)
+	for name, field := range properties(mustParse(specific)) {
+		p[name] = field
+	}
+
+	return m, nil
+}
+
+// indexBody returns the settings, mapping and aliases used to create the
+// index for a document type
+func indexBody(doctype string, aliases ...string) (map[string]interface{}, error) {
+	m, err := typeMapping(doctype)
+	if err != nil {
+		return nil, err
+	}
+
+	a := make(map[string]interface{}, len(aliases))
+	for _, alias := range aliases {
+		a[alias] = map[string]interface{}{}
+	}
+
+	return map[string]interface{}{
+		"settings": mustParse(settings),
+		"mappings": map[string]interface{}{
+			doctype: m,
+		},
+		"aliases": a,
+	}, nil
+}
@@ -0,0 +1,141 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/olivere/elastic.v5"
+)
+
+// reindexType copies the documents of a type from the legacy index to the
+// index for the type, verifying all of them arrived. Documents already in
+// the new index, e.g. from an earlier run or crawlers, are kept.
+func (i *Indexer) reindexType(ctx context.Context, doctype string) error {
+	logger := log.WithFields(log.Fields{
+		"type":  doctype,
+		"index": indexName(doctype),
+	})
+	logger.Info("Reindexing documents")
+
+	result, err := i.ElasticSearch.Reindex().
+		Source(elastic.NewReindexSource().Index(searchAlias).Type(doctype)).
+		Destination(elastic.NewReindexDestination().
+			Index(indexName(doctype)).
+			Type(doctype).
+			OpType("create")).
+		ProceedOnVersionConflict().
+		Refresh("true").
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("error reindexing %s: %v", doctype, err)
+	}
+	if len(result.Failures) > 0 {
+		return fmt.Errorf("failed reindexing %d %s documents", len(result.Failures), doctype)
+	}
+
+	legacy, err := i.ElasticSearch.Count(searchAlias).Type(doctype).Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	migrated, err := i.ElasticSearch.Count(indexName(doctype)).Type(doctype).Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	if migrated < legacy {
+		return fmt.Errorf("%s index has %d documents, legacy index %d", doctype, migrated, legacy)
+	}
+
+	logger.WithFields(log.Fields{
+		"created":  result.Created,
+		"existing": result.VersionConflicts,
+		"total":    migrated,
+	}).Info("Reindexed documents")
+
+	return nil
+}
+
+// migrate moves documents from the legacy index to an index per type,
+// deleting the legacy index and adding the search alias when
+// deleteLegacy is set
+func (i *Indexer) migrate(ctx context.Context, deleteLegacy bool) error {
+	for _, doctype := range docTypes {
+		// The search alias is added after removing the legacy index
+		if _, err := i.createTypeIndex(ctx, doctype, typeAliases[doctype]); err != nil {
+			return err
+		}
+
+		if err := i.reindexType(ctx, doctype); err != nil {
+			return err
+		}
+	}
+
+	if !deleteLegacy {
+		log.WithField("index", searchAlias).Info("Keeping legacy index, searches use it until it is deleted")
+		return nil
+	}
+
+	log.WithField("index", searchAlias).Info("Deleting legacy index")
+
+	if _, err := i.ElasticSearch.DeleteIndex(searchAlias).Do(ctx); err != nil {
+		return err
+	}
+
+	alias := i.ElasticSearch.Alias()
+	for _, doctype := range docTypes {
+		alias.Add(indexName(doctype), searchAlias)
+	}
+
+	_, err := alias.Do(ctx)
+	return err
+}
+
+// Migrate moves documents from the legacy single index, where document
+// types are ES types, to an index per type, on the primary and the standby
+// cluster. Only when deleteLegacy is set, the legacy index is deleted
+// afterwards so that its name can be used for the search alias; without
+// it, Migrate can be run again to copy documents added in the meantime.
+// Deleting is irreversible, and running crawlers should be stopped, as
+// documents they index in the legacy index during migration are lost.
+func (i *Indexer) Migrate(ctx context.Context, deleteLegacy bool) error {
+	if i.routing == RoutingCID {
+		// Reindexing keeps the routing of legacy documents, by ID
+		return fmt.Errorf("migrating is not supported with routing by %s", RoutingCID)
+	}
+
+	clusters := map[string]*Indexer{"primary": i}
+	if i.Standby != nil {
+		clusters["standby"] = &Indexer{ElasticSearch: i.Standby}
+	}
+
+	migrated := 0
+	for _, name := range []string{"primary", "standby"} {
+		cluster, ok := clusters[name]
+		if !ok {
+			continue
+		}
+
+		legacy, err := cluster.legacyIndex(ctx)
+		if err != nil {
+			return fmt.Errorf("%s cluster: %v", name, err)
+		}
+		if !legacy {
+			log.WithField("cluster", name).Info("No legacy index found")
+			continue
+		}
+
+		log.WithField("cluster", name).Info("Migrating legacy index")
+
+		if err := cluster.migrate(ctx, deleteLegacy); err != nil {
+			return fmt.Errorf("%s cluster: %v", name, err)
+		}
+		migrated++
+	}
+
+	if migrated == 0 {
+		return fmt.Errorf("no legacy index '%s' found", searchAlias)
+	}
+
+	return nil
+}
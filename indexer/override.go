@@ -0,0 +1,265 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/olivere/elastic.v5"
+	"strings"
+)
+
+// Override replaces automatically extracted metadata of a document.
+// Overrides are kept in the meta index, so they survive recrawls, and are
+// copied onto the document in the "override" field to make them
+// searchable. Overrides by verified publishers are kept apart, in the
+// "publisher-override" field, so that they never replace those set by
+// operators, which take precedence.
+type Override struct {
+	Hash        string   `json:"-"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Publisher   string   `json:"publisher,omitempty"` // Verified publisher name, empty when set by operators
+}
+
+// Kinds of overrides, used as type in the meta index and as document field
+const (
+	operatorOverride  = "override"
+	publisherOverride = "publisher-override"
+)
+
+// kind returns the kind of override
+func (o *Override) kind() string {
+	if o.Publisher != "" {
+		return publisherOverride
+	}
+	return operatorOverride
+}
+
+// String returns a human readable description of the override
+func (o *Override) String() string {
+	s := fmt.Sprintf("%s: title '%s', description '%s', tags [%s]",
+		o.Hash, o.Title, o.Description, strings.Join(o.Tags, ", "))
+
+	if o.Publisher != "" {
+		s += fmt.Sprintf(" by %s", o.Publisher)
+	}
+
+	return s
+}
+
+// updateDocuments applies an update to the document for hash in the
+// indices of all document types; missing documents are ignored
+func (i *Indexer) updateDocuments(ctx context.Context, hash string, update func(*elastic.UpdateService) *elastic.UpdateService) error {
+	return i.write(func(c *elastic.Client) error {
+		for _, doctype := range docTypes {
+			u := c.Update().
+				Index(typeAliases[doctype]).
+				Type(doctype).
+				Id(i.id(hash)).
+				Routing(i.route(hash))
+
+			_, err := update(u).Do(ctx)
+			if err != nil && !elastic.IsNotFound(err) {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SetOverride stores the metadata override for a hash in the meta index,
+// replacing any earlier override of the same kind, and applies it to the
+// indexed document
+func (i *Indexer) SetOverride(ctx context.Context, o *Override) error {
+	kind := o.kind()
+
+	err := i.write(func(c *elastic.Client) error {
+		_, err := c.Index().
+			Index(metaIndex).
+			Type(kind).
+			Id(i.id(o.Hash)).
+			BodyJson(o).
+			Refresh("true").
+			Do(ctx)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Assigned by script, as a partial update would keep fields left out
+	// of the new override
+	script := elastic.NewScript("ctx._source[params.field] = params.override").
+		Lang("painless").
+		Param("field", kind).
+		Param("override", o)
+
+	return i.updateDocuments(ctx, o.Hash, func(u *elastic.UpdateService) *elastic.UpdateService {
+		return u.Script(script)
+	})
+}
+
+// removeOverride removes an override of a kind for a hash, both from the
+// meta index and from the indexed document
+func (i *Indexer) removeOverride(ctx context.Context, kind, hash string) error {
+	err := i.write(func(c *elastic.Client) error {
+		_, err := c.Delete().
+			Index(metaIndex).
+			Type(kind).
+			Id(i.id(hash)).
+			Refresh("true").
+			Do(ctx)
+
+		if elastic.IsNotFound(err) && c == i.Standby {
+			// Set before the standby was configured
+			return nil
+		}
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	script := elastic.NewScript("ctx._source.remove(params.field)").
+		Lang("painless").
+		Param("field", kind)
+
+	return i.updateDocuments(ctx, hash, func(u *elastic.UpdateService) *elastic.UpdateService {
+		return u.Script(script)
+	})
+}
+
+// RemoveOverride removes the operator's metadata override for a hash
+func (i *Indexer) RemoveOverride(ctx context.Context, hash string) error {
+	return i.removeOverride(ctx, operatorOverride, hash)
+}
+
+// RemovePublisherOverride removes the metadata override for a hash set by
+// a verified publisher
+func (i *Indexer) RemovePublisherOverride(ctx context.Context, hash string) error {
+	return i.removeOverride(ctx, publisherOverride, hash)
+}
+
+// getOverride returns the override of a kind for a hash, or nil if there
+// is none
+func (i *Indexer) getOverride(ctx context.Context, kind, hash string) (*Override, error) {
+	result, err := i.ElasticSearch.Get().
+		Index(metaIndex).
+		Type(kind).
+		Id(i.id(hash)).
+		Do(ctx)
+	if err != nil {
+		if elastic.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	o := &Override{Hash: result.Id}
+	if err := json.Unmarshal(*result.Source, o); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// GetOverride returns the operator's metadata override for a hash, or nil
+// if there is none
+func (i *Indexer) GetOverride(ctx context.Context, hash string) (*Override, error) {
+	return i.getOverride(ctx, operatorOverride, hash)
+}
+
+// GetPublisherOverride returns the metadata override for a hash set by a
+// verified publisher, or nil if there is none
+func (i *Indexer) GetPublisherOverride(ctx context.Context, hash string) (*Override, error) {
+	return i.getOverride(ctx, publisherOverride, hash)
+}
+
+// maxOverrides limits the amount of overrides retrieved
+const maxOverrides = 10000
+
+// Overrides returns all metadata overrides stored by operators
+func (i *Indexer) Overrides(ctx context.Context) ([]Override, error) {
+	exists, err := i.ElasticSearch.IndexExists(metaIndex).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	result, err := i.ElasticSearch.Search(metaIndex).
+		Type(operatorOverride).
+		Size(maxOverrides).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make([]Override, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		o := Override{Hash: hit.Id}
+		if err := json.Unmarshal(*hit.Source, &o); err != nil {
+			return nil, err
+		}
+
+		overrides = append(overrides, o)
+	}
+
+	return overrides, nil
+}
+
+// applyOverride sets metadata fields from an override in a document source
+func applyOverride(metadata map[string]interface{}, o map[string]interface{}) {
+	// Extracted metadata consists of lists of values
+	if title, ok := o["title"]; ok {
+		metadata["title"] = []interface{}{title}
+	}
+	if description, ok := o["description"]; ok {
+		metadata["description"] = []interface{}{description}
+	}
+	if tags, ok := o["tags"]; ok {
+		metadata["keywords"] = tags
+	}
+}
+
+// mergeOverride returns the document source with the overrides, if any,
+// applied over extracted metadata; fields of operator overrides take
+// precedence over those of publishers
+func mergeOverride(source *json.RawMessage) (*json.RawMessage, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(*source, &doc); err != nil {
+		return nil, err
+	}
+
+	published, hasPublished := doc[publisherOverride].(map[string]interface{})
+	o, hasOverride := doc[operatorOverride].(map[string]interface{})
+	if !hasPublished && !hasOverride {
+		return source, nil
+	}
+
+	metadata, ok := doc["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = make(map[string]interface{})
+		doc["metadata"] = metadata
+	}
+
+	if hasPublished {
+		applyOverride(metadata, published)
+	}
+	if hasOverride {
+		applyOverride(metadata, o)
+	}
+
+	merged, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := json.RawMessage(merged)
+	return &raw, nil
+}
@@ -0,0 +1,65 @@
+package indexer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMergeOverride(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			"none",
+			`{"metadata":{"title":["Extracted"]}}`,
+			`{"metadata":{"title":["Extracted"]}}`,
+		},
+		{
+			"operator",
+			`{"metadata":{"title":["Extracted"]},"override":{"title":"Operator","tags":["a"]}}`,
+			`{"metadata":{"keywords":["a"],"title":["Operator"]},"override":{"tags":["a"],"title":"Operator"}}`,
+		},
+		{
+			"publisher",
+			`{"publisher-override":{"title":"Publisher","publisher":"example.com"}}`,
+			`{"metadata":{"title":["Publisher"]},"publisher-override":{"publisher":"example.com","title":"Publisher"}}`,
+		},
+		{
+			"operator over publisher",
+			`{"override":{"title":"Operator"},"publisher-override":{"title":"Publisher","description":"Published"}}`,
+			`{"metadata":{"description":["Published"],"title":["Operator"]},"override":{"title":"Operator"},"publisher-override":{"description":"Published","title":"Publisher"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		source := json.RawMessage(test.source)
+
+		merged, err := mergeOverride(&source)
+		if err != nil {
+			t.Errorf("mergeOverride() %s: %v", test.name, err)
+			continue
+		}
+
+		if got := string(*merged); got != test.want {
+			t.Errorf("mergeOverride() %s = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestOverrideKind(t *testing.T) {
+	tests := []struct {
+		o    Override
+		want string
+	}{
+		{Override{Title: "title"}, operatorOverride},
+		{Override{Title: "title", Publisher: "example.com"}, publisherOverride},
+	}
+
+	for _, test := range tests {
+		if got := test.o.kind(); got != test.want {
+			t.Errorf("%s kind() = %s, want %s", test.o.String(), got, test.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package indexer
+
+import (
+	"github.com/ipfs-search/ipfs-search/cids"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Permalink returns the stable identifier of the content at path below
+// hash: its base32 CIDv1 followed by the cleaned, escaped path, if any.
+// Unlike document IDs, it does not depend on the CID version or encoding
+// content was indexed under.
+func Permalink(hash, p string) (string, error) {
+	c, err := cids.Decode(hash)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := cid.NewCidV1(c.Type(), c.Hash()).StringOfBase(multibase.Base32)
+	if err != nil {
+		return "", err
+	}
+
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		return id, nil
+	}
+
+	segments := strings.Split(p, "/")
+	for n, s := range segments {
+		segments[n] = url.PathEscape(s)
+	}
+
+	return id + "/" + strings.Join(segments, "/"), nil
+}
+
+// permalink returns the permalink of a document, or nothing for documents
+// with invalid hashes
+func permalink(hash string) string {
+	id, _ := Permalink(hash, "")
+	return id
+}
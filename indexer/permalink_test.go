@@ -0,0 +1,38 @@
+package indexer
+
+import (
+	"testing"
+)
+
+func TestPermalink(t *testing.T) {
+	const v1 = "bafybeie5nqv6kd3qnfjupgvz34woh3oksc3iau6abmyajn7qvtf6d2ho34"
+
+	tests := []struct {
+		hash string
+		path string
+		want string
+	}{
+		{"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", "", v1},
+		{v1, "", v1},
+		{"BAFYBEIE5NQV6KD3QNFJUPGVZ34WOH3OKSC3IAU6ABMYAJN7QVTF6D2HO34", "", v1},
+		{"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", "readme", v1 + "/readme"},
+		{v1, "/docs//a b.txt/", v1 + "/docs/a%20b.txt"},
+		{v1, "docs/../readme", v1 + "/readme"},
+		{v1, "../..", v1},
+	}
+
+	for _, test := range tests {
+		got, err := Permalink(test.hash, test.path)
+		if err != nil {
+			t.Errorf("Permalink(%s, %s) error %v", test.hash, test.path, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Permalink(%s, %s) = %s, want %s", test.hash, test.path, got, test.want)
+		}
+	}
+
+	if _, err := Permalink("invalid", ""); err == nil {
+		t.Error("Permalink(invalid) returned no error")
+	}
+}
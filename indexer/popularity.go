@@ -0,0 +1,76 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/olivere/elastic.v5"
+)
+
+const popularityScript = `
+if (ctx._source.popularity == null) {
+	ctx._source.popularity = params.count;
+} else {
+	ctx._source.popularity += params.count;
+}`
+
+// getTypes returns the document types for existing hashes; missing hashes are omitted
+func (i *Indexer) getTypes(ctx context.Context, hashes []string) (map[string]string, error) {
+	found, err := i.multiGet(ctx, elastic.NewFetchSourceContext(false), hashes...)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]string, len(found))
+	for _, doc := range found {
+		types[doc.Id] = doc.Type
+	}
+
+	return types, nil
+}
+
+// AddPopularity increments the popularity of documents by given counts.
+// Counts for hashes which have not been indexed are ignored.
+func (i *Indexer) AddPopularity(ctx context.Context, counts map[string]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	hashes := make([]string, 0, len(counts))
+	for hash := range counts {
+		hashes = append(hashes, hash)
+	}
+
+	types, err := i.getTypes(ctx, hashes)
+	if err != nil {
+		return err
+	}
+
+	requests := make([]elastic.BulkableRequest, 0, len(types))
+	for hash, doctype := range types {
+		script := elastic.NewScriptInline(popularityScript).
+			Lang("painless").
+			Param("count", counts[hash])
+
+		requests = append(requests, elastic.NewBulkUpdateRequest().
+			Index(typeAliases[doctype]).Type(doctype).
+			Id(i.id(hash)).
+			Routing(i.route(hash)).
+			Script(script).
+			RetryOnConflict(3))
+	}
+
+	if len(requests) == 0 {
+		return nil
+	}
+
+	result, err := i.bulk(ctx, requests)
+	if err != nil {
+		return err
+	}
+
+	if failed := result.Failed(); len(failed) > 0 {
+		return fmt.Errorf("failed updating popularity for %d documents, first: %s", len(failed), failed[0].Id)
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+package indexer
+
+// Provenance records how a document entered the index
+type Provenance struct {
+	Source    string   `json:"source"`              // How the hash was discovered, e.g. "directory"
+	Submitter string   `json:"submitter,omitempty"` // Key or client which submitted the root
+	Job       string   `json:"job,omitempty"`       // Crawl job the root was submitted in
+	Roots     []string `json:"roots,omitempty"`     // Chain of documents leading here, starting at the submitted root
+}
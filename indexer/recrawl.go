@@ -0,0 +1,70 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/olivere/elastic.v5"
+	"time"
+)
+
+// Stale returns up to size files and directories not seen since before,
+// least recently seen first. Documents queued for recrawling since before
+// are left out, so unavailable items are retried once per period.
+func (i *Indexer) Stale(ctx context.Context, before time.Time, size int) ([]Document, error) {
+	b := before.UTC().Format(time.RFC3339)
+
+	query := elastic.NewBoolQuery().
+		Filter(elastic.NewRangeQuery("last-seen").Lt(b)).
+		MustNot(elastic.NewRangeQuery("last-recrawl").Gte(b))
+
+	result, err := i.ElasticSearch.Search(typeAliases["file"], typeAliases["directory"]).
+		Query(query).
+		Sort("last-seen", true).
+		FetchSource(false).
+		Size(size).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return hitsToDocuments(result.Hits.Hits), nil
+}
+
+// MarkRecrawl records that documents have been queued for recrawling at
+func (i *Indexer) MarkRecrawl(ctx context.Context, documents []Document, at time.Time) error {
+	return i.markDocuments(ctx, documents, "recrawl", map[string]interface{}{
+		"last-recrawl": at.UTC().Format(time.RFC3339),
+	})
+}
+
+// markDocuments sets properties on documents queued for what
+func (i *Indexer) markDocuments(ctx context.Context, documents []Document, what string, properties map[string]interface{}) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	requests := make([]elastic.BulkableRequest, 0, len(documents))
+	for _, d := range documents {
+		alias, err := typeAlias(d.Type)
+		if err != nil {
+			return err
+		}
+
+		requests = append(requests, elastic.NewBulkUpdateRequest().
+			Index(alias).Type(d.Type).
+			Id(i.id(d.Hash)).
+			Routing(i.route(d.Hash)).
+			Doc(properties))
+	}
+
+	result, err := i.bulk(ctx, requests)
+	if err != nil {
+		return err
+	}
+
+	if failed := result.Failed(); len(failed) > 0 {
+		return fmt.Errorf("failed marking %d documents for %s, first: %s", len(failed), what, failed[0].Id)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package indexer
+
+import (
+	"context"
+	"gopkg.in/olivere/elastic.v5"
+)
+
+// Outdated returns up to size files of content types starting with any of
+// mimetypes, or of any type when there are none, which were extracted with
+// a version of the extractor capability registry before version and have
+// not been queued for extraction with it yet. Files indexed without a
+// version count as extracted with version 1.
+func (i *Indexer) Outdated(ctx context.Context, mimetypes []string, version int, size int) ([]Document, error) {
+	extracted := elastic.NewBoolQuery().
+		Should(elastic.NewRangeQuery("extractor-version").Lt(version)).
+		MinimumNumberShouldMatch(1)
+	if version > 1 {
+		extracted.Should(elastic.NewBoolQuery().MustNot(elastic.NewExistsQuery("extractor-version")))
+	}
+
+	query := elastic.NewBoolQuery().
+		Filter(extracted).
+		MustNot(elastic.NewRangeQuery("extractor-queued").Gte(version))
+
+	if len(mimetypes) > 0 {
+		types := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+		for _, prefix := range mimetypes {
+			types.Should(elastic.NewPrefixQuery("mimetype", prefix))
+		}
+		query.Filter(types)
+	}
+
+	result, err := i.ElasticSearch.Search(typeAliases["file"]).
+		Query(query).
+		FetchSource(false).
+		Size(size).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return hitsToDocuments(result.Hits.Hits), nil
+}
+
+// MarkExtract records that documents have been queued for extraction with
+// a version of the extractor capability registry
+func (i *Indexer) MarkExtract(ctx context.Context, documents []Document, version int) error {
+	return i.markDocuments(ctx, documents, "extraction", map[string]interface{}{
+		"extractor-queued": version,
+	})
+}
@@ -24,3 +24,13 @@ func (references References) Contains(newRef *Reference) bool {
 
 	return false
 }
+
+// Names returns the names of all references
+func (references References) Names() []string {
+	names := make([]string, len(references))
+	for n, r := range references {
+		names[n] = r.Name
+	}
+
+	return names
+}
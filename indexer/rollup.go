@@ -0,0 +1,163 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/olivere/elastic.v5"
+	"time"
+)
+
+// statsIndex holds periodic rollups of indexing statistics, for charting
+// long-term trends from Elasticsearch directly
+const statsIndex = "ipfs-stats"
+
+// maxRollupTerms limits the number of values reported per dimension
+const maxRollupTerms = 100
+
+// statsMapping has one flat document per period, dimension and value,
+// which is what the Grafana Elasticsearch datasource handles best
+const statsMapping = `{
+	"mappings": {
+		"rollup": {
+			"properties": {
+				"timestamp": {
+					"type": "date",
+					"format": "strict_date_time_no_millis"
+				},
+				"period": {
+					"type": "keyword"
+				},
+				"dimension": {
+					"type": "keyword"
+				},
+				"value": {
+					"type": "keyword"
+				},
+				"count": {
+					"type": "long"
+				}
+			}
+		}
+	}
+}`
+
+// Rollup is the number of documents first seen in a period with a given
+// value for a dimension, e.g. type: file, or of searches with query
+// analytics
+type Rollup struct {
+	Timestamp string `json:"timestamp"` // Start of the period
+	Period    string `json:"period"`
+	Dimension string `json:"dimension"` // type, mime or source; query or search for query analytics; gateway-probed or gateway-retrievable for probes
+	Value     string `json:"value"`
+	Count     int64  `json:"count"`
+}
+
+// id identifies a rollup, so rolling up a period again replaces it
+func (r *Rollup) id() string {
+	return fmt.Sprintf("%s-%s-%s-%s", r.Timestamp, r.Period, r.Dimension, r.Value)
+}
+
+// Rollups returns the counts of documents first seen from start, for the
+// duration of period, by type, mime type and source. The source is
+// "referenced" for documents found through directories and links and
+// "unreferenced" for documents found otherwise, e.g. by sniffing.
+func (i *Indexer) Rollups(ctx context.Context, start time.Time, period time.Duration) ([]Rollup, error) {
+	start = start.UTC()
+	end := start.Add(period)
+
+	referenced := elastic.NewExistsQuery("references.parent_hash")
+
+	result, err := i.ElasticSearch.Search(searchAlias).
+		Size(0).
+		Query(elastic.NewRangeQuery("first-seen").
+			Gte(start.Format(time.RFC3339)).
+			Lt(end.Format(time.RFC3339))).
+		Aggregation("type", elastic.NewTermsAggregation().Field("_type").Size(maxRollupTerms)).
+		Aggregation("mime", elastic.NewTermsAggregation().Field("metadata.Content-Type").Size(maxRollupTerms)).
+		Aggregation("source", elastic.NewFiltersAggregation().
+			FilterWithName("referenced", referenced).
+			FilterWithName("unreferenced", elastic.NewBoolQuery().MustNot(referenced))).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	newRollup := func(dimension, value string, count int64) Rollup {
+		return Rollup{
+			Timestamp: start.Format(time.RFC3339),
+			Period:    period.String(),
+			Dimension: dimension,
+			Value:     value,
+			Count:     count,
+		}
+	}
+
+	var rollups []Rollup
+
+	for _, dimension := range []string{"type", "mime"} {
+		terms, ok := result.Aggregations.Terms(dimension)
+		if !ok {
+			continue
+		}
+
+		for _, bucket := range terms.Buckets {
+			if value, ok := bucket.Key.(string); ok {
+				rollups = append(rollups, newRollup(dimension, value, bucket.DocCount))
+			}
+		}
+	}
+
+	if sources, ok := result.Aggregations.Filters("source"); ok {
+		for value, bucket := range sources.NamedBuckets {
+			rollups = append(rollups, newRollup("source", value, bucket.DocCount))
+		}
+	}
+
+	return rollups, nil
+}
+
+// ensureStatsIndex creates the stats index with its mapping, if it does
+// not exist yet
+func (i *Indexer) ensureStatsIndex(ctx context.Context) error {
+	exists, err := i.ElasticSearch.IndexExists(statsIndex).Do(ctx)
+	if err != nil || exists {
+		return err
+	}
+
+	log.WithField("index", statsIndex).Info("Creating index")
+
+	_, err = i.ElasticSearch.CreateIndex(statsIndex).BodyString(statsMapping).Do(ctx)
+	return err
+}
+
+// WriteRollups stores rollups in the stats index, replacing earlier
+// rollups for the same period
+func (i *Indexer) WriteRollups(ctx context.Context, rollups []Rollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	if err := i.ensureStatsIndex(ctx); err != nil {
+		return err
+	}
+
+	bulk := i.ElasticSearch.Bulk()
+	for _, r := range rollups {
+		bulk.Add(elastic.NewBulkIndexRequest().
+			Index(statsIndex).Type("rollup").
+			Id(r.id()).
+			Doc(r))
+	}
+
+	result, err := bulk.Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	if failed := result.Failed(); len(failed) > 0 {
+		return fmt.Errorf("failed writing %d rollups, first: %s", len(failed), failed[0].Id)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package indexer
+
+import (
+	"fmt"
+)
+
+// Routing of item documents over shards
+const (
+	// RoutingID routes by document ID, the CID as crawled; the default
+	RoutingID = "id"
+
+	// RoutingCID routes by canonical CID, the base32 CIDv1, so all forms
+	// of the CID of content are on the same shard
+	RoutingCID = "cid"
+)
+
+// checkRouting returns an error for unsupported routing
+func checkRouting(routing string) error {
+	switch routing {
+	case "", RoutingID, RoutingCID:
+		return nil
+	default:
+		return fmt.Errorf("unsupported routing %q, use %s or %s", routing, RoutingID, RoutingCID)
+	}
+}
+
+// routingKey returns the routing key of the item document for hash, or
+// nothing when Elasticsearch routes by document ID. Invalid hashes are
+// routed by themselves.
+func routingKey(routing, hash string) string {
+	if routing != RoutingCID {
+		return ""
+	}
+
+	if id := permalink(hash); id != "" {
+		return id
+	}
+
+	return hash
+}
+
+// route returns the routing key of the item document for hash
+func (i *Indexer) route(hash string) string {
+	return routingKey(i.routing, hash)
+}
@@ -0,0 +1,50 @@
+package indexer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestRoutingKey(t *testing.T) {
+	tests := []struct {
+		routing string
+		hash    string
+		want    string
+	}{
+		{"", "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", ""},
+		{RoutingID, "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", ""},
+		{RoutingCID, "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", "bafybeie5nqv6kd3qnfjupgvz34woh3oksc3iau6abmyajn7qvtf6d2ho34"},
+		{RoutingCID, "bafybeie5nqv6kd3qnfjupgvz34woh3oksc3iau6abmyajn7qvtf6d2ho34", "bafybeie5nqv6kd3qnfjupgvz34woh3oksc3iau6abmyajn7qvtf6d2ho34"},
+		{RoutingCID, "invalid", "invalid"},
+	}
+
+	for _, test := range tests {
+		if got := routingKey(test.routing, test.hash); got != test.want {
+			t.Errorf("routingKey(%q, %s) = %q, want %q", test.routing, test.hash, got, test.want)
+		}
+	}
+
+	if err := checkRouting("shard"); err == nil {
+		t.Error("checkRouting(shard) returned no error")
+	}
+}
+
+func TestTypelessRouting(t *testing.T) {
+	var queries []string
+	index := newTestTypeless(t, func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(`{}`))
+	})
+	index.routing = RoutingCID
+
+	err := index.IndexItem(context.Background(), "file", "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "retry_on_conflict=3&routing=bafybeie5nqv6kd3qnfjupgvz34woh3oksc3iau6abmyajn7qvtf6d2ho34"
+	if len(queries) != 1 || queries[0] != want {
+		t.Errorf("IndexItem() requested %v, want query %s", queries, want)
+	}
+}
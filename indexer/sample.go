@@ -0,0 +1,49 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"gopkg.in/olivere/elastic.v5"
+)
+
+// Document represents a single indexed item
+type Document struct {
+	Hash      string           `json:"hash"`
+	Type      string           `json:"type"`
+	Permalink string           `json:"permalink,omitempty"` // Stable across reindexes
+	Source    *json.RawMessage `json:"source"`
+}
+
+// Sample returns a random sample of documents of up to size items. When
+// query is given, only documents matching this query string are sampled.
+// When doctype is given, only documents of this type are sampled. A given
+// seed results in the same sample for an unchanged index.
+func (i *Indexer) Sample(ctx context.Context, doctype string, query string, size int, seed int64) ([]Document, error) {
+	var q elastic.Query = elastic.NewMatchAllQuery()
+	if query != "" {
+		q = elastic.NewQueryStringQuery(query)
+	}
+
+	random := elastic.NewRandomFunction()
+	if seed != 0 {
+		random.Seed(seed)
+	}
+
+	search := i.ElasticSearch.Search(searchAlias).
+		Query(elastic.NewFunctionScoreQuery().
+			Query(q).
+			AddScoreFunc(random).
+			BoostMode("replace")).
+		Size(size)
+
+	if doctype != "" {
+		search = search.Type(doctype)
+	}
+
+	result, err := search.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return hitsToDocuments(result.Hits.Hits), nil
+}
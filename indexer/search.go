@@ -0,0 +1,203 @@
+package indexer
+
+import (
+	"context"
+	"gopkg.in/olivere/elastic.v5"
+)
+
+// hitsToDocuments converts search hits into Documents
+func hitsToDocuments(hits []*elastic.SearchHit) []Document {
+	documents := make([]Document, 0, len(hits))
+	for _, hit := range hits {
+		documents = append(documents, Document{
+			Hash:      hit.Id,
+			Type:      hit.Type,
+			Permalink: permalink(hit.Id),
+			Source:    hit.Source,
+		})
+	}
+
+	return documents
+}
+
+// Range restricts a field to values from From up to and including To;
+// nil bounds are open
+type Range struct {
+	Field   string
+	From    interface{}
+	To      interface{}
+	Missing bool // Also match documents without the field
+}
+
+// GeoDistance restricts documents to those located within Distance of a
+// point
+type GeoDistance struct {
+	Lat      float64
+	Lon      float64
+	Distance string // With unit, e.g. 10km
+}
+
+// GeoBox restricts documents to those located within a bounding box
+type GeoBox struct {
+	Top    float64
+	Left   float64
+	Bottom float64
+	Right  float64
+}
+
+// locationField is the geo_point field documents are located by
+const locationField = "location"
+
+// filter restricts q to documents matching all filters, without affecting
+// their score
+func filter(q elastic.Query, filters []elastic.Query) elastic.Query {
+	if len(filters) == 0 {
+		return q
+	}
+
+	return elastic.NewBoolQuery().
+		Must(q).
+		Filter(filters...)
+}
+
+// searchQuery returns the query used for ranking documents for a given
+// query string. Relevance is multiplied by the quality score, demoting
+// likely spam; documents without a score are not affected. Popularity
+// and the amount of references boost logarithmically. Curations are
+// applied on top of this, and results are restricted by filters.
+func searchQuery(query string, curations []Curation, filters []elastic.Query) elastic.Query {
+	q := elastic.NewQueryStringQuery(query).
+		DefaultOperator("AND")
+
+	quality := elastic.NewFieldValueFactorFunction().
+		Field("quality").
+		Missing(1)
+
+	popularity := elastic.NewFieldValueFactorFunction().
+		Field("popularity").
+		Modifier("log2p").
+		Missing(0)
+
+	// Content referenced from many directories is likely worth
+	// finding
+	references := elastic.NewFieldValueFactorFunction().
+		Field("reference-count").
+		Modifier("log2p").
+		Missing(0)
+
+	// Documents probed without any gateway retrieving them rank lower
+	unavailable := elastic.NewTermQuery("availability.ratio", 0)
+
+	fsq := elastic.NewFunctionScoreQuery().
+		Query(q).
+		AddScoreFunc(quality).
+		AddScoreFunc(popularity).
+		AddScoreFunc(references).
+		Add(unavailable, elastic.NewWeightFactorFunction(unavailableWeight)).
+		ScoreMode("multiply").
+		BoostMode("multiply")
+
+	return filter(curate(fsq, curations), filters)
+}
+
+// SearchOptions determine which results of a search are returned
+type SearchOptions struct {
+	Ranges   []Range      // Only return documents within these ranges
+	Distance *GeoDistance // Only return documents located near a point
+	Box      *GeoBox      // Only return documents located within a box
+	Author   string       // Only return documents by this normalized author
+	Tag      string       // Only return documents tagged by rules with this tag
+	Language string       // Only return documents in this language, e.g. en
+	Category string       // Only return documents classified in this category
+	From     int          // Offset of the first result
+	Size     int          // Maximum amount of results
+	Source   bool         // Return sources with overrides applied, without content
+}
+
+// filters returns the queries restricting results to the options' ranges
+// and locations
+func (o *SearchOptions) filters() []elastic.Query {
+	var filters []elastic.Query
+
+	for _, r := range o.Ranges {
+		var q elastic.Query = elastic.NewRangeQuery(r.Field).Gte(r.From).Lte(r.To)
+		if r.Missing {
+			q = elastic.NewBoolQuery().Should(q, elastic.NewBoolQuery().MustNot(elastic.NewExistsQuery(r.Field)))
+		}
+		filters = append(filters, q)
+	}
+
+	if o.Author != "" {
+		filters = append(filters, elastic.NewTermQuery("authors", o.Author))
+	}
+
+	if o.Tag != "" {
+		filters = append(filters, elastic.NewTermQuery("tags", o.Tag))
+	}
+
+	if o.Language != "" {
+		filters = append(filters, elastic.NewTermQuery("language.language", o.Language))
+	}
+
+	if o.Category != "" {
+		filters = append(filters, elastic.NewTermQuery("categories", o.Category))
+	}
+
+	if d := o.Distance; d != nil {
+		filters = append(filters, elastic.NewGeoDistanceQuery(locationField).
+			Point(d.Lat, d.Lon).
+			Distance(d.Distance))
+	}
+
+	if b := o.Box; b != nil {
+		filters = append(filters, elastic.NewGeoBoundingBoxQuery(locationField).
+			TopLeft(b.Top, b.Left).
+			BottomRight(b.Bottom, b.Right))
+	}
+
+	return filters
+}
+
+// SearchResult contains a page of search results
+type SearchResult struct {
+	Total     int64      `json:"total"`
+	Documents []Document `json:"documents"`
+}
+
+// Search returns documents matching query, ordered by relevance
+func (i *Indexer) Search(ctx context.Context, query string, options *SearchOptions) (*SearchResult, error) {
+	curations, err := i.matchingCurations(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	source := elastic.NewFetchSourceContext(options.Source)
+	if options.Source {
+		source.Exclude("content")
+	}
+
+	result, err := i.ElasticSearch.Search(searchAlias).
+		Query(searchQuery(query, curations, options.filters())).
+		FetchSourceContext(source).
+		From(options.From).
+		Size(options.Size).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := hitsToDocuments(result.Hits.Hits)
+	if options.Source {
+		for n := range documents {
+			documents[n].Source, err = mergeOverride(documents[n].Source)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &SearchResult{
+		Total:     result.TotalHits(),
+		Documents: documents,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package indexer
+
+import (
+	"encoding/json"
+	"gopkg.in/olivere/elastic.v5"
+	"testing"
+)
+
+// querySource returns the JSON representation of a query
+func querySource(t *testing.T, q interface {
+	Source() (interface{}, error)
+}) string {
+	source, err := q.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := json.Marshal(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(bs)
+}
+
+func TestSearchQueryRanges(t *testing.T) {
+	unfiltered := querySource(t, searchQuery("test", nil, nil))
+
+	options := &SearchOptions{
+		Ranges: []Range{
+			{Field: "size", From: 10, To: nil},
+		},
+	}
+	q := searchQuery("test", nil, options.filters())
+
+	want := `{"bool":{"filter":{"range":{"size":{"from":10,"include_lower":true,"include_upper":true,"to":null}}},"must":` + unfiltered + `}}`
+	if got := querySource(t, q); got != want {
+		t.Errorf("searchQuery() = %s, want %s", got, want)
+	}
+}
+
+func TestSearchOptionsMissingRange(t *testing.T) {
+	options := &SearchOptions{
+		Ranges: []Range{
+			{Field: "nsfw_score", To: 0.5, Missing: true},
+		},
+	}
+
+	want := `{"bool":{"should":[{"range":{"nsfw_score":{"from":null,"include_lower":true,"include_upper":true,"to":0.5}}},{"bool":{"must_not":{"exists":{"field":"nsfw_score"}}}}]}}`
+	if got := querySource(t, options.filters()[0].(*elastic.BoolQuery)); got != want {
+		t.Errorf("filters() = %s, want %s", got, want)
+	}
+}
+
+func TestSearchOptionsAuthorFilter(t *testing.T) {
+	filters := (&SearchOptions{Author: "jane doe"}).filters()
+
+	want := `{"term":{"authors":"jane doe"}}`
+	if len(filters) != 1 || querySource(t, filters[0]) != want {
+		t.Errorf("filters() = %v, want %s", filters, want)
+	}
+}
+
+func TestSearchOptionsTagFilter(t *testing.T) {
+	filters := (&SearchOptions{Tag: "dataset"}).filters()
+
+	want := `{"term":{"tags":"dataset"}}`
+	if len(filters) != 1 || querySource(t, filters[0]) != want {
+		t.Errorf("filters() = %v, want %s", filters, want)
+	}
+}
+
+func TestSearchOptionsLanguageFilter(t *testing.T) {
+	filters := (&SearchOptions{Language: "nl"}).filters()
+
+	want := `{"term":{"language.language":"nl"}}`
+	if len(filters) != 1 || querySource(t, filters[0]) != want {
+		t.Errorf("filters() = %v, want %s", filters, want)
+	}
+}
+
+func TestSearchOptionsCategoryFilter(t *testing.T) {
+	filters := (&SearchOptions{Category: "science"}).filters()
+
+	want := `{"term":{"categories":"science"}}`
+	if len(filters) != 1 || querySource(t, filters[0]) != want {
+		t.Errorf("filters() = %v, want %s", filters, want)
+	}
+}
+
+func TestSearchOptionsGeoFilters(t *testing.T) {
+	options := &SearchOptions{
+		Distance: &GeoDistance{Lat: 52.37, Lon: 4.89, Distance: "10km"},
+		Box:      &GeoBox{Top: 53, Left: 4, Bottom: 52, Right: 5},
+	}
+
+	filters := options.filters()
+	if len(filters) != 2 {
+		t.Fatalf("filters() = %d filters, want 2", len(filters))
+	}
+
+	want := []string{
+		`{"geo_distance":{"distance":"10km","location":{"lat":52.37,"lon":4.89}}}`,
+		`{"geo_bounding_box":{"location":{"bottom_right":[5,52],"top_left":[4,53]}}}`,
+	}
+	for n, f := range filters {
+		if got := querySource(t, f); got != want[n] {
+			t.Errorf("filters()[%d] = %s, want %s", n, got, want[n])
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/olivere/elastic.v5"
+	"sort"
+	"strings"
+)
+
+// CountByType returns the amount of indexed documents per type
+func (i *Indexer) CountByType(ctx context.Context) (map[string]int64, error) {
+	result, err := i.ElasticSearch.Search(searchAlias).
+		Size(0).
+		Aggregation("types", elastic.NewTermsAggregation().Field("_type")).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64)
+
+	types, ok := result.Aggregations.Terms("types")
+	if !ok {
+		return counts, nil
+	}
+
+	for _, bucket := range types.Buckets {
+		if t, ok := bucket.Key.(string); ok {
+			counts[t] = bucket.DocCount
+		}
+	}
+
+	return counts, nil
+}
+
+// Health returns the cluster health status: green, yellow or red
+func (i *Indexer) Health(ctx context.Context) (string, error) {
+	health, err := i.ElasticSearch.ClusterHealth().Do(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return health.Status, nil
+}
+
+// IndexSizes returns the size on disk in bytes for the index of every type
+func (i *Indexer) IndexSizes(ctx context.Context) (map[string]int64, error) {
+	result, err := i.ElasticSearch.IndexStats(searchAlias).Metric("store").Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]int64, len(result.Indices))
+	for name, stats := range result.Indices {
+		if stats.Total != nil && stats.Total.Store != nil {
+			sizes[name] = stats.Total.Store.SizeInBytes
+		}
+	}
+
+	return sizes, nil
+}
+
+// Version returns the Elasticsearch version of the cluster's nodes,
+// comma separated when they differ
+func (i *Indexer) Version(ctx context.Context) (string, error) {
+	info, err := i.ElasticSearch.NodesInfo().Metric("version").Do(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	seen := make(map[string]bool)
+	var versions []string
+	for _, node := range info.Nodes {
+		if !seen[node.Version] {
+			seen[node.Version] = true
+			versions = append(versions, node.Version)
+		}
+	}
+	sort.Strings(versions)
+
+	return strings.Join(versions, ", "), nil
+}
+
+// IndexHealth returns the health status of the document indices, with an
+// error when any of them does not exist
+func (i *Indexer) IndexHealth(ctx context.Context) (string, error) {
+	aliases := make([]string, 0, len(docTypes))
+	for _, doctype := range docTypes {
+		alias := typeAliases[doctype]
+
+		exists, err := i.ElasticSearch.IndexExists(alias).Do(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", fmt.Errorf("index %s does not exist", alias)
+		}
+
+		aliases = append(aliases, alias)
+	}
+
+	health, err := i.ElasticSearch.ClusterHealth().Index(aliases...).Do(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return health.Status, nil
+}
@@ -0,0 +1,87 @@
+package indexer
+
+import (
+	"context"
+	"gopkg.in/olivere/elastic.v5"
+	"sort"
+)
+
+// suggestFields are the fields term suggestions are drawn from; names are
+// included as much of the content on IPFS is only findable by filename
+var suggestFields = []string{"content", "references.name"}
+
+// correction replaces a term in a query with a suggested term
+type correction struct {
+	offset int
+	length int
+	option elastic.SearchSuggestionOption
+}
+
+// better returns whether an option is preferable over the current correction
+func (c *correction) better(option elastic.SearchSuggestionOption) bool {
+	return option.Score > c.option.Score ||
+		(option.Score == c.option.Score && option.Freq > c.option.Freq)
+}
+
+// Suggest returns a "did you mean" alternative for query, based on terms
+// in the index; an empty string is returned when there is no better query
+func (i *Indexer) Suggest(ctx context.Context, query string) (string, error) {
+	search := i.ElasticSearch.Search(searchAlias).Size(0)
+
+	for _, field := range suggestFields {
+		search = search.Suggester(elastic.NewTermSuggester(field).
+			Text(query).
+			Field(field).
+			SuggestMode("popular").
+			Size(1))
+	}
+
+	result, err := search.Do(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// Find the best correction per term over all fields
+	corrections := make(map[int]*correction)
+	for _, suggestions := range result.Suggest {
+		for _, s := range suggestions {
+			for _, option := range s.Options {
+				c, ok := corrections[s.Offset]
+				if !ok {
+					corrections[s.Offset] = &correction{s.Offset, s.Length, option}
+				} else if c.better(option) {
+					c.option = option
+				}
+			}
+		}
+	}
+
+	if len(corrections) == 0 {
+		return "", nil
+	}
+
+	// Replace from the end, so offsets remain valid
+	ordered := make([]*correction, 0, len(corrections))
+	for _, c := range corrections {
+		ordered = append(ordered, c)
+	}
+	sort.Slice(ordered, func(a, b int) bool {
+		return ordered[a].offset > ordered[b].offset
+	})
+
+	suggestion := []rune(query)
+	for _, c := range ordered {
+		if c.offset+c.length > len(suggestion) {
+			continue
+		}
+
+		replaced := append([]rune(c.option.Text), suggestion[c.offset+c.length:]...)
+		suggestion = append(suggestion[:c.offset], replaced...)
+	}
+
+	if string(suggestion) == query {
+		return "", nil
+	}
+
+	return string(suggestion), nil
+}
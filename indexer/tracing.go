@@ -0,0 +1,16 @@
+package indexer
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts a span for writing a document
+func startSpan(ctx context.Context, name string, doctype string, hash string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name,
+		attribute.String("type", doctype),
+		attribute.String("hash", hash),
+	)
+}
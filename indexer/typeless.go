@@ -0,0 +1,454 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/tracing"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Typeless indexes items for crawling through the REST API of
+// Elasticsearch 7 and later or OpenSearch, which have no mapping types.
+// Indices, aliases and mappings are those used with Elasticsearch 5;
+// documents in the meta index are identified by "<kind>:<hash>", e.g.
+// "override:<hash>", instead of by type and hash.
+type Typeless struct {
+	urls     []string
+	client   *http.Client
+	username string
+	password string
+	routing  string // Routing of item documents, RoutingID if empty
+	ids      string // Scheme of document IDs, IDsCID if empty
+
+	// Standby receives writes of documents as well; optional
+	Standby *Typeless
+}
+
+// NewTypeless returns a typeless index for given configuration, writing to
+// the standby cluster as well if configured
+func NewTypeless(config *Config) (*Typeless, error) {
+	if err := checkRouting(config.Routing); err != nil {
+		return nil, err
+	}
+	if err := checkIDs(config.IDs); err != nil {
+		return nil, err
+	}
+
+	transport, err := config.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Typeless{
+		urls:     config.URLs,
+		client:   &http.Client{Transport: transport},
+		username: config.Username,
+		password: config.Password,
+		routing:  config.Routing,
+		ids:      config.IDs,
+	}
+
+	if config.Standby != nil {
+		t.Standby, err = NewTypeless(config.Standby)
+		if err != nil {
+			return nil, fmt.Errorf("standby cluster: %v", err)
+		}
+	}
+
+	return t, nil
+}
+
+// responseError is an unsuccessful response of the REST API
+type responseError struct {
+	Status int
+	Body   string
+}
+
+// Error returns the status and body of the response
+func (e *responseError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.Status, e.Body)
+}
+
+// isNotFound returns whether err is a response for a missing document or
+// index
+func isNotFound(err error) bool {
+	var r *responseError
+	return errors.As(err, &r) && r.Status == http.StatusNotFound
+}
+
+// maxErrorBody limits the part of error responses kept in errors
+const maxErrorBody = 1024
+
+// read decodes a successful response into result, if not nil
+func read(resp *http.Response, result interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &responseError{Status: resp.StatusCode, Body: string(body)}
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// do performs a request with JSON body, if not nil, decoding the response
+// into result. Nodes are tried in order until one can be reached.
+func (t *Typeless) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	err := fmt.Errorf("no nodes configured")
+	for _, node := range t.urls {
+		var req *http.Request
+		req, err = http.NewRequest(method, strings.TrimSuffix(node, "/")+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if t.username != "" {
+			req.SetBasicAuth(t.username, t.password)
+		}
+
+		var resp *http.Response
+		resp, err = t.client.Do(req.WithContext(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			log.WithError(err).WithField("node", node).Debug("Error reaching node")
+			continue
+		}
+
+		return read(resp, result)
+	}
+
+	return err
+}
+
+// write performs a write on the primary cluster and, when it succeeds, on
+// the standby, logging standby failures like Indexer
+func (t *Typeless) write(fn func(*Typeless) error) error {
+	if err := fn(t); err != nil {
+		return err
+	}
+
+	if t.Standby != nil {
+		if err := fn(t.Standby); err != nil {
+			log.WithError(err).Warn("Error writing to standby cluster")
+		}
+	}
+
+	return nil
+}
+
+// docPath returns the path of an API endpoint for a document
+func docPath(index, endpoint, id string) string {
+	return "/" + index + "/" + endpoint + "/" + url.PathEscape(id)
+}
+
+// itemQuery returns the query string of requests for the item document
+// for hash, with its routing key if any, and parameters
+func (t *Typeless) itemQuery(hash string, params url.Values) string {
+	if key := routingKey(t.routing, hash); key != "" {
+		params.Set("routing", key)
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+
+	return "?" + params.Encode()
+}
+
+// id returns the ID of the documents for hash
+func (t *Typeless) id(hash string) string {
+	return documentID(t.ids, hash)
+}
+
+// metaID returns the ID of a document of a kind in the meta index
+func metaID(kind, hash string) string {
+	return kind + ":" + hash
+}
+
+// update performs a partial or scripted update of a document
+func (t *Typeless) update(ctx context.Context, doctype, hash string, body map[string]interface{}) error {
+	alias, err := typeAlias(doctype)
+	if err != nil {
+		return err
+	}
+
+	return t.write(func(c *Typeless) error {
+		return c.do(ctx, http.MethodPost, docPath(alias, "_update", c.id(hash))+c.itemQuery(hash, url.Values{"retry_on_conflict": {"3"}}), body, nil)
+	})
+}
+
+// IndexItem adds or updates an IPFS item with arbitrary properties
+func (t *Typeless) IndexItem(ctx context.Context, doctype string, hash string, properties map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, "IndexItem", doctype, hash)
+	defer func() { tracing.End(span, err) }()
+
+	return t.update(ctx, doctype, hash, map[string]interface{}{
+		"doc":           properties,
+		"doc_as_upsert": true,
+	})
+}
+
+// UpdateItem indexes properties for an item, adding references and
+// aliases to those already indexed, like Indexer.UpdateItem
+func (t *Typeless) UpdateItem(ctx context.Context, doctype string, hash string, properties map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, "UpdateItem", doctype, hash)
+	defer func() { tracing.End(span, err) }()
+
+	return t.update(ctx, doctype, hash, map[string]interface{}{
+		"script": map[string]interface{}{
+			"source": updateScript,
+			"lang":   "painless",
+			"params": updateParams(properties),
+		},
+		"scripted_upsert": true,
+		"upsert":          map[string]interface{}{},
+	})
+}
+
+// getResult is a document in a get or multi get response
+type getResult struct {
+	Index  string          `json:"_index"`
+	ID     string          `json:"_id"`
+	Found  bool            `json:"found"`
+	Source json.RawMessage `json:"_source"`
+}
+
+// GetItem returns existing references, aliases and the type for an object,
+// like Indexer.GetItem
+func (t *Typeless) GetItem(ctx context.Context, hash string) (*Item, error) {
+	docs := make([]map[string]interface{}, len(docTypes))
+	for n, doctype := range docTypes {
+		docs[n] = map[string]interface{}{
+			"_index":  typeAliases[doctype],
+			"_id":     t.id(hash),
+			"_source": []string{"references", "aliases", "size", "content-quality", "structure-only"},
+		}
+		if key := routingKey(t.routing, hash); key != "" {
+			docs[n]["routing"] = key
+		}
+	}
+
+	var result struct {
+		Docs []getResult `json:"docs"`
+	}
+	err := t.do(ctx, http.MethodPost, "/_mget", map[string]interface{}{"docs": docs}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	// Documents are returned in the order requested
+	for n, doc := range result.Docs {
+		if !doc.Found || n >= len(docTypes) {
+			continue
+		}
+
+		item := new(Item)
+		if err := json.Unmarshal(doc.Source, item); err != nil {
+			return nil, err
+		}
+		item.Type = docTypes[n]
+
+		return item, nil
+	}
+
+	return &Item{References: []Reference{}}, nil
+}
+
+// DeleteItem removes the document for a hash from the indices of all
+// document types, returning whether any document was deleted
+func (t *Typeless) DeleteItem(ctx context.Context, hash string) (bool, error) {
+	var deleted bool
+
+	err := t.write(func(c *Typeless) error {
+		for _, doctype := range docTypes {
+			err := c.do(ctx, http.MethodDelete, docPath(typeAliases[doctype], "_doc", c.id(hash))+c.itemQuery(hash, url.Values{}), nil, nil)
+			if isNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if c == t {
+				deleted = true
+			}
+		}
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+// getOverride returns the override of a kind for a hash, or nil if there
+// is none
+func (t *Typeless) getOverride(ctx context.Context, kind, hash string) (*Override, error) {
+	var result getResult
+	err := t.do(ctx, http.MethodGet, docPath(metaIndex, "_doc", metaID(kind, t.id(hash))), nil, &result)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	o := &Override{Hash: hash}
+	if err := json.Unmarshal(result.Source, o); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// GetOverride returns the operator's metadata override for a hash, or nil
+// if there is none
+func (t *Typeless) GetOverride(ctx context.Context, hash string) (*Override, error) {
+	return t.getOverride(ctx, operatorOverride, hash)
+}
+
+// GetPublisherOverride returns the metadata override for a hash set by a
+// verified publisher, or nil if there is none
+func (t *Typeless) GetPublisherOverride(ctx context.Context, hash string) (*Override, error) {
+	return t.getOverride(ctx, publisherOverride, hash)
+}
+
+// RecordAttempt adds a crawl attempt to the history of a hash in the meta
+// index, keeping the last size attempts
+func (t *Typeless) RecordAttempt(ctx context.Context, hash string, attempt *Attempt, size int) error {
+	body := map[string]interface{}{
+		"script": map[string]interface{}{
+			"source": historyScript,
+			"lang":   "painless",
+			"params": map[string]interface{}{
+				"attempt": attempt,
+				"size":    size,
+			},
+		},
+		"upsert": map[string]interface{}{
+			"attempts": []*Attempt{attempt},
+		},
+	}
+
+	return t.write(func(c *Typeless) error {
+		return c.do(ctx, http.MethodPost, docPath(metaIndex, "_update", metaID("history", c.id(hash)))+"?retry_on_conflict=3", body, nil)
+	})
+}
+
+// typelessIndexBody returns the settings, mapping and aliases used to
+// create the index for a document type, without mapping type
+func typelessIndexBody(doctype string, aliases ...string) (map[string]interface{}, error) {
+	body, err := indexBody(doctype, aliases...)
+	if err != nil {
+		return nil, err
+	}
+
+	body["mappings"] = body["mappings"].(map[string]interface{})[doctype]
+
+	return body, nil
+}
+
+// CreateIndex creates the indices for all document types with settings,
+// mapping and aliases, if they do not exist yet, on the primary and the
+// standby cluster. It returns whether any index was created on the primary.
+func (t *Typeless) CreateIndex(ctx context.Context) (bool, error) {
+	var created bool
+
+	err := t.write(func(c *Typeless) error {
+		for _, doctype := range docTypes {
+			name := indexName(doctype)
+
+			err := c.do(ctx, http.MethodHead, "/"+name, nil, nil)
+			if err == nil {
+				continue
+			}
+			if !isNotFound(err) {
+				return err
+			}
+
+			body, err := typelessIndexBody(doctype, typeAliases[doctype], searchAlias)
+			if err != nil {
+				return err
+			}
+
+			log.WithField("index", name).Info("Creating index")
+
+			if err := c.do(ctx, http.MethodPut, "/"+name, body, nil); err != nil {
+				return err
+			}
+
+			if c == t {
+				created = true
+			}
+		}
+
+		return nil
+	})
+
+	return created, err
+}
+
+// Version returns the distribution and version of the cluster
+func (t *Typeless) Version(ctx context.Context) (string, error) {
+	var info struct {
+		Version struct {
+			Number       string `json:"number"`
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+
+	if err := t.do(ctx, http.MethodGet, "/", nil, &info); err != nil {
+		return "", err
+	}
+
+	if info.Version.Distribution != "" {
+		return info.Version.Distribution + " " + info.Version.Number, nil
+	}
+
+	return info.Version.Number, nil
+}
+
+// IndexHealth returns the health status of the document indices, with an
+// error when any of them does not exist
+func (t *Typeless) IndexHealth(ctx context.Context) (string, error) {
+	aliases := make([]string, 0, len(docTypes))
+	for _, doctype := range docTypes {
+		aliases = append(aliases, typeAliases[doctype])
+	}
+
+	var health struct {
+		Status string `json:"status"`
+	}
+
+	err := t.do(ctx, http.MethodGet, "/_cluster/health/"+strings.Join(aliases, ","), nil, &health)
+	if isNotFound(err) {
+		return "", fmt.Errorf("indices %s do not all exist", strings.Join(aliases, ", "))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return health.Status, nil
+}
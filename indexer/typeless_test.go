@@ -0,0 +1,143 @@
+package indexer
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestTypeless returns a typeless index on a test server answering
+// requests with handler
+func newTestTypeless(t *testing.T, handler http.HandlerFunc) *Typeless {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &Typeless{
+		urls:   []string{server.URL},
+		client: server.Client(),
+	}
+}
+
+func TestTypelessIndexBody(t *testing.T) {
+	for _, doctype := range docTypes {
+		body, err := typelessIndexBody(doctype, typeAliases[doctype])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mappings, ok := body["mappings"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("typelessIndexBody(%s) mappings = %v", doctype, body["mappings"])
+		}
+		if _, ok := mappings[doctype]; ok {
+			t.Errorf("typelessIndexBody(%s) has mapping type", doctype)
+		}
+		if len(mappings) == 0 {
+			t.Errorf("typelessIndexBody(%s) has empty mapping", doctype)
+		}
+	}
+}
+
+func TestTypelessGetItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"none", `{"docs":[{"found":false},{"found":false},{"found":false}]}`, ""},
+		{"file", `{"docs":[{"found":true,"_source":{"size":10}},{"found":false},{"found":false}]}`, "file"},
+		{"directory", `{"docs":[{"found":false},{"found":true,"_source":{}},{"found":false}]}`, "directory"},
+		{"invalid", `{"docs":[{"found":false},{"found":false},{"found":true,"_source":{}}]}`, "invalid"},
+	}
+
+	for _, test := range tests {
+		index := newTestTypeless(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/_mget" {
+				t.Errorf("%s: request to %s, want /_mget", test.name, r.URL.Path)
+			}
+			w.Write([]byte(test.response))
+		})
+
+		item, err := index.GetItem(context.Background(), "hash")
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if item.Type != test.want {
+			t.Errorf("%s: GetItem() type = %q, want %q", test.name, item.Type, test.want)
+		}
+	}
+}
+
+func TestTypelessDeleteItem(t *testing.T) {
+	var paths []string
+	index := newTestTypeless(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/ipfs_directories/_doc/hash" {
+			http.NotFound(w, r)
+		}
+	})
+
+	deleted, err := index.DeleteItem(context.Background(), "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted {
+		t.Error("DeleteItem() = false, want true")
+	}
+	if len(paths) != len(docTypes) {
+		t.Errorf("DeleteItem() requested %v, want one request per type", paths)
+	}
+}
+
+func TestTypelessCreateIndex(t *testing.T) {
+	var created []string
+	index := newTestTypeless(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			if r.URL.Path != "/"+indexName("file") {
+				http.NotFound(w, r)
+			}
+		case http.MethodPut:
+			body, _ := ioutil.ReadAll(r.Body)
+			if len(body) == 0 {
+				t.Errorf("PUT %s without body", r.URL.Path)
+			}
+			created = append(created, r.URL.Path)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	ok, err := index.CreateIndex(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("CreateIndex() = false, want true")
+	}
+
+	want := []string{"/" + indexName("directory"), "/" + indexName("invalid")}
+	if len(created) != len(want) || created[0] != want[0] || created[1] != want[1] {
+		t.Errorf("CreateIndex() created %v, want %v", created, want)
+	}
+}
+
+func TestIsTypeless(t *testing.T) {
+	tests := []struct {
+		backend string
+		want    bool
+	}{
+		{"", false},
+		{BackendElasticSearch5, false},
+		{BackendElasticSearch, true},
+		{BackendOpenSearch, true},
+	}
+
+	for _, test := range tests {
+		if got := IsTypeless(test.backend); got != test.want {
+			t.Errorf("IsTypeless(%q) = %v, want %v", test.backend, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package indexer
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/tracing"
+	"gopkg.in/olivere/elastic.v5"
+	"time"
+)
+
+// TaskKey is the property holding the idempotency key of the task an
+// update results from; updates with a key recently applied to a document
+// are skipped, so tasks performed again change nothing
+const TaskKey = "task-key"
+
+// maxTaskKeys limits the idempotency keys remembered per document; tasks
+// are redelivered shortly after failing, so recent keys suffice
+const maxTaskKeys = 32
+
+// updateScript sets properties on a document, appending references and
+// aliases which are not yet present instead of replacing them. The amount
+// of references is kept in reference-count, and the time the document was
+// first referenced in first-referenced; documents referenced before it was
+// kept are taken to be referenced since they were first seen. Updates of
+// which the task key has been applied before are skipped; as the script
+// runs with optimistic concurrency control, checking and recording the key
+// is atomic with the update.
+const updateScript = `
+boolean duplicate = false;
+if (params.task_key != null) {
+	if (ctx._source['task-keys'] == null) {
+		ctx._source['task-keys'] = [];
+	}
+	duplicate = ctx._source['task-keys'].contains(params.task_key);
+	if (!duplicate) {
+		ctx._source['task-keys'].add(params.task_key);
+		while (ctx._source['task-keys'].size() > params.max_task_keys) {
+			ctx._source['task-keys'].remove(0);
+		}
+	}
+}
+
+if (duplicate) {
+	ctx.op = 'none';
+} else {
+	for (def key : params.properties.keySet()) {
+		ctx._source[key] = params.properties[key];
+	}
+
+	if (params.references != null) {
+		if (ctx._source.references == null) {
+			ctx._source.references = [];
+		}
+		int before = ctx._source.references.size();
+		for (def ref : params.references) {
+			boolean found = false;
+			for (def r : ctx._source.references) {
+				if (r.parent_hash == ref.parent_hash) {
+					found = true;
+					break;
+				}
+			}
+			if (!found) {
+				ctx._source.references.add(ref);
+			}
+		}
+
+		ctx._source['reference-count'] = ctx._source.references.size();
+		if (ctx._source['first-referenced'] == null && ctx._source.references.size() > 0) {
+			if (before > 0 && ctx._source['first-seen'] != null) {
+				ctx._source['first-referenced'] = ctx._source['first-seen'];
+			} else {
+				ctx._source['first-referenced'] = params.now;
+			}
+		}
+	}
+
+	if (params.aliases != null) {
+		if (ctx._source.aliases == null) {
+			ctx._source.aliases = [];
+		}
+		for (def alias : params.aliases) {
+			if (!ctx._source.aliases.contains(alias)) {
+				ctx._source.aliases.add(alias);
+			}
+		}
+	}
+}
+`
+
+// updateParams returns the parameters of updateScript for properties
+func updateParams(properties map[string]interface{}) map[string]interface{} {
+	params := map[string]interface{}{
+		"references":    properties["references"],
+		"aliases":       properties["aliases"],
+		"task_key":      properties[TaskKey],
+		"max_task_keys": maxTaskKeys,
+		"now":           time.Now().UTC().Format(time.RFC3339),
+	}
+
+	others := make(map[string]interface{}, len(properties))
+	for k, v := range properties {
+		if k != "references" && k != "aliases" && k != TaskKey {
+			others[k] = v
+		}
+	}
+	params["properties"] = others
+
+	return params
+}
+
+// UpdateItem indexes properties for an item like IndexItem, but adds the
+// references and aliases in properties to those already indexed within a
+// single scripted upsert. Unlike reading, modifying and writing them back,
+// concurrent updates of the same item are not lost. Updates with a TaskKey
+// applied before are skipped.
+func (i *Indexer) UpdateItem(ctx context.Context, doctype string, hash string, properties map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, "UpdateItem", doctype, hash)
+	defer func() { tracing.End(span, err) }()
+
+	alias, err := typeAlias(doctype)
+	if err != nil {
+		return err
+	}
+
+	script := elastic.NewScriptInline(updateScript).
+		Lang("painless").
+		Params(updateParams(properties))
+
+	return i.write(func(c *elastic.Client) error {
+		_, err := c.Update().
+			Index(alias).
+			Type(doctype).
+			Id(i.id(hash)).
+			Routing(i.route(hash)).
+			Script(script).
+			ScriptedUpsert(true).
+			Upsert(map[string]interface{}{}).
+			RetryOnConflict(3).
+			Do(ctx)
+
+		return err
+	})
+}
@@ -0,0 +1,28 @@
+package indexer
+
+import (
+	"testing"
+)
+
+func TestUpdateParams(t *testing.T) {
+	params := updateParams(map[string]interface{}{
+		"size":       10,
+		"references": References{{Name: "a", ParentHash: "p"}},
+		"aliases":    []string{"b"},
+		TaskKey:      "key",
+	})
+
+	if params["task_key"] != "key" || params["max_task_keys"] != maxTaskKeys {
+		t.Errorf("updateParams() = %v, want task key", params)
+	}
+
+	// First references are dated by the time of the update
+	if now, ok := params["now"].(string); !ok || now == "" {
+		t.Errorf("updateParams() now = %v, want time of update", params["now"])
+	}
+
+	properties := params["properties"].(map[string]interface{})
+	if len(properties) != 1 || properties["size"] != 10 {
+		t.Errorf("updateParams() properties = %v, want size only", properties)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Package ipfspool distributes IPFS API requests over several daemons, so a
+single node is no bottleneck. Requests go to healthy nodes in turn; nodes
+which can not be reached are skipped, and requests fail over to the next
+node, until health checks or time bring them back.
+*/
+package ipfspool
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// downTime is the time nodes failing requests are skipped for, unless
+	// a health check finds them up before
+	downTime = 30 * time.Second
+
+	// checkTimeout is the time nodes have to answer a health check
+	checkTimeout = 5 * time.Second
+)
+
+// node is an IPFS API endpoint
+type node struct {
+	host string
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+// up returns whether the node is considered healthy
+func (n *node) up(now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return now.After(n.downUntil)
+}
+
+// setUp marks the node as healthy or down
+func (n *node) setUp(up bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	wasUp := time.Now().After(n.downUntil)
+	if up {
+		n.downUntil = time.Time{}
+	} else {
+		n.downUntil = time.Now().Add(downTime)
+	}
+
+	if up != wasUp {
+		log.WithFields(log.Fields{"node": n.host, "up": up}).Warn("IPFS node changed state")
+	}
+}
+
+// Pool is a transport sending requests to the nodes of a pool, replacing
+// the host of request URLs
+type Pool struct {
+	base  http.RoundTripper
+	nodes []*node
+	next  uint32
+}
+
+// host returns the host of an API URL, which may lack a scheme like the
+// URL of IPFS shells
+func host(url string) string {
+	if n := strings.Index(url, "://"); n != -1 {
+		url = url[n+3:]
+	}
+
+	return strings.TrimSuffix(url, "/")
+}
+
+// New returns a pool of the nodes at urls, performing requests through base
+func New(urls []string, base http.RoundTripper) *Pool {
+	p := &Pool{base: base}
+	for _, url := range urls {
+		p.nodes = append(p.nodes, &node{host: host(url)})
+	}
+
+	return p
+}
+
+// order returns the nodes to try a request on: healthy nodes in turn,
+// starting with the next one, followed by those down as a last resort
+func (p *Pool) order() []*node {
+	now := time.Now()
+	start := int(atomic.AddUint32(&p.next, 1))
+
+	var up, down []*node
+	for i := range p.nodes {
+		n := p.nodes[(start+i)%len(p.nodes)]
+		if n.up(now) {
+			up = append(up, n)
+		} else {
+			down = append(down, n)
+		}
+	}
+
+	return append(up, down...)
+}
+
+// RoundTrip performs the request on the next healthy node, failing over
+// to others when a node can not be reached
+func (p *Pool) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	err := errors.New("no IPFS nodes configured")
+
+	for n, node := range p.order() {
+		r := req.Clone(ctx)
+		r.URL.Host = node.host
+		r.Host = ""
+
+		if n > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				// The body has been consumed; can't retry
+				return nil, err
+			}
+			if r.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		var resp *http.Response
+		resp, err = p.base.RoundTrip(r)
+		if err == nil {
+			return resp, nil
+		}
+
+		if ctx.Err() != nil {
+			// Cancelled or timed out by the caller; the node may be fine
+			return nil, err
+		}
+
+		log.WithError(err).WithField("node", node.host).Debug("Error reaching IPFS node, failing over")
+		node.setUp(false)
+	}
+
+	return nil, err
+}
+
+// check returns whether a node answers a version request in time
+func (p *Pool) check(ctx context.Context, n *node) bool {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+n.host+"/api/v0/version", nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := p.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
+
+// Check marks nodes up or down depending on whether they answer requests
+// in time
+func (p *Pool) Check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, n := range p.nodes {
+		wg.Add(1)
+		go func(n *node) {
+			defer wg.Done()
+
+			up := p.check(ctx, n)
+			if ctx.Err() == nil {
+				n.setUp(up)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+// Watch checks the health of nodes at interval until the context is
+// cancelled
+func (p *Pool) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
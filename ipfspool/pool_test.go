@@ -0,0 +1,88 @@
+package ipfspool
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newNode returns a test server answering with its name
+func newNode(t *testing.T, name string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// get performs a request through the pool, returning the answering node
+func get(t *testing.T, p *Pool) string {
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:5001/api/v0/version", strings.NewReader("body"))
+
+	resp, err := (&http.Client{Transport: p}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	name, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(name)
+}
+
+func TestPoolDistributes(t *testing.T) {
+	a, b := newNode(t, "a"), newNode(t, "b")
+	p := New([]string{a.URL, b.URL}, http.DefaultTransport)
+
+	seen := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		seen[get(t, p)]++
+	}
+
+	if seen["a"] != 2 || seen["b"] != 2 {
+		t.Errorf("requests per node = %v, want 2 each", seen)
+	}
+}
+
+func TestPoolFailsOver(t *testing.T) {
+	a, b := newNode(t, "a"), newNode(t, "b")
+	b.Close()
+
+	p := New([]string{a.URL, b.URL}, http.DefaultTransport)
+
+	for i := 0; i < 4; i++ {
+		if node := get(t, p); node != "a" {
+			t.Errorf("request %d answered by %s, want a", i, node)
+		}
+	}
+
+	if p.nodes[1].up(time.Now()) {
+		t.Error("unreachable node is up")
+	}
+}
+
+func TestPoolCheck(t *testing.T) {
+	a, b := newNode(t, "a"), newNode(t, "b")
+	p := New([]string{"http://" + strings.TrimPrefix(a.URL, "http://"), strings.TrimPrefix(b.URL, "http://")}, http.DefaultTransport)
+
+	p.nodes[0].setUp(false)
+	b.Close()
+
+	p.Check(context.Background())
+
+	now := time.Now()
+	if !p.nodes[0].up(now) {
+		t.Error("healthy node is down after check")
+	}
+	if p.nodes[1].up(now) {
+		t.Error("unreachable node is up after check")
+	}
+}
@@ -0,0 +1,27 @@
+package ipfspool
+
+import (
+	"github.com/ipfs-search/ipfs-search/concurrency"
+	"github.com/ipfs/go-ipfs-api"
+	"net/http"
+)
+
+// NewShell returns an IPFS API shell distributing requests over the nodes
+// at urls, with requests in flight and their rate limited by config. With
+// several nodes, the pool is returned for health checks; otherwise nil.
+func NewShell(urls []string, config concurrency.Config) (*shell.Shell, *Pool) {
+	var transport http.RoundTripper = concurrency.NewShellTransport()
+
+	var url string
+	if len(urls) > 0 {
+		url = urls[0]
+	}
+
+	var pool *Pool
+	if len(urls) > 1 {
+		pool = New(urls, transport)
+		transport = pool
+	}
+
+	return concurrency.NewShellWithTransport(url, config, transport), pool
+}
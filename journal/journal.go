@@ -0,0 +1,258 @@
+/*
+Package journal records the tasks a crawler has started and completed in a
+local file, so that after a crash the tasks which were in flight are known
+and can be queued again. Brokers redeliver unacknowledged messages as well,
+but don't tell which ones were being crawled when a crawler died, and
+messages rejected or expired meanwhile are lost.
+
+Records are appended as JSON lines with a single write each, so they survive
+the crawler process crashing. The file is compacted to the tasks in flight
+once enough records have been appended.
+*/
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// compactAfter is the amount of records appended after which the file is
+// rewritten with the tasks in flight only
+const compactAfter = 10000
+
+// Entry is a task in flight
+type Entry struct {
+	ID       uint64          `json:"id"`
+	Queue    string          `json:"queue"`
+	Priority uint8           `json:"priority"`
+	Body     json.RawMessage `json:"body"`
+	Started  time.Time       `json:"started"`
+}
+
+// record is a line in the journal: a started task, or the completion of
+// the task with ID
+type record struct {
+	*Entry
+	ID   uint64 `json:"id"`
+	Done bool   `json:"done,omitempty"`
+}
+
+// Journal records tasks in flight in a file
+type Journal struct {
+	path string
+
+	mu       sync.Mutex
+	file     *os.File
+	nextID   uint64
+	pending  map[uint64]*Entry
+	appended int // Records appended since the last compaction
+}
+
+// read returns the tasks in flight according to the journal at path, and
+// the highest ID used; a missing file has none
+func read(path string) (map[uint64]*Entry, uint64, error) {
+	pending := make(map[uint64]*Entry)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return pending, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var maxID uint64
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		r := &record{}
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			// The last record may have been cut short by a power failure
+			log.WithError(err).WithField("line", line).Warn("Skipping invalid journal record")
+			continue
+		}
+
+		if r.Done {
+			delete(pending, r.ID)
+			continue
+		}
+
+		if r.Entry != nil {
+			r.Entry.ID = r.ID
+			pending[r.ID] = r.Entry
+		}
+		if r.ID > maxID {
+			maxID = r.ID
+		}
+	}
+
+	return pending, maxID, scanner.Err()
+}
+
+// Open opens the journal at path, creating it if it doesn't exist. Tasks
+// left in flight by an earlier run remain pending until marked done.
+func Open(path string) (*Journal, error) {
+	pending, maxID, err := read(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading journal: %v", err)
+	}
+
+	j := &Journal{
+		path:    path,
+		nextID:  maxID + 1,
+		pending: pending,
+	}
+
+	// Start from a compacted file
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// append writes a record to the file
+func (j *Journal) append(r *record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	j.appended++
+	if j.appended >= compactAfter {
+		return j.compact()
+	}
+
+	return nil
+}
+
+// compact replaces the file by one holding the tasks in flight only
+func (j *Journal) compact() error {
+	tmp := j.path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(f)
+	for _, e := range j.sorted() {
+		if err := encoder.Encode(&record{Entry: e, ID: e.ID}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	// Sync before renaming, so the file is never replaced by a partial one
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+
+	j.file, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0644)
+	j.appended = 0
+
+	return err
+}
+
+// sorted returns the tasks in flight, oldest first
+func (j *Journal) sorted() []*Entry {
+	entries := make([]*Entry, 0, len(j.pending))
+	for _, e := range j.pending {
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].ID < entries[b].ID
+	})
+
+	return entries
+}
+
+// Start records that the task with body, received from queue, has been
+// started, returning its ID. Nil journals record nothing.
+func (j *Journal) Start(queue string, priority uint8, body []byte) (uint64, error) {
+	if j == nil {
+		return 0, nil
+	}
+
+	if !json.Valid(body) {
+		return 0, fmt.Errorf("task in %s is not JSON: %q", queue, body)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e := &Entry{
+		ID:       j.nextID,
+		Queue:    queue,
+		Priority: priority,
+		Body:     json.RawMessage(append([]byte{}, body...)),
+		Started:  time.Now().UTC(),
+	}
+	j.nextID++
+
+	j.pending[e.ID] = e
+
+	return e.ID, j.append(&record{Entry: e, ID: e.ID})
+}
+
+// Done records that the task with id has completed, successfully or not
+func (j *Journal) Done(id uint64) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.pending[id]; !ok {
+		return nil
+	}
+	delete(j.pending, id)
+
+	return j.append(&record{ID: id, Done: true})
+}
+
+// Pending returns the tasks in flight, oldest first
+func (j *Journal) Pending() []*Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.sorted()
+}
+
+// Close compacts and closes the journal
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.compact(); err != nil {
+		return err
+	}
+
+	return j.file.Close()
+}
@@ -0,0 +1,117 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := j.Start("hashes", 9, []byte(`{"hash":"a"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Start("files", 0, []byte(`{"hash":"b"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Done(first); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := j.Start("files", 0, []byte("not json")); err == nil {
+		t.Error("Start() accepted body which is not JSON")
+	}
+
+	// Reopen without closing, as after a crash
+	j, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending := j.Pending()
+	if len(pending) != 1 {
+		t.Fatalf("Pending() = %d entries, want 1", len(pending))
+	}
+	if e := pending[0]; e.Queue != "files" || string(e.Body) != `{"hash":"b"}` || e.Started.IsZero() {
+		t.Errorf("Pending() = %+v, want task b from files", e)
+	}
+
+	// IDs are not reused after reopening
+	id, err := j.Start("hashes", 0, []byte(`{"hash":"c"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id <= pending[0].ID {
+		t.Errorf("Start() = %d, want ID after %d", id, pending[0].ID)
+	}
+
+	if err := j.Done(pending[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := j.Pending(); len(pending) != 1 || pending[0].ID != id {
+		t.Errorf("Pending() = %+v, want task %d only", pending, id)
+	}
+}
+
+func TestJournalTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+
+	data := `{"id":1,"queue":"hashes","priority":0,"body":{"hash":"a"},"started":"2020-01-01T00:00:00Z"}
+{"id":2,"queue":"hashes","priority":0,"body":{"hash":"b"},"started":"2020-01-01T00:00:00Z"}
+{"id":1,"done":true}
+{"id":3,"queue":"hashes","prio`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pending := j.Pending(); len(pending) != 1 || pending[0].ID != 2 {
+		t.Errorf("Pending() = %+v, want task 2 only", pending)
+	}
+}
+
+func TestJournalCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n := 0; n < compactAfter; n++ {
+		id, err := j.Start("files", 0, []byte(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := j.Done(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 100*1024 {
+		t.Errorf("journal of %d bytes with no tasks in flight, want compacted", info.Size())
+	}
+}
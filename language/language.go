@@ -0,0 +1,186 @@
+/*
+Package language detects the language of extracted text, for documents
+whose extractor did not, so results can be filtered by language. Text in
+a script used by a single language is recognized by its script; other
+text by the frequency of common words.
+*/
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Confidence levels, as reported by Tika's language detection
+const (
+	High   = "HIGH"
+	Medium = "MEDIUM"
+	Low    = "LOW"
+)
+
+const (
+	// maxWords is the amount of words of text considered; detection of
+	// long documents is not improved by reading all of them
+	maxWords = 2000
+
+	// minWords is the amount of words below which languages are not
+	// detected from common words
+	minWords = 5
+
+	// minHits is the amount of common words required to detect a language
+	minHits = 3
+
+	// minScriptShare is the share of letters in a script required to
+	// detect its language
+	minScriptShare = 0.5
+)
+
+// scripts are the scripts used by a single language
+var scripts = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Arabic, "ar"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Armenian, "hy"},
+	{unicode.Georgian, "ka"},
+}
+
+// commonWords are frequent words which are rare in the other languages
+var commonWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "that", "for", "with", "was", "this", "are", "be", "have", "from", "which", "not", "by", "it", "you", "they"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "mit", "den", "sich", "auf", "für", "dem", "auch", "wird", "von", "zu", "ich", "wir"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "du", "dans", "que", "pour", "qui", "pas", "sur", "au", "avec", "ce", "sont", "nous", "il"},
+	"es": {"el", "los", "las", "y", "del", "que", "es", "por", "una", "con", "para", "se", "como", "su", "al", "pero", "está", "muy", "sus", "fue"},
+	"it": {"il", "di", "che", "è", "della", "per", "non", "sono", "gli", "con", "una", "del", "nel", "anche", "più", "questo", "alla", "lo", "ha", "ma"},
+	"pt": {"o", "os", "e", "do", "da", "não", "uma", "em", "que", "com", "para", "por", "mais", "dos", "das", "são", "como", "foi", "ao", "você"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "dat", "op", "zijn", "voor", "met", "ook", "maar", "er", "wordt", "aan", "bij", "ik", "wij"},
+	"sv": {"och", "att", "det", "som", "en", "är", "av", "för", "med", "inte", "på", "den", "till", "har", "jag", "om", "ett", "vi", "var", "men"},
+	"pl": {"i", "w", "nie", "na", "się", "jest", "to", "że", "do", "z", "jak", "ale", "są", "od", "po", "tak", "dla", "jego", "czy", "był"},
+	"ru": {"и", "в", "не", "на", "что", "я", "с", "он", "как", "это", "по", "но", "из", "к", "у", "за", "от", "так", "же", "было"},
+	"uk": {"і", "в", "не", "на", "що", "з", "я", "та", "як", "це", "до", "але", "він", "від", "за", "у", "ми", "був", "його", "й"},
+}
+
+// wordLanguages maps common words to their languages
+var wordLanguages = make(map[string][]string)
+
+func init() {
+	for language, words := range commonWords {
+		for _, w := range words {
+			wordLanguages[w] = append(wordLanguages[w], language)
+		}
+	}
+}
+
+// words returns the first maxWords lowercased words in text
+func words(text string) []string {
+	var result []string
+
+	for _, w := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		result = append(result, strings.ToLower(w))
+		if len(result) == maxWords {
+			break
+		}
+	}
+
+	return result
+}
+
+// byScript returns the language of text written mostly in a script used
+// by a single language, or an empty string
+func byScript(text string) string {
+	counts := make([]int, len(scripts))
+	letters := 0
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+
+		letters++
+		if letters > maxWords*10 {
+			break
+		}
+
+		for n, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[n]++
+				break
+			}
+		}
+	}
+
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese is written with kana as well as Chinese characters
+	kana := counts[1] + counts[2]
+	if kana > 0 && float64(kana+counts[3])/float64(letters) >= minScriptShare {
+		return "ja"
+	}
+
+	for n, s := range scripts {
+		if float64(counts[n])/float64(letters) >= minScriptShare {
+			return s.language
+		}
+	}
+
+	return ""
+}
+
+// Detect returns the language of text as an ISO 639-1 code, the
+// confidence of the detection and a score in [0, 1]; an empty language
+// when it cannot be detected
+func Detect(text string) (string, string, float64) {
+	if l := byScript(text); l != "" {
+		return l, High, 1
+	}
+
+	w := words(text)
+	if len(w) < minWords {
+		return "", "", 0
+	}
+
+	hits := make(map[string]int)
+	for _, word := range w {
+		for _, l := range wordLanguages[word] {
+			hits[l]++
+		}
+	}
+
+	var best, second string
+	for l, h := range hits {
+		switch {
+		case best == "" || h > hits[best] || (h == hits[best] && l < best):
+			best, second = l, best
+		case second == "" || h > hits[second] || (h == hits[second] && l < second):
+			second = l
+		}
+	}
+
+	if best == "" || hits[best] < minHits {
+		return "", "", 0
+	}
+
+	// Confidence depends on how clearly the best language stands out
+	margin := float64(hits[best]-hits[second]) / float64(hits[best])
+	confidence := Low
+	switch {
+	case margin >= 0.5:
+		confidence = High
+	case margin >= 0.2:
+		confidence = Medium
+	}
+
+	return best, confidence, float64(hits[best]) / float64(len(w))
+}
@@ -0,0 +1,42 @@
+package language
+
+import (
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text     string
+		language string
+	}{
+		{"The quick brown fox jumps over the lazy dog and this is the story of that fox.", "en"},
+		{"Der schnelle braune Fuchs springt über den faulen Hund, und das ist nicht die ganze Geschichte.", "de"},
+		{"Le renard brun saute par-dessus le chien paresseux et les autres sont dans la maison.", "fr"},
+		{"De snelle bruine vos springt over de luie hond en het is niet de eerste keer.", "nl"},
+		{"Быстрая лиса прыгает через ленивую собаку, и это не в первый раз, как он говорил.", "ru"},
+		{"東京は日本の首都です。ここにはたくさんの人が住んでいます。", "ja"},
+		{"北京是中华人民共和国的首都。", "zh"},
+		{"서울은 대한민국의 수도입니다.", "ko"},
+		{"Η Αθήνα είναι η πρωτεύουσα της Ελλάδας.", "el"},
+		{"", ""},
+		{"12345 67890", ""},
+		{"Lorem ipsum", ""},
+	}
+
+	for _, test := range tests {
+		language, _, _ := Detect(test.text)
+		if language != test.language {
+			t.Errorf("Detect(%q) = %q, want %q", test.text, language, test.language)
+		}
+	}
+}
+
+func TestDetectConfidence(t *testing.T) {
+	_, confidence, score := Detect("The cat and the dog went to the park with the children of the town.")
+	if confidence != High {
+		t.Errorf("Detect() confidence = %s, want %s", confidence, High)
+	}
+	if score <= 0 || score > 1 {
+		t.Errorf("Detect() score = %v, want in (0, 1]", score)
+	}
+}
@@ -1,5 +1,4 @@
 /*
-
 Search engine for IPFS using Elasticsearch, RabbitMQ and Tika.
 */
 package main
@@ -9,36 +8,381 @@ import (
 	"fmt"
 	"github.com/ipfs-search/ipfs-search/commands"
 	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
 	"gopkg.in/urfave/cli.v1"
-	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func main() {
-	// Prefix logging with filename and line number: "d.go:23"
-	// log.SetFlags(log.Lshortfile)
-
-	// Logging w/o prefix
-	log.SetFlags(0)
-
 	app := cli.NewApp()
 	app.Name = "ipfs-search"
 	app.Usage = "IPFS search engine."
 
 	app.Commands = []cli.Command{
 		{
-			Name:    "add",
-			Aliases: []string{"a"},
-			Usage:   "add `HASH` to crawler queue",
-			Action:  add,
+			Name:      "add",
+			Aliases:   []string{"a"},
+			Usage:     "add hashes to crawler queue; - reads them from stdin",
+			ArgsUsage: "[HASH...]",
+			Action:    add,
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "file, f",
+					Usage: "read hashes from `FILE`, one per line",
+				},
+				cli.BoolFlag{
+					Name:  "wait, w",
+					Usage: "wait for crawls to complete and write manifests as JSON",
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Value: time.Hour,
+					Usage: "maximum time to wait for crawls",
+				},
+				cli.DurationFlag{
+					Name:  "ttl",
+					Usage: "drop crawls not started within `DURATION`, along with items found by them",
+				},
+				cli.BoolFlag{
+					Name:  "structure-only",
+					Usage: "index directory structure and file names only, without fetching file contents",
+				},
+			},
+		},
+		{
+			Name:      "crawl",
+			Aliases:   []string{"c"},
+			Usage:     "start crawler; with --standalone, queue HASHes or - for stdin",
+			Action:    crawl,
+			ArgsUsage: "[HASH...]",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "wait-for-deps",
+					Usage: "wait for IPFS, AMQP, Elasticsearch and ipfs-tika to become available",
+				},
+				cli.BoolFlag{
+					Name:  "standalone",
+					Usage: "queue in memory instead of on a broker and serve the API, for development and small indexes",
+				},
+				cli.IntFlag{
+					Name:  "queue-size",
+					Value: 10000,
+					Usage: "maximum `MESSAGES` in each in-memory queue",
+				},
+			},
+		},
+		{
+			Name:   "recrawl",
+			Usage:  "periodically queue stale items for crawling again",
+			Action: recrawl,
+		},
+		{
+			Name:   "probe",
+			Usage:  "periodically probe the availability of a sample of indexed content on public gateways",
+			Action: probe,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "once",
+					Usage: "probe a single sample",
+				},
+			},
+		},
+		{
+			Name:   "snapshot",
+			Usage:  "periodically publish snapshots of the index to IPFS under an IPNS key",
+			Action: publishSnapshot,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "once",
+					Usage: "publish a single snapshot and write its hash",
+				},
+			},
+		},
+		{
+			Name:      "mirror",
+			Usage:     "import snapshots published by another instance instead of crawling",
+			ArgsUsage: "NAME",
+			Action:    mirror,
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "interval",
+					Value: time.Hour,
+					Usage: "check for new snapshots every `DURATION`",
+				},
+				cli.BoolFlag{
+					Name:  "once",
+					Usage: "import the current snapshot and exit",
+				},
+			},
+		},
+		{
+			Name:   "api",
+			Usage:  "start HTTP API server",
+			Action: serveAPI,
+		},
+		{
+			Name:   "exporter",
+			Usage:  "serve queue and index metrics for Prometheus and write stats rollups, without crawling",
+			Action: exporter,
+		},
+		{
+			Name:   "status",
+			Usage:  "show status of queues, index and IPFS daemon",
+			Action: status,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "output status as JSON",
+				},
+				cli.DurationFlag{
+					Name:  "sample",
+					Value: 10 * time.Second,
+					Usage: "time queues are observed to estimate their trend and drain time, 0 to skip",
+				},
+			},
+		},
+		{
+			Name:  "index",
+			Usage: "manage the Elasticsearch indices",
+			Subcommands: []cli.Command{
+				{
+					Name:   "ensure",
+					Usage:  "create indices or verify and update their mappings",
+					Action: indexEnsure,
+				},
+				{
+					Name:   "migrate",
+					Usage:  "move documents from the legacy single index to per-type indices",
+					Action: indexMigrate,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "delete-legacy",
+							Usage: "delete the legacy index after verifying all documents were moved, so searches use the new indices; irreversible",
+						},
+					},
+				},
+				{
+					Name:   "migrate-ids",
+					Usage:  "move documents to the IDs of the configured elasticsearch.document_ids scheme, merging duplicates",
+					Action: indexMigrateIDs,
+				},
+			},
+		},
+		{
+			Name:      "purge",
+			Usage:     "remove documents from the index",
+			ArgsUsage: "HASH...",
+			Action:    purge,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "recursive, r",
+					Usage: "also remove directory entries which are not referenced from elsewhere",
+				},
+				cli.BoolFlag{
+					Name:  "unlink",
+					Usage: "also remove links to HASH from indexed parent directories",
+				},
+			},
+		},
+		{
+			Name:      "erase",
+			Usage:     "erase content for good: deny it, remove its documents, thumbnails and references to it, and record it in the audit log",
+			ArgsUsage: "CID|SHA256",
+			Action:    erase,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "reason",
+					Usage: "`REASON` recorded in the audit log and the denylist, required",
+				},
+				cli.StringFlag{
+					Name:   "operator",
+					Usage:  "`NAME` of whoever erases the content",
+					EnvVar: "USER",
+				},
+				cli.BoolFlag{
+					Name:  "recursive, r",
+					Usage: "also remove directory entries which are not referenced from elsewhere",
+				},
+			},
+		},
+		{
+			Name:   "erasures",
+			Usage:  "write the audit log of erasures as JSON lines, most recent first",
+			Action: erasures,
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "size, n",
+					Value: 100,
+					Usage: "amount of erasures to write",
+				},
+			},
+		},
+		{
+			Name:  "queue",
+			Usage: "dump and replay queued messages, e.g. for broker migrations",
+			Subcommands: []cli.Command{
+				{
+					Name:   "dump",
+					Usage:  "write queued messages as JSON lines",
+					Action: queueDump,
+					Flags: []cli.Flag{
+						cli.StringSliceFlag{
+							Name:  "queue, q",
+							Usage: "dump `QUEUE` only, may be repeated; defaults to all crawler queues",
+						},
+						cli.BoolFlag{
+							Name:  "remove",
+							Usage: "remove dumped messages from the queues",
+						},
+					},
+				},
+				{
+					Name:      "replay",
+					Usage:     "publish dumped messages to their queues; - reads them from stdin",
+					ArgsUsage: "FILE",
+					Action:    queueReplay,
+				},
+			},
 		},
 		{
-			Name:    "crawl",
-			Aliases: []string{"c"},
-			Usage:   "start crawler",
-			Action:  crawl,
+			Name:   "journal",
+			Usage:  "write tasks journaled as in flight, e.g. after a crash, as queue dump JSON lines",
+			Action: journalInFlight,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "requeue",
+					Usage: "publish the tasks to their queues again and remove them from the journal; stop the crawler first",
+				},
+			},
+		},
+		{
+			Name:  "denylist",
+			Usage: "manage denied CIDs",
+			Subcommands: []cli.Command{
+				{
+					Name:   "apply",
+					Usage:  "remove indexed documents for CIDs on the denylist",
+					Action: denylistApply,
+				},
+			},
+		},
+		{
+			Name:   "sample",
+			Usage:  "write random sample of indexed documents as JSON lines",
+			Action: sample,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "type, t",
+					Usage: "only sample documents of `TYPE` (file, directory, invalid)",
+				},
+				cli.StringFlag{
+					Name:  "query, q",
+					Usage: "only sample documents matching `QUERY`, e.g. 'size:>1024'",
+				},
+				cli.IntFlag{
+					Name:  "size, n",
+					Value: 10,
+					Usage: "amount of documents to sample",
+				},
+				cli.Int64Flag{
+					Name:  "seed",
+					Usage: "seed for reproducible samples",
+				},
+			},
+		},
+		{
+			Name:  "relevance",
+			Usage: "evaluate search relevance",
+			Subcommands: []cli.Command{
+				{
+					Name:      "eval",
+					Usage:     "report nDCG and recall for labeled queries in FILE",
+					ArgsUsage: "FILE",
+					Action:    relevanceEval,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "k",
+							Value: 10,
+							Usage: "evaluate the top `K` results",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "curation",
+			Usage: "manage boosted and pinned results",
+			Subcommands: []cli.Command{
+				{
+					Name:      "add",
+					Usage:     "boost or pin HASH for queries matching PATTERN",
+					ArgsUsage: "PATTERN HASH",
+					Action:    curationAdd,
+					Flags: []cli.Flag{
+						cli.Float64Flag{
+							Name:  "boost",
+							Value: 2,
+							Usage: "multiply relevance by `FACTOR`",
+						},
+						cli.BoolFlag{
+							Name:  "pin",
+							Usage: "rank above all other results",
+						},
+					},
+				},
+				{
+					Name:   "list",
+					Usage:  "list boosts and pins",
+					Action: curationList,
+				},
+				{
+					Name:      "remove",
+					Usage:     "remove boost or pin",
+					ArgsUsage: "ID",
+					Action:    curationRemove,
+				},
+			},
+		},
+		{
+			Name:  "override",
+			Usage: "manage metadata overrides",
+			Subcommands: []cli.Command{
+				{
+					Name:      "set",
+					Usage:     "replace extracted title, description or tags of HASH",
+					ArgsUsage: "HASH",
+					Action:    overrideSet,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "title",
+							Usage: "use `TITLE` instead of the extracted title",
+						},
+						cli.StringFlag{
+							Name:  "description",
+							Usage: "use `DESCRIPTION` instead of the extracted description",
+						},
+						cli.StringSliceFlag{
+							Name:  "tag",
+							Usage: "use `TAG` instead of extracted keywords, can be repeated",
+						},
+					},
+				},
+				{
+					Name:   "list",
+					Usage:  "list metadata overrides",
+					Action: overrideList,
+				},
+				{
+					Name:      "remove",
+					Usage:     "remove metadata override, restoring extracted metadata",
+					ArgsUsage: "HASH",
+					Action:    overrideRemove,
+				},
+			},
 		},
 	}
 
@@ -47,14 +391,65 @@ func main() {
 			Name:  "config, c",
 			Usage: "Load configuration from `FILE`",
 		},
+		cli.StringFlag{
+			Name:  "log-level",
+			Value: "info",
+			Usage: "Log messages at `LEVEL` and above (debug, info, warning, error)",
+		},
+		cli.StringFlag{
+			Name:  "log-format",
+			Value: "text",
+			Usage: "Log in `FORMAT` (text, json)",
+		},
+		cli.StringFlag{
+			Name:  "debug-addr",
+			Usage: "Serve pprof profiles, expvar and goroutine dumps on `ADDRESS`, e.g. localhost:6060",
+		},
 	}
 
+	app.Before = setup
+
 	err := app.Run(os.Args)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+// setup configures logging and starts debug endpoints from global flags
+func setup(c *cli.Context) error {
+	if err := setupLogging(c); err != nil {
+		return err
+	}
+
+	if addr := c.GlobalString("debug-addr"); addr != "" {
+		if err := commands.ServeDebug(addr); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
+	return nil
+}
+
+// setupLogging configures log level and format from global flags
+func setupLogging(c *cli.Context) error {
+	level, err := log.ParseLevel(c.GlobalString("log-level"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	log.SetLevel(level)
+
+	switch c.GlobalString("log-format") {
+	case "text":
+		log.SetFormatter(&log.TextFormatter{})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return cli.NewExitError(fmt.Sprintf("Unknown log format: %s", c.GlobalString("log-format")), 1)
+	}
+
+	return nil
+}
+
 func getConfig(c *cli.Context) (*config.Config, error) {
 	configFile := c.GlobalString("config")
 
@@ -67,19 +462,47 @@ func getConfig(c *cli.Context) (*config.Config, error) {
 }
 
 func add(c *cli.Context) error {
-	if c.NArg() != 1 {
-		return cli.NewExitError("Please supply one hash as argument.", 1)
+	if c.NArg() == 0 && len(c.StringSlice("file")) == 0 {
+		return cli.NewExitError("Please supply hashes as arguments, - for stdin or --file.", 1)
 	}
-	hash := c.Args().Get(0)
 
 	cfg, err := getConfig(c)
 	if err != nil {
 		return cli.NewExitError(err.Error(), 1)
 	}
 
-	fmt.Printf("Adding hash '%s' to queue\n", hash)
+	options := &commands.AddOptions{
+		Wait:    c.Bool("wait"),
+		Timeout: c.Duration("timeout"),
+		TTL:     c.Duration("ttl"),
+
+		StructureOnly: c.Bool("structure-only"),
+	}
+
+	for _, arg := range c.Args() {
+		if arg == "-" {
+			options.Sources = append(options.Sources, os.Stdin)
+		} else {
+			options.Hashes = append(options.Hashes, arg)
+		}
+	}
+
+	for _, filename := range c.StringSlice("file") {
+		f, err := os.Open(filename)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer f.Close()
+
+		options.Sources = append(options.Sources, f)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 
-	err = commands.AddHash(cfg, hash)
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	err = commands.AddHashes(ctx, cfg, options, os.Stdout)
 	if err != nil {
 		return cli.NewExitError(err.Error(), 1)
 	}
@@ -122,8 +545,534 @@ func crawl(c *cli.Context) error {
 		return cli.NewExitError(err.Error(), 1)
 	}
 
-	err = commands.Crawl(ctx, cfg)
+	standalone := c.Bool("standalone")
+	if !standalone && c.NArg() > 0 {
+		return cli.NewExitError("Hashes can only be queued with --standalone, use add otherwise.", 1)
+	}
+	if standalone {
+		commands.UseMemoryQueues(cfg, c.Int("queue-size"))
+	}
+
+	err = commands.CheckDependencies(ctx, cfg, c.Bool("wait-for-deps"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if standalone {
+		options := &commands.AddOptions{}
+		for _, arg := range c.Args() {
+			if arg == "-" {
+				options.Sources = append(options.Sources, os.Stdin)
+			} else {
+				options.Hashes = append(options.Hashes, arg)
+			}
+		}
+
+		err = commands.Standalone(ctx, cfg, options, os.Stdout)
+	} else {
+		err = commands.Crawl(ctx, cfg)
+	}
+
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func recrawl(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.Recrawl(ctx, cfg)
+	if err != nil && err != context.Canceled {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func probe(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.Probe(ctx, cfg, c.Bool("once"))
+	if err != nil && err != context.Canceled {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func publishSnapshot(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.Snapshot(ctx, cfg, c.Bool("once"))
+	if err != nil && err != context.Canceled {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func mirror(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.NewExitError("Please supply the IPNS name of snapshots as argument.", 1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	options := &commands.MirrorOptions{
+		Name:     c.Args().Get(0),
+		Interval: c.Duration("interval"),
+		Once:     c.Bool("once"),
+	}
+
+	err = commands.Mirror(ctx, cfg, options)
+	if err != nil && err != context.Canceled {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func serveAPI(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.API(ctx, cfg)
+	if err != nil && err != context.Canceled {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func exporter(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.Exporter(ctx, cfg)
+	if err != nil && err != context.Canceled {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func status(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.WriteStatus(context.Background(), cfg, c.Duration("sample"), c.Bool("json"), os.Stdout)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func indexEnsure(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.EnsureIndex(context.Background(), cfg)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Println("Index up to date")
+
+	return nil
+}
+
+func indexMigrate(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.MigrateIndex(context.Background(), cfg, c.Bool("delete-legacy"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if c.Bool("delete-legacy") {
+		fmt.Println("Index migrated")
+	} else {
+		fmt.Println("Documents copied; run again with --delete-legacy to delete the legacy index")
+	}
+
+	return nil
+}
+
+func indexMigrateIDs(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	m, err := commands.MigrateIDs(context.Background(), cfg)
+	if m != nil {
+		fmt.Printf("Checked %d documents: moved %d, merged %d duplicates\n", m.Checked, m.Moved, m.Merged)
+	}
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func purge(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cli.NewExitError("Please supply at least one hash as argument.", 1)
+	}
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	options := &commands.PurgeOptions{
+		Hashes:    c.Args(),
+		Recursive: c.Bool("recursive"),
+		Unlink:    c.Bool("unlink"),
+	}
+
+	purged, err := commands.Purge(context.Background(), cfg, options)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Printf("Purged %d documents\n", purged)
+
+	return nil
+}
+
+func erase(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.NewExitError("Please supply one CID or SHA-256 checksum as argument.", 1)
+	}
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	options := &commands.EraseOptions{
+		Request:   c.Args().First(),
+		Reason:    c.String("reason"),
+		Operator:  c.String("operator"),
+		Recursive: c.Bool("recursive"),
+	}
+
+	e, err := commands.Erase(context.Background(), cfg, options)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Printf("Erased %d documents, removed links from %d and references from %d documents\n", len(e.Removed), len(e.Unlinked), len(e.Dereferenced))
+
+	return nil
+}
+
+func erasures(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if err := commands.WriteErasures(context.Background(), cfg, c.Int("size"), os.Stdout); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func queueDump(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	options := &commands.DumpOptions{
+		Queues: c.StringSlice("queue"),
+		Remove: c.Bool("remove"),
+	}
+
+	dumped, err := commands.DumpQueues(cfg, options, os.Stdout)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Dumped %d messages\n", dumped)
+
+	return nil
+}
+
+func queueReplay(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.NewExitError("Please supply one dump file as argument.", 1)
+	}
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	r := os.Stdin
+	if c.Args().First() != "-" {
+		r, err = os.Open(c.Args().First())
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer r.Close()
+	}
+
+	replayed, err := commands.ReplayQueues(cfg, r)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Printf("Replayed %d messages\n", replayed)
+
+	return nil
+}
+
+func journalInFlight(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	n, err := commands.InFlight(cfg, c.Bool("requeue"), os.Stdout)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if c.Bool("requeue") {
+		fmt.Fprintf(os.Stderr, "Requeued %d tasks\n", n)
+	} else {
+		fmt.Fprintf(os.Stderr, "%d tasks in flight\n", n)
+	}
+
+	return nil
+}
+
+func denylistApply(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	removed, err := commands.ApplyDenylist(context.Background(), cfg)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Printf("Removed %d denied documents\n", removed)
+
+	return nil
+}
+
+func sample(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	options := &commands.SampleOptions{
+		Type:  c.String("type"),
+		Query: c.String("query"),
+		Size:  c.Int("size"),
+		Seed:  c.Int64("seed"),
+	}
+
+	err = commands.Sample(context.Background(), cfg, options, os.Stdout)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func relevanceEval(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.NewExitError("Please supply one judgements file as argument.", 1)
+	}
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.EvaluateRelevance(context.Background(), cfg, c.Args().Get(0), c.Int("k"), os.Stdout)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func curationAdd(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.NewExitError("Please supply a pattern and a hash as arguments.", 1)
+	}
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	curation := &indexer.Curation{
+		Pattern: c.Args().Get(0),
+		Hash:    c.Args().Get(1),
+		Boost:   c.Float64("boost"),
+		Pinned:  c.Bool("pin"),
+	}
+
+	err = commands.AddCuration(context.Background(), cfg, curation, os.Stdout)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func curationList(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.ListCurations(context.Background(), cfg, os.Stdout)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func curationRemove(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.NewExitError("Please supply one curation ID as argument.", 1)
+	}
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.RemoveCuration(context.Background(), cfg, c.Args().Get(0))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func overrideSet(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.NewExitError("Please supply one hash as argument.", 1)
+	}
+
+	if c.String("title") == "" && c.String("description") == "" && len(c.StringSlice("tag")) == 0 {
+		return cli.NewExitError("Please supply a title, description or tags.", 1)
+	}
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	override := &indexer.Override{
+		Hash:        c.Args().Get(0),
+		Title:       c.String("title"),
+		Description: c.String("description"),
+		Tags:        c.StringSlice("tag"),
+	}
+
+	err = commands.SetOverride(context.Background(), cfg, override)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func overrideList(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.ListOverrides(context.Background(), cfg, os.Stdout)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func overrideRemove(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.NewExitError("Please supply one hash as argument.", 1)
+	}
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
 
+	err = commands.RemoveOverride(context.Background(), cfg, c.Args().Get(0))
 	if err != nil {
 		return cli.NewExitError(err.Error(), 1)
 	}
@@ -0,0 +1,205 @@
+// Package metrics exports queue and index metrics to Prometheus
+package metrics
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs-search/ipfs-search/queue"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"time"
+)
+
+const namespace = "ipfs_search"
+
+// healthValues map Elasticsearch cluster health to a gauge value
+var healthValues = map[string]float64{
+	"green":  0,
+	"yellow": 1,
+	"red":    2,
+}
+
+var (
+	queueMessages = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "queue", "messages"),
+		"Messages ready for delivery in a queue.",
+		[]string{"queue"}, nil,
+	)
+	queueConsumers = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "queue", "consumers"),
+		"Consumers of a queue.",
+		[]string{"queue"}, nil,
+	)
+	queueRate = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "queue", "rate"),
+		"Change in messages per second in a queue over recent scrapes; negative when draining.",
+		[]string{"queue"}, nil,
+	)
+	queueETA = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "queue", "eta_seconds"),
+		"Estimated time until a queue is empty at its current rate; +Inf when not draining.",
+		[]string{"queue"}, nil,
+	)
+	documents = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "index", "documents"),
+		"Indexed documents by type.",
+		[]string{"type"}, nil,
+	)
+	indexSize = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "index", "size_bytes"),
+		"Size of an index on disk.",
+		[]string{"index"}, nil,
+	)
+	diskUsed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "disk", "used_bytes"),
+		"Disk space used on an Elasticsearch data node.",
+		[]string{"node"}, nil,
+	)
+	diskTotal = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "disk", "total_bytes"),
+		"Disk space of an Elasticsearch data node.",
+		[]string{"node"}, nil,
+	)
+	indexGrowth = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "index", "growth_bytes_per_second"),
+		"Growth of the index at the crawl throughput of the capacity window.",
+		nil, nil,
+	)
+	watermarkETA = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "disk", "watermark_eta_seconds"),
+		"Forecast time until the first node hits a disk watermark; 0 when hit, +Inf when not growing.",
+		[]string{"watermark"}, nil,
+	)
+	health = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "index", "health"),
+		"Elasticsearch cluster health; 0 is green, 1 yellow and 2 red.",
+		nil, nil,
+	)
+	scrapeError = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "error"),
+		"Whether collecting metrics from a component failed.",
+		[]string{"component"}, nil,
+	)
+)
+
+// Collector gathers queue and index metrics on every scrape, so values are
+// never stale and no collection happens without scrapers
+type Collector struct {
+	Connection *queue.Connection
+	Indexer    *indexer.Indexer
+	Queues     []string
+	Timeout    time.Duration    // Maximum time spent on each scrape
+	Estimator  *queue.Estimator // Estimates queue trends from depths over scrapes; optional
+	Window     time.Duration    // Crawl throughput disk watermarks are forecast by
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueMessages
+	ch <- queueConsumers
+	ch <- queueRate
+	ch <- queueETA
+	ch <- documents
+	ch <- indexSize
+	ch <- diskUsed
+	ch <- diskTotal
+	ch <- indexGrowth
+	ch <- watermarkETA
+	ch <- health
+	ch <- scrapeError
+}
+
+// reportError sets the scrape error gauge for a component
+func reportError(ch chan<- prometheus.Metric, component string, err error) {
+	value := 0.0
+	if err != nil {
+		log.WithError(err).WithField("component", component).Warn("Error collecting metrics")
+		value = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(scrapeError, prometheus.GaugeValue, value, component)
+}
+
+func (c *Collector) collectQueues(ch chan<- prometheus.Metric) error {
+	for _, name := range c.Queues {
+		state, err := c.Connection.Inspect(name)
+		if err != nil {
+			return err
+		}
+
+		ch <- prometheus.MustNewConstMetric(queueMessages, prometheus.GaugeValue, float64(state.Messages), name)
+		ch <- prometheus.MustNewConstMetric(queueConsumers, prometheus.GaugeValue, float64(state.Consumers), name)
+
+		if c.Estimator == nil {
+			continue
+		}
+
+		trend := c.Estimator.Add(name, time.Now(), state.Messages)
+		if trend == nil {
+			continue
+		}
+
+		eta := math.Inf(1)
+		if trend.Draining() {
+			eta = trend.ETA
+		}
+
+		ch <- prometheus.MustNewConstMetric(queueRate, prometheus.GaugeValue, trend.Rate, name)
+		ch <- prometheus.MustNewConstMetric(queueETA, prometheus.GaugeValue, eta, name)
+	}
+
+	return nil
+}
+
+func (c *Collector) collectIndex(ctx context.Context, ch chan<- prometheus.Metric) error {
+	status, err := c.Indexer.Health(ctx)
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(health, prometheus.GaugeValue, healthValues[status])
+
+	counts, err := c.Indexer.CountByType(ctx)
+	if err != nil {
+		return err
+	}
+	for doctype, count := range counts {
+		ch <- prometheus.MustNewConstMetric(documents, prometheus.GaugeValue, float64(count), doctype)
+	}
+
+	sizes, err := c.Indexer.IndexSizes(ctx)
+	if err != nil {
+		return err
+	}
+	for index, size := range sizes {
+		ch <- prometheus.MustNewConstMetric(indexSize, prometheus.GaugeValue, float64(size), index)
+	}
+
+	capacity, err := c.Indexer.Capacity(ctx, c.Window)
+	if err != nil {
+		return err
+	}
+	for _, d := range capacity.Disks {
+		ch <- prometheus.MustNewConstMetric(diskUsed, prometheus.GaugeValue, float64(d.Used), d.Node)
+		ch <- prometheus.MustNewConstMetric(diskTotal, prometheus.GaugeValue, float64(d.Total), d.Node)
+	}
+	ch <- prometheus.MustNewConstMetric(indexGrowth, prometheus.GaugeValue, capacity.Growth)
+	for _, w := range capacity.Watermarks {
+		eta := w.ETA
+		if eta < 0 {
+			eta = math.Inf(1)
+		}
+		ch <- prometheus.MustNewConstMetric(watermarkETA, prometheus.GaugeValue, eta, w.Name)
+	}
+
+	return nil
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	reportError(ch, "amqp", c.collectQueues(ch))
+	reportError(ch, "elasticsearch", c.collectIndex(ctx, ch))
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// rollupPeriod is the period covered by every stats rollup
+const rollupPeriod = time.Hour
+
+// rollupDelay allows documents to become searchable before rolling up
+const rollupDelay = time.Minute
+
+// RollupWriter periodically writes rollups of indexing statistics to the
+// stats index
+type RollupWriter struct {
+	Indexer *indexer.Indexer
+	Timeout time.Duration // Maximum time spent on each rollup
+}
+
+// rollup writes the rollups for the period starting at start
+func (w *RollupWriter) rollup(ctx context.Context, start time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+
+	rollups, err := w.Indexer.Rollups(ctx, start, rollupPeriod)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"start":   start,
+		"rollups": len(rollups),
+	}).Info("Writing stats rollups")
+
+	return w.Indexer.WriteRollups(ctx, rollups)
+}
+
+// Work writes rollups for every completed period until the context is
+// cancelled, starting with the last completed period
+func (w *RollupWriter) Work(ctx context.Context) {
+	for {
+		// Start of the last completed period
+		start := time.Now().Add(-rollupDelay).Truncate(rollupPeriod).Add(-rollupPeriod)
+
+		if err := w.rollup(ctx, start); err != nil {
+			log.WithError(err).WithField("start", start).Error("Error writing stats rollups")
+		}
+
+		next := start.Add(2 * rollupPeriod).Add(rollupDelay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
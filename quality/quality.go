@@ -0,0 +1,234 @@
+/*
+Package quality implements heuristic quality scoring of indexed items, used
+to demote spam and low-quality content.
+*/
+package quality
+
+import (
+	"math"
+	"path"
+	"strings"
+	"unicode"
+)
+
+const (
+	randomNameMinLength      = 16  // Names shorter than this are never considered random
+	randomNameMinEntropy     = 3.5 // Shannon entropy (bits/char) below which names are not random
+	randomNameMinTransitions = 0.4 // Fraction of character class changes above which names look random
+
+	stuffingMinWords    = 20  // Minimum amount of words in a field to detect stuffing
+	stuffingUniqueRatio = 0.3 // Fraction of unique words below which a field is stuffed
+
+	tinyContentLength = 32 // Textual content shorter than this is considered tiny
+
+	tinyDuplicateMaxSize       = 1024 // Items smaller than this (in bytes) are tiny
+	tinyDuplicateMinReferences = 10   // References above which tiny items are considered duplicated
+)
+
+// Penalties, multiplied into the score
+const (
+	randomNamePenalty       = 0.5
+	stuffingPenalty         = 0.3
+	tinyContentPenalty      = 0.7
+	randomReferencesPenalty = 0.7
+	tinyDuplicatePenalty    = 0.5
+)
+
+// Item contains the properties of an item relevant for scoring
+type Item struct {
+	Name           string                 // Name of the item, if any
+	ReferenceNames []string               // Names of the item in referring directories
+	Size           uint64                 // Size in bytes, 0 when unknown
+	Metadata       map[string]interface{} // Extracted metadata, for files
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	var e float64
+	for _, c := range counts {
+		p := float64(c) / float64(total)
+		e -= p * math.Log2(p)
+	}
+
+	return e
+}
+
+// charClass returns the class of a character: digit, lower, upper or other
+func charClass(r rune) int {
+	switch {
+	case unicode.IsDigit(r):
+		return 1
+	case unicode.IsLower(r):
+		return 2
+	case unicode.IsUpper(r):
+		return 3
+	}
+	return 0
+}
+
+// transitions returns the fraction of consecutive characters in s that
+// are of a different class, which is high for generated identifiers and
+// low for natural language
+func transitions(s string) float64 {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return 0
+	}
+
+	changes := 0
+	for n := 1; n < len(runes); n++ {
+		if charClass(runes[n]) != charClass(runes[n-1]) {
+			changes++
+		}
+	}
+
+	return float64(changes) / float64(len(runes)-1)
+}
+
+// looksRandom returns whether a name appears to be generated rather than
+// chosen; e.g. a hash rather than a title
+func looksRandom(name string) bool {
+	base := strings.TrimSuffix(name, path.Ext(name))
+
+	// Names with separators are composed of words
+	if len(base) < randomNameMinLength || strings.ContainsAny(base, " _-.") {
+		return false
+	}
+
+	return entropy(base) >= randomNameMinEntropy && transitions(base) >= randomNameMinTransitions
+}
+
+// isStuffed returns whether text consists mostly of repeated words
+func isStuffed(text string) bool {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) < stuffingMinWords {
+		return false
+	}
+
+	unique := make(map[string]bool)
+	for _, w := range words {
+		unique[w] = true
+	}
+
+	return float64(len(unique))/float64(len(words)) < stuffingUniqueRatio
+}
+
+// fieldText returns the textual value of a metadata field, which ipfs-tika
+// returns as a list of strings
+func fieldText(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []interface{}:
+		parts := make([]string, 0, len(t))
+		for _, p := range t {
+			if s, ok := p.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+
+	return ""
+}
+
+// hasStuffedMetadata returns whether any of the descriptive metadata fields is stuffed
+func hasStuffedMetadata(m map[string]interface{}) bool {
+	fields, ok := m["metadata"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, key := range []string{"title", "dc:title", "keywords", "meta:keyword", "description", "dc:description", "subject"} {
+		if isStuffed(fieldText(fields[key])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasTinyContent returns whether extracted textual content is present but tiny
+func hasTinyContent(m map[string]interface{}) bool {
+	content, ok := m["content"].(string)
+	if !ok {
+		return false
+	}
+
+	return len(strings.Join(strings.Fields(content), "")) < tinyContentLength
+}
+
+// hasRandomReferences returns whether most of multiple references have random names
+func hasRandomReferences(names []string) bool {
+	if len(names) < 2 {
+		return false
+	}
+
+	random := 0
+	for _, n := range names {
+		if looksRandom(n) {
+			random++
+		}
+	}
+
+	return random*2 > len(names)
+}
+
+// isTinyDuplicate returns whether a tiny item is referenced from many
+// directories. As identical content has the same hash, this is the same
+// tiny file copied all over, as spam tends to be.
+func isTinyDuplicate(size uint64, references int) bool {
+	return size > 0 && size < tinyDuplicateMaxSize && references >= tinyDuplicateMinReferences
+}
+
+// ContentScore returns the part of the quality score determined by
+// extracted metadata, which does not change until an item is recrawled
+func ContentScore(metadata map[string]interface{}) float64 {
+	score := 1.0
+
+	if metadata != nil {
+		if hasStuffedMetadata(metadata) {
+			score *= stuffingPenalty
+		}
+
+		if hasTinyContent(metadata) {
+			score *= tinyContentPenalty
+		}
+	}
+
+	return score
+}
+
+// Rescore returns the quality score of an item given its content score,
+// for when references change but extracted metadata is not at hand; the
+// item's Metadata is ignored
+func Rescore(i *Item, content float64) float64 {
+	score := content
+
+	if i.Name != "" && looksRandom(i.Name) {
+		score *= randomNamePenalty
+	}
+
+	if hasRandomReferences(i.ReferenceNames) {
+		score *= randomReferencesPenalty
+	}
+
+	if isTinyDuplicate(i.Size, len(i.ReferenceNames)) {
+		score *= tinyDuplicatePenalty
+	}
+
+	return score
+}
+
+// Score returns a quality score between 0 (most likely spam) and 1 (no
+// indication of low quality)
+func Score(i *Item) float64 {
+	return Rescore(i, ContentScore(i.Metadata))
+}
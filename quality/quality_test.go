@@ -0,0 +1,144 @@
+package quality
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLooksRandom(t *testing.T) {
+	tests := []struct {
+		name   string
+		random bool
+	}{
+		{"QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX", true},
+		{"a8Fk2LqZ9xW3mB7pR4tY.jpg", true},
+		{"holiday.jpg", false},
+		{"Introduction to IPFS.pdf", false},
+		{"my_favourite_song_of_all_time.mp3", false},
+		{"aaaaaaaaaaaaaaaaaaaaaaaa", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if random := looksRandom(test.name); random != test.random {
+			t.Errorf("looksRandom(%q) = %v, want %v", test.name, random, test.random)
+		}
+	}
+}
+
+func TestIsStuffed(t *testing.T) {
+	tests := []struct {
+		text    string
+		stuffed bool
+	}{
+		{strings.Repeat("free download ", 20), true},
+		{strings.Repeat("free ", 10), false},
+		{"The InterPlanetary File System is a protocol and peer-to-peer network for storing and sharing data in a distributed file system.", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if stuffed := isStuffed(test.text); stuffed != test.stuffed {
+			t.Errorf("isStuffed(%q) = %v, want %v", test.text, stuffed, test.stuffed)
+		}
+	}
+}
+
+func TestHasTinyContent(t *testing.T) {
+	tests := []struct {
+		metadata map[string]interface{}
+		tiny     bool
+	}{
+		{map[string]interface{}{"content": "hi"}, true},
+		{map[string]interface{}{"content": "  a  b  c  "}, true},
+		{map[string]interface{}{"content": strings.Repeat("word ", 10)}, false},
+		{map[string]interface{}{}, false},
+	}
+
+	for _, test := range tests {
+		if tiny := hasTinyContent(test.metadata); tiny != test.tiny {
+			t.Errorf("hasTinyContent(%v) = %v, want %v", test.metadata, tiny, test.tiny)
+		}
+	}
+}
+
+func TestIsTinyDuplicate(t *testing.T) {
+	tests := []struct {
+		size       uint64
+		references int
+		duplicate  bool
+	}{
+		{100, tinyDuplicateMinReferences, true},
+		{100, tinyDuplicateMinReferences - 1, false},
+		{tinyDuplicateMaxSize, tinyDuplicateMinReferences, false},
+		{0, tinyDuplicateMinReferences, false},
+	}
+
+	for _, test := range tests {
+		if duplicate := isTinyDuplicate(test.size, test.references); duplicate != test.duplicate {
+			t.Errorf("isTinyDuplicate(%d, %d) = %v, want %v", test.size, test.references, duplicate, test.duplicate)
+		}
+	}
+}
+
+// names returns n distinct names
+func names(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = strings.Repeat("x", i+1) + ".txt"
+	}
+	return names
+}
+
+func TestScore(t *testing.T) {
+	stuffed := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"keywords": []interface{}{strings.Repeat("cheap ", 30)},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		item  *Item
+		score float64
+	}{
+		{"clean", &Item{Name: "holiday.jpg", Size: 1 << 20}, 1},
+		{"random name", &Item{Name: "QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX"}, randomNamePenalty},
+		{"stuffed", &Item{Metadata: stuffed}, stuffingPenalty},
+		{"tiny content", &Item{Metadata: map[string]interface{}{"content": "hi"}}, tinyContentPenalty},
+		{"tiny duplicate", &Item{Size: 10, ReferenceNames: names(tinyDuplicateMinReferences)}, tinyDuplicatePenalty},
+		{
+			"random references",
+			&Item{ReferenceNames: []string{"QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX", "a8Fk2LqZ9xW3mB7pR4tY"}},
+			randomReferencesPenalty,
+		},
+		{
+			"combined",
+			&Item{Name: "QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX", Metadata: stuffed},
+			randomNamePenalty * stuffingPenalty,
+		},
+	}
+
+	for _, test := range tests {
+		if score := Score(test.item); math.Abs(score-test.score) > 1e-9 {
+			t.Errorf("%s: Score() = %v, want %v", test.name, score, test.score)
+		}
+	}
+}
+
+func TestRescore(t *testing.T) {
+	item := &Item{Size: 10, ReferenceNames: names(1)}
+	content := ContentScore(map[string]interface{}{"content": "hi"})
+
+	if score := Rescore(item, content); score != tinyContentPenalty {
+		t.Errorf("Rescore() = %v, want %v", score, tinyContentPenalty)
+	}
+
+	// More references make a tiny item a duplicate, keeping the content score
+	item.ReferenceNames = names(tinyDuplicateMinReferences)
+	want := tinyContentPenalty * tinyDuplicatePenalty
+	if score := Rescore(item, content); math.Abs(score-want) > 1e-9 {
+		t.Errorf("Rescore() = %v, want %v", score, want)
+	}
+}
@@ -0,0 +1,50 @@
+package queue
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// dedupHeader carries the message hash of tasks, by which the RabbitMQ
+	// message deduplication plugin drops duplicates on queues or exchanges
+	// it is enabled for
+	dedupHeader = "x-deduplication-header"
+
+	// natsMsgIDHeader identifies messages to JetStream, which drops those
+	// with the ID of one published within the duplicate window of the
+	// stream, 2 minutes by default
+	natsMsgIDHeader = "Nats-Msg-Id"
+)
+
+// seenKey returns the key of the message hash of a task in the seen-set
+func (q *Queue) seenKey(task *Task) string {
+	return "task/" + q.Name + "/" + task.Hash
+}
+
+// duplicate returns whether a task with the message hash of task was
+// recently published on the queue, according to the seen-set. Errors are
+// logged, and the task published.
+func (q *Queue) duplicate(task *Task) bool {
+	if task.Hash == "" || q.seen == nil {
+		return false
+	}
+
+	seen, err := q.seen.Contains(q.seenKey(task))
+	if err != nil {
+		log.WithError(err).WithField("queue", q.Name).Warn("Error reading published tasks")
+		return false
+	}
+
+	return seen
+}
+
+// markPublished adds the message hash of a published task to the seen-set
+func (q *Queue) markPublished(task *Task) {
+	if task.Hash == "" || q.seen == nil {
+		return
+	}
+
+	if err := q.seen.Add(q.seenKey(task)); err != nil {
+		log.WithError(err).WithField("queue", q.Name).Warn("Error recording published task")
+	}
+}
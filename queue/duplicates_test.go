@@ -0,0 +1,32 @@
+package queue
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/dedup"
+	"testing"
+	"time"
+)
+
+func TestDuplicateTasks(t *testing.T) {
+	conn, _ := NewConnection(MemoryURL(10))
+	conn.SetSeen(dedup.New(&dedup.Config{Size: 10, TTL: time.Hour}))
+
+	q, err := conn.NewChannelQueue("test-" + newID())
+	if err != nil {
+		t.Fatalf("NewChannelQueue() error %v", err)
+	}
+
+	for _, hash := range []string{"a", "a", "b", "", ""} {
+		task, _ := NewTask(map[string]string{}, 0, "")
+		task.Hash = hash
+
+		if err := q.PublishTask(context.Background(), task); err != nil {
+			t.Fatalf("PublishTask() error %v", err)
+		}
+	}
+
+	// Tasks without message hash are never duplicates
+	if depth, _ := q.Depth(); depth != 4 {
+		t.Errorf("Depth() = %d, want 4", depth)
+	}
+}
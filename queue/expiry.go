@@ -0,0 +1,32 @@
+package queue
+
+import (
+	"time"
+)
+
+// Expiry determines when tasks expire and what becomes of them, so tasks
+// from a stale backlog are not performed long after they were relevant.
+// Tasks also expire past their own deadline.
+type Expiry struct {
+	TTLs       map[string]time.Duration // Maximum age of tasks by queue name, since first published
+	DeadLetter bool                     // Move expired tasks to the dead letter queue, rather than dropping them
+}
+
+// defaultMessageTTL is how long brokers keep messages on queues without a
+// maximum age of tasks
+const defaultMessageTTL = 24 * time.Hour
+
+// ttl returns the maximum age of tasks on a queue, 0 for none
+func (e *Expiry) ttl(queue string) time.Duration {
+	return e.TTLs[queue]
+}
+
+// messageTTL returns how long brokers keep messages on a queue before
+// expiring them: the maximum age of its tasks, or else a day
+func (e *Expiry) messageTTL(queue string) time.Duration {
+	if ttl := e.ttl(queue); ttl > 0 {
+		return ttl
+	}
+
+	return defaultMessageTTL
+}
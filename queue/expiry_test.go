@@ -0,0 +1,33 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueArgsMessageTTL(t *testing.T) {
+	conn := &Connection{}
+	conn.SetExpiry(Expiry{TTLs: map[string]time.Duration{
+		"hashes":      168 * time.Hour,
+		"directories": 30 * 24 * time.Hour,
+	}})
+
+	tests := []struct {
+		queue string
+		want  interface{}
+	}{
+		{"hashes", 604800000},              // Over 24 hours
+		{"files", 86400000},                // Default of 24 hours
+		{"directories", int64(2592000000)}, // Too long for 32 bits
+	}
+
+	for _, test := range tests {
+		args := queueArgs(test.queue, conn.messageTTL(test.queue))
+		if got := args["x-message-ttl"]; got != test.want {
+			t.Errorf("x-message-ttl of %s = %#v, want %#v", test.queue, got, test.want)
+		}
+		if got := args["x-dead-letter-routing-key"]; got != test.queue+"-dead" {
+			t.Errorf("x-dead-letter-routing-key of %s = %v", test.queue, got)
+		}
+	}
+}
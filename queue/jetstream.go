@@ -0,0 +1,403 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// natsTimeout limits connecting and requests to the JetStream API
+	natsTimeout = 10 * time.Second
+
+	// jsPrefix namespaces stream names and subjects on a shared server
+	jsPrefix = "ipfs-search"
+
+	// jsDurable is the durable consumer all crawlers pull messages from
+	jsDurable = "crawlers"
+
+	// Headers carrying message properties; other headers are those of
+	// the AMQP message
+	priorityHeader      = "Priority"
+	correlationIDHeader = "Correlation-Id"
+)
+
+// isNATS returns whether a broker URL refers to NATS; tls:// URLs require
+// TLS, which nats:// URLs use when the server does
+func isNATS(url string) bool {
+	return strings.HasPrefix(url, "nats://") || strings.HasPrefix(url, "tls://")
+}
+
+// jetStream is a connection to a NATS server with JetStream. The client
+// reconnects on its own when the connection is lost, buffering messages
+// published meanwhile.
+type jetStream struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+// newJetStream connects to a NATS server, checking JetStream is enabled.
+// Credentials are taken from the URL, as user and password or token.
+func newJetStream(url string) (*jetStream, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("ipfs-search"),
+		nats.Timeout(natsTimeout),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			log.WithError(err).Warn("NATS connection lost, reconnecting")
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			log.Info("NATS connection re-established")
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsTimeout)
+	defer cancel()
+
+	if _, err := js.AccountInfo(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("JetStream not available: %v", err)
+	}
+
+	return &jetStream{conn: conn, js: js}, nil
+}
+
+// ensureStream creates a stream with retention policy, on the subject of
+// its name, unless it exists; maxAge of 0 keeps messages indefinitely
+func (js *jetStream) ensureStream(ctx context.Context, name string, retention jetstream.RetentionPolicy, maxAge time.Duration) error {
+	_, err := js.js.Stream(ctx, name)
+	if !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return err
+	}
+
+	_, err = js.js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:      name,
+		Subjects:  []string{name},
+		Retention: retention,
+		Storage:   jetstream.FileStorage,
+		MaxAge:    maxAge,
+	})
+
+	return err
+}
+
+// close closes the connection; it is not re-established
+func (js *jetStream) close() error {
+	js.conn.Close()
+	return nil
+}
+
+// jsStreamName returns the name of the stream of a queue, which is its
+// subject as well
+func jsStreamName(queue string) string {
+	return jsPrefix + "-" + queue
+}
+
+// jsHeader returns the header of a message for publishing, with its
+// priority and correlation ID. The message hash identifies it to
+// JetStream, which drops duplicates.
+func jsHeader(priority uint8, correlationID string, headers amqp.Table) nats.Header {
+	header := make(nats.Header, len(headers)+2)
+	for name, value := range headers {
+		header.Set(name, fmt.Sprint(value))
+	}
+	header.Set(priorityHeader, strconv.Itoa(int(priority)))
+	header.Set(correlationIDHeader, correlationID)
+	if hash := header.Get(dedupHeader); hash != "" {
+		header.Set(natsMsgIDHeader, hash)
+		header.Del(dedupHeader)
+	}
+
+	return header
+}
+
+// jsQueue is a queue on a JetStream stream with work queue retention,
+// consumed by a durable pull consumer. Messages are deleted when
+// acknowledged.
+type jsQueue struct {
+	js       *jetStream
+	name     string
+	consumer jetstream.Consumer
+
+	mu   sync.Mutex
+	stop chan struct{} // Closed to stop consuming; nil when not consuming
+}
+
+// newJSQueue returns a named queue, creating its stream, dead letter
+// stream and consumer when they do not exist. Messages on a stream created
+// expire after maxAge, like the message TTL of AMQP queues.
+func newJSQueue(js *jetStream, name string, maxAge time.Duration) (*jsQueue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), natsTimeout)
+	defer cancel()
+
+	q := &jsQueue{js: js, name: name}
+
+	if err := js.ensureStream(ctx, q.stream(), jetstream.WorkQueuePolicy, maxAge); err != nil {
+		return nil, err
+	}
+	if err := js.ensureStream(ctx, q.deadStream(), jetstream.LimitsPolicy, 0); err != nil {
+		return nil, err
+	}
+
+	consumer, err := js.js.Consumer(ctx, q.stream(), jsDurable)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		// One more delivery than attempts, in which the message is dead lettered
+		consumer, err = js.js.CreateConsumer(ctx, q.stream(), jetstream.ConsumerConfig{
+			Durable:       jsDurable,
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			AckWait:       claimAfter,
+			MaxDeliver:    MaxAttempts + 1,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	q.consumer = consumer
+	return q, nil
+}
+
+// stream returns the name of the stream
+func (q *jsQueue) stream() string {
+	return jsStreamName(q.name)
+}
+
+// deadStream returns the name of the dead letter stream
+func (q *jsQueue) deadStream() string {
+	return jsStreamName(q.name + "-dead")
+}
+
+// send publishes a message to a stream, waiting for it to be stored
+func (q *jsQueue) send(stream string, header nats.Header, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), natsTimeout)
+	defer cancel()
+
+	_, err := q.js.js.PublishMsg(ctx, &nats.Msg{
+		Subject: stream,
+		Header:  header,
+		Data:    data,
+	})
+
+	return err
+}
+
+// publish publishes a message to the stream
+func (q *jsQueue) publish(body []byte, priority uint8, correlationID string, headers amqp.Table) error {
+	return q.send(q.stream(), jsHeader(priority, correlationID, headers), body)
+}
+
+// deadLetter moves a message to the dead letter stream, terminating its
+// delivery
+func (q *jsQueue) deadLetter(msg jetstream.Msg) error {
+	header := make(nats.Header, len(msg.Headers()))
+	for name, values := range msg.Headers() {
+		header[name] = values
+	}
+
+	// Dead letters of duplicates are kept
+	header.Del(natsMsgIDHeader)
+
+	if err := q.send(q.deadStream(), header, msg.Data()); err != nil {
+		return err
+	}
+
+	return msg.Term()
+}
+
+// jsAcknowledger acknowledges a message from JetStream, like the broker
+// does for AMQP deliveries. Messages rejected without requeueing go to the
+// dead letter stream; requeued messages are delivered again.
+type jsAcknowledger struct {
+	queue *jsQueue
+	msg   jetstream.Msg
+}
+
+// Ack removes the message from the stream
+func (a *jsAcknowledger) Ack(tag uint64, multiple bool) error {
+	return a.msg.Ack()
+}
+
+// Nack requeues the message or moves it to the dead letter stream
+func (a *jsAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	if requeue {
+		return a.msg.Nak()
+	}
+
+	return a.queue.deadLetter(a.msg)
+}
+
+// Reject requeues the message or moves it to the dead letter stream
+func (a *jsAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+// deliveries returns how often a message has been delivered
+func deliveries(msg jetstream.Msg) int {
+	metadata, err := msg.Metadata()
+	if err != nil {
+		return 0
+	}
+
+	return int(metadata.NumDelivered)
+}
+
+// delivery returns a JetStream message as AMQP delivery, so it is handled
+// like messages from AMQP brokers
+func (q *jsQueue) delivery(msg jetstream.Msg) amqp.Delivery {
+	header := msg.Headers()
+	priority, _ := strconv.ParseUint(header.Get(priorityHeader), 10, 8)
+
+	headers := amqp.Table{}
+	for name := range header {
+		if name != priorityHeader && name != correlationIDHeader {
+			headers[name] = header.Get(name)
+		}
+	}
+
+	return amqp.Delivery{
+		Acknowledger:  &jsAcknowledger{q, msg},
+		Headers:       headers,
+		ContentType:   "application/json",
+		CorrelationId: header.Get(correlationIDHeader),
+		MessageId:     msg.Reply(),
+		Priority:      uint8(priority),
+		Redelivered:   deliveries(msg) > 1,
+		RoutingKey:    q.name,
+		Body:          msg.Data(),
+	}
+}
+
+// next pulls the next message from the consumer, waiting up to blockTime
+// for one. Messages which have been delivered MaxAttempts times, which
+// likely crash their consumers, are moved to the dead letter stream.
+func (q *jsQueue) next() ([]amqp.Delivery, error) {
+	msg, err := q.consumer.Next(jetstream.FetchMaxWait(blockTime))
+	if errors.Is(err, nats.ErrTimeout) {
+		// No message arrived in time
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if n := deliveries(msg); n > MaxAttempts {
+		log.WithField("queue", q.name).Warnf("Giving up after %d deliveries of %s", n-1, msg.Reply())
+		return nil, q.deadLetter(msg)
+	}
+
+	return []amqp.Delivery{q.delivery(msg)}, nil
+}
+
+// consume delivers messages from the stream until close is called or
+// pulling fails, after which the returned channel is closed. Messages
+// pulled but not yet taken on close are requeued.
+func (q *jsQueue) consume() <-chan amqp.Delivery {
+	stop := make(chan struct{})
+
+	q.mu.Lock()
+	q.stop = stop
+	q.mu.Unlock()
+
+	messages := make(chan amqp.Delivery)
+
+	go func() {
+		defer close(messages)
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			next, err := q.next()
+			if err != nil {
+				log.WithField("queue", q.name).WithError(err).Warn("Error pulling from stream")
+				return
+			}
+
+			for n, msg := range next {
+				select {
+				case messages <- msg:
+				case <-stop:
+					for _, m := range next[n:] {
+						m.Nack(false, true)
+					}
+					return
+				}
+			}
+		}
+	}()
+
+	return messages
+}
+
+// close stops consuming
+func (q *jsQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.stop != nil {
+		close(q.stop)
+		q.stop = nil
+	}
+}
+
+// info returns the state of the consumer
+func (q *jsQueue) info() (*jetstream.ConsumerInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), natsTimeout)
+	defer cancel()
+
+	return q.consumer.Info(ctx)
+}
+
+// depth returns the amount of messages ready for delivery
+func (q *jsQueue) depth() (int, error) {
+	info, err := q.info()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(info.NumPending), nil
+}
+
+// inspectJetStream returns the state of a named queue; consumers are those
+// waiting for messages
+func inspectJetStream(js *jetStream, name string) (*State, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), natsTimeout)
+	defer cancel()
+
+	consumer, err := js.js.Consumer(ctx, jsStreamName(name), jsDurable)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &State{
+		Name:      name,
+		Messages:  int(info.NumPending),
+		Consumers: info.NumWaiting,
+	}, nil
+}
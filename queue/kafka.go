@@ -0,0 +1,470 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// kafkaPrefix namespaces topic names and consumer groups on a shared
+	// cluster
+	kafkaPrefix = "ipfs-search"
+
+	// kafkaPartitions is the default amount of partitions of topics created,
+	// which limits the consumers of a queue sharing its messages
+	kafkaPartitions = 16
+
+	// kafkaReplication is the default replication factor of topics created
+	kafkaReplication = 1
+
+	// kafkaTimeout limits requests to the cluster, including writes
+	kafkaTimeout = 10 * time.Second
+
+	// partitionHeader carries the partition key of tasks, which brokers
+	// partitioning queues take as the key of the message
+	partitionHeader = "x-partition-key"
+
+	// redeliveredHeader marks messages requeued after being delivered
+	redeliveredHeader = "x-redelivered"
+)
+
+// isKafka returns whether a broker URL refers to Kafka
+func isKafka(url string) bool {
+	return strings.HasPrefix(url, "kafka://")
+}
+
+// kafkaCluster is a Kafka cluster on which queues are topics
+type kafkaCluster struct {
+	brokers     []string
+	partitions  int
+	replication int
+	client      *kafka.Client
+}
+
+// parseKafkaURL returns the cluster at a kafka:// URL, listing brokers
+// separated by commas, with the partitions and replication factor of
+// topics created, e.g. kafka://a:9092,b:9092/?partitions=32&replication=3
+func parseKafkaURL(rawurl string) (*kafkaCluster, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &kafkaCluster{
+		partitions:  kafkaPartitions,
+		replication: kafkaReplication,
+	}
+
+	for _, broker := range strings.Split(u.Host, ",") {
+		if broker != "" {
+			k.brokers = append(k.brokers, broker)
+		}
+	}
+	if len(k.brokers) == 0 {
+		return nil, fmt.Errorf("no Kafka brokers in %s", rawurl)
+	}
+
+	for name, value := range map[string]*int{"partitions": &k.partitions, "replication": &k.replication} {
+		s := u.Query().Get(name)
+		if s == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid Kafka %s: %s", name, s)
+		}
+		*value = n
+	}
+
+	k.client = &kafka.Client{
+		Addr:    kafka.TCP(k.brokers...),
+		Timeout: kafkaTimeout,
+	}
+
+	return k, nil
+}
+
+// newKafka connects to the Kafka cluster at a kafka:// URL, checking it
+// can be reached
+func newKafka(rawurl string) (*kafkaCluster, error) {
+	k, err := parseKafkaURL(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+
+	if _, err := k.client.Metadata(ctx, &kafka.MetadataRequest{}); err != nil {
+		return nil, fmt.Errorf("Kafka not available: %v", err)
+	}
+
+	return k, nil
+}
+
+// ensureTopic creates a topic unless it exists
+func (k *kafkaCluster) ensureTopic(ctx context.Context, topic string) error {
+	resp, err := k.client.CreateTopics(ctx, &kafka.CreateTopicsRequest{
+		Topics: []kafka.TopicConfig{{
+			Topic:             topic,
+			NumPartitions:     k.partitions,
+			ReplicationFactor: k.replication,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := resp.Errors[topic]; err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+		return err
+	}
+
+	return nil
+}
+
+// kafkaTopic returns the topic of a queue
+func kafkaTopic(queue string) string {
+	return kafkaPrefix + "-" + queue
+}
+
+// kafkaGroup returns the consumer group consuming a queue
+func kafkaGroup(queue string) string {
+	return kafkaTopic(queue) + "-crawlers"
+}
+
+// kafkaHeaders returns the headers of a message for publishing, with its
+// priority and correlation ID, and the partition key taken out
+func kafkaHeaders(priority uint8, correlationID string, headers amqp.Table) ([]kafka.Header, []byte) {
+	var key []byte
+
+	h := make([]kafka.Header, 0, len(headers)+2)
+	for name, value := range headers {
+		if name == partitionHeader {
+			key = []byte(fmt.Sprint(value))
+			continue
+		}
+		h = append(h, kafka.Header{Key: name, Value: []byte(fmt.Sprint(value))})
+	}
+	h = append(h,
+		kafka.Header{Key: priorityHeader, Value: []byte(strconv.Itoa(int(priority)))},
+		kafka.Header{Key: correlationIDHeader, Value: []byte(correlationID)},
+	)
+
+	return h, key
+}
+
+// kafkaQueue is a queue on a Kafka topic with a consumer group, of which
+// each consumer reads its partitions in order. Messages are kept until the
+// retention of the topic expires; acknowledging them commits their offset,
+// so the history of the queue can be replayed by resetting the offsets.
+type kafkaQueue struct {
+	cluster *kafkaCluster
+	name    string
+	writer  *kafka.Writer
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // Stops consuming; nil when not consuming
+}
+
+// newKafkaWriter returns a writer of messages to topic, partitioned by the
+// hash of their key; messages without key are spread over partitions
+func newKafkaWriter(k *kafkaCluster, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(k.brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond, // Publishing waits for writes
+		WriteTimeout: kafkaTimeout,
+	}
+}
+
+// newKafkaQueue returns a named queue, creating its topic and dead letter
+// topic when they do not exist
+func newKafkaQueue(k *kafkaCluster, name string) (*kafkaQueue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+
+	for _, topic := range []string{kafkaTopic(name), kafkaTopic(name + "-dead")} {
+		if err := k.ensureTopic(ctx, topic); err != nil {
+			return nil, err
+		}
+	}
+
+	return &kafkaQueue{
+		cluster: k,
+		name:    name,
+		writer:  newKafkaWriter(k, kafkaTopic(name)),
+	}, nil
+}
+
+// write writes messages through writer, waiting for all replicas
+func (q *kafkaQueue) write(writer *kafka.Writer, msgs ...kafka.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+
+	return writer.WriteMessages(ctx, msgs...)
+}
+
+// publish publishes a message to the topic, keyed by its partition key
+func (q *kafkaQueue) publish(body []byte, priority uint8, correlationID string, headers amqp.Table) error {
+	h, key := kafkaHeaders(priority, correlationID, headers)
+
+	return q.write(q.writer, kafka.Message{
+		Key:     key,
+		Value:   body,
+		Headers: h,
+	})
+}
+
+// kafkaAcknowledger acknowledges a message from Kafka, like the broker
+// does for AMQP deliveries, by committing its offset. As offsets are
+// committed in order, requeued messages are published to the topic again
+// and rejected ones to the dead letter topic before committing them.
+type kafkaAcknowledger struct {
+	queue  *kafkaQueue
+	reader *kafka.Reader
+	msg    kafka.Message
+}
+
+// commit commits the offset of the message
+func (a *kafkaAcknowledger) commit() error {
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+
+	return a.reader.CommitMessages(ctx, a.msg)
+}
+
+// Ack commits the offset of the message
+func (a *kafkaAcknowledger) Ack(tag uint64, multiple bool) error {
+	return a.commit()
+}
+
+// Nack requeues the message or moves it to the dead letter topic
+func (a *kafkaAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	msg := kafka.Message{
+		Key:     a.msg.Key,
+		Value:   a.msg.Value,
+		Headers: a.msg.Headers,
+	}
+
+	writer := a.queue.writer
+	if requeue {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: redeliveredHeader, Value: []byte("true")})
+	} else {
+		writer = newKafkaWriter(a.queue.cluster, kafkaTopic(a.queue.name+"-dead"))
+		defer writer.Close()
+	}
+
+	if err := a.queue.write(writer, msg); err != nil {
+		return err
+	}
+
+	return a.commit()
+}
+
+// Reject requeues the message or moves it to the dead letter topic
+func (a *kafkaAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+// delivery returns a Kafka message as AMQP delivery, so it is handled like
+// messages from AMQP brokers
+func (q *kafkaQueue) delivery(reader *kafka.Reader, msg kafka.Message) amqp.Delivery {
+	d := amqp.Delivery{
+		Acknowledger: &kafkaAcknowledger{q, reader, msg},
+		Headers:      amqp.Table{},
+		ContentType:  "application/json",
+		MessageId:    fmt.Sprintf("%d-%d", msg.Partition, msg.Offset),
+		RoutingKey:   q.name,
+		Body:         msg.Value,
+	}
+
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case priorityHeader:
+			priority, _ := strconv.ParseUint(string(h.Value), 10, 8)
+			d.Priority = uint8(priority)
+		case correlationIDHeader:
+			d.CorrelationId = string(h.Value)
+		case redeliveredHeader:
+			d.Redelivered = true
+		default:
+			d.Headers[h.Key] = string(h.Value)
+		}
+	}
+
+	return d
+}
+
+// consume delivers messages from the topic until close is called or
+// reading fails, after which the returned channel is closed. Each call
+// joins the consumer group with a reader of its own, which is assigned
+// some of the partitions. A message read but not yet taken on close is not
+// committed, so it is delivered again.
+func (q *kafkaQueue) consume() <-chan amqp.Delivery {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	q.mu.Lock()
+	q.cancel = cancel
+	q.mu.Unlock()
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     q.cluster.brokers,
+		GroupID:     kafkaGroup(q.name),
+		Topic:       kafkaTopic(q.name),
+		MaxWait:     blockTime,
+		StartOffset: kafka.FirstOffset,
+	})
+
+	messages := make(chan amqp.Delivery)
+
+	go func() {
+		defer close(messages)
+		defer reader.Close()
+
+		for {
+			msg, err := reader.FetchMessage(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.WithField("queue", q.name).WithError(err).Warn("Error reading topic")
+				}
+				return
+			}
+
+			select {
+			case messages <- q.delivery(reader, msg):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return messages
+}
+
+// close stops consuming
+func (q *kafkaQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.cancel != nil {
+		q.cancel()
+		q.cancel = nil
+	}
+}
+
+// lag returns the amount of messages on the topic of a queue which the
+// consumer group has not committed
+func (k *kafkaCluster) lag(ctx context.Context, queue string) (int, error) {
+	topic := kafkaTopic(queue)
+
+	metadata, err := k.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return 0, err
+	}
+	if len(metadata.Topics) != 1 || metadata.Topics[0].Error != nil {
+		return 0, fmt.Errorf("no topic %s", topic)
+	}
+
+	partitions := make([]int, len(metadata.Topics[0].Partitions))
+	requests := make([]kafka.OffsetRequest, len(partitions))
+	for n, p := range metadata.Topics[0].Partitions {
+		partitions[n] = p.ID
+		requests[n] = kafka.LastOffsetOf(p.ID)
+	}
+
+	committed, err := k.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: kafkaGroup(queue),
+		Topics:  map[string][]int{topic: partitions},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	offsets, err := k.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	next := make(map[int]int64, len(partitions))
+	for _, p := range committed.Topics[topic] {
+		next[p.Partition] = p.CommittedOffset
+	}
+
+	lag := 0
+	for _, p := range offsets.Topics[topic] {
+		if p.Error != nil {
+			return 0, p.Error
+		}
+
+		// Partitions without commits are read from their first offset
+		from, ok := next[p.Partition]
+		if !ok || from < 0 {
+			from = p.FirstOffset
+		}
+		lag += int(p.LastOffset - from)
+	}
+
+	return lag, nil
+}
+
+// consumers returns the amount of members of the consumer group of a queue
+func (k *kafkaCluster) consumers(ctx context.Context, queue string) (int, error) {
+	resp, err := k.client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{
+		GroupIDs: []string{kafkaGroup(queue)},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Groups) != 1 {
+		return 0, nil
+	}
+	if err := resp.Groups[0].Error; err != nil {
+		return 0, err
+	}
+
+	return len(resp.Groups[0].Members), nil
+}
+
+// depth returns the amount of messages not yet consumed
+func (q *kafkaQueue) depth() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+
+	return q.cluster.lag(ctx, q.name)
+}
+
+// inspectKafka returns the state of a named queue
+func inspectKafka(k *kafkaCluster, name string) (*State, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+
+	messages, err := k.lag(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	consumers, err := k.consumers(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &State{
+		Name:      name,
+		Messages:  messages,
+		Consumers: consumers,
+	}, nil
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"github.com/segmentio/kafka-go"
+	"github.com/streadway/amqp"
+	"reflect"
+	"testing"
+)
+
+func TestParseKafkaURL(t *testing.T) {
+	k, err := parseKafkaURL("kafka://a:9092,b:9092/?partitions=32&replication=3")
+	if err != nil {
+		t.Fatalf("parseKafkaURL() error %v", err)
+	}
+
+	if !reflect.DeepEqual(k.brokers, []string{"a:9092", "b:9092"}) || k.partitions != 32 || k.replication != 3 {
+		t.Errorf("parseKafkaURL() = %+v", k)
+	}
+
+	k, err = parseKafkaURL("kafka://localhost:9092")
+	if err != nil {
+		t.Fatalf("parseKafkaURL() error %v", err)
+	}
+	if k.partitions != kafkaPartitions || k.replication != kafkaReplication {
+		t.Errorf("parseKafkaURL() defaults = %d partitions, replication %d", k.partitions, k.replication)
+	}
+
+	for _, invalid := range []string{"kafka://", "kafka://a:9092/?partitions=0", "kafka://a:9092/?replication=x"} {
+		if _, err := parseKafkaURL(invalid); err == nil {
+			t.Errorf("parseKafkaURL(%q) returned no error", invalid)
+		}
+	}
+}
+
+func TestKafkaHeaders(t *testing.T) {
+	headers, key := kafkaHeaders(9, "abc", amqp.Table{
+		partitionHeader: "1220abcd",
+		dedupHeader:     "hash",
+	})
+
+	if string(key) != "1220abcd" {
+		t.Errorf("kafkaHeaders() key = %q, want partition key", key)
+	}
+
+	got := map[string]string{}
+	for _, h := range headers {
+		got[h.Key] = string(h.Value)
+	}
+
+	want := map[string]string{
+		dedupHeader:         "hash",
+		priorityHeader:      "9",
+		correlationIDHeader: "abc",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("kafkaHeaders() = %v, want %v", got, want)
+	}
+}
+
+func TestKafkaDelivery(t *testing.T) {
+	q := &kafkaQueue{name: "hashes"}
+
+	d := q.delivery(nil, kafka.Message{
+		Partition: 3,
+		Offset:    42,
+		Value:     []byte(`{"version":1}`),
+		Headers: []kafka.Header{
+			{Key: priorityHeader, Value: []byte("9")},
+			{Key: correlationIDHeader, Value: []byte("abc")},
+			{Key: redeliveredHeader, Value: []byte("true")},
+			{Key: "traceparent", Value: []byte("00-1-2-01")},
+		},
+	})
+
+	if string(d.Body) != `{"version":1}` || d.Priority != 9 || d.CorrelationId != "abc" || d.RoutingKey != "hashes" || !d.Redelivered {
+		t.Errorf("delivery() = %+v", d)
+	}
+	if d.MessageId != "3-42" {
+		t.Errorf("delivery() message ID = %q, want 3-42", d.MessageId)
+	}
+	if !reflect.DeepEqual(d.Headers, amqp.Table{"traceparent": "00-1-2-01"}) {
+		t.Errorf("delivery() headers = %v", d.Headers)
+	}
+	if d.Acknowledger == nil {
+		t.Error("delivery() has no acknowledger")
+	}
+}
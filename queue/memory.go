@@ -0,0 +1,406 @@
+package queue
+
+import (
+	"container/heap"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// memoryCapacity is the default amount of messages in-memory queues
+	// hold ready for delivery
+	memoryCapacity = 10000
+
+	// memoryWait is the time publishing waits for room in a full queue
+	memoryWait = 10 * time.Second
+)
+
+// isMemory returns whether a broker URL refers to in-memory queues
+func isMemory(url string) bool {
+	return strings.HasPrefix(url, "memory://")
+}
+
+// MemoryURL returns the broker URL of in-memory queues holding up to size
+// messages each
+func MemoryURL(size int) string {
+	return fmt.Sprintf("memory://?size=%d", size)
+}
+
+// memoryCapacityOf returns the capacity of queues given in a memory:// URL
+func memoryCapacityOf(rawurl string) (int, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return 0, err
+	}
+
+	size := u.Query().Get("size")
+	if size == "" {
+		return memoryCapacity, nil
+	}
+
+	capacity, err := strconv.Atoi(size)
+	if err != nil || capacity <= 0 {
+		return 0, fmt.Errorf("invalid size of in-memory queues: %s", size)
+	}
+
+	return capacity, nil
+}
+
+// memMessage is a message in an in-memory queue
+type memMessage struct {
+	body          []byte
+	priority      uint8
+	correlationID string
+	headers       amqp.Table
+	seq           uint64 // Order of publishing
+	redelivered   bool
+}
+
+// memMessages is a heap of messages, by priority and then publishing order
+type memMessages []*memMessage
+
+func (m memMessages) Len() int      { return len(m) }
+func (m memMessages) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+
+func (m memMessages) Less(i, j int) bool {
+	if m[i].priority != m[j].priority {
+		return m[i].priority > m[j].priority
+	}
+
+	return m[i].seq < m[j].seq
+}
+
+func (m *memMessages) Push(x interface{}) {
+	*m = append(*m, x.(*memMessage))
+}
+
+func (m *memMessages) Pop() interface{} {
+	old := *m
+	msg := old[len(old)-1]
+	*m = old[:len(old)-1]
+
+	return msg
+}
+
+// memQueue is a bounded priority queue within this process. Only messages
+// ready for delivery count towards its capacity, so consumers holding
+// messages can always publish once others have taken some.
+type memQueue struct {
+	name     string
+	capacity int
+	dead     *memQueue // Dead letter queue; nil for dead letter queues
+
+	mu        sync.Mutex
+	ready     memMessages
+	unacked   int
+	consumers int
+	seq       uint64
+	changed   chan struct{} // Closed and replaced when messages are added or taken
+}
+
+// memoryQueues holds the in-memory queues of this process by name, so all
+// connections share them like they share queues on a broker
+var memoryQueues = struct {
+	sync.Mutex
+	queues map[string]*memQueue
+}{queues: make(map[string]*memQueue)}
+
+// newMemQueue returns an empty in-memory queue
+func newMemQueue(name string, capacity int) *memQueue {
+	return &memQueue{
+		name:     name,
+		capacity: capacity,
+		changed:  make(chan struct{}),
+	}
+}
+
+// memoryQueue returns the named in-memory queue, creating it and its dead
+// letter queue with capacity when they do not exist
+func memoryQueue(name string, capacity int) *memQueue {
+	memoryQueues.Lock()
+	defer memoryQueues.Unlock()
+
+	q, ok := memoryQueues.queues[name]
+	if !ok {
+		q = newMemQueue(name, capacity)
+		q.dead = newMemQueue(name+"-dead", capacity)
+
+		memoryQueues.queues[name] = q
+		memoryQueues.queues[q.dead.name] = q.dead
+	}
+
+	return q
+}
+
+// notify wakes up publishers and consumers waiting for a change; it is
+// called with mu held
+func (q *memQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// put adds a message, waiting up to wait for room when the queue is full
+func (q *memQueue) put(m *memMessage, wait time.Duration) error {
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	q.mu.Lock()
+	for len(q.ready) >= q.capacity {
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timeout.C:
+			return fmt.Errorf("in-memory queue %s is full with %d messages", q.name, q.capacity)
+		}
+
+		q.mu.Lock()
+	}
+	defer q.mu.Unlock()
+
+	q.seq++
+	m.seq = q.seq
+	heap.Push(&q.ready, m)
+	q.notify()
+
+	return nil
+}
+
+// get takes the next message for delivery, waiting for one until stop is
+// closed, in which case nil is returned
+func (q *memQueue) get(stop <-chan struct{}) *memMessage {
+	q.mu.Lock()
+	for len(q.ready) == 0 {
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-stop:
+			return nil
+		}
+
+		q.mu.Lock()
+	}
+	defer q.mu.Unlock()
+
+	m := heap.Pop(&q.ready).(*memMessage)
+	q.unacked++
+	q.notify()
+
+	return m
+}
+
+// settle removes a taken message, requeueing it when requeue is set. Unlike
+// publishing, requeueing never waits for room, so messages are not lost.
+func (q *memQueue) settle(m *memMessage, requeue bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.unacked--
+	if requeue {
+		heap.Push(&q.ready, m)
+	}
+	q.notify()
+}
+
+// depth returns the amount of messages ready for delivery
+func (q *memQueue) depth() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.ready), nil
+}
+
+// state returns the state of the queue
+func (q *memQueue) state() *State {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return &State{
+		Name:      q.name,
+		Messages:  len(q.ready),
+		Consumers: q.consumers,
+	}
+}
+
+// memAcknowledger acknowledges a message from an in-memory queue, like the
+// broker does for AMQP deliveries. Messages rejected without requeueing go
+// to the dead letter queue, or are dropped when it is full.
+type memAcknowledger struct {
+	queue *memQueue
+
+	mu      sync.Mutex
+	message *memMessage // Nil once acknowledged
+}
+
+// settle removes the message from the queue, returning it to be requeued
+// or dead lettered
+func (a *memAcknowledger) settle(requeue bool) (*memMessage, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	m := a.message
+	if m == nil {
+		return nil, fmt.Errorf("message from %s already acknowledged", a.queue.name)
+	}
+	a.message = nil
+
+	if requeue {
+		m.redelivered = true
+	}
+	a.queue.settle(m, requeue)
+
+	return m, nil
+}
+
+// Ack removes the message from the queue
+func (a *memAcknowledger) Ack(tag uint64, multiple bool) error {
+	_, err := a.settle(false)
+	return err
+}
+
+// Nack requeues the message or moves it to the dead letter queue
+func (a *memAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	m, err := a.settle(requeue)
+	if err != nil || requeue {
+		return err
+	}
+
+	if err := a.queue.dead.put(m, 0); err != nil {
+		log.WithField("queue", a.queue.name).WithError(err).Warn("Dropping dead letter")
+	}
+
+	return nil
+}
+
+// Reject requeues the message or moves it to the dead letter queue
+func (a *memAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+// memBackend publishes to and consumes from an in-memory queue
+type memBackend struct {
+	queue *memQueue
+
+	mu   sync.Mutex
+	stop chan struct{} // Closed to stop consuming; nil when not consuming
+}
+
+// publish adds a message to the queue, failing when the queue stays full
+// for memoryWait
+func (b *memBackend) publish(body []byte, priority uint8, correlationID string, headers amqp.Table) error {
+	return b.queue.put(&memMessage{
+		body:          body,
+		priority:      priority,
+		correlationID: correlationID,
+		headers:       headers,
+	}, memoryWait)
+}
+
+// publishAfter adds a message to the queue after delay; messages held
+// back are lost on exit, like those in the queue
+func (b *memBackend) publishAfter(body []byte, priority uint8, correlationID string, headers amqp.Table, delay time.Duration) error {
+	time.AfterFunc(delay, func() {
+		if err := b.publish(body, priority, correlationID, headers); err != nil {
+			log.WithField("queue", b.queue.name).WithError(err).Warn("Dropping delayed message")
+		}
+	})
+
+	return nil
+}
+
+// delivery returns a message as AMQP delivery, so it is handled like
+// messages from AMQP brokers
+func (b *memBackend) delivery(m *memMessage) amqp.Delivery {
+	return amqp.Delivery{
+		Acknowledger:  &memAcknowledger{queue: b.queue, message: m},
+		Headers:       m.headers,
+		ContentType:   "application/json",
+		CorrelationId: m.correlationID,
+		MessageId:     strconv.FormatUint(m.seq, 10),
+		Priority:      m.priority,
+		Redelivered:   m.redelivered,
+		RoutingKey:    b.queue.name,
+		Body:          m.body,
+	}
+}
+
+// consume delivers messages from the queue until close is called, after
+// which the returned channel is closed. A message taken but not yet
+// delivered on close is requeued.
+func (b *memBackend) consume() <-chan amqp.Delivery {
+	stop := make(chan struct{})
+
+	b.mu.Lock()
+	b.stop = stop
+	b.mu.Unlock()
+
+	b.queue.mu.Lock()
+	b.queue.consumers++
+	b.queue.mu.Unlock()
+
+	messages := make(chan amqp.Delivery)
+
+	go func() {
+		defer close(messages)
+		defer func() {
+			b.queue.mu.Lock()
+			b.queue.consumers--
+			b.queue.mu.Unlock()
+		}()
+
+		for {
+			m := b.queue.get(stop)
+			if m == nil {
+				return
+			}
+
+			select {
+			case messages <- b.delivery(m):
+			case <-stop:
+				b.queue.settle(m, true)
+				return
+			}
+		}
+	}()
+
+	return messages
+}
+
+// close stops consuming
+func (b *memBackend) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stop != nil {
+		close(b.stop)
+		b.stop = nil
+	}
+}
+
+// depth returns the amount of messages ready for delivery
+func (b *memBackend) depth() (int, error) {
+	return b.queue.depth()
+}
+
+// inspectMemory returns the state of a named in-memory queue; queues which
+// have not been used yet are empty
+func inspectMemory(name string) *State {
+	memoryQueues.Lock()
+	q, ok := memoryQueues.queues[name]
+	memoryQueues.Unlock()
+
+	if !ok {
+		return &State{Name: name}
+	}
+
+	return q.state()
+}
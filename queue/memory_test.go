@@ -0,0 +1,122 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCapacityOf(t *testing.T) {
+	tests := []struct {
+		url  string
+		want int
+		err  bool
+	}{
+		{"memory://", memoryCapacity, false},
+		{MemoryURL(5), 5, false},
+		{"memory://?size=0", 0, true},
+		{"memory://?size=many", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := memoryCapacityOf(test.url)
+		if got != test.want || (err != nil) != test.err {
+			t.Errorf("memoryCapacityOf(%s) = %d, %v", test.url, got, err)
+		}
+	}
+}
+
+func TestMemoryQueue(t *testing.T) {
+	conn, err := NewConnection(MemoryURL(2))
+	if err != nil {
+		t.Fatalf("NewConnection() error %v", err)
+	}
+
+	// Queues remain for the process, so runs use their own
+	name := "test-" + newID()
+
+	q, err := conn.NewChannelQueue(name)
+	if err != nil {
+		t.Fatalf("NewChannelQueue() error %v", err)
+	}
+
+	for _, p := range []uint8{1, 9} {
+		if err := q.publish([]byte{'0' + p}, p, "", nil, 0); err != nil {
+			t.Fatalf("publish() error %v", err)
+		}
+	}
+
+	// Full queues fail publishing after waiting for room
+	full := newMemQueue("full", 1)
+	full.put(&memMessage{}, 0)
+	if err := full.put(&memMessage{}, time.Millisecond); err == nil {
+		t.Error("put() in full queue returned no error")
+	}
+
+	// Connections share queues
+	other, _ := NewConnection(MemoryURL(2))
+	if state, _ := other.Inspect(name); state.Messages != 2 {
+		t.Errorf("Inspect() = %+v, want 2 messages", state)
+	}
+
+	msgs, err := q.Consume()
+	if err != nil {
+		t.Fatalf("Consume() error %v", err)
+	}
+
+	// Highest priority first
+	d := <-msgs
+	if string(d.Body) != "9" || d.Redelivered {
+		t.Errorf("first delivery = %+v, want priority 9", d)
+	}
+
+	if err := d.Nack(false, true); err != nil {
+		t.Fatalf("Nack() error %v", err)
+	}
+	if err := d.Ack(false); err == nil {
+		t.Error("Ack() of requeued message returned no error")
+	}
+
+	// The next message was taken while the first was processed
+	d = <-msgs
+	if string(d.Body) != "1" {
+		t.Errorf("second delivery = %+v, want priority 1", d)
+	}
+	d.Reject(false)
+
+	d = <-msgs
+	if string(d.Body) != "9" || !d.Redelivered {
+		t.Errorf("requeued delivery = %+v, want redelivered priority 9", d)
+	}
+	d.Ack(false)
+	q.Close()
+
+	if depth, _ := q.Depth(); depth != 0 {
+		t.Errorf("Depth() = %d, want 0", depth)
+	}
+	if state, _ := conn.Inspect(name + "-dead"); state.Messages != 1 {
+		t.Errorf("dead letter queue = %+v, want 1 message", state)
+	}
+}
+
+func TestMemoryPublishAfter(t *testing.T) {
+	conn, _ := NewConnection(MemoryURL(2))
+
+	q, err := conn.NewChannelQueue("test-" + newID())
+	if err != nil {
+		t.Fatalf("NewChannelQueue() error %v", err)
+	}
+
+	if err := q.publish([]byte("{}"), 0, "", nil, 50*time.Millisecond); err != nil {
+		t.Fatalf("publish() error %v", err)
+	}
+
+	// Held back until the delay has passed
+	if depth, _ := q.Depth(); depth != 0 {
+		t.Errorf("Depth() = %d right after publishing, want 0", depth)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if depth, _ := q.Depth(); depth != 1 {
+		t.Errorf("Depth() = %d after delay, want 1", depth)
+	}
+}
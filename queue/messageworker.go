@@ -5,20 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"github.com/ipfs-search/ipfs-search/worker"
+	log "github.com/sirupsen/logrus"
 	"github.com/streadway/amqp"
-	"log"
+	"runtime/debug"
 )
 
 // MessageWorkerFactory instantiates a worker for a single AMQP message
 type MessageWorkerFactory func(msg *amqp.Delivery) worker.Worker
 
+// PanicHandler is called with the message for which a worker panicked,
+// e.g. to record the failure
+type PanicHandler func(msg *amqp.Delivery, err error)
+
 // newMessageWorker implements MessageWorkerFactory and wraps a factory with
 // a messageWorker, such that messages will be properly acked/rejected and
 // errors/panics handled
-func newMessageWorker(factory MessageWorkerFactory) MessageWorkerFactory {
+func newMessageWorker(queue *Queue, factory MessageWorkerFactory, onPanic PanicHandler) MessageWorkerFactory {
 	return func(msg *amqp.Delivery) worker.Worker {
 		return &messageWorker{
+			Queue:    queue,
 			Factory:  factory,
+			OnPanic:  onPanic,
 			Delivery: msg,
 		}
 	}
@@ -27,7 +34,9 @@ func newMessageWorker(factory MessageWorkerFactory) MessageWorkerFactory {
 // messageWorker instantiates and wraps a single worker for every message for
 // error handling and ack/rejection
 type messageWorker struct {
+	Queue   *Queue // Queue the message was received from, for retrying
 	Factory MessageWorkerFactory
+	OnPanic PanicHandler // Optional
 	*amqp.Delivery
 }
 
@@ -40,13 +49,42 @@ func (m *messageWorker) Work(ctx context.Context) (err error) {
 		}
 	}()
 
-	log.Printf("Received: %s (priority: %d)", m.Body, m.Priority)
+	log.WithFields(log.Fields{
+		"queue":    m.RoutingKey,
+		"priority": m.Priority,
+	}).Debugf("Received: %s", m.Body)
+
+	if m.expire() {
+		return nil
+	}
 
 	// Create new worker for the actual work and perform it
 	worker := m.Factory(m.Delivery)
 	err = worker.Work(ctx)
 
 	if err != nil {
+		if ctx.Err() != nil {
+			// Interrupted by shutdown or scaling down; let another worker handle it
+			m.Nack(false, true)
+			return
+		}
+
+		var publishErr *PublishError
+		if errors.As(err, &publishErr) {
+			// Items found could not all be queued; retry the whole
+			// message rather than losing them
+			log.WithField("queue", m.RoutingKey).WithError(err).Warn("Retrying after failed publish")
+			m.retry(ctx)
+			return
+		}
+
+		var retryErr *RetryError
+		if errors.As(err, &retryErr) {
+			log.WithField("queue", m.RoutingKey).WithError(err).Warn("Retrying after temporary failure")
+			m.retry(ctx)
+			return
+		}
+
 		// Don't retry
 		m.Reject(false)
 
@@ -59,8 +97,62 @@ func (m *messageWorker) Work(ctx context.Context) (err error) {
 	return
 }
 
+// expire drops the message, or moves it to the dead letter queue, when its
+// task has expired; it returns whether it did. Messages which are not
+// tasks are left to the worker.
+func (m *messageWorker) expire() bool {
+	task, err := ParseTask(m.Body)
+	if err != nil || !task.ExpiredAfter(m.Queue.expiry.ttl(m.Queue.Name)) {
+		return false
+	}
+
+	log.WithFields(log.Fields{
+		"queue": m.RoutingKey,
+		"task":  task.ID,
+	}).Info("Task expired")
+
+	if m.Queue.expiry.DeadLetter {
+		m.Reject(false)
+	} else {
+		m.Ack(false)
+	}
+
+	return true
+}
+
+// retry publishes the message again with its attempts counted, as
+// messages requeued by the broker can't be changed, to be delivered after
+// the retry delay of the queue, or rejects it to the dead letter queue
+// once it has been tried too often
+func (m *messageWorker) retry(ctx context.Context) {
+	task, err := ParseTask(m.Body)
+	if err != nil {
+		m.Reject(false)
+		return
+	}
+	task.Priority = m.Priority
+
+	task, ok := task.Retry()
+	if !ok {
+		log.WithField("queue", m.RoutingKey).Warnf("Giving up after %d attempts", task.Attempts)
+		m.Reject(false)
+		return
+	}
+
+	if err := m.Queue.PublishTaskAfter(ContextFromDelivery(ctx, m.Delivery), task, m.Queue.retryDelay); err != nil {
+		// Keep the message, albeit without counting the attempt
+		log.WithField("queue", m.RoutingKey).WithError(err).Warn("Error retrying, requeueing")
+		m.Nack(false, true)
+		return
+	}
+
+	m.Ack(false)
+}
+
+// recoverPanic rejects the message, moving it to the dead letter queue,
+// and returns the panic as error, so the worker can continue consuming
 func (m *messageWorker) recoverPanic(r interface{}) (err error) {
-	log.Printf("Panic in: %s", m.Body)
+	log.WithField("queue", m.RoutingKey).Errorf("Panic in: %s\n%s", m.Body, debug.Stack())
 
 	// Permanently remove message from original queue
 	m.Reject(false)
@@ -75,5 +167,9 @@ func (m *messageWorker) recoverPanic(r interface{}) (err error) {
 		err = fmt.Errorf("Unassertable panic error: %v", r)
 	}
 
+	if m.OnPanic != nil {
+		m.OnPanic(m.Delivery, err)
+	}
+
 	return
 }
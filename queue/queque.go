@@ -1,58 +1,393 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/ipfs-search/ipfs-search/dedup"
+	"github.com/ipfs-search/ipfs-search/tracing"
+	log "github.com/sirupsen/logrus"
 	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	"math"
+	"strconv"
+	"sync"
 	"time"
 )
 
-const confirmTimeout = 10 * time.Second
+const (
+	confirmTimeout = 10 * time.Second
 
-// Connection wraps an AMQP connection
+	// Reconnection backoff starts at minBackoff and doubles up to maxBackoff
+	minBackoff    = time.Second
+	maxBackoff    = time.Minute
+	maxReconnects = 20
+)
+
+// backoff returns the time to wait before a given reconnection attempt
+func backoff(attempt int) time.Duration {
+	wait := minBackoff << uint(attempt)
+	if wait > maxBackoff || wait <= 0 {
+		return maxBackoff
+	}
+
+	return wait
+}
+
+// QoS limits the messages delivered by AMQP brokers ahead of their
+// acknowledgement. Workers consume one message at a time, so prefetched
+// messages wait in memory; more save round trips for small messages.
+type QoS struct {
+	Prefetch int // Messages per consumer, at least 1
+
+	// Messages per channel on RabbitMQ, or per connection on brokers
+	// following the AMQP specification; 0 for no limit
+	GlobalPrefetch int
+}
+
+// DefaultQoS prefetches a single message per consumer
+var DefaultQoS = QoS{Prefetch: 1}
+
+// Connection wraps an AMQP connection, which is re-established when it
+// has been closed by the broker or by network failure. With a redis://
+// URL, queues are Redis Streams instead, with a nats:// URL JetStream
+// streams, with a kafka:// URL Kafka topics and with a memory:// URL
+// bounded queues within this process.
 type Connection struct {
-	*amqp.Connection
+	url        string
+	qos        QoS           // Of channels opened after it is set
+	expiry     Expiry        // Of queues opened after it is set
+	retryDelay time.Duration // Of queues opened after it is set
+	seen       dedup.Cache   // Of queues opened after it is set
+
+	mu         sync.Mutex
+	connection *amqp.Connection
+	redis      *redis.Pool   // Instead of connection, for Redis Streams
+	jetstream  *jetStream    // Instead of connection, for NATS JetStream
+	kafka      *kafkaCluster // Instead of connection, for Kafka
+	memory     int           // Capacity of in-memory queues, instead of connection
+
+	reconnecting chan struct{} // Closed once reconnected; nil unless reconnecting
+	reconnectErr error         // Of the last reconnection
 }
 
-// NewConnection returns new AMQP connection
+// NewConnection returns new AMQP connection, or a connection to Redis for
+// redis:// and rediss:// URLs, to NATS for nats:// and tls:// URLs or to
+// Kafka for kafka:// URLs. Connections to memory:// URLs share the queues of this
+// process, e.g. memory://?size=1000 for queues of up to 1000 messages.
 func NewConnection(url string) (*Connection, error) {
+	if isMemory(url) {
+		capacity, err := memoryCapacityOf(url)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Connection{
+			url:    url,
+			memory: capacity,
+		}, nil
+	}
+
+	if isRedis(url) {
+		pool, err := newPool(url)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Connection{
+			url:   url,
+			redis: pool,
+		}, nil
+	}
+
+	if isNATS(url) {
+		js, err := newJetStream(url)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Connection{
+			url:       url,
+			jetstream: js,
+		}, nil
+	}
+
+	if isKafka(url) {
+		k, err := newKafka(url)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Connection{
+			url:   url,
+			kafka: k,
+		}, nil
+	}
+
 	connection, err := amqp.Dial(url)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &Connection{Connection: connection}, nil
+	return &Connection{
+		url:        url,
+		qos:        DefaultQoS,
+		connection: connection,
+	}, nil
+}
+
+// SetQoS sets the prefetch limits of channels subsequently opened, e.g.
+// by queues of consumers
+func (conn *Connection) SetQoS(qos QoS) error {
+	if qos.Prefetch < 1 || qos.GlobalPrefetch < 0 {
+		return fmt.Errorf("invalid prefetch %d and global prefetch %d; prefetch must be at least 1", qos.Prefetch, qos.GlobalPrefetch)
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	conn.qos = qos
+	return nil
+}
+
+// current returns the underlying connection, dialing again with backoff
+// when it has been closed. One caller reconnects while others wait for it;
+// the lock is only held to read and replace the connection, so it is not
+// held while dialing or waiting.
+func (conn *Connection) current() (*amqp.Connection, error) {
+	if conn.redis != nil {
+		return nil, fmt.Errorf("AMQP channels are not available on Redis Streams")
+	}
+	if conn.jetstream != nil {
+		return nil, fmt.Errorf("AMQP channels are not available on NATS JetStream")
+	}
+	if conn.kafka != nil {
+		return nil, fmt.Errorf("AMQP channels are not available on Kafka")
+	}
+	if conn.memory > 0 {
+		return nil, fmt.Errorf("AMQP channels are not available on in-memory queues")
+	}
+
+	conn.mu.Lock()
+
+	if !conn.connection.IsClosed() {
+		connection := conn.connection
+		conn.mu.Unlock()
+		return connection, nil
+	}
+
+	if reconnected := conn.reconnecting; reconnected != nil {
+		conn.mu.Unlock()
+		<-reconnected
+
+		conn.mu.Lock()
+		defer conn.mu.Unlock()
+
+		if conn.reconnectErr != nil {
+			return nil, conn.reconnectErr
+		}
+		return conn.connection, nil
+	}
+
+	reconnected := make(chan struct{})
+	conn.reconnecting = reconnected
+	conn.mu.Unlock()
+
+	connection, err := conn.reconnect()
+
+	conn.mu.Lock()
+	if err == nil {
+		conn.connection = connection
+	}
+	conn.reconnectErr = err
+	conn.reconnecting = nil
+	conn.mu.Unlock()
+
+	close(reconnected)
+	return connection, err
+}
+
+// reconnect dials the broker again with backoff
+func (conn *Connection) reconnect() (*amqp.Connection, error) {
+	var err error
+	for attempt := 0; attempt < maxReconnects; attempt++ {
+		wait := backoff(attempt)
+		log.WithError(err).Warnf("AMQP connection closed, reconnecting in %s", wait)
+		time.Sleep(wait)
+
+		var connection *amqp.Connection
+		connection, err = amqp.Dial(conn.url)
+		if err == nil {
+			log.Info("AMQP connection re-established")
+			return connection, nil
+		}
+	}
+
+	return nil, fmt.Errorf("giving up reconnecting to AMQP broker: %v", err)
+}
+
+// Close closes the connection; it is not re-established
+func (conn *Connection) Close() error {
+	if conn.redis != nil {
+		return conn.redis.Close()
+	}
+	if conn.jetstream != nil {
+		return conn.jetstream.close()
+	}
+	if conn.kafka != nil {
+		// Queues close their own readers and writers
+		return nil
+	}
+	if conn.memory > 0 {
+		// Queues remain for other connections
+		return nil
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.connection.Close()
+}
+
+// SetExpiry sets when tasks consumed from queues subsequently opened expire,
+// and when brokers expire their messages. Queues are declared with the
+// maximum age of their tasks as message TTL, so all connections to the
+// same queues should have the same expiry.
+func (conn *Connection) SetExpiry(expiry Expiry) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	conn.expiry = expiry
+}
+
+// messageTTL returns how long the broker keeps messages on a named queue
+func (conn *Connection) messageTTL(name string) time.Duration {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	return conn.expiry.messageTTL(name)
+}
+
+// SetRetryDelay sets the time after which tasks failing temporarily on
+// queues subsequently opened are tried again; 0 retries them right away
+func (conn *Connection) SetRetryDelay(delay time.Duration) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	conn.retryDelay = delay
+}
+
+// SetSeen sets the seen-set shared between publishers, by which tasks with
+// a message hash published on queues subsequently opened are deduplicated;
+// nil leaves deduplication to the broker
+func (conn *Connection) SetSeen(seen dedup.Cache) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	conn.seen = seen
+}
+
+// channel opens an AMQP channel on the current connection
+func (conn *Connection) channel() (*amqp.Channel, error) {
+	connection, err := conn.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return connection.Channel()
 }
 
 // NewChannel initialises an AMQP channel
 func (conn *Connection) NewChannel() (*Channel, error) {
 	// Create channel
-	ch, err := conn.Channel()
+	ch, err := conn.channel()
 	if err != nil {
 		return nil, err
 	}
 
+	conn.mu.Lock()
+	qos := conn.qos
+	conn.mu.Unlock()
+
 	// Set Qos
 	err = ch.Qos(
-		1,     // prefetch count
-		0,     // prefetch size
-		false, // global
+		qos.Prefetch, // prefetch count
+		0,            // prefetch size
+		false,        // global
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if qos.GlobalPrefetch > 0 {
+		err = ch.Qos(
+			qos.GlobalPrefetch, // prefetch count
+			0,                  // prefetch size
+			true,               // global
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Enable confirm
 	if err := ch.Confirm(false); err != nil {
 		return nil, fmt.Errorf("Channel could not be put into confirm mode: %s", err)
 	}
 
 	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	closes := ch.NotifyClose(make(chan *amqp.Error, 1))
 
 	return &Channel{
-		Channel:  ch,
-		Confirms: confirms,
+		Channel:    ch,
+		Confirms:   confirms,
+		closes:     closes,
+		connection: conn,
+	}, nil
+}
+
+// State describes a queue on the broker
+type State struct {
+	Name      string `json:"name"`
+	Messages  int    `json:"messages"`
+	Consumers int    `json:"consumers"`
+
+	Trend *Trend `json:"trend,omitempty"` // When estimated from several samples
+}
+
+// Inspect returns the state of a named queue, using a temporary channel
+// as inspecting a non-existent queue closes the channel
+func (conn *Connection) Inspect(name string) (*State, error) {
+	if conn.redis != nil {
+		return inspectStream(conn.redis, name)
+	}
+	if conn.jetstream != nil {
+		return inspectJetStream(conn.jetstream, name)
+	}
+	if conn.kafka != nil {
+		return inspectKafka(conn.kafka, name)
+	}
+	if conn.memory > 0 {
+		return inspectMemory(name), nil
+	}
+
+	ch, err := conn.channel()
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueInspect(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &State{
+		Name:      q.Name,
+		Messages:  q.Messages,
+		Consumers: q.Consumers,
 	}, nil
 }
 
@@ -60,11 +395,19 @@ func (conn *Connection) NewChannel() (*Channel, error) {
 type Channel struct {
 	*amqp.Channel
 	Confirms chan amqp.Confirmation
+
+	closes     chan *amqp.Error
+	connection *Connection
+}
+
+// closed returns a channel which is closed when the AMQP channel is
+func (c *Channel) closed() <-chan *amqp.Error {
+	return c.closes
 }
 
 // Close closes a Channel
 func (c *Channel) Close() error {
-	err := c.Close()
+	err := c.Channel.Close()
 	if err != nil {
 		return err
 	}
@@ -72,10 +415,35 @@ func (c *Channel) Close() error {
 	return nil
 }
 
-// Queue wraps an channel/queue for tasks
+// backend is a broker other than AMQP, to which queues delegate. Its
+// messages are delivered as AMQP deliveries, acknowledged through their
+// Acknowledger.
+type backend interface {
+	publish(body []byte, priority uint8, correlationID string, headers amqp.Table) error
+	consume() <-chan amqp.Delivery
+	close()
+	depth() (int, error)
+}
+
+// delayer is a backend which can delay the delivery of messages
+type delayer interface {
+	publishAfter(body []byte, priority uint8, correlationID string, headers amqp.Table, delay time.Duration) error
+}
+
+// Queue wraps an channel/queue for tasks. When the channel is closed due
+// to connection failure, a new channel is opened on the next use. Queues
+// on other brokers have no channel.
 type Queue struct {
 	Channel *Channel
 	*amqp.Queue
+
+	backend    backend       // Instead of Channel, on Redis Streams, NATS JetStream, Kafka or in memory
+	expiry     Expiry        // Of tasks consumed
+	retryDelay time.Duration // Before tasks failing temporarily are tried again
+	seen       dedup.Cache   // Message hashes of tasks recently published, if any
+
+	mu        sync.Mutex
+	publishMu sync.Mutex
 }
 
 // String returns the name of the queue
@@ -83,30 +451,42 @@ func (q *Queue) String() string {
 	return q.Name
 }
 
-// NewQueue creates a named queue on a given chennel
-func (c *Channel) NewQueue(name string) (*Queue, error) {
+// queueArgs returns the arguments of a named queue, of which messages
+// expire after ttl
+func queueArgs(name string, ttl time.Duration) amqp.Table {
+	// Sent as 32 bit integer, as before, unless too long for one; the
+	// arguments of existing queues can't be changed
+	var messageTTL interface{} = milliseconds(ttl)
+	if ms := milliseconds(ttl); ms <= math.MaxInt32 {
+		messageTTL = int(ms)
+	}
+
+	return amqp.Table{
+		"x-max-priority":            9,          // Enable all 9 priorities
+		"x-message-ttl":             messageTTL, // Expire messages not consumed in time
+		"x-dead-letter-exchange":    "",         // Anything failing or expiring goes here
+		"x-dead-letter-routing-key": fmt.Sprintf("%s-dead", name),
+	}
+}
+
+// declare declares a named queue and its dead letter queue on a channel,
+// expiring messages after ttl
+func declare(ch *amqp.Channel, name string, ttl time.Duration) (amqp.Queue, error) {
 	deadQueue := fmt.Sprintf("%s-dead", name)
 
-	args := amqp.Table{
-		"x-max-priority":            9,                   // Enable all 9 priorities
-		"x-message-ttl":             1000 * 60 * 60 * 24, // Expire messages after 24 hours
-		"x-dead-letter-exchange":    "",                  // Anything failing or expiring goes here
-		"x-dead-letter-routing-key": deadQueue,
-	}
-
-	q, err := c.Channel.QueueDeclare(
-		name,  // name
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		args,  // arguments
+	q, err := ch.QueueDeclare(
+		name,                 // name
+		true,                 // durable
+		false,                // delete when unused
+		false,                // exclusive
+		false,                // no-wait
+		queueArgs(name, ttl), // arguments
 	)
 	if err != nil {
-		return nil, err
+		return q, err
 	}
 
-	_, err = c.Channel.QueueDeclare(
+	_, err = ch.QueueDeclare(
 		deadQueue, // name
 		true,      // durable
 		false,     // delete when unused
@@ -114,6 +494,38 @@ func (c *Channel) NewQueue(name string) (*Queue, error) {
 		false,     // no-wait
 		nil,       // arguments
 	)
+
+	return q, err
+}
+
+// declareDelay declares the delay queue of a named queue on a channel and
+// returns its name. It has no consumers: messages are published to it with
+// an expiration, on which they are dead lettered into the named queue.
+// Expiring messages rather than the queue allows changing the delay, as
+// the arguments of existing queues can't be changed.
+func declareDelay(ch *amqp.Channel, name string) (string, error) {
+	delayQueue := fmt.Sprintf("%s-delay", name)
+
+	args := amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": name,
+	}
+
+	_, err := ch.QueueDeclare(
+		delayQueue, // name
+		true,       // durable
+		false,      // delete when unused
+		false,      // exclusive
+		false,      // no-wait
+		args,       // arguments
+	)
+
+	return delayQueue, err
+}
+
+// NewQueue creates a named queue on a given chennel
+func (c *Channel) NewQueue(name string) (*Queue, error) {
+	q, err := declare(c.Channel, name, c.connection.messageTTL(name))
 	if err != nil {
 		return nil, err
 	}
@@ -126,6 +538,65 @@ func (c *Channel) NewQueue(name string) (*Queue, error) {
 
 // NewChannelQueue returns a new queue on a new channel
 func (conn *Connection) NewChannelQueue(name string) (*Queue, error) {
+	q, err := conn.newChannelQueue(name)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.mu.Lock()
+	q.expiry = conn.expiry
+	q.retryDelay = conn.retryDelay
+	q.seen = conn.seen
+	conn.mu.Unlock()
+
+	return q, nil
+}
+
+// newChannelQueue returns a new queue on the broker of the connection
+func (conn *Connection) newChannelQueue(name string) (*Queue, error) {
+	if conn.redis != nil {
+		s, err := newStream(conn.redis, name)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Queue{
+			Queue:   &amqp.Queue{Name: name},
+			backend: s,
+		}, nil
+	}
+
+	if conn.jetstream != nil {
+		q, err := newJSQueue(conn.jetstream, name, conn.messageTTL(name))
+		if err != nil {
+			return nil, err
+		}
+
+		return &Queue{
+			Queue:   &amqp.Queue{Name: name},
+			backend: q,
+		}, nil
+	}
+
+	if conn.kafka != nil {
+		q, err := newKafkaQueue(conn.kafka, name)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Queue{
+			Queue:   &amqp.Queue{Name: name},
+			backend: q,
+		}, nil
+	}
+
+	if conn.memory > 0 {
+		return &Queue{
+			Queue:   &amqp.Queue{Name: name},
+			backend: &memBackend{queue: memoryQueue(name, conn.memory)},
+		}, nil
+	}
+
 	channel, err := conn.NewChannel()
 	if err != nil {
 		return nil, err
@@ -134,31 +605,196 @@ func (conn *Connection) NewChannelQueue(name string) (*Queue, error) {
 	return channel.NewQueue(name)
 }
 
-// Publish adds a task with specified params to the Queue
+// channel returns the channel of the queue, replacing it with a new one
+// when it has been closed
+func (q *Queue) channel() (*Channel, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case <-q.Channel.closed():
+	default:
+		return q.Channel, nil
+	}
+
+	log.WithField("queue", q.Name).Info("Reopening channel")
+
+	channel, err := q.Channel.connection.NewChannel()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := declare(channel.Channel, q.Name, channel.connection.messageTTL(q.Name)); err != nil {
+		return nil, err
+	}
+
+	q.Channel = channel
+	return channel, nil
+}
+
+// Close closes the current channel of the queue, cancelling its consumers
+// and returning their unacknowledged messages to the queue
+func (q *Queue) Close() error {
+	if q.backend != nil {
+		q.backend.close()
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.Channel.Close()
+}
+
+// PublishError is returned when a message could not be published or was
+// not confirmed by the broker
+type PublishError struct {
+	Queue string
+	Err   error
+}
+
+// Error returns the error message
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("publishing to %s: %v", e.Queue, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *PublishError) Unwrap() error {
+	return e.Err
+}
+
+// RetryError is returned by workers for tasks to be tried again rather
+// than rejected, e.g. after temporary failures writing their results
+type RetryError struct {
+	Err error
+}
+
+// Error returns the error message
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("retrying: %v", e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// Publish adds a new task with specified params to the Queue and waits for
+// the broker to confirm it; failures are returned as PublishError.
 // priority: higher number, higher priority
 func (q *Queue) Publish(params interface{}, priority uint8) error {
-	body, err := json.Marshal(params)
+	task, err := NewTask(params, priority, "")
+	if err != nil {
+		return err
+	}
+
+	return q.PublishTask(context.Background(), task)
+}
+
+// PublishTask adds a task to the Queue with its priority, like Publish.
+// The trace context of ctx is propagated in the message headers. Tasks
+// published for the first time get their creation time set.
+func (q *Queue) PublishTask(ctx context.Context, task *Task) error {
+	return q.PublishTaskAfter(ctx, task, 0)
+}
+
+// PublishTaskAfter adds a task to the Queue like PublishTask, to be
+// delivered after delay. On AMQP brokers, it waits in the delay queue;
+// in-memory queues hold it back in-process. Redis Streams, NATS JetStream
+// and Kafka deliver it right away. Tasks with a message hash are skipped
+// when a task with the same hash was published recently. Tasks with a
+// partition key are partitioned by it on Kafka.
+func (q *Queue) PublishTaskAfter(ctx context.Context, task *Task, delay time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "Publish", attribute.String("queue", q.Name))
+	defer func() { tracing.End(span, err) }()
+
+	if q.duplicate(task) {
+		log.WithFields(log.Fields{
+			"queue": q.Name,
+			"task":  task.ID,
+		}).Debug("Skipping duplicate task")
+		return nil
+	}
+
+	if task.Created == nil {
+		now := time.Now().UTC()
+		task.Created = &now
+	}
+
+	body, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	headers := amqp.Table{}
+	tracing.Inject(ctx, headerCarrier(headers))
+	if task.Hash != "" {
+		headers[dedupHeader] = task.Hash
+	}
+	if task.Partition != "" {
+		headers[partitionHeader] = task.Partition
+	}
+
+	if err := q.publish(body, task.Priority, task.ID, headers, delay); err != nil {
+		return &PublishError{Queue: q.Name, Err: err}
+	}
+
+	q.markPublished(task)
+	return nil
+}
+
+// publish publishes a message body, to be delivered after delay, waiting
+// for confirmation. Publishes are serialized, so confirmations match their
+// messages.
+func (q *Queue) publish(body []byte, priority uint8, correlationID string, headers amqp.Table, delay time.Duration) error {
+	if q.backend != nil {
+		if d, ok := q.backend.(delayer); ok && delay > 0 {
+			return d.publishAfter(body, priority, correlationID, headers, delay)
+		}
+
+		return q.backend.publish(body, priority, correlationID, headers)
+	}
+
+	q.publishMu.Lock()
+	defer q.publishMu.Unlock()
+
+	ch, err := q.channel()
 	if err != nil {
 		return err
 	}
 
-	err = q.Channel.Publish(
-		"",     // exchange
-		q.Name, // routing key
-		false,  // mandatory
-		false,  // immediate
+	routingKey := q.Name
+	var expiration string
+	if delay > 0 {
+		if routingKey, err = declareDelay(ch.Channel, q.Name); err != nil {
+			return err
+		}
+		expiration = strconv.FormatInt(milliseconds(delay), 10)
+	}
+
+	err = ch.Publish(
+		"",         // exchange
+		routingKey, // routing key
+		false,      // mandatory
+		false,      // immediate
 		amqp.Publishing{
-			DeliveryMode: amqp.Persistent,
-			ContentType:  "application/json",
-			Body:         body,
-			Priority:     priority,
+			DeliveryMode:  amqp.Persistent,
+			ContentType:   "application/json",
+			CorrelationId: correlationID,
+			Headers:       headers,
+			Body:          body,
+			Priority:      priority,
+			Expiration:    expiration,
 		})
 	if err != nil {
 		return err
 	}
 
 	select {
-	case confirmed := <-q.Channel.Confirms:
+	case confirmed, ok := <-ch.Confirms:
+		if !ok {
+			return fmt.Errorf("Channel closed waiting for confirmation of publish")
+		}
 		if !confirmed.Ack {
 			return fmt.Errorf("Failed delivery of delivery tag: %d", confirmed.DeliveryTag)
 		}
@@ -169,9 +805,38 @@ func (q *Queue) Publish(params interface{}, priority uint8) error {
 	return nil
 }
 
-// Consume consumes messages from a queue
+// Depth returns the amount of messages ready for delivery in the queue
+func (q *Queue) Depth() (int, error) {
+	if q.backend != nil {
+		return q.backend.depth()
+	}
+
+	ch, err := q.channel()
+	if err != nil {
+		return 0, err
+	}
+
+	state, err := ch.QueueInspect(q.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	return state.Messages, nil
+}
+
+// Consume consumes messages from a queue. The returned channel is closed
+// when the underlying channel is, after which Consume may be called again.
 func (q *Queue) Consume() (<-chan amqp.Delivery, error) {
-	msgs, err := q.Channel.Consume(
+	if q.backend != nil {
+		return q.backend.consume(), nil
+	}
+
+	ch, err := q.channel()
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := ch.Consume(
 		q.Name, // queue
 		"",     // consumer
 		false,  // auto-ack
@@ -0,0 +1,477 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// streamPrefix namespaces stream keys in a shared Redis database
+	streamPrefix = "ipfs-search:queue:"
+
+	// streamGroup is the consumer group all crawlers consume streams in
+	streamGroup = "crawlers"
+
+	// claimAfter is the time after which messages left unacknowledged,
+	// e.g. by crashed consumers, are delivered to another consumer
+	claimAfter = 5 * time.Minute
+
+	// blockTime is the time consumers wait for new messages at once
+	blockTime = 5 * time.Second
+)
+
+// isRedis returns whether a broker URL refers to Redis
+func isRedis(url string) bool {
+	return strings.HasPrefix(url, "redis://") || strings.HasPrefix(url, "rediss://")
+}
+
+// milliseconds returns a duration in milliseconds, as Redis takes them
+func milliseconds(d time.Duration) int64 {
+	return d.Nanoseconds() / int64(time.Millisecond)
+}
+
+// newPool returns a pool of connections to Redis, checking it can be
+// reached
+func newPool(url string) (*redis.Pool, error) {
+	pool := &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 5 * time.Minute,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(url)
+		},
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("PING"); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// entry is a message in a stream
+type entry struct {
+	id     string
+	fields map[string]string
+}
+
+// parseEntries parses stream entries as returned by XREADGROUP and XCLAIM,
+// leaving out entries which have been deleted meanwhile. Like the reply
+// helpers of redigo, it takes the error of the command as well.
+func parseEntries(reply interface{}, err error) ([]entry, error) {
+	items, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]entry, 0, len(items))
+	for _, item := range items {
+		pair, err := redis.Values(item, nil)
+		if err != nil || len(pair) != 2 {
+			return nil, fmt.Errorf("invalid stream entry: %v", item)
+		}
+
+		id, err := redis.String(pair[0], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		fields, err := redis.StringMap(pair[1], nil)
+		if err == redis.ErrNil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry{id, fields})
+	}
+
+	return entries, nil
+}
+
+// infoFields returns the fields of an XINFO reply, a flat list of names
+// and values
+func infoFields(reply interface{}) (map[string]interface{}, error) {
+	values, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{}, len(values)/2)
+	for n := 0; n+1 < len(values); n += 2 {
+		name, err := redis.String(values[n], nil)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = values[n+1]
+	}
+
+	return fields, nil
+}
+
+// stream is a queue on Redis Streams, consumed by a consumer group. Messages
+// are deleted when acknowledged; the stream holds those ready for delivery
+// and those pending acknowledgement only.
+type stream struct {
+	pool     *redis.Pool
+	name     string
+	consumer string // Name of this consumer in the group
+
+	mu   sync.Mutex
+	stop chan struct{} // Closed to stop consuming; nil when not consuming
+}
+
+// newStream returns a named stream, creating it and its consumer group
+// when they do not exist
+func newStream(pool *redis.Pool, name string) (*stream, error) {
+	host, _ := os.Hostname()
+
+	s := &stream{
+		pool:     pool,
+		name:     name,
+		consumer: fmt.Sprintf("%s-%s", host, newID()[:8]),
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	// Read from the start, so messages published before the group existed are delivered
+	_, err := conn.Do("XGROUP", "CREATE", s.key(), streamGroup, "0", "MKSTREAM")
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// key returns the key of the stream
+func (s *stream) key() string {
+	return streamPrefix + s.name
+}
+
+// deadKey returns the key of the dead letter stream
+func (s *stream) deadKey() string {
+	return streamPrefix + s.name + "-dead"
+}
+
+// publish appends a message to the stream
+func (s *stream) publish(body []byte, priority uint8, correlationID string, headers amqp.Table) error {
+	h, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("XADD", s.key(), "*",
+		"body", body,
+		"priority", priority,
+		"correlation_id", correlationID,
+		"headers", h,
+	)
+	return err
+}
+
+// move acknowledges and deletes a message, appending it to the stream with
+// key to first unless that is empty, atomically
+func (s *stream) move(e entry, to string) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	if to != "" {
+		args := redis.Args{to, "*"}
+		for name, value := range e.fields {
+			args = args.Add(name, value)
+		}
+		conn.Send("XADD", args...)
+	}
+	conn.Send("XACK", s.key(), streamGroup, e.id)
+	conn.Send("XDEL", s.key(), e.id)
+
+	_, err := conn.Do("EXEC")
+	return err
+}
+
+// acknowledger acknowledges a message from a stream, like the broker does
+// for AMQP deliveries. Messages rejected without requeueing go to the
+// dead letter stream; requeued messages are appended to the stream again.
+type acknowledger struct {
+	stream *stream
+	entry  entry
+}
+
+// Ack removes the message from the stream
+func (a *acknowledger) Ack(tag uint64, multiple bool) error {
+	return a.stream.move(a.entry, "")
+}
+
+// Nack requeues the message or moves it to the dead letter stream
+func (a *acknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	if requeue {
+		return a.stream.move(a.entry, a.stream.key())
+	}
+
+	return a.stream.move(a.entry, a.stream.deadKey())
+}
+
+// Reject requeues the message or moves it to the dead letter stream
+func (a *acknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+// delivery returns a stream entry as AMQP delivery, so it is handled like
+// messages from AMQP brokers
+func (s *stream) delivery(e entry, redelivered bool) amqp.Delivery {
+	priority, _ := strconv.ParseUint(e.fields["priority"], 10, 8)
+
+	var headers amqp.Table
+	if h := e.fields["headers"]; h != "" {
+		// Traces are lost rather than the message
+		json.Unmarshal([]byte(h), &headers)
+	}
+
+	return amqp.Delivery{
+		Acknowledger:  &acknowledger{s, e},
+		Headers:       headers,
+		ContentType:   "application/json",
+		CorrelationId: e.fields["correlation_id"],
+		MessageId:     e.id,
+		Priority:      uint8(priority),
+		Redelivered:   redelivered,
+		RoutingKey:    s.name,
+		Body:          []byte(e.fields["body"]),
+	}
+}
+
+// claim takes over the oldest message left unacknowledged for claimAfter,
+// if any. Messages which have been delivered MaxAttempts times, which
+// likely crash their consumers, are moved to the dead letter stream.
+func (s *stream) claim(conn redis.Conn) ([]amqp.Delivery, error) {
+	pending, err := redis.Values(conn.Do("XPENDING", s.key(), streamGroup, "IDLE", milliseconds(claimAfter), "-", "+", 1))
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+
+	info, err := redis.Values(pending[0], nil)
+	if err != nil || len(info) != 4 {
+		return nil, fmt.Errorf("invalid pending entry: %v", pending[0])
+	}
+
+	id, _ := redis.String(info[0], nil)
+	deliveries, _ := redis.Int(info[3], nil)
+
+	// Claiming fails when another consumer was first
+	entries, err := parseEntries(conn.Do("XCLAIM", s.key(), streamGroup, s.consumer, milliseconds(claimAfter), id))
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+
+	if deliveries >= MaxAttempts {
+		log.WithField("queue", s.name).Warnf("Giving up after %d deliveries of %s", deliveries, id)
+		return nil, s.move(entries[0], s.deadKey())
+	}
+
+	log.WithField("queue", s.name).Infof("Claimed message %s left unacknowledged", id)
+	return []amqp.Delivery{s.delivery(entries[0], true)}, nil
+}
+
+// next returns the next messages for this consumer: a message left
+// unacknowledged by another consumer, or otherwise a new message, waiting
+// up to blockTime for one
+func (s *stream) next() ([]amqp.Delivery, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	claimed, err := s.claim(conn)
+	if err != nil || len(claimed) > 0 {
+		return claimed, err
+	}
+
+	reply, err := conn.Do("XREADGROUP", "GROUP", streamGroup, s.consumer,
+		"COUNT", 1, "BLOCK", milliseconds(blockTime),
+		"STREAMS", s.key(), ">")
+	if err != nil || reply == nil {
+		// Nil when no message arrived in time
+		return nil, err
+	}
+
+	streams, err := redis.Values(reply, nil)
+	if err != nil || len(streams) != 1 {
+		return nil, fmt.Errorf("invalid stream reply: %v", reply)
+	}
+
+	pair, err := redis.Values(streams[0], nil)
+	if err != nil || len(pair) != 2 {
+		return nil, fmt.Errorf("invalid stream reply: %v", reply)
+	}
+
+	entries, err := parseEntries(pair[1], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]amqp.Delivery, len(entries))
+	for n, e := range entries {
+		messages[n] = s.delivery(e, false)
+	}
+
+	return messages, nil
+}
+
+// retire removes this consumer from the group when it holds no pending
+// messages, so stopped consumers are not counted
+func (s *stream) retire() {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	pending, err := redis.Values(conn.Do("XPENDING", s.key(), streamGroup, "-", "+", 1, s.consumer))
+	if err != nil || len(pending) > 0 {
+		return
+	}
+
+	if _, err := conn.Do("XGROUP", "DELCONSUMER", s.key(), streamGroup, s.consumer); err != nil {
+		log.WithField("queue", s.name).WithError(err).Warn("Error removing consumer")
+	}
+}
+
+// consume delivers messages from the stream until close is called or
+// reading fails, after which the returned channel is closed. Messages
+// read but not yet taken on close are requeued.
+func (s *stream) consume() <-chan amqp.Delivery {
+	stop := make(chan struct{})
+
+	s.mu.Lock()
+	s.stop = stop
+	s.mu.Unlock()
+
+	messages := make(chan amqp.Delivery)
+
+	go func() {
+		defer close(messages)
+		defer s.retire()
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			next, err := s.next()
+			if err != nil {
+				log.WithField("queue", s.name).WithError(err).Warn("Error reading stream")
+				return
+			}
+
+			for n, msg := range next {
+				select {
+				case messages <- msg:
+				case <-stop:
+					for _, m := range next[n:] {
+						m.Nack(false, true)
+					}
+					return
+				}
+			}
+		}
+	}()
+
+	return messages
+}
+
+// close stops consuming
+func (s *stream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// depth returns the amount of messages ready for delivery: those in the
+// stream which are not pending acknowledgement
+func (s *stream) depth() (int, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	length, err := redis.Int(conn.Do("XLEN", s.key()))
+	if err != nil {
+		return 0, err
+	}
+
+	summary, err := redis.Values(conn.Do("XPENDING", s.key(), streamGroup))
+	if err != nil || len(summary) == 0 {
+		return 0, err
+	}
+
+	pending, err := redis.Int(summary[0], nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return length - pending, nil
+}
+
+// consumers returns the amount of consumers which have read from the
+// stream within claimAfter
+func (s *stream) consumers() (int, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	replies, err := redis.Values(conn.Do("XINFO", "CONSUMERS", s.key(), streamGroup))
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, reply := range replies {
+		fields, err := infoFields(reply)
+		if err != nil {
+			return 0, err
+		}
+
+		idle, err := redis.Int64(fields["idle"], nil)
+		if err == nil && idle < milliseconds(claimAfter) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// inspectStream returns the state of a named stream
+func inspectStream(pool *redis.Pool, name string) (*State, error) {
+	s := &stream{pool: pool, name: name}
+
+	messages, err := s.depth()
+	if err != nil {
+		return nil, err
+	}
+
+	consumers, err := s.consumers()
+	if err != nil {
+		return nil, err
+	}
+
+	return &State{
+		Name:      name,
+		Messages:  messages,
+		Consumers: consumers,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"testing"
+)
+
+// reply returns a stream entry as Redis returns it
+func reply(id string, fields ...string) interface{} {
+	if fields == nil {
+		// Deleted entry
+		return []interface{}{[]byte(id), nil}
+	}
+
+	values := make([]interface{}, len(fields))
+	for n, f := range fields {
+		values[n] = []byte(f)
+	}
+
+	return []interface{}{[]byte(id), values}
+}
+
+func TestParseEntries(t *testing.T) {
+	entries, err := parseEntries([]interface{}{
+		reply("1-0", "body", `{"version":1}`, "priority", "9"),
+		reply("2-0"),
+		reply("3-0", "body", `{}`),
+	}, nil)
+	if err != nil {
+		t.Fatalf("parseEntries() error %v", err)
+	}
+
+	if len(entries) != 2 || entries[0].id != "1-0" || entries[1].id != "3-0" {
+		t.Fatalf("parseEntries() = %+v, want entries 1-0 and 3-0", entries)
+	}
+	if entries[0].fields["body"] != `{"version":1}` || entries[0].fields["priority"] != "9" {
+		t.Errorf("parseEntries() fields = %v", entries[0].fields)
+	}
+
+	if _, err := parseEntries([]interface{}{"invalid"}, nil); err == nil {
+		t.Error("parseEntries() of invalid entry returned no error")
+	}
+}
+
+func TestStreamDelivery(t *testing.T) {
+	s := &stream{name: "hashes"}
+
+	d := s.delivery(entry{"1-0", map[string]string{
+		"body":           `{"version":1}`,
+		"priority":       "9",
+		"correlation_id": "abc",
+		"headers":        `{"traceparent":"00-1-2-01"}`,
+	}}, true)
+
+	if string(d.Body) != `{"version":1}` || d.Priority != 9 || d.CorrelationId != "abc" || d.RoutingKey != "hashes" || !d.Redelivered {
+		t.Errorf("delivery() = %+v", d)
+	}
+	if d.Headers["traceparent"] != "00-1-2-01" {
+		t.Errorf("delivery() headers = %v", d.Headers)
+	}
+	if d.Acknowledger == nil {
+		t.Error("delivery() has no acknowledger")
+	}
+
+	// Messages without headers or with invalid ones are delivered nonetheless
+	d = s.delivery(entry{"2-0", map[string]string{"body": `{}`, "headers": "null"}}, false)
+	if string(d.Body) != `{}` || d.Headers != nil {
+		t.Errorf("delivery() = %+v", d)
+	}
+}
@@ -0,0 +1,142 @@
+package queue
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// TaskVersion is the schema version of tasks published
+const TaskVersion = 1
+
+// MaxAttempts limits the times a task failing temporarily is tried, after
+// which it is moved to the dead letter queue
+const MaxAttempts = 5
+
+// Task is the envelope of messages on all queues
+type Task struct {
+	Version  int             `json:"version"`
+	ID       string          `json:"id"`                 // Correlation ID, shared with tasks created while performing this one
+	Key      string          `json:"key,omitempty"`      // Idempotency key, the same for redeliveries, retries and republished children
+	Priority uint8           `json:"priority"`           // Priority the task was queued with
+	Deadline *time.Time      `json:"deadline,omitempty"` // Tasks are dropped when not performed before
+	Created  *time.Time      `json:"created,omitempty"`  // First published, kept on retries; unset by older versions
+	Attempts int             `json:"attempts"`           // Times the task has been tried before
+	Source   string          `json:"source,omitempty"`   // What created the task
+	Payload  json.RawMessage `json:"payload"`
+
+	// Partition key of the task on brokers partitioning queues, kept on
+	// retries, so that tasks with the same key are consumed in order
+	Partition string `json:"partition,omitempty"`
+
+	// Message hash identifying duplicates of the task, which are published
+	// once per deduplication window. It is not part of the message, so
+	// retries are never taken for duplicates.
+	Hash string `json:"-"`
+}
+
+// newID returns a random correlation ID
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// NewTask returns a task with a new correlation ID for payload
+func NewTask(payload interface{}, priority uint8, source string) (*Task, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Task{
+		Version:  TaskVersion,
+		ID:       newID(),
+		Key:      newID(),
+		Priority: priority,
+		Source:   source,
+		Payload:  body,
+	}, nil
+}
+
+// childKey returns the idempotency key of the child with payload of the
+// task with key, so that children published again when a task is
+// performed again have the same keys
+func childKey(key string, payload []byte) string {
+	sum := sha256.Sum256(append([]byte(key+"/"), payload...))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Child returns a task for payload created while performing t, sharing its
+// correlation ID and deadline. Its key derives from that of t.
+func (t *Task) Child(payload interface{}, priority uint8, source string) (*Task, error) {
+	child, err := NewTask(payload, priority, source)
+	if err != nil {
+		return nil, err
+	}
+
+	child.ID = t.ID
+	child.Deadline = t.Deadline
+	if t.Key != "" {
+		child.Key = childKey(t.Key, child.Payload)
+	}
+
+	return child, nil
+}
+
+// Expired returns whether the deadline of the task has passed
+func (t *Task) Expired() bool {
+	return t.Deadline != nil && time.Now().After(*t.Deadline)
+}
+
+// ExpiredAfter returns whether the task has passed its deadline or was
+// created more than ttl ago; tasks without creation time don't expire by
+// age. A ttl of 0 sets no maximum age.
+func (t *Task) ExpiredAfter(ttl time.Duration) bool {
+	if t.Expired() {
+		return true
+	}
+
+	return ttl > 0 && t.Created != nil && time.Since(*t.Created) > ttl
+}
+
+// SetTTL sets the deadline of the task to ttl from now
+func (t *Task) SetTTL(ttl time.Duration) {
+	deadline := time.Now().Add(ttl).UTC()
+	t.Deadline = &deadline
+}
+
+// Retry returns the task for trying again after a temporary failure,
+// counting the attempt, or false when it has been tried MaxAttempts times
+func (t *Task) Retry() (*Task, bool) {
+	retry := *t
+	retry.Attempts++
+
+	return &retry, retry.Attempts < MaxAttempts
+}
+
+// ParseTask reads a task from a message body. Bodies without version are
+// payloads published before tasks were introduced, returned in a new task
+// without key, as are tasks published before keys were introduced.
+func ParseTask(body []byte) (*Task, error) {
+	t := &Task{}
+	if err := json.Unmarshal(body, t); err != nil {
+		return nil, err
+	}
+
+	if t.Version == 0 {
+		return &Task{
+			Version: TaskVersion,
+			ID:      newID(),
+			Payload: json.RawMessage(bytes.TrimSpace(body)),
+		}, nil
+	}
+
+	return t, nil
+}
@@ -0,0 +1,164 @@
+package queue
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTask(t *testing.T) {
+	tests := []struct {
+		body     string
+		valid    bool
+		version  int
+		priority uint8
+		payload  string
+	}{
+		{`{"version":1,"id":"a","priority":3,"attempts":2,"payload":{"hash":"h"}}`, true, 1, 3, `{"hash":"h"}`},
+		// Bare payloads from before tasks were introduced
+		{` {"hash":"h"} `, true, 1, 0, `{"hash":"h"}`},
+		{`not json`, false, 0, 0, ""},
+	}
+
+	for _, test := range tests {
+		task, err := ParseTask([]byte(test.body))
+		if (err == nil) != test.valid {
+			t.Errorf("ParseTask(%s) error = %v, valid %v", test.body, err, test.valid)
+			continue
+		}
+		if !test.valid {
+			continue
+		}
+
+		if task.Version != test.version || task.Priority != test.priority || string(task.Payload) != test.payload {
+			t.Errorf("ParseTask(%s) = %+v", test.body, task)
+		}
+		if task.ID == "" {
+			t.Errorf("ParseTask(%s) has no ID", test.body)
+		}
+	}
+}
+
+func TestTaskJSON(t *testing.T) {
+	task, err := NewTask(map[string]string{"hash": "h"}, 5, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	task.SetTTL(time.Hour)
+
+	body, err := json.Marshal(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseTask(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.ID != task.ID || parsed.Key != task.Key || parsed.Priority != 5 || parsed.Source != "test" || !parsed.Deadline.Equal(*task.Deadline) {
+		t.Errorf("ParseTask(%s) = %+v, want %+v", body, parsed, task)
+	}
+}
+
+func TestChild(t *testing.T) {
+	parent, err := NewTask("parent", 5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent.SetTTL(time.Hour)
+	parent.Attempts = 2
+
+	child, err := parent.Child("child", 3, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if child.ID != parent.ID || child.Deadline != parent.Deadline || child.Attempts != 0 || child.Priority != 3 {
+		t.Errorf("Child() = %+v of %+v", child, parent)
+	}
+
+	// Children published again have the same key
+	again, err := parent.Child("child", 3, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := parent.Child("other", 3, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if child.Key == "" || child.Key == parent.Key || again.Key != child.Key || other.Key == child.Key {
+		t.Errorf("Child() keys %s, %s and %s of %s", child.Key, again.Key, other.Key, parent.Key)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	tests := []struct {
+		ttl     time.Duration
+		expired bool
+	}{
+		{0, false},
+		{time.Hour, false},
+		{-time.Second, true},
+	}
+
+	for _, test := range tests {
+		task := &Task{}
+		if test.ttl != 0 {
+			task.SetTTL(test.ttl)
+		}
+
+		if got := task.Expired(); got != test.expired {
+			t.Errorf("Expired() with TTL %s = %v, want %v", test.ttl, got, test.expired)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     int
+		ok       bool
+	}{
+		{0, 1, true},
+		{MaxAttempts - 2, MaxAttempts - 1, true},
+		{MaxAttempts - 1, MaxAttempts, false},
+	}
+
+	for _, test := range tests {
+		task := &Task{ID: "a", Key: "k", Attempts: test.attempts}
+
+		retry, ok := task.Retry()
+		if retry.Attempts != test.want || ok != test.ok {
+			t.Errorf("Retry() after %d attempts = %d, %v, want %d, %v", test.attempts, retry.Attempts, ok, test.want, test.ok)
+		}
+		if task.Attempts != test.attempts || retry.ID != task.ID || retry.Key != task.Key {
+			t.Errorf("Retry() changed task %+v, returned %+v", task, retry)
+		}
+	}
+}
+
+func TestExpiredAfter(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour)
+	deadline := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		task    *Task
+		ttl     time.Duration
+		expired bool
+	}{
+		{&Task{Created: &old}, time.Hour, true},
+		{&Task{Created: &old}, 3 * time.Hour, false},
+		{&Task{Created: &old}, 0, false},
+		// Tasks published by older versions don't expire by age
+		{&Task{}, time.Hour, false},
+		{&Task{Deadline: &deadline}, 0, true},
+	}
+
+	for n, test := range tests {
+		if expired := test.task.ExpiredAfter(test.ttl); expired != test.expired {
+			t.Errorf("test %d: ExpiredAfter(%s) = %v, want %v", n, test.ttl, expired, test.expired)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"context"
+	"github.com/ipfs-search/ipfs-search/tracing"
+	"github.com/streadway/amqp"
+)
+
+// headerCarrier carries trace context in AMQP message headers
+type headerCarrier amqp.Table
+
+// Get returns the value of a header, if it is a string
+func (h headerCarrier) Get(key string) string {
+	value, _ := h[key].(string)
+	return value
+}
+
+// Set sets a header
+func (h headerCarrier) Set(key, value string) {
+	h[key] = value
+}
+
+// Keys returns the names of all headers
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// ContextFromDelivery returns ctx with the trace context the message was
+// published with, if any
+func ContextFromDelivery(ctx context.Context, d *amqp.Delivery) context.Context {
+	if d.Headers == nil {
+		return ctx
+	}
+
+	return tracing.Extract(ctx, headerCarrier(d.Headers))
+}
@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Trend describes how the depth of a queue develops
+type Trend struct {
+	// Rate is the change in messages per second; negative when the queue
+	// is being drained faster than it is filled
+	Rate float64 `json:"rate"`
+
+	// ETA is the estimated time until the queue is empty at Rate, in
+	// seconds; 0 for empty queues and -1 when it is not draining
+	ETA float64 `json:"eta_seconds"`
+}
+
+// Draining returns whether the queue is expected to become empty
+func (t *Trend) Draining() bool {
+	return t.ETA >= 0
+}
+
+// sample is the depth of a queue at a moment
+type sample struct {
+	time     time.Time
+	messages int
+}
+
+// Estimator estimates trends of queues from the samples of their depth
+// taken within a window
+type Estimator struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	samples map[string][]sample // By queue name, oldest first
+}
+
+// NewEstimator returns an estimator using samples of the last window
+func NewEstimator(window time.Duration) *Estimator {
+	return &Estimator{
+		window:  window,
+		samples: make(map[string][]sample),
+	}
+}
+
+// slope returns the least squares rate of change in messages per second
+func slope(samples []sample) float64 {
+	var sumX, sumY, sumXY, sumXX float64
+	n := float64(len(samples))
+
+	for _, s := range samples {
+		x := s.time.Sub(samples[0].time).Seconds()
+		y := float64(s.messages)
+
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	d := n*sumXX - sumX*sumX
+	if d == 0 {
+		return 0
+	}
+
+	return (n*sumXY - sumX*sumY) / d
+}
+
+// Add records the depth of a queue at a moment and returns its trend, or
+// nil while there are not enough samples to estimate it
+func (e *Estimator) Add(name string, t time.Time, messages int) *Trend {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	samples := append(e.samples[name], sample{t, messages})
+
+	// Drop samples outside the window, keeping at least two
+	cutoff := t.Add(-e.window)
+	for len(samples) > 2 && samples[0].time.Before(cutoff) {
+		samples = samples[1:]
+	}
+	e.samples[name] = samples
+
+	if len(samples) < 2 || !samples[len(samples)-1].time.After(samples[0].time) {
+		return nil
+	}
+
+	trend := &Trend{Rate: slope(samples)}
+
+	switch {
+	case messages == 0:
+		trend.ETA = 0
+	case trend.Rate < 0:
+		trend.ETA = math.Round(float64(messages) / -trend.Rate)
+	default:
+		trend.ETA = -1
+	}
+
+	return trend
+}
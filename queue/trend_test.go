@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimator(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	tests := []struct {
+		name   string
+		depths []int
+		rate   float64
+		eta    float64
+	}{
+		{"draining", []int{100, 90, 80}, -1, 80},
+		{"growing", []int{10, 20, 30}, 1, -1},
+		{"steady", []int{50, 50}, 0, -1},
+		{"empty", []int{10, 0}, -1, 0},
+	}
+
+	for _, test := range tests {
+		e := NewEstimator(time.Minute)
+
+		var trend *Trend
+		for n, depth := range test.depths {
+			trend = e.Add("files", start.Add(time.Duration(n)*10*time.Second), depth)
+			if n == 0 && trend != nil {
+				t.Errorf("%s: Add() with a single sample = %v, want nil", test.name, trend)
+			}
+		}
+
+		if trend == nil {
+			t.Fatalf("%s: Add() = nil", test.name)
+		}
+		if trend.Rate != test.rate || trend.ETA != test.eta {
+			t.Errorf("%s: Add() = %+v, want rate %v, ETA %v", test.name, trend, test.rate, test.eta)
+		}
+	}
+}
+
+func TestEstimatorWindow(t *testing.T) {
+	e := NewEstimator(time.Minute)
+	start := time.Unix(1000, 0)
+
+	// Growth long ago is forgotten
+	e.Add("files", start, 0)
+	e.Add("files", start.Add(time.Minute), 1000)
+	e.Add("files", start.Add(5*time.Minute), 900)
+	trend := e.Add("files", start.Add(5*time.Minute+10*time.Second), 890)
+
+	if trend.Rate != -1 {
+		t.Errorf("Add() rate = %v, want -1 within window", trend.Rate)
+	}
+}
@@ -2,7 +2,9 @@ package queue
 
 import (
 	"context"
-	"log"
+	log "github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+	"time"
 )
 
 // Worker instantiates and calls MessageWorker for every Message in Queue
@@ -14,12 +16,13 @@ type Worker struct {
 
 // NewWorker returns a worker for a given queue with error channel. The
 // MessageWorkerFactory is itself wrapped in a messageWorker for proper
-// error handling etc.
-func NewWorker(errc chan<- error, queue *Queue, factory MessageWorkerFactory) *Worker {
+// error handling etc. The optional onPanic is called for messages on
+// which workers panic.
+func NewWorker(errc chan<- error, queue *Queue, factory MessageWorkerFactory, onPanic PanicHandler) *Worker {
 	return &Worker{
 		errChan: errc,
 		queue:   queue,
-		factory: newMessageWorker(factory),
+		factory: newMessageWorker(queue, factory, onPanic),
 	}
 }
 
@@ -28,6 +31,26 @@ func (w *Worker) String() string {
 	return w.queue.String()
 }
 
+// consume (re-)registers the consumer, retrying with backoff until the
+// context is cancelled
+func (w *Worker) consume(ctx context.Context) (<-chan amqp.Delivery, error) {
+	for attempt := 0; ; attempt++ {
+		msgs, err := w.queue.Consume()
+		if err == nil {
+			return msgs, nil
+		}
+
+		wait := backoff(attempt)
+		log.WithField("queue", w.String()).WithError(err).Warnf("Error consuming, retrying in %s", wait)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
 // Work performs consumption of messages in the worker's Queue
 func (w *Worker) Work(ctx context.Context) error {
 	msgs, err := w.queue.Consume()
@@ -40,9 +63,28 @@ func (w *Worker) Work(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			// Context canceled, stop processing messages
-			log.Printf("Stopping worker %s: %s", w, ctx.Err())
+			log.WithField("queue", w.String()).Debugf("Stopping worker: %s", ctx.Err())
+
+			// Close channel, cancelling the consumer and returning
+			// prefetched messages to the queue
+			if err := w.queue.Close(); err != nil {
+				log.WithField("queue", w.String()).WithError(err).Warn("Error closing channel")
+			}
+
 			return ctx.Err()
-		case msg := <-msgs:
+		case msg, ok := <-msgs:
+			if !ok {
+				// Channel closed by broker or connection failure
+				log.WithField("queue", w.String()).Warn("Consumer closed, re-registering")
+
+				msgs, err = w.consume(ctx)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
 			worker := w.factory(&msg)
 			err = worker.Work(ctx)
 			if err != nil {
@@ -0,0 +1,55 @@
+/*
+Package relevance implements evaluation of search ranking against labeled queries.
+*/
+package relevance
+
+import (
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/crawler"
+	yaml "gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+// Judgement lists the hashes relevant for a query
+type Judgement struct {
+	Query    string   `yaml:"query"`
+	Relevant []string `yaml:"relevant"`
+}
+
+// ReadJudgements reads a list of judgements from a YAML file, e.g.:
+//
+//	# judgements.yml
+//	- query: "ipfs whitepaper"
+//	  relevant:
+//	    - QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX
+//
+// Relevant hashes are normalized, so they match indexed documents
+// regardless of CID version or encoding.
+func ReadJudgements(filename string) ([]Judgement, error) {
+	bs, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseJudgements(bs)
+}
+
+// parseJudgements parses YAML judgements, normalizing relevant hashes
+func parseJudgements(bs []byte) ([]Judgement, error) {
+	var judgements []Judgement
+	err := yaml.Unmarshal(bs, &judgements)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, j := range judgements {
+		for n, hash := range j.Relevant {
+			j.Relevant[n], err = crawler.NormalizeHash(hash)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hash '%s' for query '%s': %v", hash, j.Query, err)
+			}
+		}
+	}
+
+	return judgements, nil
+}
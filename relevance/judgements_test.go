@@ -0,0 +1,83 @@
+package relevance
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// TestReadJudgementsExample checks the example in the doc comment of
+// ReadJudgements is valid, as gofmt reindents code blocks in comments
+func TestReadJudgementsExample(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "judgements.go", nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var example []string
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == "ReadJudgements" {
+			for _, line := range strings.Split(fn.Doc.Text(), "\n") {
+				if strings.HasPrefix(line, "\t") {
+					example = append(example, strings.TrimPrefix(line, "\t"))
+				}
+			}
+		}
+	}
+	if len(example) == 0 {
+		t.Fatal("no example in doc comment of ReadJudgements")
+	}
+
+	judgements, err := parseJudgements([]byte(strings.Join(example, "\n")))
+	if err != nil {
+		t.Fatalf("parsing example: %v", err)
+	}
+	if len(judgements) != 1 || judgements[0].Query != "ipfs whitepaper" || len(judgements[0].Relevant) != 1 {
+		t.Errorf("example = %+v, want one query with a relevant hash", judgements)
+	}
+}
+
+func TestParseJudgements(t *testing.T) {
+	judgements, err := parseJudgements([]byte(`
+- query: "ipfs whitepaper"
+  relevant:
+    - QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX
+    - bafybeibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq
+    - zb2rhZQtvTZNtTy2q2E5fsG7tm6QGxudcE51HosNR1acaYDYF
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(judgements) != 1 {
+		t.Fatalf("got %d judgements, want 1", len(judgements))
+	}
+
+	want := []string{
+		"QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX",
+		"QmR7GSQM93Cx5eAg6a6yRzNde1FQv7uL6X1o4k7zrJa3LX",
+		"bafkreibjevkcernyjeigpgg3ir2pztybwntib3eiynjzteupzcq324ctzq",
+	}
+
+	relevant := judgements[0].Relevant
+	if len(relevant) != len(want) {
+		t.Fatalf("got %d relevant hashes, want %d", len(relevant), len(want))
+	}
+	for n := range want {
+		if relevant[n] != want[n] {
+			t.Errorf("relevant[%d] = %s, want %s", n, relevant[n], want[n])
+		}
+	}
+}
+
+func TestParseJudgementsInvalidHash(t *testing.T) {
+	_, err := parseJudgements([]byte(`
+- query: test
+  relevant: [notahash]
+`))
+	if err == nil {
+		t.Error("expected error for invalid hash")
+	}
+}
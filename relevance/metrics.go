@@ -0,0 +1,84 @@
+package relevance
+
+import (
+	"math"
+)
+
+// Result contains evaluation metrics for a single query
+type Result struct {
+	Query  string
+	NDCG   float64
+	Recall float64
+}
+
+// discount returns the DCG discount for a 0-based rank
+func discount(rank int) float64 {
+	return 1 / math.Log2(float64(rank+2))
+}
+
+// Evaluate computes nDCG@k and recall@k for ranked result hashes, using
+// binary relevance. The ideal ranking has all relevant hashes at the top.
+// Without relevant hashes, or for k <= 0, both are 0.
+func (j *Judgement) Evaluate(results []string, k int) *Result {
+	if k < 0 {
+		k = 0
+	}
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	relevant := make(map[string]bool, len(j.Relevant))
+	for _, hash := range j.Relevant {
+		relevant[hash] = true
+	}
+	total := len(relevant)
+
+	var dcg float64
+	found := 0
+	for rank, hash := range results {
+		if relevant[hash] {
+			dcg += discount(rank)
+			found++
+
+			// Prevent counting duplicates
+			delete(relevant, hash)
+		}
+	}
+
+	var idcg float64
+	for rank := 0; rank < total && rank < k; rank++ {
+		idcg += discount(rank)
+	}
+
+	result := &Result{
+		Query: j.Query,
+	}
+
+	if idcg > 0 {
+		result.NDCG = dcg / idcg
+	}
+	if total > 0 {
+		result.Recall = float64(found) / float64(total)
+	}
+
+	return result
+}
+
+// Mean returns the average metrics over multiple results
+func Mean(results []*Result) *Result {
+	mean := &Result{}
+
+	if len(results) == 0 {
+		return mean
+	}
+
+	for _, r := range results {
+		mean.NDCG += r.NDCG
+		mean.Recall += r.Recall
+	}
+
+	mean.NDCG /= float64(len(results))
+	mean.Recall /= float64(len(results))
+
+	return mean
+}
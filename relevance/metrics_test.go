@@ -0,0 +1,70 @@
+package relevance
+
+import (
+	"math"
+	"testing"
+)
+
+// approx returns whether a and b are equal up to rounding errors
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestEvaluate(t *testing.T) {
+	j := &Judgement{
+		Query:    "test",
+		Relevant: []string{"a", "b"},
+	}
+
+	tests := []struct {
+		name    string
+		results []string
+		k       int
+		ndcg    float64
+		recall  float64
+	}{
+		{"ideal", []string{"a", "b", "c"}, 3, 1, 1},
+		{"reversed", []string{"b", "a"}, 2, 1, 1},
+		{"none", []string{"c", "d"}, 2, 0, 0},
+		{"empty", nil, 10, 0, 0},
+		{"second", []string{"c", "a"}, 2, discount(1) / (discount(0) + discount(1)), 0.5},
+		{"cut off", []string{"c", "a", "b"}, 1, 0, 0},
+		{"duplicates", []string{"a", "a"}, 2, discount(0) / (discount(0) + discount(1)), 0.5},
+		{"k zero", []string{"a", "b"}, 0, 0, 0},
+		{"k negative", []string{"a", "b"}, -1, 0, 0},
+	}
+
+	for _, test := range tests {
+		r := j.Evaluate(test.results, test.k)
+
+		if math.IsNaN(r.NDCG) || !approx(r.NDCG, test.ndcg) {
+			t.Errorf("%s: NDCG = %v, want %v", test.name, r.NDCG, test.ndcg)
+		}
+		if !approx(r.Recall, test.recall) {
+			t.Errorf("%s: Recall = %v, want %v", test.name, r.Recall, test.recall)
+		}
+	}
+}
+
+func TestEvaluateNoRelevant(t *testing.T) {
+	j := &Judgement{Query: "test"}
+
+	r := j.Evaluate([]string{"a"}, 10)
+	if r.NDCG != 0 || r.Recall != 0 {
+		t.Errorf("Evaluate() = %+v, want zero metrics", r)
+	}
+}
+
+func TestMean(t *testing.T) {
+	if m := Mean(nil); m.NDCG != 0 || m.Recall != 0 {
+		t.Errorf("Mean(nil) = %+v, want zero metrics", m)
+	}
+
+	m := Mean([]*Result{
+		{NDCG: 1, Recall: 0.5},
+		{NDCG: 0, Recall: 1},
+	})
+	if !approx(m.NDCG, 0.5) || !approx(m.Recall, 0.75) {
+		t.Errorf("Mean() = %+v, want NDCG 0.5 and Recall 0.75", m)
+	}
+}
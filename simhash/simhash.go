@@ -0,0 +1,105 @@
+/*
+Package simhash computes locality sensitive signatures of text, so
+near-duplicate documents, such as mirrors with tiny differences, can be
+found by comparing signatures rather than content.
+*/
+package simhash
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+const (
+	// shingleSize is the amount of consecutive words hashed together
+	shingleSize = 3
+
+	// bandCount is the amount of parts a signature is split into for
+	// lookups; signatures within MaxDistance share at least one band
+	bandCount = 4
+	bandBits  = 64 / bandCount
+)
+
+// MaxDistance is the largest amount of differing bits at which documents
+// are considered near-duplicates
+const MaxDistance = bandCount - 1
+
+// words returns the lowercased words in text
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Signature returns the simhash of the word shingles of text; false when
+// text contains no words
+func Signature(text string) (uint64, bool) {
+	w := words(text)
+	if len(w) == 0 {
+		return 0, false
+	}
+
+	size := shingleSize
+	if len(w) < size {
+		size = len(w)
+	}
+
+	var weights [64]int
+	for n := 0; n+size <= len(w); n++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(w[n:n+size], " ")))
+		sum := h.Sum64()
+
+		for b := 0; b < 64; b++ {
+			if sum&(1<<uint(b)) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+
+	var signature uint64
+	for b, weight := range weights {
+		if weight > 0 {
+			signature |= 1 << uint(b)
+		}
+	}
+
+	return signature, true
+}
+
+// Distance returns the amount of bits by which signatures differ
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Format returns the hexadecimal representation of a signature
+func Format(signature uint64) string {
+	return fmt.Sprintf("%016x", signature)
+}
+
+// Parse returns the signature for its hexadecimal representation
+func Parse(s string) (uint64, error) {
+	var signature uint64
+	if _, err := fmt.Sscanf(s, "%016x", &signature); err != nil {
+		return 0, fmt.Errorf("invalid signature %q: %v", s, err)
+	}
+
+	return signature, nil
+}
+
+// Bands returns the parts of a signature, prefixed with their position, to
+// look up candidate near-duplicates by exact match
+func Bands(signature uint64) []string {
+	bands := make([]string, bandCount)
+	for n := range bands {
+		band := (signature >> uint(n*bandBits)) & (1<<bandBits - 1)
+		bands[n] = fmt.Sprintf("%d:%04x", n, band)
+	}
+
+	return bands
+}
@@ -0,0 +1,95 @@
+package simhash
+
+import (
+	"strings"
+	"testing"
+)
+
+const text = `The InterPlanetary File System is a protocol and peer-to-peer network
+for storing and sharing data in a distributed file system. IPFS uses content
+addressing to uniquely identify each file in a global namespace connecting
+all computing devices. IPFS allows users to host and receive content in a
+manner similar to BitTorrent. As opposed to a centrally located server, IPFS is
+built around a decentralized system of user-operators who hold a portion of
+the overall data, creating a resilient system of file storage and sharing. Any
+user in the network can serve a file by its content address, and other peers
+in the network can find and request that content from any node who has it
+using a distributed hash table. In contrast to BitTorrent, IPFS aims to create
+a single global network. This means that if two users publish a block of data
+with the same hash, the peers downloading the content from one user will also
+exchange data with the ones downloading it from the other.`
+
+func TestSignature(t *testing.T) {
+	original, ok := Signature(text)
+	if !ok {
+		t.Fatal("Signature() of text = false")
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		similar bool
+	}{
+		{"identical", text, true},
+		{"case and punctuation", strings.ToUpper(strings.Replace(text, ".", "!", -1)), true},
+		{"small change", strings.Replace(text, "global", "single", 1), true},
+		{"different", "A recipe for pancakes: mix flour, eggs and milk, then fry in butter until golden brown on both sides.", false},
+	}
+
+	for _, test := range tests {
+		signature, ok := Signature(test.text)
+		if !ok {
+			t.Errorf("%s: Signature() = false", test.name)
+			continue
+		}
+
+		d := Distance(original, signature)
+		if similar := d <= MaxDistance; similar != test.similar {
+			t.Errorf("%s: distance %d, want similar %v", test.name, d, test.similar)
+		}
+	}
+
+	if _, ok := Signature(" ... "); ok {
+		t.Error("Signature() without words = true")
+	}
+}
+
+func TestBands(t *testing.T) {
+	a := uint64(0x0123456789abcdef)
+
+	tests := []struct {
+		b      uint64
+		shared int
+	}{
+		{a, 4},
+		{a ^ 1, 3},
+		{a ^ (1 | 1<<16 | 1<<32), 1},
+		{a ^ (1 | 1<<16 | 1<<32 | 1<<48), 0},
+	}
+
+	for _, test := range tests {
+		shared := 0
+		for n, band := range Bands(test.b) {
+			if band == Bands(a)[n] {
+				shared++
+			}
+		}
+
+		if shared != test.shared {
+			t.Errorf("Bands(%x) shares %d bands with %x, want %d", test.b, shared, a, test.shared)
+		}
+	}
+}
+
+func TestFormatParse(t *testing.T) {
+	for _, signature := range []uint64{0, 1, 0xfedcba9876543210} {
+		parsed, err := Parse(Format(signature))
+		if err != nil || parsed != signature {
+			t.Errorf("Parse(Format(%x)) = %x, %v", signature, parsed, err)
+		}
+	}
+
+	if _, err := Parse("nothex"); err == nil {
+		t.Error("Parse(nothex) succeeded")
+	}
+}
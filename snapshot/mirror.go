@@ -0,0 +1,153 @@
+package snapshot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs/go-ipfs-api"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// importBatchSize is the amount of documents indexed at once
+const importBatchSize = 1000
+
+// maxLineSize limits the size of a single document in a shard
+const maxLineSize = 16 * 1024 * 1024
+
+// Mirror imports snapshots published by another instance into the local
+// index, instead of crawling
+type Mirror struct {
+	Indexer *indexer.Indexer
+	Shell   *shell.Shell
+	Name    string // IPNS name or DNSLink domain snapshots are published under
+
+	current string // Root of the last imported snapshot
+}
+
+// readManifest returns the manifest of a snapshot
+func (m *Mirror) readManifest(root string) (*Manifest, error) {
+	r, err := m.Shell.Cat(root + "/" + manifestName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	manifest := new(Manifest)
+	if err := json.NewDecoder(r).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+
+	return manifest, nil
+}
+
+// importShard indexes the documents in a shard, in batches
+func (m *Mirror) importShard(ctx context.Context, shard *Shard) error {
+	r, err := m.Shell.Cat(shard.Hash)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(nil, maxLineSize)
+
+	batch := make([]indexer.Document, 0, importBatchSize)
+	for scanner.Scan() {
+		var d indexer.Document
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			return err
+		}
+
+		batch = append(batch, d)
+		if len(batch) == importBatchSize {
+			if err := m.Indexer.Import(ctx, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return m.Indexer.Import(ctx, batch)
+}
+
+// resolve returns the root of the currently published snapshot
+func (m *Mirror) resolve() (string, error) {
+	name := m.Name
+	if !strings.HasPrefix(name, "/ipns/") {
+		name = "/ipns/" + name
+	}
+
+	return m.Shell.ResolvePath(name)
+}
+
+// importSnapshot imports a snapshot, after its bases which have not been
+// imported yet in case of differential snapshots
+func (m *Mirror) importSnapshot(ctx context.Context, root string) error {
+	manifest, err := m.readManifest(root)
+	if err != nil {
+		return err
+	}
+
+	if manifest.Base != "" && manifest.Base != m.current {
+		if err := m.importSnapshot(ctx, manifest.Base); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+
+	for _, shard := range manifest.Shards {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		log.WithField("shard", shard.Name).WithField("documents", shard.Documents).Debug("Importing shard")
+
+		if err := m.importShard(ctx, &shard); err != nil {
+			return fmt.Errorf("error importing %s: %v", shard.Name, err)
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"root":      root,
+		"created":   manifest.Created,
+		"base":      manifest.Base,
+		"documents": manifest.Documents,
+		"duration":  time.Since(start),
+	}).Info("Imported snapshot")
+
+	m.current = root
+
+	return nil
+}
+
+// Sync imports the currently published snapshot, unless it has been
+// imported already, returning its root. For differential snapshots, only
+// snapshots published since the last import are imported. Documents
+// removed from snapshots are not removed from the local index.
+func (m *Mirror) Sync(ctx context.Context) (string, error) {
+	root, err := m.resolve()
+	if err != nil {
+		return "", fmt.Errorf("error resolving %s: %v", m.Name, err)
+	}
+
+	if root == m.current {
+		log.WithField("root", root).Debug("Snapshot unchanged")
+		return root, nil
+	}
+
+	return root, m.importSnapshot(ctx, root)
+}
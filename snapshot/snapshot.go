@@ -0,0 +1,245 @@
+// Package snapshot publishes the index to IPFS as a directory of
+// compressed JSON lines shards under an IPNS name, so third parties can
+// use the index without depending on the search API.
+//
+// Snapshots are either full, or differential, in which case they only
+// contain documents seen since their base snapshot, which is linked from
+// them as baseName.
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ipfs-search/ipfs-search/indexer"
+	"github.com/ipfs/go-ipfs-api"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// manifestName is the name of the file describing a snapshot
+const manifestName = "manifest.json"
+
+// baseName is the name of the link to the base of differential snapshots
+const baseName = "base"
+
+// Shard is a file in the snapshot containing a part of the documents
+type Shard struct {
+	Name      string `json:"name"`
+	Hash      string `json:"hash"`
+	Documents int    `json:"documents"`
+}
+
+// Manifest describes a snapshot and is stored in its root
+type Manifest struct {
+	Created   time.Time  `json:"created"`
+	Since     *time.Time `json:"since,omitempty"` // Documents seen since, for differential snapshots
+	Base      string     `json:"base,omitempty"`  // Snapshot this differential snapshot applies to
+	Documents int        `json:"documents"`
+	Shards    []Shard    `json:"shards"`
+}
+
+// Publisher writes snapshots of the index to IPFS and publishes them
+type Publisher struct {
+	Indexer      *indexer.Indexer
+	Shell        *shell.Shell
+	Key          string        // Name of the IPNS key to publish under
+	Lifetime     time.Duration // Validity of published IPNS records
+	ShardSize    int           // Maximum amount of documents per shard
+	FullInterval time.Duration // Time between full snapshots, differential in between; 0 for full snapshots only
+
+	previous *Manifest // Last published snapshot, unpinned after publishing the next
+	root     string    // Root of the last published snapshot
+	full     time.Time // Creation of the last full snapshot
+}
+
+// shardWriter collects documents into a gzipped JSON lines shard
+type shardWriter struct {
+	buf       bytes.Buffer
+	gz        *gzip.Writer
+	documents int
+}
+
+// newShardWriter returns an empty shard
+func newShardWriter() *shardWriter {
+	w := new(shardWriter)
+	w.gz = gzip.NewWriter(&w.buf)
+	return w
+}
+
+// write adds a document to the shard
+func (w *shardWriter) write(d *indexer.Document) error {
+	w.documents++
+	return json.NewEncoder(w.gz).Encode(d)
+}
+
+// add closes the shard and adds it to IPFS unpinned; it is pinned as
+// part of the snapshot
+func (p *Publisher) add(w *shardWriter, n int) (*Shard, error) {
+	if err := w.gz.Close(); err != nil {
+		return nil, err
+	}
+
+	hash, err := p.Shell.Add(&w.buf, shell.Pin(false))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Shard{
+		Name:      fmt.Sprintf("documents-%05d.jsonl.gz", n),
+		Hash:      hash,
+		Documents: w.documents,
+	}, nil
+}
+
+// differential returns whether the next snapshot should be differential
+func (p *Publisher) differential(now time.Time) bool {
+	return p.previous != nil && p.FullInterval > 0 && now.Sub(p.full) < p.FullInterval
+}
+
+// write exports the index in shards, returning the manifest. Differential
+// snapshots contain documents seen since the previous snapshot.
+func (p *Publisher) write(ctx context.Context) (*Manifest, error) {
+	m := &Manifest{
+		Created: time.Now().UTC(),
+		Shards:  []Shard{},
+	}
+
+	var since time.Time
+	if p.differential(m.Created) {
+		since = p.previous.Created
+		m.Since = &since
+		m.Base = p.root
+	}
+
+	w := newShardWriter()
+
+	flush := func() error {
+		shard, err := p.add(w, len(m.Shards))
+		if err != nil {
+			return err
+		}
+
+		log.WithField("shard", shard.Name).WithField("hash", shard.Hash).Debug("Added shard")
+
+		m.Shards = append(m.Shards, *shard)
+		m.Documents += shard.Documents
+		w = newShardWriter()
+
+		return nil
+	}
+
+	err := p.Indexer.Export(ctx, since, func(d *indexer.Document) error {
+		if err := w.write(d); err != nil {
+			return err
+		}
+
+		if w.documents == p.ShardSize {
+			return flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if w.documents > 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// directory creates the snapshot directory with shards and manifest, and
+// the base for differential snapshots, returning its hash
+func (p *Publisher) directory(m *Manifest) (string, error) {
+	root, err := p.Shell.NewObject("unixfs-dir")
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := p.Shell.Add(bytes.NewReader(manifest), shell.Pin(false))
+	if err != nil {
+		return "", err
+	}
+
+	root, err = p.Shell.PatchLink(root, manifestName, hash, false)
+	if err != nil {
+		return "", err
+	}
+
+	for _, shard := range m.Shards {
+		root, err = p.Shell.PatchLink(root, shard.Name, shard.Hash, false)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Linking the base keeps it pinned along with this snapshot
+	if m.Base != "" {
+		root, err = p.Shell.PatchLink(root, baseName, m.Base, false)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return root, nil
+}
+
+// Publish writes a snapshot of the index, pins it and publishes it under
+// the IPNS key, returning the root hash. The previously published
+// snapshot is unpinned; differential snapshots keep their bases pinned.
+func (p *Publisher) Publish(ctx context.Context) (string, error) {
+	start := time.Now()
+
+	m, err := p.write(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error writing snapshot: %v", err)
+	}
+
+	root, err := p.directory(m)
+	if err != nil {
+		return "", fmt.Errorf("error creating snapshot directory: %v", err)
+	}
+
+	if err := p.Shell.Pin(root); err != nil {
+		return "", fmt.Errorf("error pinning snapshot: %v", err)
+	}
+
+	published, err := p.Shell.PublishWithDetails(root, p.Key, p.Lifetime, 0, false)
+	if err != nil {
+		return "", fmt.Errorf("error publishing snapshot: %v", err)
+	}
+
+	log.WithFields(log.Fields{
+		"root":      root,
+		"name":      published.Name,
+		"documents": m.Documents,
+		"shards":    len(m.Shards),
+		"base":      m.Base,
+		"duration":  time.Since(start),
+	}).Info("Published snapshot")
+
+	if p.root != "" && p.root != root {
+		if err := p.Shell.Unpin(p.root); err != nil {
+			log.WithError(err).WithField("root", p.root).Warn("Error unpinning previous snapshot")
+		}
+	}
+
+	if m.Base == "" {
+		p.full = m.Created
+	}
+	p.previous, p.root = m, root
+
+	return root, nil
+}
@@ -0,0 +1,73 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+)
+
+// fit returns the dimensions of a w by h image scaled down to fit within
+// max by max, keeping its aspect ratio
+func fit(w, h, max int) (int, int) {
+	if w <= max && h <= max {
+		return w, h
+	}
+
+	if w >= h {
+		h = h * max / w
+		w = max
+	} else {
+		w = w * max / h
+		h = max
+	}
+
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	return w, h
+}
+
+// scale returns img scaled down to fit within max by max pixels, averaging
+// the source pixels covered by every target pixel
+func scale(img image.Image, max int) *image.RGBA {
+	src := img.Bounds()
+	w, h := fit(src.Dx(), src.Dy(), max)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := src.Min.Y + y*src.Dy()/h
+		y1 := src.Min.Y + (y+1)*src.Dy()/h
+		if y1 == y0 {
+			y1++
+		}
+
+		for x := 0; x < w; x++ {
+			x0 := src.Min.X + x*src.Dx()/w
+			x1 := src.Min.X + (x+1)*src.Dx()/w
+			if x1 == x0 {
+				x1++
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,101 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ipfs/go-ipfs-api"
+	"net/http"
+	"strings"
+)
+
+// store stores thumbnails, setting their location on t
+type store interface {
+	put(ctx context.Context, hash string, data []byte, t *Thumbnail) error
+}
+
+// ipfsStore adds thumbnails to IPFS, pinning them
+type ipfsStore struct {
+	shell *shell.Shell
+}
+
+// put adds the thumbnail to IPFS, setting its hash
+func (s *ipfsStore) put(ctx context.Context, hash string, data []byte, t *Thumbnail) error {
+	added, err := s.shell.Add(bytes.NewReader(data), shell.Pin(true))
+	if err != nil {
+		return err
+	}
+
+	t.Hash = added
+	return nil
+}
+
+// httpStore puts thumbnails in an object store, named by the hash of the
+// file they preview
+type httpStore struct {
+	url    string
+	client *http.Client
+}
+
+// newHTTPStore returns a store putting objects under url
+func newHTTPStore(url string) *httpStore {
+	return &httpStore{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{},
+	}
+}
+
+// put puts the thumbnail in the object store, setting its URL
+func (s *httpStore) put(ctx context.Context, hash string, data []byte, t *Thumbnail) error {
+	url := s.url + "/" + hash + ".jpg"
+
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "image/jpeg")
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("object store returned %s", resp.Status)
+	}
+
+	t.URL = url
+	return nil
+}
+
+// Remove unpins a thumbnail added to IPFS, or deletes it from the object
+// store, e.g. when the file it previews is erased
+func Remove(ctx context.Context, sh *shell.Shell, t *Thumbnail) error {
+	if t.Hash != "" {
+		if err := sh.Unpin(t.Hash); err != nil && !strings.Contains(err.Error(), "not pinned") {
+			return err
+		}
+	}
+
+	if t.URL == "" {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, t.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("object store returned %s", resp.Status)
+	}
+
+	return nil
+}
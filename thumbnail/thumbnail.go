@@ -0,0 +1,212 @@
+/*
+Package thumbnail generates small JPEG previews of images, and of videos
+with ffmpeg, for search results. Thumbnails are added to IPFS, or put in an
+object store when one is configured.
+*/
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ipfs/go-ipfs-api"
+	"image"
+	_ "image/gif" // Register decoder
+	"image/jpeg"
+	_ "image/png" // Register decoder
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maxPixels limits the pixels of images decoded, as small files may claim
+// huge dimensions
+const maxPixels = 50 * 1000 * 1000
+
+// Config configures thumbnail generation
+type Config struct {
+	MaxSize    int           // Longest side of thumbnails in pixels
+	Quality    int           // JPEG quality of thumbnails, 1 to 100
+	MaxImage   uint64        // Largest images read, in bytes
+	FFmpeg     string        // Path of ffmpeg for video thumbnails, empty for images only
+	GatewayURL string        // IPFS gateway ffmpeg reads videos from
+	Timeout    time.Duration // Time to generate and store a thumbnail
+	StoreURL   string        // Object store thumbnails are PUT in instead of IPFS, optional
+}
+
+// Thumbnail is a stored thumbnail
+type Thumbnail struct {
+	Hash   string `json:"hash,omitempty"` // When added to IPFS
+	URL    string `json:"url,omitempty"`  // When put in an object store
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// runFunc runs a command, returning its output
+type runFunc func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// run runs a command, returning its output or its error output in errors
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	return out, err
+}
+
+// Generator generates and stores thumbnails
+type Generator struct {
+	config *Config
+	shell  *shell.Shell
+	store  store
+	run    runFunc
+}
+
+// New returns a generator for configuration, or nil without configuration.
+// Images are read through sh, as are thumbnails added to IPFS.
+func New(config *Config, sh *shell.Shell) *Generator {
+	if config == nil {
+		return nil
+	}
+
+	g := &Generator{
+		config: config,
+		shell:  sh,
+		store:  &ipfsStore{shell: sh},
+		run:    run,
+	}
+
+	if config.StoreURL != "" {
+		g.store = newHTTPStore(config.StoreURL)
+	}
+
+	return g
+}
+
+// imageTypes are the content types of images thumbnails are generated for
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// isVideo returns whether mimetype is that of a video
+func isVideo(mimetype string) bool {
+	return strings.HasPrefix(mimetype, "video/")
+}
+
+// Supported returns whether thumbnails are generated for files of a
+// content type; nil generators support none
+func (g *Generator) Supported(mimetype string) bool {
+	if g == nil {
+		return false
+	}
+
+	return imageTypes[mimetype] || (isVideo(mimetype) && g.config.FFmpeg != "")
+}
+
+// readImage returns the image with hash, read from IPFS
+func (g *Generator) readImage(ctx context.Context, hash string, size uint64) (image.Image, error) {
+	if size > g.config.MaxImage {
+		return nil, fmt.Errorf("image of %d bytes exceeds %d", size, g.config.MaxImage)
+	}
+
+	resp, err := g.shell.Request("cat", hash).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	defer resp.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Output, int64(g.config.MaxImage)))
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(data)
+}
+
+// videoFrame returns a frame near the start of the video with hash,
+// extracted by ffmpeg through the gateway
+func (g *Generator) videoFrame(ctx context.Context, hash string) (image.Image, error) {
+	url := strings.TrimSuffix(g.config.GatewayURL, "/") + "/ipfs/" + hash
+
+	// Seeking before the input only reads from the nearest keyframe
+	output, err := g.run(ctx, g.config.FFmpeg,
+		"-v", "error",
+		"-ss", "1",
+		"-i", url,
+		"-frames:v", "1",
+		"-f", "image2pipe",
+		"-c:v", "png",
+		"-",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg %s: %v", hash, err)
+	}
+
+	return decode(output)
+}
+
+// decode decodes an image, refusing images with too many pixels
+func decode(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("image of %dx%d pixels too large", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Generate generates and stores a thumbnail of the file with hash and
+// content type; images already no larger than a thumbnail get none
+func (g *Generator) Generate(ctx context.Context, hash string, mimetype string, size uint64) (*Thumbnail, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.config.Timeout)
+	defer cancel()
+
+	var (
+		img image.Image
+		err error
+	)
+	if isVideo(mimetype) {
+		img, err = g.videoFrame(ctx, hash)
+	} else {
+		img, err = g.readImage(ctx, hash, size)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	if !isVideo(mimetype) && bounds.Dx() <= g.config.MaxSize && bounds.Dy() <= g.config.MaxSize {
+		// Don't create thumbnails of thumbnails found on IPFS
+		return nil, nil
+	}
+
+	thumb := scale(img, g.config.MaxSize)
+
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, thumb, &jpeg.Options{Quality: g.config.Quality}); err != nil {
+		return nil, err
+	}
+
+	t := &Thumbnail{
+		Width:  thumb.Bounds().Dx(),
+		Height: thumb.Bounds().Dy(),
+	}
+
+	if err := g.store.put(ctx, hash, b.Bytes(), t); err != nil {
+		return nil, fmt.Errorf("storing thumbnail: %v", err)
+	}
+
+	return t, nil
+}
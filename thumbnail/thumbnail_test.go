@@ -0,0 +1,149 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"github.com/ipfs/go-ipfs-api"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, max int
+		wantW     int
+		wantH     int
+	}{
+		{100, 50, 256, 100, 50},
+		{1024, 512, 256, 256, 128},
+		{512, 1024, 256, 128, 256},
+		{10000, 10, 256, 256, 1},
+	}
+
+	for _, test := range tests {
+		w, h := fit(test.w, test.h, test.max)
+		if w != test.wantW || h != test.wantH {
+			t.Errorf("fit(%d, %d, %d) = %d, %d, want %d, %d", test.w, test.h, test.max, w, h, test.wantW, test.wantH)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	// Alternating black and white columns average to grey
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x += 2 {
+		img.SetGray(x, 0, color.Gray{255})
+		img.SetGray(x, 1, color.Gray{255})
+	}
+
+	thumb := scale(img, 2)
+	if thumb.Bounds().Dx() != 2 || thumb.Bounds().Dy() != 1 {
+		t.Fatalf("scale() = %v, want 2x1", thumb.Bounds())
+	}
+	if c := thumb.RGBAAt(0, 0); c.R < 126 || c.R > 128 || c.A != 255 {
+		t.Errorf("scale() pixel = %v, want grey", c)
+	}
+}
+
+// encodePNG returns a w by h PNG image
+func encodePNG(t *testing.T, w, h int) []byte {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// testServer serves file from the IPFS API and stores objects put
+func testServer(t *testing.T, file []byte, stored map[string][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v0/cat":
+			w.Write(file)
+		case r.Method == http.MethodPut:
+			var b bytes.Buffer
+			b.ReadFrom(r.Body)
+			stored[r.URL.Path] = b.Bytes()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		mimetype string
+		file     []byte
+		want     *Thumbnail
+	}{
+		{"image", "image/png", encodePNG(t, 1000, 500), &Thumbnail{Width: 100, Height: 50}},
+		{"small image", "image/png", encodePNG(t, 80, 40), nil},
+		{"video", "video/mp4", nil, &Thumbnail{Width: 100, Height: 75}},
+	}
+
+	for _, test := range tests {
+		stored := make(map[string][]byte)
+		server := testServer(t, test.file, stored)
+
+		g := New(&Config{
+			MaxSize:  100,
+			Quality:  75,
+			MaxImage: 1 << 20,
+			FFmpeg:   "ffmpeg",
+			Timeout:  time.Second,
+			StoreURL: server.URL + "/thumbs/",
+		}, shell.NewShell(server.URL))
+		g.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return encodePNG(t, 640, 480), nil
+		}
+
+		got, err := g.Generate(context.Background(), "hash", test.mimetype, uint64(len(test.file)))
+		server.Close()
+
+		if err != nil {
+			t.Errorf("%s: Generate() error %v", test.name, err)
+			continue
+		}
+
+		if test.want == nil {
+			if got != nil || len(stored) != 0 {
+				t.Errorf("%s: Generate() = %+v, want none", test.name, got)
+			}
+			continue
+		}
+
+		if got == nil || got.Width != test.want.Width || got.Height != test.want.Height || !strings.HasSuffix(got.URL, "/thumbs/hash.jpg") {
+			t.Errorf("%s: Generate() = %+v, want %+v", test.name, got, test.want)
+			continue
+		}
+
+		config, err := jpeg.DecodeConfig(bytes.NewReader(stored["/thumbs/hash.jpg"]))
+		if err != nil || config.Width != got.Width || config.Height != got.Height {
+			t.Errorf("%s: stored %+v, %v, want %dx%d JPEG", test.name, config, err, got.Width, got.Height)
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	images := New(&Config{}, nil)
+	videos := New(&Config{FFmpeg: "ffmpeg"}, nil)
+	var disabled *Generator
+
+	if !images.Supported("image/jpeg") || images.Supported("video/mp4") || images.Supported("text/plain") {
+		t.Error("Supported() without ffmpeg should only support images")
+	}
+	if !videos.Supported("video/mp4") || !videos.Supported("image/gif") {
+		t.Error("Supported() with ffmpeg should support images and videos")
+	}
+	if disabled.Supported("image/jpeg") {
+		t.Error("Supported() of nil generator should support nothing")
+	}
+}
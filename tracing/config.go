@@ -0,0 +1,8 @@
+package tracing
+
+// Config contains user configurable options for tracing
+type Config struct {
+	Endpoint    string  // OTLP/HTTP collector URL, e.g. http://localhost:4318; empty disables tracing
+	SampleRatio float64 // Fraction of traces started here which are recorded
+	ServiceName string  // Name of the service spans are reported for
+}
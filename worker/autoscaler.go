@@ -0,0 +1,348 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+	"sync"
+	"time"
+)
+
+// LoadFunc returns the amount of work waiting to be picked up, e.g. queue depth
+type LoadFunc func() (int, error)
+
+// Utilization thresholds for scaling: workers are only added while most
+// of them are busy, and removed when many are idle
+const (
+	scaleUpUtilization   = 0.8
+	scaleDownUtilization = 0.5
+)
+
+// Autoscaler runs between Min and Max workers, created by Factory, based
+// on the amount of waiting work reported by Load and the fraction of
+// workers busy according to Utilization. Without Utilization, workers are
+// assumed to be busy. While running, it can be paused, throttled to Min
+// workers and its limits changed.
+type Autoscaler struct {
+	Factory     Factory
+	Load        LoadFunc
+	Utilization *Utilization // Optional
+	Min         uint
+	Max         uint
+	Wait        time.Duration // Time to wait between starting workers
+	Interval    time.Duration // Time between scaling decisions
+
+	mu          sync.Mutex
+	paused      bool
+	throttled   bool
+	running     uint
+	utilization float64       // Fraction of workers busy during the last interval
+	wake        chan struct{} // Requests a scaling decision before the next interval
+}
+
+// Stats describes the state of an Autoscaler
+type Stats struct {
+	Running     uint    `json:"running"`
+	Min         uint    `json:"min"`
+	Max         uint    `json:"max"`
+	Paused      bool    `json:"paused"`
+	Throttled   bool    `json:"throttled"`
+	Utilization float64 `json:"utilization"`
+	Done        uint64  `json:"done"` // Works finished, when tracked
+}
+
+// pool keeps track of running workers
+type pool struct {
+	ctx     context.Context
+	errg    *errgroup.Group
+	cancels []context.CancelFunc
+}
+
+// size returns the amount of running workers
+func (p *pool) size() uint {
+	return uint(len(p.cancels))
+}
+
+// start creates and starts a single worker
+func (p *pool) start(factory Factory) error {
+	worker, err := factory()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	p.cancels = append(p.cancels, cancel)
+
+	log.WithFields(log.Fields{
+		"worker": worker,
+		"id":     p.size(),
+	}).Debug("Starting worker")
+
+	p.errg.Go(func() error {
+		err := worker.Work(ctx)
+		if err == context.Canceled && p.ctx.Err() == nil {
+			// Stopped by scaling down
+			return nil
+		}
+		return err
+	})
+
+	return nil
+}
+
+// stop stops the most recently started worker
+func (p *pool) stop() {
+	last := len(p.cancels) - 1
+	log.WithField("id", last+1).Debug("Stopping worker")
+
+	p.cancels[last]()
+	p.cancels = p.cancels[:last]
+}
+
+// scaleTo grows or shrinks the pool to target workers
+func (a *Autoscaler) scaleTo(p *pool, target uint) error {
+	for p.size() > target {
+		p.stop()
+	}
+
+	for p.size() < target {
+		if err := p.start(a.Factory); err != nil {
+			return err
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return nil
+		case <-time.After(a.Wait):
+		}
+	}
+
+	return nil
+}
+
+// wakeup returns the channel requesting a scaling decision; a.mu must be held
+func (a *Autoscaler) wakeup() chan struct{} {
+	if a.wake == nil {
+		a.wake = make(chan struct{}, 1)
+	}
+	return a.wake
+}
+
+// notify requests a scaling decision without waiting for the next interval
+func (a *Autoscaler) notify() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	select {
+	case a.wakeup() <- struct{}{}:
+	default:
+		// Already requested
+	}
+}
+
+// Pause stops all workers, returning their prefetched work, until Resume
+// is called
+func (a *Autoscaler) Pause() {
+	a.mu.Lock()
+	a.paused = true
+	a.mu.Unlock()
+
+	a.notify()
+}
+
+// Resume restarts workers after Pause
+func (a *Autoscaler) Resume() {
+	a.mu.Lock()
+	a.paused = false
+	a.mu.Unlock()
+
+	a.notify()
+}
+
+// Throttle limits the amount of workers to Min while throttled is set,
+// independent of Pause
+func (a *Autoscaler) Throttle(throttled bool) {
+	a.mu.Lock()
+	a.throttled = throttled
+	a.mu.Unlock()
+
+	a.notify()
+}
+
+// SetLimits changes the minimal and maximal amount of workers
+func (a *Autoscaler) SetLimits(min, max uint) error {
+	if max == 0 || min > max {
+		return fmt.Errorf("invalid worker limits: min %d, max %d", min, max)
+	}
+
+	a.mu.Lock()
+	a.Min, a.Max = min, max
+	a.mu.Unlock()
+
+	a.notify()
+
+	return nil
+}
+
+// Stats returns the current state
+func (a *Autoscaler) Stats() Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := Stats{
+		Running:     a.running,
+		Min:         a.Min,
+		Max:         a.Max,
+		Paused:      a.paused,
+		Throttled:   a.throttled,
+		Utilization: a.utilization,
+	}
+	if a.Utilization != nil {
+		stats.Done = a.Utilization.Done()
+	}
+
+	return stats
+}
+
+// measure returns the fraction of size workers which were busy since the
+// previous measurement
+func (a *Autoscaler) measure(size uint) float64 {
+	if a.Utilization == nil {
+		return 1
+	}
+
+	busy := a.Utilization.Take()
+	if size == 0 {
+		// Without workers, waiting work can not wait for idle ones
+		return 1
+	}
+
+	utilization := busy / float64(size)
+	if utilization > 1 {
+		// Workers stopped by scaling down finish their message
+		utilization = 1
+	}
+
+	a.mu.Lock()
+	a.utilization = utilization
+	a.mu.Unlock()
+
+	return utilization
+}
+
+// desired returns the amount of workers to scale to, within the current
+// limits; none while paused and Min while throttled
+func (a *Autoscaler) desired(size uint, pending int, utilization float64) uint {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.paused {
+		return 0
+	}
+	if a.throttled {
+		return a.Min
+	}
+
+	target := a.target(size, pending, utilization)
+	if target < a.Min {
+		return a.Min
+	}
+	if target > a.Max {
+		return a.Max
+	}
+
+	return target
+}
+
+// scale scales the pool to target workers, keeping track of its size
+func (a *Autoscaler) scale(p *pool, target uint) error {
+	err := a.scaleTo(p, target)
+
+	a.mu.Lock()
+	a.running = p.size()
+	a.mu.Unlock()
+
+	return err
+}
+
+// target returns the desired amount of workers given current size, amount
+// of waiting work and utilization; growing fast and shrinking slowly.
+// Waiting work only calls for more workers when those running are busy,
+// and idle workers are removed even when work is waiting.
+func (a *Autoscaler) target(size uint, pending int, utilization float64) uint {
+	step := (a.Max - a.Min) / 10
+	if step == 0 {
+		step = 1
+	}
+
+	switch {
+	case pending > 0 && utilization >= scaleUpUtilization && size < a.Max:
+		grow := step
+		if uint(pending) < grow {
+			grow = uint(pending)
+		}
+		if size+grow > a.Max {
+			return a.Max
+		}
+		return size + grow
+	case (pending == 0 || utilization < scaleDownUtilization) && size > a.Min:
+		return size - 1
+	}
+
+	return size
+}
+
+// Work starts Min workers and scales them until the context is cancelled
+// or one of the workers returns an error
+func (a *Autoscaler) Work(ctx context.Context) error {
+	errg, ctx := errgroup.WithContext(ctx)
+
+	p := &pool{
+		ctx:  ctx,
+		errg: errg,
+	}
+
+	err := a.scale(p, a.desired(0, 0, 0))
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+
+	a.mu.Lock()
+	wake := a.wakeup()
+	a.mu.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Block until all workers are done
+			return errg.Wait()
+		case <-ticker.C:
+		case <-wake:
+		}
+
+		pending, err := a.Load()
+		if err != nil {
+			log.WithError(err).Warn("Error getting load, not scaling")
+			continue
+		}
+
+		utilization := a.measure(p.size())
+
+		target := a.desired(p.size(), pending, utilization)
+		if target != p.size() {
+			log.WithFields(log.Fields{
+				"from":        p.size(),
+				"to":          target,
+				"pending":     pending,
+				"utilization": utilization,
+			}).Info("Scaling workers")
+			if err := a.scale(p, target); err != nil {
+				return err
+			}
+		}
+	}
+}
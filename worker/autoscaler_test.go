@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"testing"
+)
+
+func TestTarget(t *testing.T) {
+	a := &Autoscaler{Min: 2, Max: 22}
+
+	tests := []struct {
+		name        string
+		size        uint
+		pending     int
+		utilization float64
+		target      uint
+	}{
+		{"grow by step", 5, 100, 1, 7},
+		{"grow by pending", 5, 1, 1, 6},
+		{"grow to max", 21, 100, 1, 22},
+		{"at max", 22, 100, 1, 22},
+		{"waiting work for idle workers", 5, 100, 0.6, 5},
+		{"idle workers with waiting work", 5, 100, 0.2, 4},
+		{"no work", 5, 0, 1, 4},
+		{"at min", 2, 0, 0, 2},
+	}
+
+	for _, test := range tests {
+		if target := a.target(test.size, test.pending, test.utilization); target != test.target {
+			t.Errorf("%s: target(%d, %d, %g) = %d, want %d", test.name, test.size, test.pending, test.utilization, target, test.target)
+		}
+	}
+}
+
+func TestDesired(t *testing.T) {
+	a := &Autoscaler{Min: 2, Max: 10}
+
+	if desired := a.desired(0, 0, 0); desired != 2 {
+		t.Errorf("desired() = %d, want Min", desired)
+	}
+
+	a.Pause()
+	if desired := a.desired(5, 100, 1); desired != 0 {
+		t.Errorf("desired() = %d while paused, want 0", desired)
+	}
+
+	a.Resume()
+	a.Throttle(true)
+	if desired := a.desired(5, 100, 1); desired != 2 {
+		t.Errorf("desired() = %d while throttled, want Min", desired)
+	}
+
+	a.Throttle(false)
+	if desired := a.desired(5, 100, 1); desired != 6 {
+		t.Errorf("desired() = %d after throttling, want 6", desired)
+	}
+
+	if err := a.SetLimits(4, 3); err == nil {
+		t.Error("SetLimits() accepted min above max")
+	}
+	if err := a.SetLimits(0, 0); err == nil {
+		t.Error("SetLimits() accepted max 0")
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	a := &Autoscaler{}
+	if u := a.measure(5); u != 1 {
+		t.Errorf("measure() = %g without Utilization, want 1", u)
+	}
+
+	a.Utilization = new(Utilization)
+	if u := a.measure(0); u != 1 {
+		t.Errorf("measure() = %g without workers, want 1", u)
+	}
+}
@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Utilization measures how many workers are busy over time, as opposed to
+// waiting for work
+type Utilization struct {
+	mu    sync.Mutex
+	busy  int           // Workers currently busy
+	total time.Duration // Busy time of all workers since the last Take
+	last  time.Time     // Time of the last change
+	since time.Time     // Time of the last Take
+	done  uint64        // Works finished, for throughput
+}
+
+// advance accumulates busy time up to now; u.mu must be held
+func (u *Utilization) advance(now time.Time) {
+	if u.since.IsZero() {
+		u.since = now
+	}
+	if !u.last.IsZero() {
+		u.total += time.Duration(u.busy) * now.Sub(u.last)
+	}
+	u.last = now
+}
+
+// add changes the amount of busy workers by delta; workers no longer busy
+// have finished their work
+func (u *Utilization) add(delta int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.advance(time.Now())
+	u.busy += delta
+	if delta < 0 {
+		u.done += uint64(-delta)
+	}
+}
+
+// Done returns the amount of works finished by tracked workers, whether
+// they succeeded or not
+func (u *Utilization) Done() uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.done
+}
+
+// take returns the average amount of busy workers since the previous call
+func (u *Utilization) take(now time.Time) float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.advance(now)
+
+	var busy float64
+	if elapsed := now.Sub(u.since); elapsed > 0 {
+		busy = float64(u.total) / float64(elapsed)
+	} else {
+		busy = float64(u.busy)
+	}
+
+	u.total = 0
+	u.since = now
+
+	return busy
+}
+
+// Take returns the average amount of busy workers since the previous call
+func (u *Utilization) Take() float64 {
+	return u.take(time.Now())
+}
+
+// trackedWorker marks itself busy while working
+type trackedWorker struct {
+	Worker
+	utilization *Utilization
+}
+
+// Work performs the work of the wrapped worker, counting it as busy
+func (w *trackedWorker) Work(ctx context.Context) error {
+	w.utilization.add(1)
+	defer w.utilization.add(-1)
+
+	return w.Worker.Work(ctx)
+}
+
+// Track returns a worker counted as busy while w is working, e.g. a
+// worker handling a single message
+func (u *Utilization) Track(w Worker) Worker {
+	return &trackedWorker{
+		Worker:      w,
+		utilization: u,
+	}
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestUtilizationTake(t *testing.T) {
+	start := time.Now()
+	u := &Utilization{
+		since: start,
+		last:  start,
+		busy:  2,
+	}
+
+	// Two workers busy for 1s, then one for 1s
+	u.mu.Lock()
+	u.advance(start.Add(time.Second))
+	u.busy = 1
+	u.mu.Unlock()
+
+	if busy := u.take(start.Add(2 * time.Second)); math.Abs(busy-1.5) > 1e-9 {
+		t.Errorf("take() = %g, want 1.5", busy)
+	}
+
+	// Measurement restarts after take
+	if busy := u.take(start.Add(4 * time.Second)); math.Abs(busy-1) > 1e-9 {
+		t.Errorf("take() = %g, want 1", busy)
+	}
+}
+
+// workerFunc adapts a function to a Worker
+type workerFunc func(context.Context) error
+
+func (f workerFunc) Work(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestTrack(t *testing.T) {
+	u := new(Utilization)
+
+	w := u.Track(workerFunc(func(context.Context) error {
+		u.mu.Lock()
+		busy := u.busy
+		u.mu.Unlock()
+
+		if busy != 1 {
+			t.Errorf("busy = %d while working, want 1", busy)
+		}
+		return nil
+	}))
+
+	if err := w.Work(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if u.busy != 0 {
+		t.Errorf("busy = %d after working, want 0", u.busy)
+	}
+	if done := u.Done(); done != 1 {
+		t.Errorf("Done() = %d after working, want 1", done)
+	}
+}